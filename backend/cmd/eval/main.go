@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hrygo/echomind/internal/app"
+	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/mock"
+	"github.com/hrygo/echomind/pkg/config"
+)
+
+func main() {
+	// Command-specific flags must be defined before ParseCLI calls flag.Parse
+	corpusPath := flag.String("corpus", "eval/corpus.jsonl", "Labelled fixture corpus (JSON Lines)")
+	provider := flag.String("provider", "config", "config (the configured summary route) | mock | replay")
+	replayPath := flag.String("replay", "", "Saved run whose results are replayed (with -provider replay)")
+	name := flag.String("name", "", "Run name (default: the provider)")
+	outDir := flag.String("out", "eval/runs", "Directory for the run file (empty: do not save)")
+	baselinePath := flag.String("baseline", "", "Saved run to compare with; exits 1 on a regression")
+	tolerance := flag.Float64("tolerance", 0.02, "Allowed drop of a quality metric before it counts as a regression")
+	timeout := flag.Duration("timeout", 2*time.Minute, "Time limit per email")
+	verbose := flag.Bool("v", false, "List the cases that were not fully correct")
+
+	cli := app.ParseCLI()
+
+	fixtures, err := service.LoadEvalCorpus(*corpusPath)
+	if err != nil {
+		log.Fatalf("Failed to load corpus: %v", err)
+	}
+
+	analyzer, err := newAnalyzer(*provider, *replayPath, cli.ConfigPath, *timeout)
+	if err != nil {
+		log.Fatalf("Failed to create analyzer: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	run, err := service.RunEval(ctx, analyzer, fixtures)
+	if err != nil {
+		log.Fatalf("Evaluation stopped: %v", err)
+	}
+	run.Provider = *provider
+	run.Corpus = *corpusPath
+	run.Name = *name
+	if run.Name == "" {
+		run.Name = *provider
+	}
+
+	printMetrics(run)
+	if *verbose {
+		printMisses(run)
+	}
+
+	if *outDir != "" {
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			log.Fatalf("Failed to create %s: %v", *outDir, err)
+		}
+		path := filepath.Join(*outDir, run.StartedAt.Format("20060102-150405")+"-"+sanitize(run.Name)+".json")
+		if err := service.SaveEvalRun(path, run); err != nil {
+			log.Fatalf("Failed to save run: %v", err)
+		}
+		fmt.Printf("\nSaved %s\n", path)
+	}
+
+	if *baselinePath != "" {
+		baseline, err := service.LoadEvalRun(*baselinePath)
+		if err != nil {
+			log.Fatalf("Failed to load baseline: %v", err)
+		}
+		if regressed := printComparison(baseline, run, *tolerance); regressed {
+			os.Exit(1)
+		}
+	}
+}
+
+func newAnalyzer(provider, replayPath, configPath string, timeout time.Duration) (service.EvalAnalyzer, error) {
+	switch provider {
+	case "mock":
+		return timeoutAnalyzer{service.ProviderEvalAnalyzer{Provider: mock.NewProvider()}, timeout}, nil
+	case "replay":
+		if replayPath == "" {
+			return nil, fmt.Errorf("-provider replay needs -replay")
+		}
+		run, err := service.LoadEvalRun(replayPath)
+		if err != nil {
+			return nil, err
+		}
+		return service.NewReplayEvalAnalyzer(run), nil
+	case "config":
+		cfg, err := config.Load(configPath)
+		if err != nil {
+			return nil, err
+		}
+		p, err := service.NewAIProvider(&cfg.AI)
+		if err != nil {
+			return nil, err
+		}
+		// The same route the analysis task uses, with its model parameters and failover.
+		return timeoutAnalyzer{service.ProviderEvalAnalyzer{Provider: service.ProviderFor(p, ai.CapabilitySummary)}, timeout}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", provider)
+}
+
+// timeoutAnalyzer limits the time spent on each email.
+type timeoutAnalyzer struct {
+	next    service.EvalAnalyzer
+	timeout time.Duration
+}
+
+func (a timeoutAnalyzer) Analyze(ctx context.Context, fixture service.EvalFixture) (ai.AnalysisResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+	return a.next.Analyze(ctx, fixture)
+}
+
+func printMetrics(run *service.EvalRun) {
+	m := run.Metrics
+	fmt.Printf("Run %q: %d emails, %d errors\n\n", run.Name, m.Cases, m.Errors)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "category accuracy\t%.3f\n", m.CategoryAccuracy)
+	fmt.Fprintf(w, "urgency accuracy\t%.3f\n", m.UrgencyAccuracy)
+	fmt.Fprintf(w, "sentiment F1 (macro)\t%.3f\n", m.SentimentF1)
+	for _, class := range ai.AnalysisSentiments {
+		if f1, ok := m.SentimentF1By[class]; ok {
+			fmt.Fprintf(w, "  %s\t%.3f\n", class, f1)
+		}
+	}
+	fmt.Fprintf(w, "action item recall\t%.3f\n", m.ActionItemRecall)
+	fmt.Fprintf(w, "latency mean / p50 / p95\t%.0f / %.0f / %.0f ms\n", m.LatencyMeanMS, m.LatencyP50MS, m.LatencyP95MS)
+	w.Flush()
+}
+
+func printMisses(run *service.EvalRun) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "case\tcategory\turgency\tsentiment\tmissed action items")
+	for _, c := range run.Results {
+		if c.Error != "" {
+			fmt.Fprintf(w, "%s\terror: %s\n", c.ID, c.Error)
+			continue
+		}
+		sentimentOK := strings.EqualFold(c.Got.Sentiment, c.Expected.Sentiment)
+		if c.CategoryOK && c.UrgencyOK && sentimentOK && len(c.MissedItems) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.ID,
+			mark(c.CategoryOK, c.Got.Category, c.Expected.Category),
+			mark(c.UrgencyOK, c.Got.Urgency, c.Expected.Urgency),
+			mark(sentimentOK, c.Got.Sentiment, c.Expected.Sentiment),
+			strings.Join(c.MissedItems, "; "))
+	}
+	w.Flush()
+}
+
+func mark(ok bool, got, want string) string {
+	if ok {
+		return "ok"
+	}
+	return got + " (want " + want + ")"
+}
+
+// printComparison prints the deltas against the baseline and reports whether any metric regressed.
+func printComparison(baseline, run *service.EvalRun, tolerance float64) bool {
+	fmt.Printf("\nCompared with %q (%s):\n\n", baseline.Name, baseline.StartedAt.Format(time.RFC3339))
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "metric\tbaseline\tthis run\tdelta\t")
+	regressed := false
+	for _, d := range service.CompareEvalRuns(baseline, run, tolerance) {
+		flag := ""
+		if d.Regressed {
+			flag = "REGRESSED"
+			regressed = true
+		}
+		fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%+.3f\t%s\n", d.Metric, d.Base, d.Head, d.Head-d.Base, flag)
+	}
+	w.Flush()
+	return regressed
+}
+
+// sanitize makes a run name safe for a file name.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
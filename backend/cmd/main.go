@@ -13,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/hrygo/echomind/internal/app"
 	"github.com/hrygo/echomind/internal/handler"
+	"github.com/hrygo/echomind/internal/middleware"
 	"github.com/hrygo/echomind/internal/router"
 	"github.com/hrygo/echomind/internal/service"
 )
@@ -42,9 +43,10 @@ func main() {
 	organizationService := service.NewOrganizationService(container.DB)
 	userService := service.NewUserService(container.DB, container.Config.Server.JWT, organizationService)
 	emailService := service.NewEmailService(container.DB)
+	emailService.SetSavedSearches(container.SavedSearchService)
 	accountService := service.NewAccountService(container.DB, &container.Config.Security)
 	insightService := service.NewInsightService(container.DB)
-	aiDraftService := service.NewAIDraftService(container.AIProvider)
+	aiDraftService := service.NewAIDraftService(container.DB, container.AIProvider)
 	defaultIMAPClient := &service.DefaultIMAPClient{}
 	connector := service.NewIMAPConnector(defaultIMAPClient, container.Config)
 	ingestor := service.NewEmailIngestor(container.EmailRepo, container.Logger)
@@ -65,8 +67,24 @@ func main() {
 	}
 
 	chatService := service.NewChatService(container.AIProvider, container.SearchService, emailService)
+	chatService.SetReranker(container.Reranker)
+	chatService.SetRetrievalOptions(service.ChatRetrievalOptions{
+		QueryRewrite:  container.Config.AI.ChatRetrieval.QueryRewrite,
+		MaxHops:       container.Config.AI.ChatRetrieval.MaxHops,
+		ContextTokens: container.Config.AI.ChatRetrieval.ContextTokens,
+	})
+	chatSessionService := service.NewChatSessionService(container.DB, container.AIProvider)
+	chatService.SetSessions(chatSessionService)
 	taskService := service.NewTaskService(container.DB)
 	opportunityService := service.NewOpportunityService(container.DB)
+	chatService.SetToolbox(service.NewChatToolbox(container.DB, service.ChatToolDeps{
+		Search:        container.SearchService,
+		Emails:        emailService,
+		Tasks:         taskService,
+		Actions:       container.ActionService,
+		Opportunities: opportunityService,
+		Drafts:        aiDraftService,
+	}))
 
 	// Initialize handlers
 	accountHandler := handler.NewAccountHandler(accountService)
@@ -74,15 +92,23 @@ func main() {
 	emailHandler := handler.NewEmailHandler(emailService)
 	authHandler := handler.NewAuthHandler(userService)
 	insightHandler := handler.NewInsightHandlerWithServices(insightService, taskService, emailService)
-	aiDraftHandler := handler.NewAIDraftHandler(aiDraftService, emailService)
+	aiDraftHandler := handler.NewAIDraftHandler(aiDraftService)
 	searchHandler := handler.NewSearchHandler(container.SearchService, container.SearchClusteringService, container.SearchSummaryService, container.Logger)
 	healthHandler := handler.NewHealthHandler(container.DB)
 	orgHandler := handler.NewOrganizationHandler(organizationService)
 	chatHandler := handler.NewChatHandler(chatService)
+	chatSessionHandler := handler.NewChatSessionHandler(chatService, chatSessionService)
+	chatToolActionHandler := handler.NewChatToolActionHandler(chatService)
 	taskHandler := handler.NewTaskHandler(taskService)
 	contextHandler := handler.NewContextHandler(container.ContextService)
+	contextSuggestionHandler := handler.NewContextSuggestionHandler(container.ContextSuggestionService)
 	actionHandler := handler.NewActionHandler(container.ActionService)
 	opportunityHandler := handler.NewOpportunityHandler(opportunityService)
+	savedSearchHandler := handler.NewSavedSearchHandler(container.SavedSearchService)
+	usageHandler := handler.NewUsageHandler(container.UsageService)
+	promptHandler := handler.NewPromptHandler(container.PromptService)
+	redactionHandler := handler.NewRedactionHandler(container.RedactionService)
+	translationHandler := handler.NewTranslationHandler(container.TranslationService)
 
 	// Setup Router and Middleware
 	r := gin.Default()
@@ -90,24 +116,32 @@ func main() {
 
 	// Register routes
 	handlers := &router.Handlers{
-		Health:      healthHandler,
-		Auth:        authHandler,
-		Org:         orgHandler,
-		Account:     accountHandler,
-		Sync:        syncHandler,
-		Email:       emailHandler,
-		Insight:     insightHandler,
-		AIDraft:     aiDraftHandler,
-		Search:      searchHandler,
-		Chat:        chatHandler,
-		Task:        taskHandler,
-		Context:     contextHandler,
-		Action:      actionHandler,
-		Opportunity: opportunityHandler,
+		Health:            healthHandler,
+		Auth:              authHandler,
+		Org:               orgHandler,
+		Account:           accountHandler,
+		Sync:              syncHandler,
+		Email:             emailHandler,
+		Insight:           insightHandler,
+		AIDraft:           aiDraftHandler,
+		Search:            searchHandler,
+		Chat:              chatHandler,
+		Task:              taskHandler,
+		Context:           contextHandler,
+		ContextSuggestion: contextSuggestionHandler,
+		Action:            actionHandler,
+		Opportunity:       opportunityHandler,
+		SavedSearch:       savedSearchHandler,
+		ChatSession:       chatSessionHandler,
+		ChatToolAction:    chatToolActionHandler,
+		Usage:             usageHandler,
+		Prompt:            promptHandler,
+		Redaction:         redactionHandler,
+		Translation:       translationHandler,
 	}
 
 	authMiddleware := router.SetupAuthMiddleware(container.Config.Server.JWT)
-	router.SetupRoutes(r, handlers, authMiddleware)
+	router.SetupRoutes(r, handlers, authMiddleware, middleware.UserScope(container.UsageService.Scope, container.PromptService.Scope, container.RedactionService.Scope, container.TranslationService.Scope))
 
 	port := container.Config.Server.Port
 
@@ -2,52 +2,127 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/hrygo/echomind/internal/app"
-	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/internal/tasks"
 	"github.com/hrygo/echomind/pkg/logger"
 )
 
 func main() {
-	// Parse CLI configuration
+	// Command-specific flags must be defined before ParseCLI calls flag.Parse
+	userID := flag.String("user", "", "Only reindex this user's emails (UUID)")
+	since := flag.String("since", "", "Only emails dated on or after this day (YYYY-MM-DD)")
+	until := flag.String("until", "", "Only emails dated before this day (YYYY-MM-DD)")
+	missingOnly := flag.Bool("missing-only", false, "Only emails without embeddings for the current model")
+	dryRun := flag.Bool("dry-run", false, "Count the matching emails without embedding them")
+	batchSize := flag.Int("batch", 100, "Emails per batch (one checkpoint per batch)")
+	workers := flag.Int("workers", 4, "Concurrent embedding requests")
+	ratePerSecond := flag.Float64("rate", 0, "Max embedding requests per second (0 = unlimited)")
+	jobID := flag.String("job", "", "Checkpoint name (default: derived from the filters)")
+	restart := flag.Bool("restart", false, "Ignore the existing checkpoint and start from the beginning")
+	async := flag.Bool("async", false, "Enqueue the job for the worker instead of running it here")
+
 	cli := app.ParseCLI()
 
-	// Initialize application container
+	payload := tasks.ReindexPayload{
+		JobID:         *jobID,
+		MissingOnly:   *missingOnly,
+		DryRun:        *dryRun,
+		Restart:       *restart,
+		BatchSize:     *batchSize,
+		Workers:       *workers,
+		RatePerSecond: *ratePerSecond,
+	}
+	if *userID != "" {
+		id, err := uuid.Parse(*userID)
+		if err != nil {
+			log.Fatalf("Invalid -user: %v", err)
+		}
+		payload.UserID = &id
+	}
+	payload.Since = parseDay("since", *since)
+	payload.Until = parseDay("until", *until)
+
 	container, err := app.NewContainer(cli.ConfigPath, cli.IsProduction)
 	if err != nil {
 		log.Fatalf("Failed to initialize application: %v", err)
 	}
 	defer container.Close()
 
-	// Fetch all emails
-	var emails []model.Email
-	if err := container.DB.Select("id, subject, snippet, body_text").Find(&emails).Error; err != nil {
-		container.Logger.Fatal("Failed to fetch emails", logger.Error(err))
-	}
-
-	container.Logger.Info("Starting reindex process",
-		logger.Int("email_count", len(emails)))
-
-	ctx := context.Background()
-	success := 0
-	failed := 0
-
-	for _, email := range emails {
-		container.Logger.Info("Reindexing email",
-			logger.String("email_id", email.ID.String()),
-			logger.String("subject", email.Subject))
-		if err := container.SearchService.GenerateAndSaveEmbedding(ctx, &email, container.ChunkSize()); err != nil {
-			container.Logger.Warn("Failed to reindex email",
-				logger.String("email_id", email.ID.String()),
-				logger.Error(err))
-			failed++
-		} else {
-			success++
+	if *async {
+		// Restart when the job is created, so that the task's retries resume from its checkpoint.
+		if payload.Restart && !payload.DryRun {
+			if err := container.ReindexService.ResetCheckpoint(context.Background(), payload); err != nil {
+				container.Logger.Fatal("Failed to reset reindex checkpoint", logger.Error(err))
+			}
+			payload.Restart = false
 		}
+		task, err := tasks.NewEmailReindexTask(payload)
+		if err != nil {
+			container.Logger.Fatal("Failed to create reindex task", logger.Error(err))
+		}
+		info, err := container.AsynqClient.Enqueue(task)
+		if err != nil {
+			container.Logger.Fatal("Failed to enqueue reindex task", logger.Error(err))
+		}
+		container.Logger.Info("Reindex job enqueued",
+			logger.String("job_id", service.ReindexJobID(payload)),
+			logger.String("task_id", info.ID))
+		return
 	}
 
+	// Stop cleanly on Ctrl-C; the last completed batch stays checkpointed.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	progress, err := container.ReindexService.Run(ctx, payload, func(p service.ReindexProgress) {
+		container.Logger.Info("Reindex progress",
+			logger.String("job_id", p.JobID),
+			logger.Int64("processed", p.Processed),
+			logger.Int64("matched", p.Matched),
+			logger.Int64("embedded", p.Embedded),
+			logger.Int64("failed", p.Failed),
+			logger.Duration("elapsed", p.Elapsed))
+	})
+	if err != nil {
+		container.Logger.Fatal("Reindex stopped; rerun the same command to resume",
+			logger.String("job_id", progress.JobID),
+			logger.Int64("processed", progress.Processed),
+			logger.Error(err))
+	}
+
+	if *dryRun {
+		container.Logger.Info("Dry run",
+			logger.String("job_id", progress.JobID),
+			logger.Int64("matched", progress.Matched),
+			logger.Int64("remaining", progress.Remaining),
+			logger.Bool("resumable", progress.Resumed))
+		return
+	}
 	container.Logger.Info("Reindex complete",
-		logger.Int("success", success),
-		logger.Int("failed", failed))
+		logger.String("job_id", progress.JobID),
+		logger.Int64("processed", progress.Processed),
+		logger.Int64("embedded", progress.Embedded),
+		logger.Int64("failed", progress.Failed),
+		logger.Bool("resumed", progress.Resumed),
+		logger.Duration("elapsed", progress.Elapsed))
+}
+
+func parseDay(name, value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	day, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Fatalf("Invalid -%s: %v", name, err)
+	}
+	return &day
 }
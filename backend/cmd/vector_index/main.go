@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hrygo/echomind/internal/app"
+	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/pkg/database"
+	"github.com/hrygo/echomind/pkg/logger"
+)
+
+func main() {
+	// Command-specific flags must be defined before ParseCLI calls flag.Parse
+	action := flag.String("action", "status", "status | rebuild | reindex | drop | bench")
+	indexType := flag.String("type", "", "Index type for rebuild: hnsw | ivfflat (default: config)")
+	m := flag.Int("m", 0, "HNSW m (default: config)")
+	efConstruction := flag.Int("ef-construction", 0, "HNSW ef_construction (default: config)")
+	lists := flag.Int("lists", 0, "IVFFlat lists (default: config)")
+	efSearch := flag.String("ef-search", "40,80,160", "Comma-separated hnsw.ef_search values to benchmark")
+	probes := flag.String("probes", "", "Comma-separated ivfflat.probes values to benchmark")
+	samples := flag.Int("samples", 100, "Number of sampled query vectors for bench")
+	k := flag.Int("k", 10, "Neighbours per query for bench")
+	table := flag.String("table", "", "Embeddings table to manage (default: the active embedding space)")
+
+	cli := app.ParseCLI()
+
+	container, err := app.NewContainer(cli.ConfigPath, cli.IsProduction)
+	if err != nil {
+		log.Fatalf("Failed to initialize application: %v", err)
+	}
+	defer container.Close()
+
+	ctx := context.Background()
+	if *table == "" {
+		*table = container.EmbeddingSpaces.ActiveTable(ctx)
+	}
+	manager := service.NewVectorIndexManager(container.DB).ForTable(*table)
+
+	switch *action {
+	case "status":
+		indexes, err := manager.List(ctx)
+		if err != nil {
+			container.Logger.Fatal("Failed to list vector indexes", logger.Error(err))
+		}
+		if len(indexes) == 0 {
+			container.Logger.Warn("No ANN index; semantic search uses sequential scans", logger.String("table", *table))
+		}
+		for _, idx := range indexes {
+			container.Logger.Info("Vector index",
+				logger.String("name", idx.Name),
+				logger.String("definition", idx.Definition),
+				logger.Int64("size_bytes", idx.SizeBytes),
+				logger.Bool("valid", idx.Valid))
+		}
+
+	case "rebuild":
+		cfg := container.Config.Database.VectorIndex
+		opts := database.VectorIndexOptions{
+			Type:           database.VectorIndexType(firstNonEmpty(*indexType, cfg.Type)),
+			M:              firstPositive(*m, cfg.M),
+			EfConstruction: firstPositive(*efConstruction, cfg.EfConstruction),
+			Lists:          firstPositive(*lists, cfg.Lists),
+		}.WithDefaults()
+		container.Logger.Info("Rebuilding vector index (concurrently)",
+			logger.String("type", string(opts.Type)),
+			logger.Int("m", opts.M),
+			logger.Int("ef_construction", opts.EfConstruction),
+			logger.Int("lists", opts.Lists))
+		if err := manager.Rebuild(ctx, opts); err != nil {
+			container.Logger.Fatal("Rebuild failed", logger.Error(err))
+		}
+		container.Logger.Info("Vector index rebuilt", logger.String("index", manager.IndexName()))
+
+	case "reindex":
+		if err := manager.Reindex(ctx); err != nil {
+			container.Logger.Fatal("Reindex failed", logger.Error(err))
+		}
+		container.Logger.Info("Vector index reindexed", logger.String("index", manager.IndexName()))
+
+	case "drop":
+		if err := manager.Drop(ctx); err != nil {
+			container.Logger.Fatal("Drop failed", logger.Error(err))
+		}
+		container.Logger.Info("Vector index dropped", logger.String("index", manager.IndexName()))
+
+	case "bench":
+		var tunings []service.ANNTuning
+		for _, v := range parseInts(*efSearch) {
+			tunings = append(tunings, service.ANNTuning{EfSearch: v})
+		}
+		for _, v := range parseInts(*probes) {
+			tunings = append(tunings, service.ANNTuning{Probes: v})
+		}
+		if len(tunings) == 0 {
+			tunings = append(tunings, service.ANNTuning{})
+		}
+
+		for _, tuning := range tunings {
+			report, err := manager.MeasureRecall(ctx, *samples, *k, tuning)
+			if err != nil {
+				container.Logger.Fatal("Benchmark failed", logger.Error(err))
+			}
+			container.Logger.Info("Recall benchmark",
+				logger.Int("ef_search", tuning.EfSearch),
+				logger.Int("probes", tuning.Probes),
+				logger.Int("k", report.K),
+				logger.Int("queries", report.Queries),
+				logger.Float64("mean_recall", report.MeanRecall),
+				logger.Float64("min_recall", report.MinRecall),
+				logger.Duration("ann_p50", report.ANNLatencyP50),
+				logger.Duration("ann_p95", report.ANNLatencyP95),
+				logger.Duration("exact_p50", report.ExactLatency))
+		}
+
+	default:
+		log.Fatalf("Unknown action %q (use status, rebuild, reindex, drop or bench)", *action)
+	}
+}
+
+func parseInts(csv string) []int {
+	var values []int
+	for _, part := range strings.Split(csv, ",") {
+		if v, err := strconv.Atoi(strings.TrimSpace(part)); err == nil && v > 0 {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstPositive(values ...int) int {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"os/signal"
@@ -64,6 +65,7 @@ func main() {
 
 	// Register task handlers
 	mux := asynq.NewServeMux()
+	mux.Use(tasks.UserScope(container.UsageService.Scope, container.PromptService.Scope, container.RedactionService.Scope, container.TranslationService.Scope))
 	mux.HandleFunc(tasks.TypeEmailAnalyze, func(ctx context.Context, t *asynq.Task) error {
 		return tasks.HandleEmailAnalyzeTask(
 			ctx, t,
@@ -83,6 +85,78 @@ func main() {
 		)
 	})
 
+	mux.HandleFunc(tasks.TypeContextSuggest, func(ctx context.Context, t *asynq.Task) error {
+		return tasks.HandleContextSuggestTask(
+			ctx, t,
+			container.DB,
+			container.ContextSuggestionService,
+			container.Logger,
+		)
+	})
+
+	mux.HandleFunc(tasks.TypeEmailReindex, func(ctx context.Context, t *asynq.Task) error {
+		return tasks.HandleEmailReindexTask(
+			ctx, t,
+			container.ReindexService,
+			container.Logger,
+		)
+	})
+
+	mux.HandleFunc(tasks.TypeSavedSearchEvaluate, func(ctx context.Context, t *asynq.Task) error {
+		return tasks.HandleSavedSearchEvaluateTask(
+			ctx, t,
+			container.SavedSearchService,
+			container.Logger,
+		)
+	})
+
+	mux.HandleFunc(tasks.TypeEmbeddingMigrate, func(ctx context.Context, t *asynq.Task) error {
+		return tasks.HandleEmbeddingMigrateTask(
+			ctx, t,
+			container.SearchService,
+			container.Logger,
+		)
+	})
+
+	// Re-embed into the configured model's space if search is still served by another one
+	if needed, err := container.SearchService.EmbeddingMigrationNeeded(context.Background()); err != nil {
+		container.Logger.Warn("Failed to check embedding spaces", logger.Error(err))
+	} else if needed {
+		migrateTask, err := tasks.NewEmbeddingMigrateTask(100, container.ChunkSize())
+		if err != nil {
+			container.Logger.Fatal("Failed to create embedding migration task", logger.Error(err))
+		}
+		if _, err := container.AsynqClient.Enqueue(migrateTask); err != nil && !errors.Is(err, asynq.ErrDuplicateTask) {
+			container.Logger.Warn("Failed to enqueue embedding migration", logger.Error(err))
+		} else {
+			container.Logger.Info("Embedding migration queued")
+		}
+	}
+
+	// Setup periodic tasks
+	redisOpt := asynq.RedisClientOpt{
+		Addr:     container.Config.Redis.Addr,
+		Password: container.Config.Redis.Password,
+		DB:       container.Config.Redis.DB,
+	}
+	scheduler := asynq.NewScheduler(redisOpt, &asynq.SchedulerOpts{
+		Logger: &LoggerAdapter{logger: container.Logger},
+	})
+	suggestTask, err := tasks.NewContextSuggestTask(nil)
+	if err != nil {
+		container.Logger.Fatal("Failed to create context suggestion task", logger.Error(err))
+	}
+	if _, err := scheduler.Register(container.ContextSuggestionSchedule(), suggestTask); err != nil {
+		container.Logger.Fatal("Failed to schedule context suggestions", logger.Error(err))
+	}
+	if _, err := scheduler.Register(container.SavedSearchSchedule(), tasks.NewSavedSearchEvaluateTask()); err != nil {
+		container.Logger.Fatal("Failed to schedule saved search alerts", logger.Error(err))
+	}
+	if err := scheduler.Start(); err != nil {
+		container.Logger.Fatal("Failed to start scheduler", logger.Error(err))
+	}
+	defer scheduler.Shutdown()
+
 	container.Logger.Info("Starting worker...")
 
 	// Run worker in a goroutine
@@ -26,17 +26,39 @@ type JWTConfig struct {
 }
 
 type DatabaseConfig struct {
-	DSN string `mapstructure:"dsn"`
+	DSN         string            `mapstructure:"dsn"`
+	VectorIndex VectorIndexConfig `mapstructure:"vector_index"`
+}
+
+// VectorIndexConfig configures the ANN index on email_embeddings and its query-time defaults.
+type VectorIndexConfig struct {
+	Type           string `mapstructure:"type"`            // "hnsw" (default) | "ivfflat"
+	M              int    `mapstructure:"m"`               // HNSW max connections per layer (default 16)
+	EfConstruction int    `mapstructure:"ef_construction"` // HNSW build candidate list size (default 64)
+	Lists          int    `mapstructure:"lists"`           // IVFFlat list count (default 100)
+	EfSearch       int    `mapstructure:"ef_search"`       // HNSW query candidate list size (0 = server default)
+	Probes         int    `mapstructure:"probes"`          // IVFFlat lists scanned per query (0 = server default)
 }
 
 type WorkerConfig struct {
-	Concurrency int `mapstructure:"concurrency"` // Number of concurrent workers
+	Concurrency               int    `mapstructure:"concurrency"`                 // Number of concurrent workers
+	ContextSuggestionSchedule string `mapstructure:"context_suggestion_schedule"` // Cron spec for context suggestion sweeps, e.g. "@daily"
+	SavedSearchSchedule       string `mapstructure:"saved_search_schedule"`       // Cron spec for saved search alerts, e.g. "@every 10m"
 }
 
 type RedisConfig struct {
-	Addr     string `mapstructure:"addr"`
-	Password string `mapstructure:"password"`
-	DB       int    `mapstructure:"db"`
+	Addr        string            `mapstructure:"addr"`
+	Password    string            `mapstructure:"password"`
+	DB          int               `mapstructure:"db"`
+	SearchCache SearchCacheConfig `mapstructure:"search_cache"`
+}
+
+// SearchCacheConfig tunes the Redis-backed search cache.
+type SearchCacheConfig struct {
+	TTLMinutes          int     `mapstructure:"ttl_minutes"`          // Search results (default 30)
+	EmbeddingTTLHours   int     `mapstructure:"embedding_ttl_hours"`  // Query embeddings (default 24)
+	SimilarityThreshold float64 `mapstructure:"similarity_threshold"` // Reuse results of queries at least this similar; 0 disables
+	MaxSimilarEntries   int     `mapstructure:"max_similar_entries"`  // Recent queries compared per user and filter set (default 50)
 }
 
 type AIConfig struct {
@@ -44,25 +66,103 @@ type AIConfig struct {
 	Providers      map[string]ProviderConfig `mapstructure:"providers"`
 	Prompts        PromptConfig              `mapstructure:"prompts"`
 	ChunkSize      int                       `mapstructure:"chunk_size"` // Max tokens per chunk for RAG processing
+	Reranker       string                    `mapstructure:"reranker"`   // "lexical" (default) | "llm" | "none"
+	ChatRetrieval  ChatRetrievalConfig       `mapstructure:"chat_retrieval"`
+	Resilience     ResilienceConfig          `mapstructure:"resilience"`
+	Usage          UsageConfig               `mapstructure:"usage"`
+	Redaction      RedactionConfig           `mapstructure:"redaction"`
+}
+
+// RedactionConfig is the default PII redaction policy for AI calls; organizations can set their own.
+type RedactionConfig struct {
+	Enabled *bool    `mapstructure:"enabled"` // Default true
+	Kinds   []string `mapstructure:"kinds"`   // email, phone, national_id, card, iban; default all
+}
+
+// UsageConfig sets token quotas and the prices used to report AI spend.
+type UsageConfig struct {
+	Quotas UsageQuotaConfig `mapstructure:"quotas"`
+	Prices []ModelPrice     `mapstructure:"prices"`
+}
+
+// UsageQuotaConfig limits prompt plus completion tokens per UTC day and calendar month; 0 is unlimited.
+// Embeddings count towards usage but are never refused.
+type UsageQuotaConfig struct {
+	UserDailyTokens   int64 `mapstructure:"user_daily_tokens"`
+	UserMonthlyTokens int64 `mapstructure:"user_monthly_tokens"`
+	OrgDailyTokens    int64 `mapstructure:"org_daily_tokens"`
+	OrgMonthlyTokens  int64 `mapstructure:"org_monthly_tokens"`
+}
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	Model          string  `mapstructure:"model"`
+	PromptPerM     float64 `mapstructure:"prompt_per_million"`
+	CompletionPerM float64 `mapstructure:"completion_per_million"`
+}
+
+// ResilienceConfig tunes retries and circuit breaking for chat providers; see chat_fallbacks.
+type ResilienceConfig struct {
+	MaxRetries             int `mapstructure:"max_retries"`              // Retries per provider for transient errors; -1 disables (default 2)
+	BaseDelayMs            int `mapstructure:"base_delay_ms"`            // First backoff, doubled per retry with jitter (default 500)
+	MaxDelayMs             int `mapstructure:"max_delay_ms"`             // Backoff cap (default 8000)
+	BreakerThreshold       int `mapstructure:"breaker_threshold"`        // Consecutive failures that take a provider out of rotation (default 5)
+	BreakerCooldownSeconds int `mapstructure:"breaker_cooldown_seconds"` // Time out of rotation before a trial call (default 30)
+}
+
+// ChatRetrievalConfig tunes how chat questions are turned into mailbox searches.
+type ChatRetrievalConfig struct {
+	QueryRewrite  bool `mapstructure:"query_rewrite"`  // Rewrite follow-ups into standalone queries with the chat model
+	MaxHops       int  `mapstructure:"max_hops"`       // Retrieval rounds per question (default 2)
+	ContextTokens int  `mapstructure:"context_tokens"` // Token budget for retrieved passages (default 1500)
 }
 
 type ServiceRoute struct {
 	Chat      string `mapstructure:"chat"`
 	Embedding string `mapstructure:"embedding"`
+	// EmbeddingPrevious keeps answering queries from the old embedding space while the
+	// background migration re-embeds mail with the new model.
+	EmbeddingPrevious string `mapstructure:"embedding_previous"`
+	// ChatFallbacks are tried in order when the chat provider fails or is out of rotation.
+	// Embeddings never fall back: vectors from another model are not comparable.
+	ChatFallbacks []string `mapstructure:"chat_fallbacks"`
+
+	// Providers for individual capabilities; empty ones use Chat.
+	Summary       string `mapstructure:"summary"`
+	Classify      string `mapstructure:"classify"`
+	Sentiment     string `mapstructure:"sentiment"`
+	Draft         string `mapstructure:"draft"`
+	SearchSummary string `mapstructure:"search_summary"`
+	Rerank        string `mapstructure:"rerank"`
+	Translate     string `mapstructure:"translate"`
+	// Params overrides model parameters per capability ("chat", "summary", "classify", "sentiment",
+	// "draft", "search_summary", "rerank", "translate").
+	Params map[string]RouteParams `mapstructure:"params"`
+}
+
+// RouteParams are model parameters for one capability. Unset fields keep the provider's settings.
+type RouteParams struct {
+	Temperature *float64 `mapstructure:"temperature"`
+	MaxTokens   int      `mapstructure:"max_tokens"`
+	JSONMode    *bool    `mapstructure:"json_mode"` // false for servers that reject native JSON output
 }
 
 type ProviderConfig struct {
-	Protocol string           `mapstructure:"protocol"` // "openai" | "gemini"
+	Protocol string           `mapstructure:"protocol"` // "openai" | "gemini" | "anthropic" | "ollama" | "llamacpp"
 	Settings ProviderSettings `mapstructure:"settings"`
 }
 
 type ProviderSettings map[string]interface{}
 
+// PromptConfig holds the default version of every prompt. Prompts are text/template templates;
+// organizations can add their own versions through the API.
 type PromptConfig struct {
-	Summary    string `mapstructure:"summary"`
-	Classify   string `mapstructure:"classify"`
-	Sentiment  string `mapstructure:"sentiment"`
-	DraftReply string `mapstructure:"draft_reply"`
+	Summary       string `mapstructure:"summary"`
+	Classify      string `mapstructure:"classify"`
+	Sentiment     string `mapstructure:"sentiment"`
+	DraftReply    string `mapstructure:"draft_reply"`
+	ChatSystem    string `mapstructure:"chat_system"`    // Optional; Copilot persona and widget instructions
+	SearchSummary string `mapstructure:"search_summary"` // Optional; instructions for search result summaries
 }
 
 // TelemetryConfig defines OpenTelemetry configuration
@@ -18,18 +18,27 @@ import (
 // It extends the bootstrap.App with commonly used services
 type Container struct {
 	*bootstrap.App
-	AIProvider              ai.AIProvider
-	Embedder                ai.EmbeddingProvider
-	SearchService           *service.SearchService
-	SearchClusteringService *service.SearchClusteringService
-	SearchSummaryService    *service.SearchSummaryService
-	ContextService          *service.ContextService
-	Summarizer              *service.SummaryService
-	ActionService           *service.ActionService
-	SyncService             *service.SyncService // Add SyncService
-	EmailRepo               repository.EmailRepository
-	AccountRepo             repository.AccountRepository
-	EventBus                *bus.Bus
+	AIProvider               ai.AIProvider
+	Embedder                 ai.EmbeddingProvider
+	SearchService            *service.SearchService
+	EmbeddingSpaces          *service.EmbeddingSpaceService
+	ReindexService           *service.ReindexService
+	SearchClusteringService  *service.SearchClusteringService
+	SearchSummaryService     *service.SearchSummaryService
+	Reranker                 service.Reranker // nil when reranking is disabled
+	ContextService           *service.ContextService
+	ContextSuggestionService *service.ContextSuggestionService
+	Summarizer               *service.SummaryService
+	ActionService            *service.ActionService
+	SyncService              *service.SyncService // Add SyncService
+	EmailRepo                repository.EmailRepository
+	AccountRepo              repository.AccountRepository
+	EventBus                 *bus.Bus
+	SavedSearchService       *service.SavedSearchService
+	UsageService             *service.UsageService
+	PromptService            *service.PromptService
+	RedactionService         *service.RedactionService
+	TranslationService       *service.TranslationService
 }
 
 // NewContainer creates a new dependency injection container
@@ -57,22 +66,70 @@ func NewContainer(configPath string, isProduction bool) (*Container, error) {
 
 	// 4. Create Redis client for caching
 	var searchCache *service.SearchCache
+	var redisClient *redis.Client
 	if app.Config.Redis.Addr != "" {
-		redisClient := redis.NewClient(&redis.Options{
+		redisClient = redis.NewClient(&redis.Options{
 			Addr:     app.Config.Redis.Addr,
 			Password: app.Config.Redis.Password,
 			DB:       app.Config.Redis.DB,
 		})
-		searchCache = service.NewSearchCache(redisClient, 30*time.Minute)
+		cacheCfg := app.Config.Redis.SearchCache
+		searchCache = service.NewSearchCache(redisClient, time.Duration(cacheCfg.TTLMinutes)*time.Minute)
+		searchCache.SetSemanticOptions(service.SemanticCacheOptions{
+			EmbeddingTTL:        time.Duration(cacheCfg.EmbeddingTTLHours) * time.Hour,
+			SimilarityThreshold: cacheCfg.SimilarityThreshold,
+			MaxSimilarEntries:   cacheCfg.MaxSimilarEntries,
+		})
 	}
 
 	// 5. Create common services
 	searchService := service.NewSearchService(app.DB, embedder, searchCache)
+	searchService.SetANNTuning(service.ANNTuning{
+		EfSearch: app.Config.Database.VectorIndex.EfSearch,
+		Probes:   app.Config.Database.VectorIndex.Probes,
+	})
+	embeddingSpaces := service.NewEmbeddingSpaceService(app.DB)
+	var queryEmbedders []ai.EmbeddingProvider
+	if previous := app.Config.AI.ActiveServices.EmbeddingPrevious; previous != "" {
+		previousEmbedder, err := service.NewEmbeddingProvider(&app.Config.AI, previous)
+		if err != nil {
+			app.Close()
+			return nil, err
+		}
+		queryEmbedders = append(queryEmbedders, previousEmbedder)
+		// Vectors stored before embedding spaces existed came from the previous model
+		embeddingSpaces.SetLegacyModel(ai.EmbeddingModelName(previousEmbedder))
+	}
+	searchService.SetEmbeddingSpaces(embeddingSpaces, queryEmbedders...)
+	var reindexCheckpoints service.ReindexCheckpointStore = service.NewDBReindexCheckpointStore(app.DB)
+	if redisClient != nil {
+		reindexCheckpoints = service.NewRedisReindexCheckpointStore(redisClient)
+	}
+	reindexService := service.NewReindexService(app.DB, searchService, reindexCheckpoints, app.Config.AI.ChunkSize, app.Logger)
 	searchClusteringService := service.NewSearchClusteringService()
-	searchSummaryService := service.NewSearchSummaryService(aiProvider)
+	searchSummaryService := service.NewSearchSummaryService(service.ProviderFor(aiProvider, ai.CapabilitySearchSummary))
+	translationService := service.NewTranslationService(app.DB, service.ProviderFor(aiProvider, ai.CapabilityTranslate))
+	searchService.SetQueryTranslator(translationService)
+	reranker, err := service.NewReranker(app.Config.AI.Reranker, service.ProviderFor(aiProvider, ai.CapabilityRerank))
+	if err != nil {
+		app.Close()
+		return nil, fmt.Errorf("failed to create reranker: %w", err)
+	}
 	contextService := service.NewContextService(app.DB)
+	contextSuggestionService := service.NewContextSuggestionService(app.DB, contextService, service.DefaultContextSuggestionOptions())
 	summarizer := service.NewSummaryService(aiProvider)
 	actionService := service.NewActionService(app.DB)
+	usageService := service.NewUsageService(app.DB, app.Config.AI.Usage)
+	promptService := service.NewPromptService(app.DB, app.Config.AI.Prompts)
+	redactionService, err := service.NewRedactionService(app.DB, app.Config.AI.Redaction)
+	if err != nil {
+		app.Close()
+		return nil, fmt.Errorf("failed to create redaction service: %w", err)
+	}
+	// Calls made outside a user's scope use the default policy and are audited without a user.
+	if composite, ok := aiProvider.(*service.CompositeProvider); ok {
+		composite.SetRedaction(redactionService)
+	}
 
 	// 6. Initialize Event Bus and Listeners
 	eventBus := bus.New()
@@ -82,6 +139,9 @@ func NewContainer(configPath string, isProduction bool) (*Container, error) {
 
 	eventBus.Subscribe(event.EmailSyncedEventName, analysisListener)
 	eventBus.Subscribe(event.EmailSyncedEventName, contactListener)
+	eventBus.Subscribe(event.SavedSearchMatchedEventName, listener.NewSavedSearchAlertListener(app.Logger))
+
+	savedSearchService := service.NewSavedSearchService(app.DB, searchService, eventBus)
 
 	// Create SyncService with dependencies
 	emailRepo := repository.NewEmailRepository(app.DB)
@@ -102,19 +162,28 @@ func NewContainer(configPath string, isProduction bool) (*Container, error) {
 	)
 
 	return &Container{
-		App:                     app,
-		AIProvider:              aiProvider,
-		Embedder:                embedder,
-		SearchService:           searchService,
-		SearchClusteringService: searchClusteringService,
-		SearchSummaryService:    searchSummaryService,
-		ContextService:          contextService,
-		Summarizer:              summarizer,
-		ActionService:           actionService,
-		SyncService:             syncService, // Add SyncService
-		EmailRepo:               emailRepo,
-		AccountRepo:             accountRepo,
-		EventBus:                eventBus,
+		App:                      app,
+		AIProvider:               aiProvider,
+		Embedder:                 embedder,
+		SearchService:            searchService,
+		EmbeddingSpaces:          embeddingSpaces,
+		ReindexService:           reindexService,
+		SearchClusteringService:  searchClusteringService,
+		SearchSummaryService:     searchSummaryService,
+		Reranker:                 reranker,
+		ContextService:           contextService,
+		ContextSuggestionService: contextSuggestionService,
+		Summarizer:               summarizer,
+		ActionService:            actionService,
+		SyncService:              syncService, // Add SyncService
+		EmailRepo:                emailRepo,
+		AccountRepo:              accountRepo,
+		EventBus:                 eventBus,
+		SavedSearchService:       savedSearchService,
+		UsageService:             usageService,
+		PromptService:            promptService,
+		RedactionService:         redactionService,
+		TranslationService:       translationService,
 	}, nil
 }
 
@@ -134,6 +203,22 @@ func (c *Container) WorkerConcurrency() int {
 	return 10 // Default fallback
 }
 
+// ContextSuggestionSchedule returns the cron spec for context suggestion sweeps with fallback
+func (c *Container) ContextSuggestionSchedule() string {
+	if c.Config.Worker.ContextSuggestionSchedule != "" {
+		return c.Config.Worker.ContextSuggestionSchedule
+	}
+	return "@daily" // Default fallback
+}
+
+// SavedSearchSchedule returns the cron spec for saved search alert evaluation with fallback
+func (c *Container) SavedSearchSchedule() string {
+	if c.Config.Worker.SavedSearchSchedule != "" {
+		return c.Config.Worker.SavedSearchSchedule
+	}
+	return "@every 10m" // Default fallback
+}
+
 // IsProduction returns true if running in production environment
 func (c *Container) IsProduction() bool {
 	return c.Config.Server.Environment == "production"
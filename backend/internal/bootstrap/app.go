@@ -181,11 +181,30 @@ func (app *App) SetupDB() error {
 		&model.Email{},
 		&model.EmailAccount{},
 		&model.EmailEmbedding{},
+		&model.EmbeddingSpace{},
+		&model.ReindexCheckpoint{},
+		&model.AnalysisFailure{},
+		&model.EmailTranslation{},
+		&model.StyleProfile{},
 		// Context and relationship entities
 		&model.Contact{},
 		&model.Context{},
 		&model.EmailContext{},
+		&model.ContextSuggestion{},
 		&model.Task{},
+		&model.SavedSearch{},
+		&model.SavedSearchMatch{},
+		// Chat entities
+		&model.ChatSession{},
+		&model.ChatMessage{},
+		&model.ChatToolAction{},
+		// AI usage accounting
+		&model.AIUsage{},
+		// Prompt template versions
+		&model.PromptTemplate{},
+		// PII redaction policies and audit log
+		&model.RedactionPolicy{},
+		&model.RedactionAudit{},
 		// Opportunity entities
 		&model.Opportunity{},
 		&model.OpportunityContact{},
@@ -199,18 +218,37 @@ func (app *App) SetupDB() error {
 
 	// Step 4: Create Indices
 	app.Logger.Info("Creating database indices...")
+	vectorCfg := app.Config.Database.VectorIndex
+	vectorIndexSQL, err := database.CreateVectorIndexSQL("email_embeddings_vector_idx", "email_embeddings", "vector", database.VectorIndexOptions{
+		Type:           database.VectorIndexType(vectorCfg.Type),
+		M:              vectorCfg.M,
+		EfConstruction: vectorCfg.EfConstruction,
+		Lists:          vectorCfg.Lists,
+	}, false)
+	if err != nil {
+		return fmt.Errorf("invalid vector index config: %w", err)
+	}
+
 	indices := []struct {
 		name string
 		sql  string
 	}{
 		{
 			name: "email_embeddings_vector_idx",
-			sql:  "CREATE INDEX IF NOT EXISTS email_embeddings_vector_idx ON email_embeddings USING hnsw (vector vector_cosine_ops)",
+			sql:  vectorIndexSQL,
 		},
 		{
 			name: "idx_emails_user_date",
 			sql:  "CREATE INDEX IF NOT EXISTS idx_emails_user_date ON emails (user_id, date DESC)",
 		},
+		{
+			name: "emails_search_vector",
+			sql:  emailSearchVectorColumnSQL,
+		},
+		{
+			name: "idx_emails_search_vector",
+			sql:  "CREATE INDEX IF NOT EXISTS idx_emails_search_vector ON emails USING GIN (search_vector)",
+		},
 		{
 			name: "idx_opportunities_user_status",
 			sql:  "CREATE INDEX IF NOT EXISTS idx_opportunities_user_status ON opportunities (user_id, status)",
@@ -256,3 +294,12 @@ func (app *App) Close() {
 	// 新日志框架会自动清理
 	_ = logger.Close()
 }
+
+// emailSearchVectorColumnSQL adds the weighted full-text column used by keyword and hybrid search.
+// It is kept out of the GORM model because it is generated by Postgres and has no SQLite equivalent.
+const emailSearchVectorColumnSQL = `ALTER TABLE emails ADD COLUMN IF NOT EXISTS search_vector tsvector
+	GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', coalesce(subject, '')), 'A') ||
+		setweight(to_tsvector('simple', coalesce(sender, '')), 'B') ||
+		setweight(to_tsvector('simple', coalesce(body_text, '')), 'C')
+	) STORED`
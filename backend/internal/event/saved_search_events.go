@@ -0,0 +1,19 @@
+package event
+
+import (
+	"github.com/google/uuid"
+)
+
+const SavedSearchMatchedEventName = "saved_search.matched"
+
+// SavedSearchMatchedEvent is published when newly analyzed emails match a saved search with alerts enabled.
+type SavedSearchMatchedEvent struct {
+	UserID        uuid.UUID
+	SavedSearchID uuid.UUID
+	SearchName    string
+	EmailIDs      []uuid.UUID
+}
+
+func (e SavedSearchMatchedEvent) Name() string {
+	return SavedSearchMatchedEventName
+}
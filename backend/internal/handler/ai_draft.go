@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/pkg/ai"
 )
 
 type AIDraftRequest struct {
@@ -14,26 +16,27 @@ type AIDraftRequest struct {
 }
 
 type AIReplyRequest struct {
-	EmailID string `json:"emailId" binding:"required"`
-	Tone    string `json:"tone,omitempty"`    // "professional", "casual", "friendly", etc.
-	Context string `json:"context,omitempty"` // "brief", "detailed", etc.
+	EmailID  string `json:"emailId" binding:"required"`
+	Tone     string `json:"tone,omitempty"`     // "professional", "casual", "friendly", etc., or a short description
+	Length   string `json:"length,omitempty"`   // "short", "medium" or "long"; default the user's usual length
+	Context  string `json:"context,omitempty"`  // "brief", "detailed", "urgent", or free-form instructions
+	Variants int    `json:"variants,omitempty"` // Alternative replies, 1 to 3; default 3
 }
 
 type AIReplyResponse struct {
-	Reply      string  `json:"reply"`
-	Confidence float64 `json:"confidence"`
+	Reply         string                 `json:"reply"`      // The most confident variant
+	Confidence    float64                `json:"confidence"` // Of the most confident variant
+	Variants      []service.DraftVariant `json:"variants"`
+	Basis         service.DraftBasis     `json:"basis"`
+	PromptVersion string                 `json:"prompt_version"` // draft_reply template version used
 }
 
 type AIDraftHandler struct {
 	aiDraftService *service.AIDraftService
-	emailService   *service.EmailService
 }
 
-func NewAIDraftHandler(aiDraftService *service.AIDraftService, emailService *service.EmailService) *AIDraftHandler {
-	return &AIDraftHandler{
-		aiDraftService: aiDraftService,
-		emailService:   emailService,
-	}
+func NewAIDraftHandler(aiDraftService *service.AIDraftService) *AIDraftHandler {
+	return &AIDraftHandler{aiDraftService: aiDraftService}
 }
 
 // GenerateDraft handles the POST /ai/draft API request.
@@ -46,11 +49,11 @@ func (h *AIDraftHandler) GenerateDraft(c *gin.Context) {
 
 	draft, err := h.aiDraftService.GenerateDraftReply(c.Request.Context(), req.EmailContent, req.UserPrompt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"draft": draft})
+	c.JSON(http.StatusOK, gin.H{"draft": draft, "prompt_version": ai.PromptRef(c.Request.Context(), ai.PromptDraftReply)})
 }
 
 // GenerateReply handles the POST /ai/reply API request.
@@ -70,66 +73,64 @@ func (h *AIDraftHandler) GenerateReply(c *gin.Context) {
 		return
 	}
 
-	// Get email content
-	email, err := h.emailService.GetEmail(c.Request.Context(), userID, emailID)
+	drafts, err := h.aiDraftService.GenerateReplies(c.Request.Context(), userID, emailID, replyOptions(req))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found"})
+		c.JSON(draftErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
-	// Build user prompt based on tone and context
-	userPrompt := h.buildPrompt(req.Tone, req.Context)
+	c.JSON(http.StatusOK, AIReplyResponse{
+		Reply:         drafts.Variants[0].Reply,
+		Confidence:    drafts.Variants[0].Confidence,
+		Variants:      drafts.Variants,
+		Basis:         drafts.Basis,
+		PromptVersion: ai.PromptRef(c.Request.Context(), ai.PromptDraftReply),
+	})
+}
+
+// replyOptions reads the request's options. Context once took only "brief", "detailed" and
+// "urgent", which still set the length or ask for clear action items; any other text is passed on
+// as instructions.
+func replyOptions(req AIReplyRequest) service.ReplyOptions {
+	opts := service.ReplyOptions{Tone: req.Tone, Length: req.Length, Variants: req.Variants}
+	switch req.Context {
+	case "brief":
+		if opts.Length == "" {
+			opts.Length = "short"
+		}
+	case "detailed":
+		if opts.Length == "" {
+			opts.Length = "long"
+		}
+	case "urgent":
+		opts.Instructions = "This is urgent: state the next steps and any deadlines clearly."
+	default:
+		opts.Instructions = req.Context
+	}
+	return opts
+}
 
-	// Generate reply using email body text
-	replyText, err := h.aiDraftService.GenerateDraftReply(c.Request.Context(), email.BodyText, userPrompt)
+// GetStyleProfile handles GET /ai/style-profile: the writing style drafts follow, learned from the
+// user's sent mail. ?refresh=true learns it again now.
+func (h *AIDraftHandler) GetStyleProfile(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	profile, err := h.aiDraftService.StyleProfile(c.Request.Context(), userID, c.Query("refresh") == "true")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-
-	// Calculate confidence (mock for now)
-	confidence := 0.85 + (float64(len(replyText)) / 10000.0) // Simple confidence calculation
-	if confidence > 0.99 {
-		confidence = 0.99
-	}
-
-	response := AIReplyResponse{
-		Reply:      replyText,
-		Confidence: confidence,
-	}
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, profile)
 }
 
-// buildPrompt creates a user prompt based on tone and context preferences
-func (h *AIDraftHandler) buildPrompt(tone, context string) string {
-	prompt := "Generate a professional email reply"
-
-	if tone != "" {
-		switch tone {
-		case "professional":
-			prompt = "Generate a professional and formal email reply"
-		case "casual":
-			prompt = "Generate a casual and friendly email reply"
-		case "friendly":
-			prompt = "Generate a warm and friendly email reply"
-		case "concise":
-			prompt = "Generate a concise and brief email reply"
-		}
-	}
-
-	if context != "" {
-		switch context {
-		case "brief":
-			prompt += " with brief context"
-		case "detailed":
-			prompt += " with detailed explanation"
-		case "urgent":
-			prompt += " with urgent and clear action items"
-		}
+// draftErrorStatus maps a drafting error to its HTTP status: 429 once the user's AI quota is spent.
+func draftErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, service.ErrDraftEmailNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrDraftLength):
+		return http.StatusBadRequest
 	}
-
-	prompt += ". Include appropriate greeting and closing. Keep it natural and human-like."
-
-	return prompt
+	return http.StatusInternalServerError
 }
@@ -99,11 +99,12 @@ func (h *AuthHandler) Login(c *gin.Context) {
 }
 
 type UpdateUserProfileRequest struct {
-	Role string `json:"role" binding:"omitempty,oneof=executive manager dealmaker"`
-	Name string `json:"name" binding:"omitempty,max=100"`
+	Role   string `json:"role" binding:"omitempty,oneof=executive manager dealmaker"`
+	Name   string `json:"name" binding:"omitempty,max=100"`
+	Locale string `json:"locale" binding:"omitempty,max=20"` // e.g. "zh-CN", available to prompt templates
 }
 
-// UpdateUserProfile handles updating the authenticated user's profile (Role, Name).
+// UpdateUserProfile handles updating the authenticated user's profile (Role, Name, Locale).
 func (h *AuthHandler) UpdateUserProfile(c *gin.Context) {
 	userID, ok := middleware.GetUserIDFromContext(c)
 	if !ok {
@@ -117,7 +118,7 @@ func (h *AuthHandler) UpdateUserProfile(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.UpdateUserProfile(c.Request.Context(), userID, req.Role, req.Name); err != nil {
+	if err := h.userService.UpdateUserProfile(c.Request.Context(), userID, req.Role, req.Name, req.Locale); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
 		return
 	}
@@ -46,6 +46,14 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 		return
 	}
 
+	streamChatChunks(c, func(ch chan<- ai.ChatCompletionChunk) error {
+		return h.chatService.StreamChat(c.Request.Context(), userID, req.Messages, req.ContextRefIDs, ch)
+	})
+}
+
+// streamChatChunks runs a chat stream and relays its chunks to the client as server-sent events.
+// run must close ch once it has started streaming.
+func streamChatChunks(c *gin.Context, run func(ch chan<- ai.ChatCompletionChunk) error) {
 	// Set headers for SSE
 	c.Writer.Header().Set("Content-Type", "text/event-stream")
 	c.Writer.Header().Set("Cache-Control", "no-cache")
@@ -57,7 +65,7 @@ func (h *ChatHandler) StreamChat(c *gin.Context) {
 	errCh := make(chan error)
 
 	go func() {
-		err := h.chatService.StreamChat(c.Request.Context(), userID, req.Messages, req.ContextRefIDs, ch)
+		err := run(ch)
 		if err != nil {
 			errCh <- err
 		}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+type ChatSessionHandler struct {
+	chatService    *service.ChatService
+	sessionService *service.ChatSessionService
+}
+
+func NewChatSessionHandler(chatService *service.ChatService, sessionService *service.ChatSessionService) *ChatSessionHandler {
+	return &ChatSessionHandler{chatService: chatService, sessionService: sessionService}
+}
+
+// CreateSession starts a new conversation.
+func (h *ChatSessionHandler) CreateSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var input model.ChatSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.sessionService.Create(c.Request.Context(), userID, input.Title)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+// ListSessions returns the user's conversations, most recent first.
+func (h *ChatSessionHandler) ListSessions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	sessions, err := h.sessionService.List(c.Request.Context(), userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetSession returns a conversation with its messages so the client can resume it.
+func (h *ChatSessionHandler) GetSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	session, err := h.sessionService.GetWithMessages(c.Request.Context(), userID, id)
+	if err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// RenameSession changes a conversation's title.
+func (h *ChatSessionHandler) RenameSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	var input model.ChatSessionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.sessionService.Rename(c.Request.Context(), userID, id, input.Title)
+	if err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+// DeleteSession deletes a conversation and its messages.
+func (h *ChatSessionHandler) DeleteSession(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	if err := h.sessionService.Delete(c.Request.Context(), userID, id); err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SendMessage streams the answer to a new question in a conversation as server-sent events.
+func (h *ChatSessionHandler) SendMessage(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session ID"})
+		return
+	}
+
+	var input model.ChatMessageInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Report a missing session before switching to an event stream.
+	if _, err := h.sessionService.Get(c.Request.Context(), userID, id); err != nil {
+		respondChatSessionError(c, err)
+		return
+	}
+
+	streamChatChunks(c, func(ch chan<- ai.ChatCompletionChunk) error {
+		return h.chatService.StreamSessionChat(c.Request.Context(), userID, id, input, ch)
+	})
+}
+
+func respondChatSessionError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrChatSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/service"
+)
+
+type ChatToolActionHandler struct {
+	chatService *service.ChatService
+}
+
+func NewChatToolActionHandler(chatService *service.ChatService) *ChatToolActionHandler {
+	return &ChatToolActionHandler{chatService: chatService}
+}
+
+// ConfirmAction runs a write action the Copilot proposed and returns its result widget.
+func (h *ChatToolActionHandler) ConfirmAction(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid action ID"})
+		return
+	}
+
+	action, widget, err := h.chatService.ConfirmToolAction(c.Request.Context(), userID, id)
+	if err != nil {
+		if action != nil {
+			// The action ran and failed; it is recorded as failed.
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "action": action})
+			return
+		}
+		respondChatToolActionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": action, "widget": widget})
+}
+
+// RejectAction discards a write action the Copilot proposed.
+func (h *ChatToolActionHandler) RejectAction(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid action ID"})
+		return
+	}
+
+	action, err := h.chatService.RejectToolAction(c.Request.Context(), userID, id)
+	if err != nil {
+		respondChatToolActionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"action": action})
+}
+
+func respondChatToolActionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrChatToolActionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrChatToolActionResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
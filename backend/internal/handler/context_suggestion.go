@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+	"gorm.io/gorm"
+)
+
+type ContextSuggestionHandler struct {
+	suggestionService *service.ContextSuggestionService
+}
+
+func NewContextSuggestionHandler(suggestionService *service.ContextSuggestionService) *ContextSuggestionHandler {
+	return &ContextSuggestionHandler{suggestionService: suggestionService}
+}
+
+type MergeSuggestionRequest struct {
+	ContextID string `json:"context_id" binding:"required"`
+}
+
+// ListSuggestions returns the user's context suggestions (default: pending only).
+func (h *ContextSuggestionHandler) ListSuggestions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	status := c.DefaultQuery("status", string(model.ContextSuggestionPending))
+	if status == "all" {
+		status = ""
+	}
+
+	suggestions, err := h.suggestionService.ListSuggestions(c.Request.Context(), userID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// GenerateSuggestions re-clusters unassigned emails on demand.
+func (h *ContextSuggestionHandler) GenerateSuggestions(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	suggestions, err := h.suggestionService.GenerateSuggestions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// AcceptSuggestion creates a context from a suggestion. The body may override name, keywords, etc.
+func (h *ContextSuggestionHandler) AcceptSuggestion(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid suggestion ID"})
+		return
+	}
+
+	var input *model.ContextInput
+	if c.Request.ContentLength > 0 {
+		input = &model.ContextInput{}
+		if err := c.ShouldBindJSON(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	ctx, assigned, err := h.suggestionService.AcceptSuggestion(c.Request.Context(), userID, id, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"context": ctx, "assigned_emails": assigned})
+}
+
+// MergeSuggestion folds a suggestion into an existing context.
+func (h *ContextSuggestionHandler) MergeSuggestion(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid suggestion ID"})
+		return
+	}
+
+	var req MergeSuggestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	contextID, err := uuid.Parse(req.ContextID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid context ID"})
+		return
+	}
+
+	ctx, assigned, err := h.suggestionService.MergeSuggestion(c.Request.Context(), userID, id, contextID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"context": ctx, "assigned_emails": assigned})
+}
+
+// RejectSuggestion dismisses a suggestion.
+func (h *ContextSuggestionHandler) RejectSuggestion(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid suggestion ID"})
+		return
+	}
+
+	if err := h.suggestionService.RejectSuggestion(c.Request.Context(), userID, id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *ContextSuggestionHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSuggestionNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSuggestionAlreadyResolved):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hrygo/echomind/internal/middleware"
 	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
 	"gorm.io/gorm"
 )
 
@@ -56,6 +58,10 @@ func (h *EmailHandler) ListEmails(c *gin.Context) {
 
 	emails, err := h.emailService.ListEmails(c.Request.Context(), userID, limit, offset, contextID, folder, category, filter)
 	if err != nil {
+		if errors.Is(err, service.ErrSavedSearchNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
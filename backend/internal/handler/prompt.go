@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/middleware"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+)
+
+type PromptHandler struct {
+	promptService *service.PromptService
+}
+
+func NewPromptHandler(promptService *service.PromptService) *PromptHandler {
+	return &PromptHandler{promptService: promptService}
+}
+
+// ListPrompts handles GET /api/v1/orgs/:id/prompts
+// It lists every prompt with its default template and the organization's versions.
+func (h *PromptHandler) ListPrompts(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	prompts, err := h.promptService.List(c.Request.Context(), orgID, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, prompts)
+}
+
+// CreateVersion handles POST /api/v1/orgs/:id/prompts/:name/versions
+func (h *PromptHandler) CreateVersion(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	var input model.PromptVersionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	version, err := h.promptService.CreateVersion(c.Request.Context(), orgID, userID, c.Param("name"), input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, version)
+}
+
+// SetRollout handles PUT /api/v1/orgs/:id/prompts/:name/rollout
+// The body maps version numbers to weights, e.g. {"weights": {"2": 90, "3": 10}}; {"weights": {}}
+// rolls back to the default template.
+func (h *PromptHandler) SetRollout(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	var input model.PromptRolloutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	versions, err := h.promptService.SetRollout(c.Request.Context(), orgID, userID, c.Param("name"), input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *PromptHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPromptForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPromptNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPromptInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update prompts"})
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/middleware"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+)
+
+type RedactionHandler struct {
+	redactionService *service.RedactionService
+}
+
+func NewRedactionHandler(redactionService *service.RedactionService) *RedactionHandler {
+	return &RedactionHandler{redactionService: redactionService}
+}
+
+// GetPolicy handles GET /api/v1/orgs/:id/redaction
+// It returns the PII kinds masked before the organization's emails are sent to AI providers.
+func (h *RedactionHandler) GetPolicy(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	policy, err := h.redactionService.GetPolicy(c.Request.Context(), orgID, userID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetPolicy handles PUT /api/v1/orgs/:id/redaction
+// The body is {"enabled": true, "kinds": ["email", "phone"]}; an empty kinds list masks every kind.
+func (h *RedactionHandler) SetPolicy(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	var input model.RedactionPolicyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	policy, err := h.redactionService.SetPolicy(c.Request.Context(), orgID, userID, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetAuditLog handles GET /api/v1/orgs/:id/redaction/audit?limit=50&offset=0
+// It lists what was masked in each AI call, newest first, with totals; values are never stored.
+func (h *RedactionHandler) GetAuditLog(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+
+	page, err := h.redactionService.AuditLog(c.Request.Context(), orgID, userID, limit, offset)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *RedactionHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrRedactionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrRedactionInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process redaction settings"})
+	}
+}
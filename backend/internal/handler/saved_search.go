@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/service"
+)
+
+type SavedSearchHandler struct {
+	savedSearchService *service.SavedSearchService
+}
+
+func NewSavedSearchHandler(savedSearchService *service.SavedSearchService) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchService: savedSearchService}
+}
+
+// CreateSavedSearch stores a query and filters. List its emails with GET /emails?folder=saved:<id>.
+func (h *SavedSearchHandler) CreateSavedSearch(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+
+	var input model.SavedSearchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.savedSearchService.Create(c.Request.Context(), userID, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, saved)
+}
+
+// ListSavedSearches returns all saved searches for a user, or with ?folders=true only those shown
+// as folders.
+func (h *SavedSearchHandler) ListSavedSearches(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	foldersOnly := c.Query("folders") == "true"
+
+	saved, err := h.savedSearchService.List(c.Request.Context(), userID, foldersOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// UpdateSavedSearch replaces a saved search's query, filters and options.
+func (h *SavedSearchHandler) UpdateSavedSearch(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search ID"})
+		return
+	}
+
+	var input model.SavedSearchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	saved, err := h.savedSearchService.Update(c.Request.Context(), userID, id, input)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, saved)
+}
+
+// DeleteSavedSearch deletes a saved search.
+func (h *SavedSearchHandler) DeleteSavedSearch(c *gin.Context) {
+	userID := c.MustGet("userID").(uuid.UUID)
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid saved search ID"})
+		return
+	}
+
+	if err := h.savedSearchService.Delete(c.Request.Context(), userID, id); err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SavedSearchHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSavedSearchEmpty), errors.Is(err, service.ErrSavedSearchInvalid):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -23,7 +23,7 @@ type SearchClusterer interface {
 
 type SearchSummarizer interface {
 	GenerateSummary(ctx context.Context, results []service.SearchResult, query string) (*service.SearchResultsSummary, error)
-	GenerateQuickSummary(results []service.SearchResult) *service.SearchResultsSummary
+	GenerateQuickSummary(ctx context.Context, results []service.SearchResult, query string) *service.SearchResultsSummary
 }
 
 type SearchHandler struct {
@@ -67,8 +67,17 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		return
 	}
 
+	// Split Gmail-style operators (from:, is:unread, before:, ...) from the free text
+	parsedQuery, err := service.ParseSearchQuery(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query = parsedQuery.Text
+
 	// Parse filters
 	var filters service.SearchFilters
+	filters.Operators = parsedQuery.Operators
 	filters.Sender = c.Query("sender")
 	if contextIDStr := c.Query("context_id"); contextIDStr != "" {
 		if contextID, err := uuid.Parse(contextIDStr); err == nil {
@@ -96,6 +105,26 @@ func (h *SearchHandler) Search(c *gin.Context) {
 		}
 	}
 
+	if modeStr := c.Query("mode"); modeStr != "" {
+		mode, err := service.ParseSearchMode(modeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		filters.Mode = mode
+	}
+
+	// Optional ANN tuning for recall/latency trade-offs; invalid values fall back to the server defaults
+	if efSearch, err := strconv.Atoi(c.Query("ef_search")); err == nil && efSearch > 0 && efSearch <= 1000 {
+		filters.ANN.EfSearch = efSearch
+	}
+	if probes, err := strconv.Atoi(c.Query("probes")); err == nil && probes > 0 && probes <= 1000 {
+		filters.ANN.Probes = probes
+	}
+
+	// Opt in to also run the query translated into the other languages of the user's mail
+	filters.CrossLanguage = c.Query("cross_language") == "true"
+
 	// Get limit parameter (optional, default to 10)
 	limitStr := c.DefaultQuery("limit", "10")
 	limit, err := strconv.Atoi(limitStr)
@@ -169,7 +198,7 @@ func (h *SearchHandler) Search(c *gin.Context) {
 			h.logger.Warn("AI summary generation failed, using quick summary",
 				logger.Error(err),
 			)
-			summary = h.summaryService.GenerateQuickSummary(results)
+			summary = h.summaryService.GenerateQuickSummary(c.Request.Context(), results, query)
 		}
 		response["summary"] = summary
 	}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -57,8 +58,8 @@ func (m *MockSearchSummarizer) GenerateSummary(ctx context.Context, results []se
 	return args.Get(0).(*service.SearchResultsSummary), args.Error(1)
 }
 
-func (m *MockSearchSummarizer) GenerateQuickSummary(results []service.SearchResult) *service.SearchResultsSummary {
-	args := m.Called(results)
+func (m *MockSearchSummarizer) GenerateQuickSummary(ctx context.Context, results []service.SearchResult, query string) *service.SearchResultsSummary {
+	args := m.Called(ctx, results, query)
 	if args.Get(0) == nil {
 		return nil
 	}
@@ -154,6 +155,110 @@ func TestSearchHandler_Search(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 
+	t.Run("Hybrid Mode", func(t *testing.T) {
+		mockSearcher := new(MockSearcher)
+		mockClusterer := new(MockSearchClusterer)
+		mockSummarizer := new(MockSearchSummarizer)
+		h := handler.NewSearchHandler(mockSearcher, mockClusterer, mockSummarizer, testLogger)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		userID := uuid.New()
+		c.Set(middleware.ContextUserIDKey, userID)
+		c.Request = httptest.NewRequest("GET", "/api/v1/search?q=invoice&mode=hybrid", nil)
+
+		mockSearcher.On("Search", mock.Anything, userID, "invoice", service.SearchFilters{Mode: service.SearchModeHybrid}, 10).Return([]service.SearchResult{}, nil)
+
+		h.Search(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSearcher.AssertExpectations(t)
+	})
+
+	t.Run("Cross Language Opt In", func(t *testing.T) {
+		mockSearcher := new(MockSearcher)
+		mockClusterer := new(MockSearchClusterer)
+		mockSummarizer := new(MockSearchSummarizer)
+		h := handler.NewSearchHandler(mockSearcher, mockClusterer, mockSummarizer, testLogger)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		userID := uuid.New()
+		c.Set(middleware.ContextUserIDKey, userID)
+		c.Request = httptest.NewRequest("GET", "/api/v1/search?q=budget&cross_language=true", nil)
+
+		mockSearcher.On("Search", mock.Anything, userID, "budget", service.SearchFilters{CrossLanguage: true}, 10).Return([]service.SearchResult{}, nil)
+
+		h.Search(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSearcher.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Mode", func(t *testing.T) {
+		mockSearcher := new(MockSearcher)
+		mockClusterer := new(MockSearchClusterer)
+		mockSummarizer := new(MockSearchSummarizer)
+		h := handler.NewSearchHandler(mockSearcher, mockClusterer, mockSummarizer, testLogger)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		userID := uuid.New()
+		c.Set(middleware.ContextUserIDKey, userID)
+		c.Request = httptest.NewRequest("GET", "/api/v1/search?q=invoice&mode=fuzzy", nil)
+
+		h.Search(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockSearcher.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Query Operators", func(t *testing.T) {
+		mockSearcher := new(MockSearcher)
+		mockClusterer := new(MockSearchClusterer)
+		mockSummarizer := new(MockSearchSummarizer)
+		h := handler.NewSearchHandler(mockSearcher, mockClusterer, mockSummarizer, testLogger)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		userID := uuid.New()
+		c.Set(middleware.ContextUserIDKey, userID)
+		c.Request = httptest.NewRequest("GET", "/api/v1/search?q="+url.QueryEscape("invoice from:acme is:unread"), nil)
+
+		expectedFilters := service.SearchFilters{Operators: []service.SearchOperator{
+			{Field: service.SearchFieldFrom, Value: "acme"},
+			{Field: service.SearchFieldIs, Value: "unread"},
+		}}
+		mockSearcher.On("Search", mock.Anything, userID, "invoice", expectedFilters, 10).Return([]service.SearchResult{}, nil)
+
+		h.Search(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockSearcher.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Operator", func(t *testing.T) {
+		mockSearcher := new(MockSearcher)
+		mockClusterer := new(MockSearchClusterer)
+		mockSummarizer := new(MockSearchSummarizer)
+		h := handler.NewSearchHandler(mockSearcher, mockClusterer, mockSummarizer, testLogger)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		userID := uuid.New()
+		c.Set(middleware.ContextUserIDKey, userID)
+		c.Request = httptest.NewRequest("GET", "/api/v1/search?q=before:someday", nil)
+
+		h.Search(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("Unauthorized", func(t *testing.T) {
 		mockSearcher := new(MockSearcher)
 		mockClusterer := new(MockSearchClusterer)
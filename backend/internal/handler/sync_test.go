@@ -40,6 +40,7 @@ func (m *MockFetcher) FetchEmails(c *clientimap.Client, mailbox string, limit in
 type MockIMAPSession struct {
 	LogoutFunc      func() error
 	FetchEmailsFunc func(mailbox string, limit int) ([]imap.EmailData, error)
+	SentMailboxFunc func() (string, error)
 }
 
 func (m *MockIMAPSession) Logout() error {
@@ -56,6 +57,13 @@ func (m *MockIMAPSession) FetchEmails(mailbox string, limit int) ([]imap.EmailDa
 	return nil, nil
 }
 
+func (m *MockIMAPSession) SentMailbox() (string, error) {
+	if m.SentMailboxFunc != nil {
+		return m.SentMailboxFunc()
+	}
+	return "", nil
+}
+
 // MockIMAPConnector implements service.IMAPConnector
 type MockIMAPConnector struct {
 	ConnectFunc func(ctx context.Context, account *model.EmailAccount) (service.IMAPSession, error)
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/middleware"
+	"github.com/hrygo/echomind/internal/service"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+type TranslationHandler struct {
+	translationService *service.TranslationService
+}
+
+func NewTranslationHandler(translationService *service.TranslationService) *TranslationHandler {
+	return &TranslationHandler{translationService: translationService}
+}
+
+type translateEmailRequest struct {
+	// Target language code ("en") or locale ("zh-CN"); defaults to the user's locale, then English
+	Language string `json:"language" binding:"omitempty,max=20"`
+}
+
+// TranslateEmail handles POST /api/v1/emails/:id/translate
+// Translations are stored, so asking again for the same language returns them without an AI call.
+func (h *TranslationHandler) TranslateEmail(c *gin.Context) {
+	userID, ok := middleware.GetUserIDFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		return
+	}
+	emailID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email ID format"})
+		return
+	}
+	var req translateEmailRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	translation, err := h.translationService.TranslateEmail(c.Request.Context(), userID, emailID, req.Language)
+	switch {
+	case errors.Is(err, service.ErrTranslationEmailNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email not found or not accessible"})
+	case errors.Is(err, service.ErrTranslationLanguage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "translation failed"})
+	default:
+		c.JSON(http.StatusOK, translation)
+	}
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/middleware"
+	"github.com/hrygo/echomind/internal/service"
+)
+
+type UsageHandler struct {
+	usageService *service.UsageService
+}
+
+func NewUsageHandler(usageService *service.UsageService) *UsageHandler {
+	return &UsageHandler{usageService: usageService}
+}
+
+// GetUsage handles GET /api/v1/usage?from=YYYY-MM-DD&to=YYYY-MM-DD
+// It reports the user's AI usage and cost by capability and model, defaulting to the month to date.
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+	from, to, err := h.usageService.UsageRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.usageService.UserUsage(c.Request.Context(), userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch usage"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetDailyUsage handles GET /api/v1/usage/daily?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *UsageHandler) GetDailyUsage(c *gin.Context) {
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+	from, to, err := h.usageService.UsageRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.usageService.DailyUsage(c.Request.Context(), userID, from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch usage"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetOrgUsage handles GET /api/v1/orgs/:id/usage?from=YYYY-MM-DD&to=YYYY-MM-DD
+// It reports usage per member and is restricted to the organization's owners and admins.
+func (h *UsageHandler) GetOrgUsage(c *gin.Context) {
+	orgID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization ID"})
+		return
+	}
+	userID := c.MustGet(middleware.ContextUserIDKey).(uuid.UUID)
+	from, to, err := h.usageService.UsageRange(c.Query("from"), c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := h.usageService.OrgUsage(c.Request.Context(), orgID, userID, from, to)
+	if err != nil {
+		if errors.Is(err, service.ErrUsageForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch usage"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
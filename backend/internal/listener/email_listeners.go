@@ -15,6 +15,7 @@ import (
 // CompatibleLogger defines the interface for structured logging
 type CompatibleLogger interface {
 	Errorw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
 	Debugw(msg string, keysAndValues ...interface{})
 	Warnw(msg string, keysAndValues ...interface{})
 }
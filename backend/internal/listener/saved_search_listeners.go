@@ -0,0 +1,35 @@
+package listener
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hrygo/echomind/internal/event"
+	"github.com/hrygo/echomind/pkg/event/bus"
+	echologger "github.com/hrygo/echomind/pkg/logger"
+)
+
+// SavedSearchAlertListener reports new saved search matches.
+type SavedSearchAlertListener struct {
+	logger CompatibleLogger
+}
+
+func NewSavedSearchAlertListener(logger echologger.Logger) *SavedSearchAlertListener {
+	return &SavedSearchAlertListener{
+		logger: echologger.AsZapSugaredLogger(logger),
+	}
+}
+
+func (l *SavedSearchAlertListener) Handle(ctx context.Context, e bus.Event) error {
+	evt, ok := e.(event.SavedSearchMatchedEvent)
+	if !ok {
+		return fmt.Errorf("invalid event type: %T", e)
+	}
+
+	l.logger.Infow("Saved search has new matches",
+		"user_id", evt.UserID,
+		"saved_search_id", evt.SavedSearchID,
+		"saved_search", evt.SearchName,
+		"matches", len(evt.EmailIDs))
+	return nil
+}
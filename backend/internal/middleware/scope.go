@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserScope applies per-user scopes, such as AI usage accounting and prompt selection, to the
+// context of an authenticated request. It must run after AuthMiddleware.
+func UserScope(scopes ...func(ctx context.Context, userID uuid.UUID) context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := c.Get(ContextUserIDKey); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				ctx := c.Request.Context()
+				for _, scope := range scopes {
+					ctx = scope(ctx, id)
+				}
+				c.Request = c.Request.WithContext(ctx)
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnalysisFailure records an email analysis that the model could not produce in the required shape,
+// even after a repair attempt. The email is left unanalyzed rather than stored with made-up fields.
+type AnalysisFailure struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	EmailID       uuid.UUID `gorm:"type:uuid;not null;index" json:"email_id"`
+	PromptVersion string    `gorm:"size:80" json:"prompt_version"`
+	Reason        string    `gorm:"type:text" json:"reason"`
+	Output        string    `gorm:"type:text" json:"output"` // The model's last reply
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ChatSession is a persisted Copilot conversation. Older turns are folded into Summary once the
+// history no longer fits the model's context window.
+type ChatSession struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID        uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	Title         string    `gorm:"type:varchar(200)" json:"title"`
+	Summary       string    `gorm:"type:text" json:"-"`          // Running summary of the turns up to SummarizedSeq
+	SummarizedSeq int       `gorm:"not null;default:0" json:"-"` // Messages with Seq <= this are covered by Summary
+	MessageCount  int       `gorm:"not null;default:0" json:"message_count"`
+	LastMessageAt time.Time `gorm:"index" json:"last_message_at"`
+
+	Messages []ChatMessage `gorm:"foreignKey:SessionID" json:"messages,omitempty"`
+}
+
+// ChatMessage is one turn of a chat session.
+type ChatMessage struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	SessionID     uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_chat_messages_session_seq" json:"session_id"`
+	Seq           int            `gorm:"not null;uniqueIndex:idx_chat_messages_session_seq" json:"seq"` // 1-based position in the session
+	Role          string         `gorm:"type:varchar(20);not null" json:"role"`                         // "user" or "assistant"
+	Content       string         `gorm:"type:text" json:"content"`
+	CitedEmailIDs datatypes.JSON `gorm:"type:jsonb" json:"cited_email_ids,omitempty"`      // []uuid.UUID, emails the answer cites
+	Citations     datatypes.JSON `gorm:"type:jsonb" json:"citations,omitempty"`            // []ai.Citation
+	PromptVersion string         `gorm:"type:varchar(80)" json:"prompt_version,omitempty"` // Chat prompt template an answer used
+}
+
+// ChatSessionInput defines the input for creating or renaming a chat session.
+type ChatSessionInput struct {
+	Title string `json:"title" binding:"max=200"`
+}
+
+// ChatMessageInput is a new user turn in a chat session.
+type ChatMessageInput struct {
+	Content       string      `json:"content" binding:"required"`
+	ContextRefIDs []uuid.UUID `json:"context_ref_ids"`
+}
+
+// ChatToolActionStatus is the lifecycle state of a write action proposed by the Copilot.
+type ChatToolActionStatus string
+
+const (
+	ChatToolActionPending   ChatToolActionStatus = "pending"
+	ChatToolActionConfirmed ChatToolActionStatus = "confirmed"
+	ChatToolActionRejected  ChatToolActionStatus = "rejected"
+	ChatToolActionFailed    ChatToolActionStatus = "failed"
+	ChatToolActionExpired   ChatToolActionStatus = "expired"
+)
+
+// ChatToolAction is a write tool call the model requested. It only runs once the user confirms it.
+type ChatToolAction struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID    uuid.UUID            `gorm:"type:uuid;not null;index" json:"user_id"`
+	SessionID *uuid.UUID           `gorm:"type:uuid;index" json:"session_id,omitempty"` // Set when proposed in a stored session
+	Tool      string               `gorm:"type:varchar(50);not null" json:"tool"`
+	Arguments datatypes.JSON       `gorm:"type:jsonb" json:"arguments"`
+	Summary   string               `gorm:"type:text" json:"summary"` // Human-readable description shown for confirmation
+	Status    ChatToolActionStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Result    datatypes.JSON       `gorm:"type:jsonb" json:"result,omitempty"`
+	Error     string               `gorm:"type:text" json:"error,omitempty"`
+	ExpiresAt time.Time            `json:"expires_at"`
+}
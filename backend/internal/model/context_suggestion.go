@@ -0,0 +1,37 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+type ContextSuggestionStatus string
+
+const (
+	ContextSuggestionPending  ContextSuggestionStatus = "pending"
+	ContextSuggestionAccepted ContextSuggestionStatus = "accepted"
+	ContextSuggestionMerged   ContextSuggestionStatus = "merged"
+	ContextSuggestionRejected ContextSuggestionStatus = "rejected"
+)
+
+// ContextSuggestion is an AI-proposed context derived from clustering emails that have no context yet.
+type ContextSuggestion struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	UserID         uuid.UUID      `gorm:"type:uuid;not null;index"`
+	Name           string         `gorm:"type:varchar(100);not null"`
+	Keywords       datatypes.JSON `gorm:"type:jsonb"` // []string
+	Stakeholders   datatypes.JSON `gorm:"type:jsonb"` // []string (email addresses)
+	EvidenceEmails datatypes.JSON `gorm:"type:jsonb"` // []uuid.UUID, closest emails to the cluster centroid
+	ClusterSize    int            `gorm:"not null;default:0"`
+	Cohesion       float64        `gorm:"not null;default:0"` // Mean cosine similarity to centroid
+
+	Status    ContextSuggestionStatus `gorm:"type:varchar(20);default:'pending';index"`
+	ContextID *uuid.UUID              `gorm:"type:uuid"` // Context created from or merged into
+}
@@ -8,6 +8,18 @@ import (
 	"gorm.io/gorm"
 )
 
+// Analysis statuses of an email.
+const (
+	AnalysisStatusAnalyzed = "analyzed"
+	AnalysisStatusFailed   = "failed"
+)
+
+// Folders emails are stored in.
+const (
+	FolderInbox = "INBOX"
+	FolderSent  = "Sent" // The user's sent mail, kept for drafting replies but not analyzed
+)
+
 // Email represents an email message stored in the database.
 type Email struct {
 	ID        uuid.UUID `gorm:"type:uuid;primary_key"`
@@ -35,4 +47,12 @@ type Email struct {
 	SnoozedUntil *time.Time     `gorm:"index"`      // If set, hide from inbox until this time
 	ActionItems  datatypes.JSON `gorm:"type:jsonb"` // Extracted tasks
 	SmartActions datatypes.JSON `gorm:"type:jsonb"` // Structured smart actions
+	// Prompt template version of the analysis, e.g. "summary@v2"
+	PromptVersion string `gorm:"size:80"`
+	// Outcome of the AI analysis: "analyzed", or "failed" when no valid analysis could be produced
+	AnalysisStatus string `gorm:"size:20;index"`
+	// Detected main language, an ISO 639-1 code such as "zh" or "en"; empty when undetermined
+	Language string `gorm:"size:10;index"`
+
+	HasAttachments bool `gorm:"default:false"` // At least one attachment part was seen during ingestion
 }
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailTranslation caches the translation of an email into one language, so that opening it again
+// costs no AI call.
+type EmailTranslation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	EmailID        uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_email_translation" json:"email_id"`
+	Language       string    `gorm:"size:10;not null;uniqueIndex:idx_email_translation" json:"language"` // Target, e.g. "en"
+	SourceLanguage string    `gorm:"size:10" json:"source_language"`
+	Subject        string    `json:"subject"`
+	Body           string    `gorm:"type:text" json:"body"`
+}
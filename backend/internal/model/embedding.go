@@ -11,8 +11,9 @@ type EmailEmbedding struct {
 	ID         uint            `gorm:"primaryKey" json:"id"`
 	EmailID    uuid.UUID       `gorm:"type:uuid;not null;index" json:"email_id"`
 	Content    string          `gorm:"type:text" json:"content"`                // Text chunk
-	Vector     pgvector.Vector `gorm:"type:vector(1024)" json:"vector"`         // Fixed dimension: 1024
-	Dimensions int             `gorm:"not null;default:1024" json:"dimensions"` // Always 1024
+	Vector     pgvector.Vector `gorm:"type:vector(1024)" json:"vector"`         // 1024 in the legacy table; other spaces size their own
+	Dimensions int             `gorm:"not null;default:1024" json:"dimensions"` // Length of Vector
+	Model      string          `gorm:"size:200;index" json:"model"`             // Embedding model that produced Vector
 	CreatedAt  time.Time       `json:"created_at"`
 
 	// Associations
@@ -22,3 +23,30 @@ type EmailEmbedding struct {
 func (EmailEmbedding) TableName() string {
 	return "email_embeddings"
 }
+
+type EmbeddingSpaceStatus string
+
+const (
+	EmbeddingSpaceBuilding EmbeddingSpaceStatus = "building" // Being backfilled; not yet used for queries
+	EmbeddingSpaceActive   EmbeddingSpaceStatus = "active"   // Serves semantic search
+	EmbeddingSpaceRetired  EmbeddingSpaceStatus = "retired"  // Superseded; kept until dropped
+)
+
+// EmbeddingSpace registers a table of email embeddings produced by a single model.
+// Vectors from different models live in separate tables so they are never compared with each other.
+// The original email_embeddings table becomes the space of whichever model first claims it.
+type EmbeddingSpace struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Key        string               `gorm:"column:space_key;size:255;uniqueIndex;not null" json:"key"` // "<model>@<dimensions>"
+	Model      string               `gorm:"size:200;not null" json:"model"`
+	Dimensions int                  `gorm:"not null" json:"dimensions"`
+	Table      string               `gorm:"column:table_name;size:63;uniqueIndex;not null" json:"table_name"`
+	Status     EmbeddingSpaceStatus `gorm:"type:varchar(20);not null;default:'building';index" json:"status"`
+
+	EmbeddedEmails int64      `json:"embedded_emails"` // Coverage snapshot from the last migration run
+	TotalEmails    int64      `json:"total_emails"`
+	ActivatedAt    *time.Time `json:"activated_at,omitempty"`
+}
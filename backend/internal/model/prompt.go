@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PromptTemplate is an organization's version of a named prompt, written as a text/template.
+// Versions are never edited; Weight decides which of them serve the organization's users, so a
+// rollout can be split for an A/B test or moved back to an older version.
+type PromptTemplate struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrgID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_prompt_templates_org_name_version" json:"org_id"`
+	Name      string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_prompt_templates_org_name_version" json:"name"`
+	Version   int       `gorm:"not null;uniqueIndex:idx_prompt_templates_org_name_version" json:"version"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	Note      string    `gorm:"type:varchar(200)" json:"note,omitempty"`
+	Weight    int       `gorm:"not null;default:0" json:"weight"` // Share of the organization's users; 0 = not serving
+	CreatedBy uuid.UUID `gorm:"type:uuid" json:"created_by"`
+}
+
+// PromptVersionInput creates a new version of a prompt.
+type PromptVersionInput struct {
+	Body     string `json:"body" binding:"required"`
+	Note     string `json:"note" binding:"max=200"`
+	Activate bool   `json:"activate"` // Serve the new version to every user at once
+}
+
+// PromptRolloutInput sets which versions of a prompt serve traffic, by version number. Versions not
+// listed stop serving; an empty map rolls back to the configured default.
+type PromptRolloutInput struct {
+	Weights map[int]int `json:"weights"`
+}
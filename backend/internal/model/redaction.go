@@ -0,0 +1,42 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// RedactionPolicy is an organization's choice of the personal data masked before email content is
+// sent to AI providers. Organizations without one use the configured default.
+type RedactionPolicy struct {
+	OrgID     uuid.UUID      `gorm:"type:uuid;primary_key" json:"org_id"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	Enabled   bool           `gorm:"not null" json:"enabled"`
+	Kinds     datatypes.JSON `gorm:"type:jsonb" json:"kinds"` // []string of ai.PIIKind
+	UpdatedBy uuid.UUID      `gorm:"type:uuid" json:"updated_by"`
+}
+
+// RedactionPolicyInput replaces an organization's policy.
+type RedactionPolicyInput struct {
+	Enabled *bool    `json:"enabled" binding:"required"`
+	Kinds   []string `json:"kinds"` // Empty masks every kind
+}
+
+// RedactionAudit records what was masked in one AI call: the kinds and counts, never the values.
+type RedactionAudit struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `gorm:"index:idx_redaction_audits_org_created,priority:2" json:"created_at"`
+
+	OrgID      *uuid.UUID `gorm:"type:uuid;index:idx_redaction_audits_org_created,priority:1" json:"org_id,omitempty"` // The user's primary organization
+	UserID     *uuid.UUID `gorm:"type:uuid;index" json:"user_id,omitempty"`                                            // Empty for calls made outside a user's scope
+	Capability string     `gorm:"type:varchar(32);not null" json:"capability"`
+
+	// Values masked, per kind
+	Email      int `gorm:"not null;default:0" json:"email"`
+	Phone      int `gorm:"not null;default:0" json:"phone"`
+	NationalID int `gorm:"not null;default:0" json:"national_id"`
+	Card       int `gorm:"not null;default:0" json:"card"`
+	IBAN       int `gorm:"not null;default:0" json:"iban"`
+	Total      int `gorm:"not null" json:"total"`
+}
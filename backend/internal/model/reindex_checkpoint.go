@@ -0,0 +1,20 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReindexCheckpoint records how far a reindex job got so it can resume after a crash.
+// Emails are processed in id order; everything up to and including Cursor has been handled.
+type ReindexCheckpoint struct {
+	JobID     string    `gorm:"type:varchar(64);primary_key" json:"job_id"`
+	Cursor    uuid.UUID `gorm:"type:uuid" json:"cursor"`
+	Processed int64     `json:"processed"`
+	Embedded  int64     `json:"embedded"`
+	Failed    int64     `json:"failed"`
+	Done      bool      `json:"done"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
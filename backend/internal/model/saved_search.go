@@ -0,0 +1,58 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// SavedSearch is a stored query that can be shown as a virtual folder and alert on new matches.
+type SavedSearch struct {
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	UserID        uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name          string         `gorm:"type:varchar(100);not null" json:"name"`
+	Query         string         `gorm:"type:text" json:"query"`          // Raw query, may contain operators like from: or is:unread
+	Filters       datatypes.JSON `gorm:"type:jsonb" json:"filters"`       // SavedSearchFilters
+	ShowInFolders bool           `gorm:"not null" json:"show_in_folders"` // No default: gorm would write it over an explicit false
+	Notify        bool           `gorm:"default:false" json:"notify"`     // Publish an event when new emails match
+	MinScore      float64        `json:"min_score"`                       // Similarity a semantic match must reach
+
+	LastEvaluatedAt *time.Time `json:"last_evaluated_at,omitempty"`
+	// LastEvaluatedEmailID is the last email checked when a run stopped at the evaluation limit; later
+	// emails updated at LastEvaluatedAt are still to be checked.
+	LastEvaluatedEmailID *uuid.UUID `gorm:"type:uuid" json:"-"`
+	LastMatchedAt        *time.Time `json:"last_matched_at,omitempty"`
+	MatchCount           int        `json:"match_count"` // New matches found by the evaluator so far
+}
+
+// SavedSearchFilters are the search filters stored with a saved search.
+type SavedSearchFilters struct {
+	Sender    string     `json:"sender,omitempty"`
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+	ContextID *uuid.UUID `json:"context_id,omitempty"`
+	Mode      string     `json:"mode,omitempty"` // semantic | keyword | hybrid
+}
+
+// SavedSearchMatch records that an email has already been reported for a saved search.
+type SavedSearchMatch struct {
+	SavedSearchID uuid.UUID `gorm:"type:uuid;primary_key"`
+	EmailID       uuid.UUID `gorm:"type:uuid;primary_key"`
+	CreatedAt     time.Time
+}
+
+// SavedSearchInput defines the input structure for creating or updating a saved search.
+type SavedSearchInput struct {
+	Name          string             `json:"name" binding:"required,max=100"`
+	Query         string             `json:"query"`
+	Filters       SavedSearchFilters `json:"filters"`
+	ShowInFolders *bool              `json:"show_in_folders"`
+	Notify        *bool              `json:"notify"`
+	MinScore      *float64           `json:"min_score" binding:"omitempty,min=0,max=1"`
+}
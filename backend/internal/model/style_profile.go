@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+// Writing formality of a style profile.
+const (
+	FormalityFormal  = "formal"
+	FormalityNeutral = "neutral"
+	FormalityCasual  = "casual"
+)
+
+// StyleProfile describes how a user writes, learned from the mail they sent, so that AI drafts
+// sound like them.
+type StyleProfile struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Samples       int            `json:"samples"` // Sent emails learned from
+	Language      string         `gorm:"size:10" json:"language"`
+	Greeting      string         `gorm:"size:100" json:"greeting"`   // Most common opening, e.g. "Hi"
+	Closing       string         `gorm:"size:100" json:"closing"`    // Most common sign-off, e.g. "Best,"
+	MedianWords   int            `json:"median_words"`               // Typical length; a Chinese character counts as a word
+	SentenceWords float64        `json:"sentence_words"`             // Average words per sentence
+	Formality     string         `gorm:"size:20" json:"formality"`   // "formal", "neutral" or "casual"
+	Examples      datatypes.JSON `gorm:"type:jsonb" json:"examples"` // []string, openings of recent emails
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AIUsage is the token usage of one AI provider call. Cost is not stored: reports price the tokens
+// with the configured price table, so price changes apply to past usage too.
+type AIUsage struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID           uuid.UUID  `gorm:"type:uuid;not null;index:idx_ai_usage_user_day,priority:1" json:"user_id"`
+	OrgID            *uuid.UUID `gorm:"type:uuid;index:idx_ai_usage_org_day,priority:1" json:"org_id,omitempty"`                                           // The user's primary organization
+	Day              string     `gorm:"type:varchar(10);not null;index:idx_ai_usage_user_day,priority:2;index:idx_ai_usage_org_day,priority:2" json:"day"` // UTC date, YYYY-MM-DD
+	Capability       string     `gorm:"type:varchar(32);not null" json:"capability"`
+	Provider         string     `gorm:"type:varchar(64)" json:"provider"`
+	Model            string     `gorm:"type:varchar(128)" json:"model"`
+	PromptTokens     int        `gorm:"not null;default:0" json:"prompt_tokens"`
+	CompletionTokens int        `gorm:"not null;default:0" json:"completion_tokens"`
+	Estimated        bool       `gorm:"not null;default:false" json:"estimated"` // The provider reported no counts
+}
@@ -13,6 +13,7 @@ type User struct {
 	PasswordHash string    `gorm:"type:varchar(255);not null"` // bcrypt hash
 	Name         string    `gorm:"type:varchar(100)"`
 	Role         string    `gorm:"type:varchar(50);default:'manager';not null"` // Add Role field
+	Locale       string    `gorm:"type:varchar(20)"`                            // e.g. "zh-CN"; used by prompt templates
 
 	// WeChat Integration
 	WeChatOpenID  string `gorm:"type:varchar(64);uniqueIndex"` // Unique per app
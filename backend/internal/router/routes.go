@@ -7,25 +7,34 @@ import (
 
 // Handlers holds all HTTP handlers
 type Handlers struct {
-	Health      *handler.HealthHandler
-	Auth        *handler.AuthHandler
-	Org         *handler.OrganizationHandler
-	Account     *handler.AccountHandler
-	Sync        *handler.SyncHandler
-	Email       *handler.EmailHandler
-	Insight     *handler.InsightHandler
-	AIDraft     *handler.AIDraftHandler
-	Search      *handler.SearchHandler
-	Chat        *handler.ChatHandler
-	Task        *handler.TaskHandler
-	Context     *handler.ContextHandler
-	Action      *handler.ActionHandler
-	Opportunity *handler.OpportunityHandler
-	WeChat      interface{ Callback(c *gin.Context) } // WeChat gateway handler
+	Health            *handler.HealthHandler
+	Auth              *handler.AuthHandler
+	Org               *handler.OrganizationHandler
+	Account           *handler.AccountHandler
+	Sync              *handler.SyncHandler
+	Email             *handler.EmailHandler
+	Insight           *handler.InsightHandler
+	AIDraft           *handler.AIDraftHandler
+	Search            *handler.SearchHandler
+	Chat              *handler.ChatHandler
+	Task              *handler.TaskHandler
+	Context           *handler.ContextHandler
+	ContextSuggestion *handler.ContextSuggestionHandler
+	Action            *handler.ActionHandler
+	Opportunity       *handler.OpportunityHandler
+	SavedSearch       *handler.SavedSearchHandler
+	ChatSession       *handler.ChatSessionHandler
+	ChatToolAction    *handler.ChatToolActionHandler
+	Usage             *handler.UsageHandler
+	Prompt            *handler.PromptHandler
+	Redaction         *handler.RedactionHandler
+	Translation       *handler.TranslationHandler
+	WeChat            interface{ Callback(c *gin.Context) } // WeChat gateway handler
 }
 
-// SetupRoutes registers all API routes
-func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc) {
+// SetupRoutes registers all API routes. protectedMiddleware runs after authentication on every
+// protected route.
+func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc, protectedMiddleware ...gin.HandlerFunc) {
 	api := router.Group("/api/v1")
 	{
 		// Public routes
@@ -39,7 +48,7 @@ func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc
 		}
 
 		// Protected routes
-		protected := api.Group("/").Use(authMiddleware)
+		protected := api.Group("/").Use(append([]gin.HandlerFunc{authMiddleware}, protectedMiddleware...)...)
 		{
 			// Users
 			protected.PATCH("/users/me", h.Auth.UpdateUserProfile)
@@ -49,6 +58,17 @@ func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc
 			protected.GET("/orgs", h.Org.ListOrganizations)
 			protected.GET("/orgs/:id", h.Org.GetOrganization)
 			protected.GET("/orgs/:id/members", h.Org.GetMembers)
+			protected.GET("/orgs/:id/usage", h.Usage.GetOrgUsage)
+
+			// Prompt templates (per-organization versions and rollout)
+			protected.GET("/orgs/:id/prompts", h.Prompt.ListPrompts)
+			protected.POST("/orgs/:id/prompts/:name/versions", h.Prompt.CreateVersion)
+			protected.PUT("/orgs/:id/prompts/:name/rollout", h.Prompt.SetRollout)
+
+			// PII redaction (per-organization policy and audit log)
+			protected.GET("/orgs/:id/redaction", h.Redaction.GetPolicy)
+			protected.PUT("/orgs/:id/redaction", h.Redaction.SetPolicy)
+			protected.GET("/orgs/:id/redaction/audit", h.Redaction.GetAuditLog)
 
 			// Account & Sync
 			protected.POST("/settings/account", h.Account.ConnectAndSaveAccount)
@@ -59,6 +79,7 @@ func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc
 			// Emails & Insights
 			protected.GET("/emails", h.Email.ListEmails)
 			protected.GET("/emails/:id", h.Email.GetEmail)
+			protected.POST("/emails/:id/translate", h.Translation.TranslateEmail)
 			protected.DELETE("/emails/all", h.Email.DeleteAllEmails)
 			protected.GET("/insights/network", h.Insight.GetNetworkGraph)
 
@@ -72,9 +93,30 @@ func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc
 			// AI & Search
 			protected.POST("/ai/draft", h.AIDraft.GenerateDraft)
 			protected.POST("/ai/reply", h.AIDraft.GenerateReply)
+			protected.GET("/ai/style-profile", h.AIDraft.GetStyleProfile)
 			protected.GET("/search", h.Search.Search)
 			protected.POST("/chat/completions", h.Chat.StreamChat)
 
+			// AI Usage
+			protected.GET("/usage", h.Usage.GetUsage)
+			protected.GET("/usage/daily", h.Usage.GetDailyUsage)
+
+			// Chat Sessions (server-side history)
+			protected.GET("/chat/sessions", h.ChatSession.ListSessions)
+			protected.POST("/chat/sessions", h.ChatSession.CreateSession)
+			protected.GET("/chat/sessions/:id", h.ChatSession.GetSession)
+			protected.PATCH("/chat/sessions/:id", h.ChatSession.RenameSession)
+			protected.DELETE("/chat/sessions/:id", h.ChatSession.DeleteSession)
+			protected.POST("/chat/sessions/:id/messages", h.ChatSession.SendMessage)
+			protected.POST("/chat/tool-actions/:id/confirm", h.ChatToolAction.ConfirmAction)
+			protected.POST("/chat/tool-actions/:id/reject", h.ChatToolAction.RejectAction)
+
+			// Saved Searches (listed as folders via GET /emails?folder=saved:<id>)
+			protected.GET("/saved-searches", h.SavedSearch.ListSavedSearches)
+			protected.POST("/saved-searches", h.SavedSearch.CreateSavedSearch)
+			protected.PATCH("/saved-searches/:id", h.SavedSearch.UpdateSavedSearch)
+			protected.DELETE("/saved-searches/:id", h.SavedSearch.DeleteSavedSearch)
+
 			// Tasks
 			protected.POST("/tasks", h.Task.CreateTask)
 			protected.GET("/tasks", h.Task.ListTasks)
@@ -88,6 +130,13 @@ func SetupRoutes(router *gin.Engine, h *Handlers, authMiddleware gin.HandlerFunc
 			protected.PATCH("/contexts/:id", h.Context.UpdateContext)
 			protected.DELETE("/contexts/:id", h.Context.DeleteContext)
 
+			// Context Suggestions
+			protected.GET("/contexts/suggestions", h.ContextSuggestion.ListSuggestions)
+			protected.POST("/contexts/suggestions/generate", h.ContextSuggestion.GenerateSuggestions)
+			protected.POST("/contexts/suggestions/:id/accept", h.ContextSuggestion.AcceptSuggestion)
+			protected.POST("/contexts/suggestions/:id/merge", h.ContextSuggestion.MergeSuggestion)
+			protected.POST("/contexts/suggestions/:id/reject", h.ContextSuggestion.RejectSuggestion)
+
 			// Actions
 			protected.POST("/actions/approve", h.Action.ApproveEmail)
 			protected.POST("/actions/snooze", h.Action.SnoozeEmail)
@@ -2,18 +2,425 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
 	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/utils"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrDraftEmailNotFound = errors.New("email not found")
+	ErrDraftLength        = errors.New("invalid draft length")
+)
+
+const (
+	// maxThreadMessages bounds the messages of a thread given as context, the newest kept.
+	maxThreadMessages = 6
+	// maxThreadMessageRunes and maxReplyToRunes bound each earlier message and the one replied to.
+	maxThreadMessageRunes = 1500
+	maxReplyToRunes       = 6000
+	// maxDraftVariants bounds the alternative replies generated at once.
+	maxDraftVariants     = 3
+	maxDraftInstructions = 500
+)
+
+// Draft lengths, in words, accepted by GenerateReplies.
+var draftLengths = map[string]int{
+	"short":  60,
+	"medium": 120,
+	"long":   250,
+}
+
+// draftTones describes the named tones; other tones are passed to the model as written.
+var draftTones = map[string]string{
+	"professional": "professional and polite",
+	"formal":       "formal and respectful",
+	"casual":       "casual and relaxed",
+	"friendly":     "warm and friendly",
+	"concise":      "concise and to the point",
+	"apologetic":   "apologetic and constructive",
+	"assertive":    "firm, clear and courteous",
+}
+
+// styleTones is the tone the user usually writes in, by the formality of their style profile.
+var styleTones = map[string]string{
+	model.FormalityFormal:  "formal and respectful",
+	model.FormalityNeutral: "professional and natural",
+	model.FormalityCasual:  "casual and friendly",
+}
+
 type AIDraftService struct {
+	db         *gorm.DB
 	aiProvider ai.AIProvider
+	now        func() time.Time
 }
 
-func NewAIDraftService(aiProvider ai.AIProvider) *AIDraftService {
-	return &AIDraftService{aiProvider: aiProvider}
+func NewAIDraftService(db *gorm.DB, aiProvider ai.AIProvider) *AIDraftService {
+	return &AIDraftService{db: db, aiProvider: aiProvider, now: time.Now}
 }
 
 func (s *AIDraftService) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
 	return s.aiProvider.GenerateDraftReply(ctx, emailContent, userPrompt)
 }
+
+// ReplyOptions shapes the replies GenerateReplies drafts.
+type ReplyOptions struct {
+	Tone         string // A named tone such as "friendly", or a short description; default the user's usual tone
+	Length       string // "short", "medium" or "long"; default the user's usual length
+	Instructions string // Free-form guidance, e.g. "decline politely"
+	Variants     int    // Alternative replies, 1 to 3; default 3
+}
+
+// DraftVariant is one alternative reply.
+type DraftVariant struct {
+	Label string `json:"label"` // The approach, e.g. "Accept and propose a time"
+	Reply string `json:"reply"`
+	// Confidence estimates, from 0 to 1, how far the reply can be sent as is: how much thread,
+	// relationship and style context it was written from, less what the checks in Issues found.
+	// It is not the model's own certainty.
+	Confidence float64  `json:"confidence"`
+	Issues     []string `json:"issues,omitempty"` // "needs_input", "length_off_target", "language_mismatch", "unstructured_output"
+}
+
+// DraftContact is what is known of the relationship with the person replied to.
+type DraftContact struct {
+	Name             string    `json:"name,omitempty"`
+	Email            string    `json:"email"`
+	InteractionCount int       `json:"interaction_count"`
+	LastInteractedAt time.Time `json:"last_interacted_at"`
+	Sentiment        string    `json:"sentiment"` // "positive", "neutral" or "negative" on average
+}
+
+// DraftBasis is the context the replies were written from.
+type DraftBasis struct {
+	ThreadMessages int           `json:"thread_messages"` // Including the email replied to
+	Contact        *DraftContact `json:"contact,omitempty"`
+	StyleSamples   int           `json:"style_samples"` // Sent emails the user's style was learned from
+	Tone           string        `json:"tone"`
+	Length         string        `json:"length"` // "short", "medium", "long", or "usual" from the style profile
+	TargetWords    int           `json:"target_words"`
+	Language       string        `json:"language,omitempty"`
+}
+
+// ReplyDrafts are the alternative replies to an email, best first.
+type ReplyDrafts struct {
+	Variants []DraftVariant `json:"variants"`
+	Basis    DraftBasis     `json:"basis"`
+}
+
+// GenerateReplies drafts alternative replies to the user's email, written from its thread, the
+// user's history with the sender and the user's own writing style.
+func (s *AIDraftService) GenerateReplies(ctx context.Context, userID, emailID uuid.UUID, opts ReplyOptions) (*ReplyDrafts, error) {
+	if opts.Length != "" && draftLengths[opts.Length] == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrDraftLength, opts.Length)
+	}
+	if opts.Variants <= 0 || opts.Variants > maxDraftVariants {
+		opts.Variants = maxDraftVariants
+	}
+
+	var email model.Email
+	err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", emailID, userID).Take(&email).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDraftEmailNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.thread(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	contact, err := s.contact(ctx, userID, email.Sender)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.StyleProfile(ctx, userID, false)
+	if err != nil {
+		// Drafts are still useful without the user's style
+		fmt.Printf("Warning: failed to load style profile: %v\n", err)
+		profile = &model.StyleProfile{UserID: userID}
+	}
+
+	basis := DraftBasis{ThreadMessages: len(thread), Contact: contact, StyleSamples: profile.Samples}
+	basis.Tone, basis.Length, basis.TargetWords = draftTone(opts.Tone, profile), opts.Length, draftLengths[opts.Length]
+	if basis.Length == "" {
+		basis.Length, basis.TargetWords = "medium", draftLengths["medium"]
+		if profile.Samples >= 3 {
+			basis.Length, basis.TargetWords = "usual", min(max(profile.MedianWords, 30), 300)
+		}
+	}
+	basis.Language = email.Language
+	if basis.Language == "" {
+		basis.Language = ai.DetectLanguage(email.Subject + "\n" + email.BodyText)
+	}
+	if basis.Language == "" {
+		basis.Language = profile.Language
+	}
+
+	reply, err := s.aiProvider.GenerateDraftReply(ctx, threadTranscript(thread), draftInstructions(opts, basis, profile))
+	if err != nil {
+		return nil, err
+	}
+	variants, structured := parseDraftVariants(reply, opts.Variants)
+	grounding := draftGrounding(thread[len(thread)-1], basis)
+	for i := range variants {
+		checkDraftVariant(&variants[i], grounding, structured, basis)
+	}
+	sort.SliceStable(variants, func(i, j int) bool { return variants[i].Confidence > variants[j].Confidence })
+	return &ReplyDrafts{Variants: variants, Basis: basis}, nil
+}
+
+// subjectPrefix matches the reply and forward markers that mail clients put before a subject.
+var subjectPrefix = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|sv|回复|答复|转发)\s*(\[\d+\])?\s*[:：]\s*)+`)
+
+// threadSubject returns a subject without its reply and forward markers, lower-cased.
+func threadSubject(subject string) string {
+	return strings.ToLower(strings.TrimSpace(subjectPrefix.ReplaceAllString(subject, "")))
+}
+
+// thread returns the messages of the email's thread up to and including it, oldest first. Threads
+// are told by their subject, as mail stores no reply headers, among the messages sent by the
+// email's participants or the user.
+func (s *AIDraftService) thread(ctx context.Context, email model.Email) ([]model.Email, error) {
+	subject := threadSubject(email.Subject)
+	if subject == "" {
+		return []model.Email{email}, nil
+	}
+	participants, err := s.participants(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	var candidates []model.Email
+	err = s.db.WithContext(ctx).
+		Where("user_id = ? AND id <> ? AND date <= ?", email.UserID, email.ID, email.Date).
+		Where(`LOWER(subject) LIKE ? ESCAPE '\'`, "%"+utils.EscapeLike(subject)+"%").
+		Order("date DESC").Limit(4 * maxThreadMessages).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	thread := []model.Email{email}
+	for _, candidate := range candidates {
+		if len(thread) == maxThreadMessages {
+			break
+		}
+		if threadSubject(candidate.Subject) == subject && participants[senderAddress(candidate.Sender)] {
+			thread = append(thread, candidate)
+		}
+	}
+	for i, j := 0, len(thread)-1; i < j; i, j = i+1, j-1 {
+		thread[i], thread[j] = thread[j], thread[i]
+	}
+	return thread, nil
+}
+
+// participants returns the lower-cased addresses of the email's sender and recipients, and the user's own.
+func (s *AIDraftService) participants(ctx context.Context, email model.Email) (map[string]bool, error) {
+	addresses, err := s.userAddresses(ctx, email.UserID)
+	if err != nil {
+		return nil, err
+	}
+	var to, cc []string
+	_ = json.Unmarshal(email.To, &to)
+	_ = json.Unmarshal(email.Cc, &cc)
+	participants := map[string]bool{senderAddress(email.Sender): true}
+	for _, address := range append(append(addresses, to...), cc...) {
+		participants[senderAddress(address)] = true
+	}
+	return participants, nil
+}
+
+// contact returns the user's contact record for the sender, or nil when there is none.
+func (s *AIDraftService) contact(ctx context.Context, userID uuid.UUID, sender string) (*DraftContact, error) {
+	address := senderAddress(sender)
+	if address == "" {
+		return nil, nil
+	}
+	var contacts []model.Contact
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND email IN ?", userID, []string{sender, address}).
+		Order("interaction_count DESC").Limit(1).
+		Find(&contacts).Error
+	if err != nil || len(contacts) == 0 {
+		return nil, err
+	}
+	c := contacts[0]
+	contact := &DraftContact{Name: c.Name, Email: address, InteractionCount: c.InteractionCount, LastInteractedAt: c.LastInteractedAt, Sentiment: "neutral"}
+	switch {
+	case c.AvgSentiment >= 0.3:
+		contact.Sentiment = "positive"
+	case c.AvgSentiment <= -0.3:
+		contact.Sentiment = "negative"
+	}
+	return contact, nil
+}
+
+// draftTone describes the requested tone, or the user's usual one.
+func draftTone(tone string, profile *model.StyleProfile) string {
+	tone = strings.TrimSpace(tone)
+	if described, ok := draftTones[strings.ToLower(tone)]; ok {
+		return described
+	}
+	if tone != "" {
+		return truncateRunes(tone, 60)
+	}
+	if profile.Samples > 0 {
+		return styleTones[profile.Formality]
+	}
+	return draftTones["professional"]
+}
+
+// threadTranscript renders a thread for the model, the message replied to last and in full.
+func threadTranscript(thread []model.Email) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Subject: %s\n", thread[len(thread)-1].Subject)
+	for i, email := range thread {
+		body := stripQuoted(email.BodyText)
+		if body == "" {
+			body = email.Snippet
+		}
+		limit, heading := maxThreadMessageRunes, fmt.Sprintf("Message %d of %d", i+1, len(thread))
+		if i == len(thread)-1 {
+			limit, heading = maxReplyToRunes, "Message to reply to"
+		}
+		fmt.Fprintf(&b, "\n--- %s ---\nFrom: %s\nDate: %s\n\n%s\n", heading, email.Sender, email.Date.Format("2006-01-02 15:04"), truncateRunes(body, limit))
+	}
+	return b.String()
+}
+
+// draftInstructions asks for the variants in the requested tone and length, from the user's
+// relationship with the sender and in the user's style.
+func draftInstructions(opts ReplyOptions, basis DraftBasis, profile *model.StyleProfile) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Write %d alternative replies, from the user, to the last message of the thread.\n", opts.Variants)
+	fmt.Fprintf(&b, "Tone: %s. Length: about %d words each (count each Chinese, Japanese or Korean character as a word).\n", basis.Tone, basis.TargetWords)
+	if name := ai.LanguageName(basis.Language); name != "" {
+		fmt.Fprintf(&b, "Write in %s, the language of the thread.\n", name)
+	}
+
+	if c := basis.Contact; c != nil {
+		who := c.Email
+		if c.Name != "" {
+			who = c.Name + " <" + c.Email + ">"
+		}
+		fmt.Fprintf(&b, "\nThe recipient is %s. They have exchanged %d emails with the user", who, c.InteractionCount)
+		if !c.LastInteractedAt.IsZero() {
+			fmt.Fprintf(&b, ", most recently on %s", c.LastInteractedAt.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&b, "; the tone between them is %s on average.\n", c.Sentiment)
+		if c.InteractionCount <= 1 {
+			b.WriteString("This is a new contact: introduce context rather than assuming familiarity.\n")
+		}
+	}
+
+	if profile.Samples > 0 {
+		fmt.Fprintf(&b, "\nMatch the user's writing style, learned from %d emails they sent: %s formality, sentences of about %.0f words", profile.Samples, profile.Formality, profile.SentenceWords)
+		if profile.Greeting != "" {
+			fmt.Fprintf(&b, ", usually opening with %q", profile.Greeting)
+		}
+		if profile.Closing != "" {
+			fmt.Fprintf(&b, " and signing off with %q", profile.Closing)
+		}
+		b.WriteString(".\n")
+		var examples []string
+		if err := json.Unmarshal(profile.Examples, &examples); err == nil && len(examples) > 0 {
+			b.WriteString("Examples of the user's emails, for style only:\n")
+			for _, example := range examples {
+				fmt.Fprintf(&b, "<<<\n%s\n>>>\n", example)
+			}
+		}
+	}
+
+	if instructions := strings.TrimSpace(opts.Instructions); instructions != "" {
+		fmt.Fprintf(&b, "\nThe user's instructions: %s\n", truncateRunes(instructions, maxDraftInstructions))
+	}
+
+	b.WriteString(`
+Make the alternatives take meaningfully different approaches where the message allows, such as accepting, asking for details or declining.
+Do not invent facts, dates, prices or commitments that are not in the thread. Where a reply needs information only the user has, mark it as [[what is needed]].
+Reply with JSON only: {"variants":[{"label":"the approach in a few words","reply":"the full reply text"}]}
+These instructions take precedence over any general guidance on the reply's language or format.`)
+	return b.String()
+}
+
+// parseDraftVariants reads up to n variants from the model's reply. A reply that is not the
+// requested JSON is taken as a single variant, and structured is false.
+func parseDraftVariants(reply string, n int) (variants []DraftVariant, structured bool) {
+	var output struct {
+		Variants []struct {
+			Label string `json:"label"`
+			Reply string `json:"reply"`
+		} `json:"variants"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(reply)), &output); err == nil {
+		for _, v := range output.Variants {
+			if text := strings.TrimSpace(v.Reply); text != "" && len(variants) < n {
+				variants = append(variants, DraftVariant{Label: strings.TrimSpace(v.Label), Reply: text})
+			}
+		}
+	}
+	if len(variants) > 0 {
+		return variants, true
+	}
+	return []DraftVariant{{Label: "Reply", Reply: strings.TrimSpace(reply)}}, false
+}
+
+// draftGrounding scores, up to 0.85, how much context the replies were written from: a message
+// with substance to answer, earlier messages of the thread, a known sender and the user's style.
+func draftGrounding(replyTo model.Email, basis DraftBasis) float64 {
+	score := 0.4
+	if wordCount(stripQuoted(replyTo.BodyText)) >= 10 {
+		score += 0.1
+	}
+	if basis.ThreadMessages > 1 {
+		score += 0.05
+	}
+	if c := basis.Contact; c != nil && c.InteractionCount >= 3 {
+		score += 0.1
+	} else if c != nil && c.InteractionCount > 0 {
+		score += 0.05
+	}
+	if basis.StyleSamples >= 5 {
+		score += 0.1
+	} else if basis.StyleSamples > 0 {
+		score += 0.05
+	}
+	return score
+}
+
+// placeholder matches the gaps a reply leaves for the user, such as [[meeting time]] or [Your Name].
+var placeholder = regexp.MustCompile(`\[\[[^\]]+\]\]|\[(?i:your [^\]]+|name|date|time|company)\]`)
+
+// checkDraftVariant sets the variant's confidence from the grounding of the replies, less what
+// checking the variant finds.
+func checkDraftVariant(v *DraftVariant, grounding float64, structured bool, basis DraftBasis) {
+	confidence := grounding
+	if structured {
+		confidence += 0.1
+	} else {
+		v.Issues = append(v.Issues, "unstructured_output")
+	}
+	if gaps := len(placeholder.FindAllString(v.Reply, -1)); gaps > 0 {
+		confidence -= math.Min(0.1*float64(gaps), 0.3)
+		v.Issues = append(v.Issues, "needs_input")
+	}
+	if ratio := float64(wordCount(v.Reply)) / float64(basis.TargetWords); ratio < 0.4 || ratio > 2.5 {
+		confidence -= 0.1
+		v.Issues = append(v.Issues, "length_off_target")
+	}
+	if language := ai.DetectLanguage(v.Reply); language != "" && basis.Language != "" && language != basis.Language {
+		confidence -= 0.2
+		v.Issues = append(v.Issues, "language_mismatch")
+	}
+	v.Confidence = math.Round(math.Min(math.Max(confidence, 0.05), 0.95)*100) / 100
+}
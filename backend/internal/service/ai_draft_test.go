@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestAIDraftService(t *testing.T) (*AIDraftService, *MockAIProvider, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file:ai_draft?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.EmailAccount{}, &model.Email{}, &model.Contact{}, &model.StyleProfile{}))
+	t.Cleanup(func() {
+		for _, table := range []string{"style_profiles", "contacts", "emails", "email_accounts", "users"} {
+			db.Exec("DELETE FROM " + table)
+		}
+	})
+	provider := new(MockAIProvider)
+	svc := NewAIDraftService(db, provider)
+	svc.now = func() time.Time { return time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC) }
+	return svc, provider, db
+}
+
+func TestLearnStyleProfile(t *testing.T) {
+	userID := uuid.New()
+	sent := []string{
+		"Hi Alice,\n\nSounds good, I'll send the numbers and the updated forecast by Friday morning. Let me know if anything's missing!\n\nCheers,\nSam\n\nOn Mon, Alice wrote:\n> Dear Sam, could you please send the figures?",
+		"Hi team,\n\nQuick update: the launch moves to Friday. Thanks for the patience!\n\nCheers,\nSam",
+		"Hey Bob,\n\nCan't make it today, sorry.\n\nThanks,\nSam",
+		"",
+	}
+	profile := LearnStyleProfile(userID, sent)
+
+	assert.Equal(t, 3, profile.Samples)
+	assert.Equal(t, "en", profile.Language)
+	assert.Equal(t, "Hi", profile.Greeting)
+	assert.Equal(t, "Cheers,", profile.Closing)
+	assert.Equal(t, model.FormalityCasual, profile.Formality)
+	assert.Equal(t, 15, profile.MedianWords)
+
+	var examples []string
+	require.NoError(t, json.Unmarshal(profile.Examples, &examples))
+	require.Len(t, examples, 1)
+	assert.NotContains(t, examples[0], "could you please", "quoted text is not the user's")
+}
+
+func TestLearnStyleProfile_Chinese(t *testing.T) {
+	profile := LearnStyleProfile(uuid.New(), []string{"您好，\n\n附件是本月的报告，烦请审阅。\n\n此致\n敬礼"})
+
+	assert.Equal(t, "zh", profile.Language)
+	assert.Equal(t, "您好", profile.Greeting)
+	assert.Equal(t, "此致", profile.Closing)
+	assert.Equal(t, model.FormalityFormal, profile.Formality)
+}
+
+func TestThreadSubject(t *testing.T) {
+	assert.Equal(t, "budget q3", threadSubject("Re: RE: Fwd: Budget Q3"))
+	assert.Equal(t, "预算", threadSubject("回复：预算"))
+	assert.Equal(t, "reorg plans", threadSubject("Reorg plans"))
+}
+
+// seedDraftThread stores a thread with Alice, in which the user wrote once, and returns the
+// user's ID and the email to reply to.
+func seedDraftThread(t *testing.T, db *gorm.DB) (uuid.UUID, model.Email) {
+	userID := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: userID, Email: "sam@example.com", Name: "Sam", WeChatOpenID: userID.String()}).Error)
+	require.NoError(t, db.Create(&model.EmailAccount{ID: uuid.New(), UserID: &userID, Email: "Sam@Work.io", ServerAddress: "imap.work.io", Username: "sam"}).Error)
+
+	day := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	emails := []model.Email{
+		{Subject: "Budget Q3", Sender: "Alice <alice@acme.com>", Date: day, BodyText: "Can we review the Q3 budget this week? The marketing line looks high."},
+		{Subject: "Re: Budget Q3", Sender: "Sam <sam@work.io>", Date: day.Add(time.Hour), BodyText: "Hi Alice,\n\nSure, I'll pull the numbers together. Let me know what works for you!\n\nCheers,\nSam"},
+		{Subject: "Lunch", Sender: "Bob <bob@acme.com>", Date: day.Add(2 * time.Hour), BodyText: "Lunch on Friday?"},
+		// Same subject, but not someone in the conversation
+		{Subject: "Re: Budget Q3", Sender: "Carol <carol@other.org>", Date: day.Add(150 * time.Minute), BodyText: "Our Q3 budget is final."},
+		{Subject: "RE: Budget Q3", Sender: "Alice <alice@acme.com>", Date: day.Add(3 * time.Hour), BodyText: "Great, thanks. Could we meet on Thursday to go through it? I can do the morning or after 3pm."},
+	}
+	for i := range emails {
+		emails[i].ID, emails[i].UserID, emails[i].MessageID, emails[i].Language = uuid.New(), userID, uuid.NewString(), "en"
+		require.NoError(t, db.Create(&emails[i]).Error)
+	}
+	require.NoError(t, db.Create(&model.Contact{ID: uuid.New(), UserID: &userID, Email: "Alice <alice@acme.com>", Name: "Alice", InteractionCount: 6, LastInteractedAt: day, AvgSentiment: 0.5}).Error)
+	return userID, emails[4]
+}
+
+func TestAIDraftService_GenerateReplies(t *testing.T) {
+	svc, provider, db := newTestAIDraftService(t)
+	userID, email := seedDraftThread(t, db)
+
+	threadContext := mock.MatchedBy(func(content string) bool {
+		return strings.Contains(content, "Message 1 of 3") && strings.Contains(content, "Message to reply to") &&
+			strings.Contains(content, "I'll pull the numbers together") && !strings.Contains(content, "Lunch") &&
+			!strings.Contains(content, "Our Q3 budget is final")
+	})
+	instructions := mock.MatchedBy(func(prompt string) bool {
+		return strings.Contains(prompt, "Write 2 alternative replies") &&
+			strings.Contains(prompt, "Tone: warm and friendly") &&
+			strings.Contains(prompt, "about 60 words") &&
+			strings.Contains(prompt, "Write in English") &&
+			strings.Contains(prompt, "Alice <alice@acme.com>") && strings.Contains(prompt, "exchanged 6 emails") &&
+			strings.Contains(prompt, `signing off with "Cheers,"`) &&
+			strings.Contains(prompt, "The user's instructions: mention the forecast") &&
+			strings.Contains(prompt, "take precedence over any general guidance")
+	})
+	provider.On("GenerateDraftReply", mock.Anything, threadContext, instructions).Return("```json\n"+`{"variants":[
+		{"label":"Ask for a time","reply":"Hi Alice,\n\nThursday works. Would [[time]] suit you? I will bring the forecast and the numbers for the marketing line so we can go through them together.\n\nCheers,\nSam"},
+		{"label":"Accept the morning","reply":"Hi Alice,\n\nThursday morning works well for me. I will bring the forecast along with the numbers for the marketing line, so we can go through everything in one sitting.\n\nCheers,\nSam"},
+		{"label":"Extra","reply":"Hi Alice, sure.\n\nCheers,\nSam"}
+	]}`+"\n```", nil).Once()
+
+	drafts, err := svc.GenerateReplies(context.Background(), userID, email.ID, ReplyOptions{Tone: "Friendly", Length: "short", Instructions: "mention the forecast", Variants: 2})
+	require.NoError(t, err)
+	provider.AssertExpectations(t)
+
+	assert.Equal(t, DraftBasis{
+		ThreadMessages: 3,
+		Contact:        &DraftContact{Name: "Alice", Email: "alice@acme.com", InteractionCount: 6, LastInteractedAt: time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC), Sentiment: "positive"},
+		StyleSamples:   1,
+		Tone:           "warm and friendly",
+		Length:         "short",
+		TargetWords:    60,
+		Language:       "en",
+	}, drafts.Basis)
+
+	// The complete draft ranks first; the one waiting on the user's input is less confident.
+	require.Len(t, drafts.Variants, 2)
+	assert.Equal(t, "Accept the morning", drafts.Variants[0].Label)
+	assert.Empty(t, drafts.Variants[0].Issues)
+	assert.Equal(t, 0.8, drafts.Variants[0].Confidence)
+	assert.Equal(t, "Ask for a time", drafts.Variants[1].Label)
+	assert.Equal(t, []string{"needs_input"}, drafts.Variants[1].Issues)
+	assert.Equal(t, 0.7, drafts.Variants[1].Confidence)
+
+	// The style profile was learned from the user's own message and stored.
+	var profile model.StyleProfile
+	require.NoError(t, db.Where("user_id = ?", userID).Take(&profile).Error)
+	assert.Equal(t, 1, profile.Samples)
+	assert.Equal(t, "Hi", profile.Greeting)
+}
+
+func TestAIDraftService_GenerateReplies_PlainTextReply(t *testing.T) {
+	svc, provider, db := newTestAIDraftService(t)
+	userID, email := seedDraftThread(t, db)
+
+	// Without the requested JSON the reply is one variant, and an answer in the wrong language is flagged.
+	provider.On("GenerateDraftReply", mock.Anything, mock.Anything, mock.Anything).Return("好的，周四上午见。", nil).Once()
+	drafts, err := svc.GenerateReplies(context.Background(), userID, email.ID, ReplyOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, drafts.Variants, 1)
+	assert.Equal(t, "好的，周四上午见。", drafts.Variants[0].Reply)
+	assert.Equal(t, []string{"unstructured_output", "length_off_target", "language_mismatch"}, drafts.Variants[0].Issues)
+	assert.Equal(t, 0.4, drafts.Variants[0].Confidence)
+	assert.Equal(t, "casual and friendly", drafts.Basis.Tone, "the user's usual tone")
+	assert.Equal(t, "medium", drafts.Basis.Length, "too few samples for the user's usual length")
+}
+
+func TestAIDraftService_GenerateReplies_Errors(t *testing.T) {
+	svc, provider, db := newTestAIDraftService(t)
+	userID, email := seedDraftThread(t, db)
+	ctx := context.Background()
+
+	_, err := svc.GenerateReplies(ctx, uuid.New(), email.ID, ReplyOptions{})
+	assert.ErrorIs(t, err, ErrDraftEmailNotFound, "another user's email")
+
+	_, err = svc.GenerateReplies(ctx, userID, email.ID, ReplyOptions{Length: "epic"})
+	assert.ErrorIs(t, err, ErrDraftLength)
+	provider.AssertNotCalled(t, "GenerateDraftReply", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAIDraftService_StyleProfile(t *testing.T) {
+	svc, _, db := newTestAIDraftService(t)
+	userID, _ := seedDraftThread(t, db)
+	ctx := context.Background()
+
+	profile, err := svc.StyleProfile(ctx, userID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, profile.Samples)
+
+	// A stored profile is used until it is a day old or refreshed.
+	require.NoError(t, db.Create(&model.Email{ID: uuid.New(), UserID: userID, MessageID: "sent-2", Subject: "Notes", Sender: "sam@example.com", Date: time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC), BodyText: "Hey, notes attached!"}).Error)
+	profile, err = svc.StyleProfile(ctx, userID, false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, profile.Samples)
+
+	profile, err = svc.StyleProfile(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, profile.Samples)
+
+	// Mail synced from the sent folder counts whichever address it was sent from.
+	require.NoError(t, db.Create(&model.Email{ID: uuid.New(), UserID: userID, MessageID: "sent-3", Subject: "Hello", Sender: "sam@alias.io", Folder: model.FolderSent, Date: time.Date(2026, 5, 3, 9, 0, 0, 0, time.UTC), BodyText: "Hello there!"}).Error)
+	profile, err = svc.StyleProfile(ctx, userID, true)
+	require.NoError(t, err)
+	assert.Equal(t, 3, profile.Samples)
+}
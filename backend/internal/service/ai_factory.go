@@ -2,66 +2,309 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hrygo/echomind/configs"
 	"github.com/hrygo/echomind/pkg/ai"
 	"github.com/hrygo/echomind/pkg/ai/mock"
 	"github.com/hrygo/echomind/pkg/ai/registry"
+	"github.com/hrygo/echomind/pkg/ai/resilient"
 
 	// Import providers to trigger registration
+	_ "github.com/hrygo/echomind/pkg/ai/anthropic"
 	_ "github.com/hrygo/echomind/pkg/ai/gemini"
+	_ "github.com/hrygo/echomind/pkg/ai/hashing"
+	_ "github.com/hrygo/echomind/pkg/ai/local"
 	_ "github.com/hrygo/echomind/pkg/ai/openai"
 )
 
-// CompositeProvider combines an AIProvider (for text) and an EmbeddingProvider.
+// CompositeProvider combines the providers routed to each capability with an EmbeddingProvider.
+// The embedded AIProvider serves chat and any capability without a route of its own.
 type CompositeProvider struct {
 	ai.AIProvider
 	ai.EmbeddingProvider
+	routes    map[ai.Capability]ai.AIProvider
+	redaction ai.RedactionScope // For calls whose context has no scope of its own
 }
 
-// NewAIProvider creates an AIProvider based on the configuration.
-func NewAIProvider(cfg *configs.AIConfig) (ai.AIProvider, error) {
-	prompts := toPromptMap(cfg.Prompts)
-	var mainProvider ai.AIProvider
-	var embeddingProvider ai.EmbeddingProvider
-	var err error
-
-	// Helper to create provider by name
-	createProvider := func(name string) (interface{}, error) {
-		// Handle "mock" special case
-		if name == "mock" {
-			return mock.NewProvider(), nil
-		}
+// Route returns the provider serving capability.
+func (c *CompositeProvider) Route(capability ai.Capability) ai.AIProvider {
+	if p, ok := c.routes[capability]; ok {
+		return p
+	}
+	return c.AIProvider
+}
 
-		pConfig, ok := cfg.Providers[name]
-		if !ok {
-			return nil, fmt.Errorf("provider configuration not found: %s", name)
-		}
+// SetRedaction sets the redaction of calls made outside a user's scope, such as sweeps over every
+// user.
+func (c *CompositeProvider) SetRedaction(scope ai.RedactionScope) {
+	c.redaction = scope
+}
 
-		factory, err := registry.Get(pConfig.Protocol)
-		if err != nil {
-			return nil, err
-		}
+// routed returns the provider for capability, labelling its calls, enforcing usage quotas and
+// redacting PII.
+func (c *CompositeProvider) routed(capability ai.Capability) *capabilityProvider {
+	return &capabilityProvider{provider: c.Route(capability), capability: capability, composite: c}
+}
+
+func (c *CompositeProvider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
+	return c.routed(ai.CapabilitySummary).Summarize(ctx, text)
+}
+
+func (c *CompositeProvider) Classify(ctx context.Context, text string) (string, error) {
+	return c.routed(ai.CapabilityClassify).Classify(ctx, text)
+}
+
+func (c *CompositeProvider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
+	return c.routed(ai.CapabilitySentiment).AnalyzeSentiment(ctx, text)
+}
+
+func (c *CompositeProvider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	return c.routed(ai.CapabilityDraft).GenerateDraftReply(ctx, emailContent, userPrompt)
+}
+
+func (c *CompositeProvider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	return c.routed(ai.CapabilityChat).StreamChat(ctx, messages, ch)
+}
+
+// EmbeddingModel forwards to the embedding provider so callers can tell embedding spaces apart.
+func (c *CompositeProvider) EmbeddingModel() string {
+	return ai.EmbeddingModelName(c.EmbeddingProvider)
+}
+
+// ChatWithTools forwards to the chat provider; see SupportsTools.
+func (c *CompositeProvider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	return c.routed(ai.CapabilityChat).ChatWithTools(ctx, messages, tools)
+}
+
+// StreamChatWithTools forwards to the chat provider; see SupportsTools.
+func (c *CompositeProvider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	return c.routed(ai.CapabilityChat).StreamChatWithTools(ctx, messages, tools, ch)
+}
+
+// SupportsTools reports whether the chat provider supports function calling.
+func (c *CompositeProvider) SupportsTools() bool {
+	_, ok := ai.AsToolCaller(c.AIProvider)
+	return ok
+}
+
+// ProviderFor returns the provider routed to capability, for services that make a single kind of
+// call (e.g. search summaries) through the generic AIProvider methods.
+func ProviderFor(p ai.AIProvider, capability ai.Capability) ai.AIProvider {
+	if composite, ok := p.(*CompositeProvider); ok {
+		return composite.routed(capability)
+	}
+	return p
+}
+
+// capabilityProvider serves every call from one route, labelled with the route's capability for
+// usage accounting. Calls are refused with ai.ErrQuotaExceeded once the caller's quota is spent.
+// PII in the request is replaced with placeholders, which are restored in the reply.
+type capabilityProvider struct {
+	provider   ai.AIProvider
+	capability ai.Capability
+	composite  *CompositeProvider // Read at call time: SetRedaction may come after ProviderFor
+}
+
+// redactionScope returns the context's redaction scope, or the composite's for unscoped calls.
+func (p *capabilityProvider) redactionScope(ctx context.Context) ai.RedactionScope {
+	if scope, ok := ai.RedactionScopeFrom(ctx); ok {
+		return scope
+	}
+	return p.composite.redaction
+}
+
+// begin checks the quota and starts the call's redaction, which is nil if nothing is masked.
+func (p *capabilityProvider) begin(ctx context.Context) (context.Context, *ai.Redaction, error) {
+	if err := ai.CheckQuota(ctx, p.capability); err != nil {
+		return ctx, nil, err
+	}
+	ctx = ai.WithCapability(ctx, p.capability)
+
+	scope := p.redactionScope(ctx)
+	if scope == nil {
+		return ctx, nil, nil
+	}
+	policy := scope.Policy(ctx)
+	if !policy.Enabled() {
+		return ctx, nil, nil
+	}
+	return ctx, ai.NewRedaction(policy), nil
+}
+
+// audit records what the call's redaction masked, once the request text has been redacted.
+func (p *capabilityProvider) audit(ctx context.Context, r *ai.Redaction) {
+	if r == nil || len(r.Counts()) == 0 {
+		return
+	}
+	p.redactionScope(ctx).Audit(ctx, p.capability, r.Counts())
+}
+
+func (p *capabilityProvider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		return ai.AnalysisResult{}, err
+	}
+	if r == nil {
+		return p.provider.Summarize(ctx, text)
+	}
+	text = r.Redact(text)
+	p.audit(ctx, r)
+	result, err := p.provider.Summarize(ctx, text)
+	return r.RestoreAnalysis(result), err
+}
+
+func (p *capabilityProvider) Classify(ctx context.Context, text string) (string, error) {
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return p.provider.Classify(ctx, text)
+	}
+	text = r.Redact(text)
+	p.audit(ctx, r)
+	label, err := p.provider.Classify(ctx, text)
+	return r.Restore(label), err
+}
+
+func (p *capabilityProvider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		return ai.SentimentResult{}, err
+	}
+	if r != nil {
+		text = r.Redact(text)
+		p.audit(ctx, r)
+	}
+	return p.provider.AnalyzeSentiment(ctx, text)
+}
+
+func (p *capabilityProvider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return p.provider.GenerateDraftReply(ctx, emailContent, userPrompt)
+	}
+	emailContent, userPrompt = r.Redact(emailContent), r.Redact(userPrompt)
+	p.audit(ctx, r)
+	reply, err := p.provider.GenerateDraftReply(ctx, emailContent, userPrompt)
+	return r.Restore(reply), err
+}
+
+func (p *capabilityProvider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		close(ch)
+		return err
+	}
+	if r == nil {
+		return p.provider.StreamChat(ctx, messages, ch)
+	}
+	messages = r.RedactMessages(messages)
+	p.audit(ctx, r)
+
+	redacted := make(chan ai.ChatCompletionChunk)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.RestoreStream(redacted, ch)
+	}()
+	err = p.provider.StreamChat(ctx, messages, redacted)
+	<-done
+	return err
+}
+
+func (p *capabilityProvider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	caller, ok := ai.AsToolCaller(p.provider)
+	if !ok {
+		return ai.ToolChatResponse{}, errors.New("chat provider does not support tools")
+	}
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		return ai.ToolChatResponse{}, err
+	}
+	if r == nil {
+		return caller.ChatWithTools(ctx, messages, tools)
+	}
+	messages = r.RedactMessages(messages)
+	p.audit(ctx, r)
+	resp, err := caller.ChatWithTools(ctx, messages, tools)
+	// Tools run with the real values, e.g. a search for a sender's address.
+	resp.Content = r.Restore(resp.Content)
+	for i := range resp.ToolCalls {
+		resp.ToolCalls[i].Arguments = r.Restore(resp.ToolCalls[i].Arguments)
+	}
+	return resp, err
+}
+
+func (p *capabilityProvider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	caller, ok := ai.AsToolCaller(p.provider)
+	if !ok {
+		close(ch)
+		return ai.ToolChatResponse{}, errors.New("chat provider does not support tools")
+	}
+	ctx, r, err := p.begin(ctx)
+	if err != nil {
+		close(ch)
+		return ai.ToolChatResponse{}, err
+	}
+	if r == nil {
+		return ai.StreamChatWithTools(ctx, caller, messages, tools, ch)
+	}
+	messages = r.RedactMessages(messages)
+	p.audit(ctx, r)
 
-		return factory(context.Background(), pConfig.Settings, prompts)
+	redacted := make(chan ai.ChatCompletionChunk)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.RestoreStream(redacted, ch)
+	}()
+	resp, err := ai.StreamChatWithTools(ctx, caller, messages, tools, redacted)
+	<-done
+	resp.Content = r.Restore(resp.Content)
+	for i := range resp.ToolCalls {
+		resp.ToolCalls[i].Arguments = r.Restore(resp.ToolCalls[i].Arguments)
 	}
+	return resp, err
+}
+
+func (p *capabilityProvider) SupportsTools() bool {
+	_, ok := ai.AsToolCaller(p.provider)
+	return ok
+}
 
-	// 1. Initialize Chat Provider
+// NewAIProvider creates an AIProvider based on the configuration.
+func NewAIProvider(cfg *configs.AIConfig) (ai.AIProvider, error) {
 	chatProviderName := cfg.ActiveServices.Chat
 	if chatProviderName == "" {
 		chatProviderName = "mock" // Default fallback
 	}
-
-	chatP, err := createProvider(chatProviderName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create chat provider '%s': %w", chatProviderName, err)
+	b := &routeBuilder{
+		cfg:       cfg,
+		prompts:   toPromptMap(cfg.Prompts),
+		instances: map[string]interface{}{},
+		chains:    map[string]ai.AIProvider{},
 	}
 
-	if p, ok := chatP.(ai.AIProvider); ok {
-		mainProvider = p
-	} else {
-		return nil, fmt.Errorf("provider '%s' does not implement AIProvider", chatProviderName)
+	// 1. Initialize a provider chain per capability. Routes with the same provider and parameters
+	// share one chain, and so its circuit breakers.
+	routes := make(map[ai.Capability]ai.AIProvider, len(ai.Capabilities))
+	for _, capability := range ai.Capabilities {
+		name := routeProviderName(cfg.ActiveServices, capability)
+		if name == "" {
+			name = chatProviderName
+		}
+		p, err := b.chain(name, routeParams(cfg.ActiveServices, capability))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s provider '%s': %w", capability, name, err)
+		}
+		routes[capability] = p
 	}
 
 	// 2. Initialize Embedding Provider
@@ -69,30 +312,193 @@ func NewAIProvider(cfg *configs.AIConfig) (ai.AIProvider, error) {
 	if embedProviderName == "" {
 		embedProviderName = chatProviderName // Fallback to same provider
 	}
+	// Reuses the chat provider's instance when it has no route parameters.
+	embedP, err := b.instance(embedProviderName, ai.ModelParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding provider '%s': %w", embedProviderName, err)
+	}
+	embeddingProvider, ok := embedP.(ai.EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' does not implement EmbeddingProvider", embedProviderName)
+	}
 
-	// Optimization: If chat and embedding use same provider name, reuse instance if it implements both
-	if embedProviderName == chatProviderName {
-		if p, ok := chatP.(ai.EmbeddingProvider); ok {
-			embeddingProvider = p
-		}
+	// 3. PII redaction with the default policy, until SetRedaction adds the audit log
+	redaction, err := DefaultRedactionPolicy(cfg.Redaction)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompositeProvider{
+		AIProvider:        routes[ai.CapabilityChat],
+		EmbeddingProvider: embeddingProvider,
+		routes:            routes,
+		redaction:         staticRedaction{policy: redaction},
+	}, nil
+}
+
+// staticRedaction applies a fixed policy and keeps no audit log.
+type staticRedaction struct {
+	policy ai.RedactionPolicy
+}
+
+func (r staticRedaction) Policy(ctx context.Context) ai.RedactionPolicy { return r.policy }
+
+func (r staticRedaction) Audit(ctx context.Context, capability ai.Capability, counts map[ai.PIIKind]int) {
+}
+
+// routeBuilder creates provider instances and fallback chains once per provider and parameters.
+type routeBuilder struct {
+	cfg       *configs.AIConfig
+	prompts   map[string]string
+	instances map[string]interface{}
+	chains    map[string]ai.AIProvider
+}
+
+func (b *routeBuilder) instance(name string, params ai.ModelParams) (interface{}, error) {
+	key := routeKey(name, params)
+	if p, ok := b.instances[key]; ok {
+		return p, nil
+	}
+	p, err := newProviderByName(b.cfg, name, b.prompts, params)
+	if err != nil {
+		return nil, err
+	}
+	b.instances[key] = p
+	return p, nil
+}
+
+// chain wraps the named provider and the chat fallbacks, with the same parameters, in retries,
+// circuit breaking and failover.
+func (b *routeBuilder) chain(name string, params ai.ModelParams) (ai.AIProvider, error) {
+	key := routeKey(name, params)
+	if p, ok := b.chains[key]; ok {
+		return p, nil
 	}
 
-	if embeddingProvider == nil {
-		embedP, err := createProvider(embedProviderName)
+	var chain []resilient.Named
+	for i, member := range append([]string{name}, b.cfg.ActiveServices.ChatFallbacks...) {
+		if i > 0 && member == name {
+			continue
+		}
+		p, err := b.instance(member, params)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create embedding provider '%s': %w", embedProviderName, err)
+			if i > 0 {
+				return nil, fmt.Errorf("failed to create chat fallback provider '%s': %w", member, err)
+			}
+			return nil, err
 		}
-		if p, ok := embedP.(ai.EmbeddingProvider); ok {
-			embeddingProvider = p
-		} else {
-			return nil, fmt.Errorf("provider '%s' does not implement EmbeddingProvider", embedProviderName)
+		provider, ok := p.(ai.AIProvider)
+		if !ok {
+			return nil, fmt.Errorf("provider '%s' does not implement AIProvider", member)
 		}
+		chain = append(chain, resilient.Named{Name: member, Provider: provider})
 	}
 
-	return &CompositeProvider{
-		AIProvider:        mainProvider,
-		EmbeddingProvider: embeddingProvider,
-	}, nil
+	p := resilient.New(chain, resilienceOptions(b.cfg.Resilience))
+	b.chains[key] = p
+	return p, nil
+}
+
+func routeKey(name string, params ai.ModelParams) string {
+	key := name
+	if params.Temperature != nil {
+		key += fmt.Sprintf("|t=%g", *params.Temperature)
+	}
+	if params.MaxTokens > 0 {
+		key += fmt.Sprintf("|max=%d", params.MaxTokens)
+	}
+	if params.JSONMode != nil {
+		key += fmt.Sprintf("|json=%t", *params.JSONMode)
+	}
+	return key
+}
+
+func routeProviderName(route configs.ServiceRoute, capability ai.Capability) string {
+	switch capability {
+	case ai.CapabilitySummary:
+		return route.Summary
+	case ai.CapabilityClassify:
+		return route.Classify
+	case ai.CapabilitySentiment:
+		return route.Sentiment
+	case ai.CapabilityDraft:
+		return route.Draft
+	case ai.CapabilitySearchSummary:
+		return route.SearchSummary
+	case ai.CapabilityRerank:
+		return route.Rerank
+	case ai.CapabilityTranslate:
+		return route.Translate
+	default:
+		return route.Chat
+	}
+}
+
+func routeParams(route configs.ServiceRoute, capability ai.Capability) ai.ModelParams {
+	rp, ok := route.Params[string(capability)]
+	if !ok {
+		return ai.ModelParams{}
+	}
+	params := ai.ModelParams{MaxTokens: rp.MaxTokens, JSONMode: rp.JSONMode}
+	if rp.Temperature != nil {
+		t := float32(*rp.Temperature)
+		params.Temperature = &t
+	}
+	return params
+}
+
+func resilienceOptions(rc configs.ResilienceConfig) resilient.Options {
+	return resilient.Options{
+		MaxRetries:       rc.MaxRetries,
+		BaseDelay:        time.Duration(rc.BaseDelayMs) * time.Millisecond,
+		MaxDelay:         time.Duration(rc.MaxDelayMs) * time.Millisecond,
+		BreakerThreshold: rc.BreakerThreshold,
+		BreakerCooldown:  time.Duration(rc.BreakerCooldownSeconds) * time.Second,
+	}
+}
+
+// NewEmbeddingProvider creates the embedding provider registered under name, e.g. the previously
+// active model that still serves queries while a re-embedding migration runs.
+func NewEmbeddingProvider(cfg *configs.AIConfig, name string) (ai.EmbeddingProvider, error) {
+	p, err := newProviderByName(cfg, name, toPromptMap(cfg.Prompts), ai.ModelParams{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedding provider '%s': %w", name, err)
+	}
+	embedder, ok := p.(ai.EmbeddingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider '%s' does not implement EmbeddingProvider", name)
+	}
+	return embedder, nil
+}
+
+// newProviderByName instantiates a configured provider through the registry, with params layered
+// over its settings.
+func newProviderByName(cfg *configs.AIConfig, name string, prompts map[string]string, params ai.ModelParams) (interface{}, error) {
+	pConfig, ok := cfg.Providers[name]
+	if !ok {
+		// "mock" works without a configuration entry
+		if name == "mock" {
+			return mock.NewProvider(), nil
+		}
+		return nil, fmt.Errorf("provider configuration not found: %s", name)
+	}
+
+	factory, err := registry.Get(pConfig.Protocol)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := pConfig.Settings
+	if overrides := params.Settings(); len(overrides) > 0 {
+		settings = make(configs.ProviderSettings, len(pConfig.Settings)+len(overrides))
+		for k, v := range pConfig.Settings {
+			settings[k] = v
+		}
+		for k, v := range overrides {
+			settings[k] = v
+		}
+	}
+	return factory(context.Background(), settings, prompts)
 }
 
 // toPromptMap converts a PromptConfig struct to a map[string]string.
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/anthropic"
+	"github.com/hrygo/echomind/pkg/ai/hashing"
+	"github.com/hrygo/echomind/pkg/ai/local"
+	"github.com/hrygo/echomind/pkg/ai/mock"
+	"github.com/hrygo/echomind/pkg/ai/resilient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAIProvider_RoutesServicesToProtocols(t *testing.T) {
+	cfg := &configs.AIConfig{
+		ActiveServices: configs.ServiceRoute{Chat: "claude", Embedding: "local_ollama"},
+		Providers: map[string]configs.ProviderConfig{
+			"claude":       {Protocol: "anthropic", Settings: configs.ProviderSettings{"model": "claude-test"}},
+			"local_ollama": {Protocol: "ollama", Settings: configs.ProviderSettings{"model": "llama3", "embedding_model": "nomic-embed-text", "embedding_dimensions": 768}},
+		},
+	}
+
+	provider, err := NewAIProvider(cfg)
+	require.NoError(t, err)
+	composite := provider.(*CompositeProvider)
+	chain := composite.AIProvider.(*resilient.Provider).Chain()
+	require.Len(t, chain, 1)
+	assert.IsType(t, &anthropic.Provider{}, chain[0].Provider)
+	assert.IsType(t, &local.Provider{}, composite.EmbeddingProvider)
+	assert.Equal(t, 768, composite.GetDimensions())
+	assert.Equal(t, "nomic-embed-text", ai.EmbeddingModelName(composite))
+
+	// The Messages API has no embeddings, so it cannot serve both routes.
+	cfg.ActiveServices.Embedding = ""
+	_, err = NewAIProvider(cfg)
+	assert.EqualError(t, err, "provider 'claude' does not implement EmbeddingProvider")
+
+	// Offline development: the in-process embedder and a configured mock
+	cfg.ActiveServices = configs.ServiceRoute{Chat: "dev_mock", Embedding: "offline"}
+	cfg.Providers["offline"] = configs.ProviderConfig{Protocol: "hashing", Settings: configs.ProviderSettings{"embedding_dimensions": 512}}
+	cfg.Providers["dev_mock"] = configs.ProviderConfig{Protocol: "mock", Settings: configs.ProviderSettings{"embedding_dimensions": 64}}
+	provider, err = NewAIProvider(cfg)
+	require.NoError(t, err)
+	composite = provider.(*CompositeProvider)
+	assert.IsType(t, &hashing.Provider{}, composite.EmbeddingProvider)
+	assert.Equal(t, 512, composite.GetDimensions())
+	assert.Equal(t, "hashing-v1", ai.EmbeddingModelName(composite))
+	assert.IsType(t, &mock.MockProvider{}, composite.AIProvider.(*resilient.Provider).Chain()[0].Provider)
+}
+
+func TestNewAIProvider_ChatFallbacks(t *testing.T) {
+	cfg := &configs.AIConfig{
+		ActiveServices: configs.ServiceRoute{Chat: "claude", Embedding: "mock", ChatFallbacks: []string{"local_ollama", "claude", "mock"}},
+		Providers: map[string]configs.ProviderConfig{
+			"claude":       {Protocol: "anthropic", Settings: configs.ProviderSettings{"model": "claude-test"}},
+			"local_ollama": {Protocol: "ollama", Settings: configs.ProviderSettings{"model": "llama3"}},
+		},
+	}
+
+	provider, err := NewAIProvider(cfg)
+	require.NoError(t, err)
+	chain := provider.(*CompositeProvider).AIProvider.(*resilient.Provider).Chain()
+	require.Len(t, chain, 3, "the primary is not repeated as its own fallback")
+	assert.Equal(t, "claude", chain[0].Name)
+	assert.IsType(t, &local.Provider{}, chain[1].Provider)
+	assert.IsType(t, &mock.MockProvider{}, chain[2].Provider)
+
+	cfg.ActiveServices.ChatFallbacks = []string{"missing"}
+	_, err = NewAIProvider(cfg)
+	assert.EqualError(t, err, "failed to create chat provider 'claude': failed to create chat fallback provider 'missing': provider configuration not found: missing")
+}
+
+func TestNewAIProvider_RoutesCapabilities(t *testing.T) {
+	temperature, jsonMode := 0.0, false
+	cfg := &configs.AIConfig{
+		ActiveServices: configs.ServiceRoute{
+			Chat:      "claude",
+			Embedding: "mock",
+			Classify:  "local_ollama",
+			Sentiment: "local_ollama",
+			Params: map[string]configs.RouteParams{
+				"classify": {Temperature: &temperature, MaxTokens: 16, JSONMode: &jsonMode},
+				"draft":    {MaxTokens: 2048},
+			},
+		},
+		Providers: map[string]configs.ProviderConfig{
+			"claude":       {Protocol: "anthropic", Settings: configs.ProviderSettings{"model": "claude-test", "max_tokens": 512}},
+			"local_ollama": {Protocol: "ollama", Settings: configs.ProviderSettings{"model": "llama3"}},
+		},
+	}
+
+	provider, err := NewAIProvider(cfg)
+	require.NoError(t, err)
+	composite := provider.(*CompositeProvider)
+	member := func(capability ai.Capability) ai.AIProvider {
+		return composite.Route(capability).(*resilient.Provider).Chain()[0].Provider
+	}
+
+	assert.IsType(t, &local.Provider{}, member(ai.CapabilityClassify))
+	assert.IsType(t, &local.Provider{}, member(ai.CapabilitySentiment))
+	assert.NotSame(t, member(ai.CapabilityClassify), member(ai.CapabilitySentiment), "different parameters need their own instance")
+	assert.IsType(t, &anthropic.Provider{}, member(ai.CapabilitySummary))
+	assert.Same(t, composite.Route(ai.CapabilitySummary), composite.Route(ai.CapabilityChat), "routes without overrides share the chat chain")
+	assert.NotSame(t, member(ai.CapabilityDraft), member(ai.CapabilityChat))
+	assert.Same(t, composite.Route(ai.CapabilityRerank), ProviderFor(provider, ai.CapabilityRerank).(*capabilityProvider).provider)
+
+	cfg.ActiveServices.Draft = "missing"
+	_, err = NewAIProvider(cfg)
+	assert.EqualError(t, err, "failed to create draft provider 'missing': provider configuration not found: missing")
+}
+
+// labelProvider answers every text call with its label.
+type labelProvider struct {
+	mock.MockProvider
+	label string
+}
+
+func (p *labelProvider) Classify(ctx context.Context, text string) (string, error) {
+	return p.label, nil
+}
+
+func (p *labelProvider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	return p.label, nil
+}
+
+func TestCompositeProvider_DispatchesByCapability(t *testing.T) {
+	composite := &CompositeProvider{
+		AIProvider: &labelProvider{label: "chat"},
+		routes:     map[ai.Capability]ai.AIProvider{ai.CapabilityClassify: &labelProvider{label: "classify"}},
+	}
+
+	label, err := composite.Classify(context.Background(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "classify", label)
+
+	draft, err := composite.GenerateDraftReply(context.Background(), "x", "")
+	require.NoError(t, err)
+	assert.Equal(t, "chat", draft, "capabilities without a route use the chat provider")
+}
+
+// echoProvider records the drafts it is asked for and replies with the email content.
+type echoProvider struct {
+	mock.MockProvider
+	seen []string
+}
+
+func (p *echoProvider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	p.seen = append(p.seen, emailContent, userPrompt)
+	return "Re: " + emailContent, nil
+}
+
+type recordingRedaction struct {
+	policy ai.RedactionPolicy
+	audits []map[ai.PIIKind]int
+}
+
+func (r *recordingRedaction) Policy(ctx context.Context) ai.RedactionPolicy { return r.policy }
+
+func (r *recordingRedaction) Audit(ctx context.Context, capability ai.Capability, counts map[ai.PIIKind]int) {
+	r.audits = append(r.audits, counts)
+}
+
+func TestCompositeProvider_RedactsAndRestores(t *testing.T) {
+	echo := &echoProvider{}
+	composite := &CompositeProvider{AIProvider: echo, routes: map[ai.Capability]ai.AIProvider{}}
+	draft := ProviderFor(composite, ai.CapabilityDraft)
+	defaults := &recordingRedaction{policy: ai.RedactionPolicy{Kinds: ai.PIIKinds}}
+	composite.SetRedaction(defaults) // After ProviderFor, as in the container
+
+	reply, err := draft.GenerateDraftReply(context.Background(), "From ada@example.com, call 415-555-0132", "Thank ada@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"From [EMAIL_1], call [PHONE_1]", "Thank [EMAIL_1]"}, echo.seen, "the provider never sees the values")
+	assert.Equal(t, "Re: From ada@example.com, call 415-555-0132", reply)
+	assert.Equal(t, []map[ai.PIIKind]int{{ai.PIIEmail: 1, ai.PIIPhone: 1}}, defaults.audits)
+
+	// A scope in the context takes precedence; a disabled policy sends the text unchanged.
+	scoped := &recordingRedaction{}
+	echo.seen = nil
+	_, err = composite.GenerateDraftReply(ai.WithRedactionScope(context.Background(), scoped), "ada@example.com", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ada@example.com", ""}, echo.seen)
+	assert.Empty(t, scoped.audits)
+	assert.Len(t, defaults.audits, 1)
+}
+
+// toolStreamProvider streams a turn that mentions and searches for the first address it was sent.
+type toolStreamProvider struct {
+	mock.MockProvider
+	seen []ai.Message
+}
+
+func (p *toolStreamProvider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	return ai.ToolChatResponse{}, errors.New("expected a streamed turn")
+}
+
+func (p *toolStreamProvider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	defer close(ch)
+	p.seen = messages
+	for _, text := range []string{"Searching for [EMA", "IL_1]."} {
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: text}}}}
+	}
+	return ai.ToolChatResponse{
+		Content:   "Searching for [EMAIL_1].",
+		ToolCalls: []ai.ToolCall{{ID: "call_1", Name: "search_emails", Arguments: `{"query":"[EMAIL_1]"}`}},
+	}, nil
+}
+
+func TestCompositeProvider_StreamsToolTurns(t *testing.T) {
+	provider := &toolStreamProvider{}
+	composite := &CompositeProvider{AIProvider: provider, routes: map[ai.Capability]ai.AIProvider{}}
+	composite.SetRedaction(&recordingRedaction{policy: ai.RedactionPolicy{Kinds: ai.PIIKinds}})
+
+	caller, ok := ai.AsToolCaller(composite)
+	require.True(t, ok, "the chat route calls tools")
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	resp, err := ai.StreamChatWithTools(context.Background(), caller, []ai.Message{{Role: "user", Content: "Mail from ada@example.com?"}}, nil, ch)
+	require.NoError(t, err)
+
+	var text strings.Builder
+	for chunk := range ch {
+		text.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, "Mail from [EMAIL_1]?", provider.seen[0].Content)
+	assert.Equal(t, "Searching for ada@example.com.", text.String())
+	assert.Equal(t, "Searching for ada@example.com.", resp.Content)
+	assert.Equal(t, `{"query":"ada@example.com"}`, resp.ToolCalls[0].Arguments)
+}
@@ -20,18 +20,74 @@ type EmailRetriever interface {
 	GetEmailsByIDs(ctx context.Context, userID uuid.UUID, emailIDs []uuid.UUID) ([]model.Email, error)
 }
 
+const (
+	// chatRAGCandidates is how many chunks are retrieved before collapsing and reranking.
+	chatRAGCandidates = 15
+	// chatRAGTopK is how many emails end up in the prompt.
+	chatRAGTopK = 3
+)
+
+// chatSystemPrompt is the default version of the chat_system prompt.
+const chatSystemPrompt = `You are EchoMind Copilot, a helpful AI assistant for managing emails and work.
+
+You can generate interactive widgets for the user.
+Supported widgets:
+1. Task List: <widget type="task_list">[{"title": "Task 1", "due": "2023-10-27"}, ...]</widget>
+2. Email Draft: <widget type="email_draft">{"to": "...", "subject": "...", "body": "..."}</widget>
+3. Calendar Event: <widget type="calendar_event">{"title": "...", "start": "...", "end": "..."}</widget>
+
+When the user asks to create tasks, draft emails, or schedule meetings, output the corresponding widget XML block.
+`
+
 type ChatService struct {
 	aiProvider    ai.AIProvider
 	searchService ContextSearcher
 	emailService  EmailRetriever
+	reranker      Reranker
+	sessions      *ChatSessionService
+	retrieval     ChatRetrievalOptions
+	toolbox       *ChatToolbox
 }
 
+// NewChatService creates a chat service that reranks retrieved context with the lexical reranker.
+// Use SetReranker to switch implementations or disable reranking.
 func NewChatService(aiProvider ai.AIProvider, searchService ContextSearcher, emailService EmailRetriever) *ChatService {
 	return &ChatService{
 		aiProvider:    aiProvider,
 		searchService: searchService,
 		emailService:  emailService,
+		reranker:      NewLexicalReranker(),
+	}
+}
+
+// SetReranker replaces the reranking stage used for auto-search context. A nil reranker disables it.
+func (s *ChatService) SetReranker(reranker Reranker) {
+	s.reranker = reranker
+}
+
+// SetSessions enables persisted chat sessions.
+func (s *ChatService) SetSessions(sessions *ChatSessionService) {
+	s.sessions = sessions
+}
+
+// retrieveContext searches for the query, collapses chunks to one passage per email and reranks them.
+func (s *ChatService) retrieveContext(ctx context.Context, userID uuid.UUID, query string) ([]SearchResult, error) {
+	if s.reranker == nil {
+		return s.searchService.Search(ctx, userID, query, SearchFilters{}, chatRAGTopK)
+	}
+
+	candidates, err := s.searchService.Search(ctx, userID, query, SearchFilters{}, chatRAGCandidates)
+	if err != nil {
+		return nil, err
 	}
+	candidates = CollapseByEmail(candidates)
+
+	reranked, err := s.reranker.Rerank(ctx, query, candidates, chatRAGTopK)
+	if err != nil {
+		// Reranking is an optimization; fall back to retrieval order.
+		return truncateResults(candidates, chatRAGTopK), nil
+	}
+	return reranked, nil
 }
 
 func (s *ChatService) StreamChat(ctx context.Context, userID uuid.UUID, messages []ai.Message, contextRefIDs []uuid.UUID, ch chan<- ai.ChatCompletionChunk) error {
@@ -44,10 +100,27 @@ func (s *ChatService) StreamChat(ctx context.Context, userID uuid.UUID, messages
 		return fmt.Errorf("last message must be from user")
 	}
 
-	// 2. RAG: Search for relevant context
-	// We use a simple heuristic: search using the last user message
-	// In a more advanced version, we might summarize the conversation history first.
+	systemPrompt, passages := s.buildSystemPrompt(ctx, userID, messages[:len(messages)-1], lastMsg.Content, contextRefIDs)
+
+	// Prepend the system prompt. OpenAI accepts a "system" role message at the beginning;
+	// Gemini maps it to its system instruction.
+	systemMsg := ai.Message{
+		Role:    "system",
+		Content: systemPrompt,
+	}
+
+	finalMessages := append([]ai.Message{systemMsg}, messages...)
+
+	// Stream response, followed by the citations it contains
+	_, _, err := s.respond(ctx, userID, nil, finalMessages, passages, ch)
+	return err
+}
+
+// buildSystemPrompt retrieves context for the question and returns the system prompt together with
+// the numbered passages it contains. The earlier turns are used to make follow-up questions searchable.
+func (s *ChatService) buildSystemPrompt(ctx context.Context, userID uuid.UUID, history []ai.Message, question string, contextRefIDs []uuid.UUID) (string, []chatPassage) {
 	var contextBuilder strings.Builder
+	var passages []chatPassage
 
 	// Strategy A: Explicit Context (High Priority)
 	if len(contextRefIDs) > 0 && s.emailService != nil {
@@ -60,59 +133,133 @@ func (s *ChatService) StreamChat(ctx context.Context, userID uuid.UUID, messages
 				if content == "" {
 					content = "(No Content)"
 				}
-				contextBuilder.WriteString(fmt.Sprintf("Subject: %s\nFrom: %s\nDate: %s\nContent: %s\n\n",
-					email.Subject, email.Sender, email.Date.Format("2006-01-02"), content))
+				passages = append(passages, chatPassage{EmailID: email.ID, Subject: email.Subject, Sender: email.Sender, Date: email.Date, Content: content})
+				contextBuilder.WriteString(fmt.Sprintf("[%d] Subject: %s\nFrom: %s\nDate: %s\nContent: %s\n\n",
+					len(passages), email.Subject, email.Sender, email.Date.Format("2006-01-02"), content))
 			}
 		}
 	} else if s.searchService != nil {
 		// Strategy B: Auto-Search (Fallback)
-		searchResults, err := s.searchService.Search(ctx, userID, lastMsg.Content, SearchFilters{}, 3)
+		var searchResults []SearchResult
+		var err error
+		if s.retrieval.QueryRewrite {
+			searchResults, err = s.multiHopRetrieve(ctx, userID, history, question)
+		} else {
+			searchResults, err = s.retrieveContext(ctx, userID, question)
+		}
 		if err != nil {
 			// Log error but continue
 			_ = err
 		} else if len(searchResults) > 0 {
 			contextBuilder.WriteString("Here is some relevant information from the user's emails:\n\n")
 			for _, result := range searchResults {
-				contextBuilder.WriteString(fmt.Sprintf("Subject: %s\nFrom: %s\nDate: %s\nContent: %s\n\n",
-					result.Subject, result.Sender, result.Date.Format("2006-01-02"), result.Snippet))
+				passages = append(passages, chatPassage{EmailID: result.EmailID, Subject: result.Subject, Sender: result.Sender, Date: result.Date, Content: result.Snippet})
+				contextBuilder.WriteString(fmt.Sprintf("[%d] Subject: %s\nFrom: %s\nDate: %s\nContent: %s\n\n",
+					len(passages), result.Subject, result.Sender, result.Date.Format("2006-01-02"), result.Snippet))
 			}
 		}
 	}
 
-	// 3. Construct System Prompt with Context
+	// Construct System Prompt with Context
 	if contextBuilder.Len() > 0 {
+		contextBuilder.WriteString(chatCitationInstructions)
 		contextBuilder.WriteString("Answer the user's question based on the above context if relevant. If the context doesn't contain the answer, say so, but you can still answer general questions.\n")
-	} else {
-		contextBuilder.WriteString("You are EchoMind Copilot, a helpful AI assistant for managing emails and work.\n")
 	}
 
-	// Add Widget Instructions
-	contextBuilder.WriteString(`
-You can generate interactive widgets for the user.
-Supported widgets:
-1. Task List: <widget type="task_list">[{"title": "Task 1", "due": "2023-10-27"}, ...]</widget>
-2. Email Draft: <widget type="email_draft">{"to": "...", "subject": "...", "body": "..."}</widget>
-3. Calendar Event: <widget type="calendar_event">{"title": "...", "start": "...", "end": "..."}</widget>
+	// Persona and widget instructions come from the prompt registry
+	contextBuilder.WriteString(ai.ResolvePrompt(ctx, ai.PromptChatSystem, chatSystemPrompt).Text)
 
-When the user asks to create tasks, draft emails, or schedule meetings, output the corresponding widget XML block.
-`)
+	return contextBuilder.String(), passages
+}
+
+// StreamSessionChat answers a new turn in a stored session. The history is loaded (and compacted)
+// server-side, and both turns are saved once the answer has been streamed completely, the answer
+// together with its citations. Like the providers, it closes ch when streaming ends.
+func (s *ChatService) StreamSessionChat(ctx context.Context, userID, sessionID uuid.UUID, input model.ChatMessageInput, ch chan<- ai.ChatCompletionChunk) error {
+	if s.sessions == nil {
+		return fmt.Errorf("chat sessions are not configured")
+	}
+	session, err := s.sessions.Get(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	summary, history, err := s.sessions.History(ctx, session)
+	if err != nil {
+		return err
+	}
 
-	// 4. Prepare messages for AI Provider
-	// We prepend the system prompt.
-	// Note: Some providers (like OpenAI) accept a "system" role message at the beginning.
-	// Gemini handles it via SystemInstruction, but our StreamChat implementation in Gemini
-	// skips "system" messages in history.
-	// So we should probably prepend the context to the *last* user message or the *first* user message?
-	// Or we can just insert a system message at the start and let the provider handle it (OpenAI does, Gemini we might need to adjust).
+	rewriteHistory := history
+	if summary != "" {
+		rewriteHistory = append([]ai.Message{{Role: "system", Content: "Earlier conversation: " + summary}}, history...)
+	}
+	systemPrompt, passages := s.buildSystemPrompt(ctx, userID, rewriteHistory, input.Content, input.ContextRefIDs)
+	if summary != "" {
+		systemPrompt += "\nSummary of the earlier conversation:\n" + summary + "\n"
+	}
+	messages := append([]ai.Message{{Role: "system", Content: systemPrompt}}, history...)
+	messages = append(messages, ai.Message{Role: "user", Content: input.Content})
 
-	// Let's try prepending a system message.
-	systemMsg := ai.Message{
-		Role:    "system",
-		Content: contextBuilder.String(),
+	answer, citations, err := s.respond(ctx, userID, &session.ID, messages, passages, ch)
+	if err != nil {
+		return err
 	}
 
-	finalMessages := append([]ai.Message{systemMsg}, messages...)
+	// Persist even if the client went away after the answer was complete.
+	saveCtx := context.WithoutCancel(ctx)
+	if _, err := s.sessions.AppendMessage(saveCtx, session, "user", input.Content, nil); err != nil {
+		return err
+	}
+	_, err = s.sessions.AppendMessage(saveCtx, session, "assistant", answer, citations)
+	return err
+}
+
+// forwardStream streams the provider's text to ch with widget blocks turned into widget deltas and
+// bogus citation markers removed, then sends the answer's validated citations in a final chunk and
+// closes ch. It returns the full answer, as streamed, and its citations.
+func (s *ChatService) forwardStream(ctx context.Context, messages []ai.Message, passages []chatPassage, ch chan<- ai.ChatCompletionChunk) (string, []ai.Citation, error) {
+	defer close(ch)
+
+	inner := make(chan ai.ChatCompletionChunk)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.aiProvider.StreamChat(ctx, messages, inner)
+	}()
+
+	send := func(id string, delta ai.DeltaContent) {
+		select {
+		case ch <- ai.ChatCompletionChunk{ID: id, Choices: []ai.Choice{{Index: 0, Delta: delta}}}:
+		case <-ctx.Done():
+			// The client stopped reading; keep draining so the provider can finish.
+		}
+	}
+
+	// Widget blocks are parsed here rather than in the providers, so they stream the same way for all.
+	answer := newAnswerStream(func() int { return len(passages) })
+	var lastID string
+	for chunk := range inner {
+		lastID = chunk.ID
+		for _, choice := range chunk.Choices {
+			for _, delta := range answer.Feed(choice.Delta.Content) {
+				send(chunk.ID, delta)
+			}
+			if choice.Delta.Widget != nil {
+				send(chunk.ID, ai.DeltaContent{Widget: choice.Delta.Widget})
+			}
+		}
+	}
+	if err := <-errCh; err != nil {
+		return "", nil, err
+	}
+	for _, delta := range answer.Flush() {
+		send(lastID, delta)
+	}
 
-	// 5. Stream response
-	return s.aiProvider.StreamChat(ctx, finalMessages, ch)
+	citations := extractCitations(answer.String(), passages)
+	if len(citations) > 0 {
+		select {
+		case ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Index: 0}}, Citations: citations}:
+		case <-ctx.Done():
+		}
+	}
+	return answer.String(), citations, nil
 }
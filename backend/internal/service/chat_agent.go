@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+// chatToolMaxSteps bounds the model turns spent calling tools before it has to answer.
+const chatToolMaxSteps = 5
+
+const chatToolInstructions = `
+You can call tools to look up the user's emails and tasks and to act for them. Actions that change something (creating tasks or opportunities, snoozing emails) are only proposed: the user confirms them in the app, so never say they are done. Prefer these tools over widget blocks for tasks and reply drafts. Emails found with search_emails can be cited by their ref number like the numbered emails above.
+`
+
+const chatQuotaNotice = "You have reached your AI usage quota, so Copilot cannot answer right now. It will be available again when your quota resets."
+
+var ErrChatToolsDisabled = errors.New("chat tools are not enabled")
+
+// SetToolbox enables tool calling for providers that support it.
+func (s *ChatService) SetToolbox(toolbox *ChatToolbox) {
+	s.toolbox = toolbox
+}
+
+// respond answers with the tool-calling loop when tools are enabled and the provider supports
+// function calling, and streams a plain completion otherwise. messages must start with the system
+// prompt. Like the providers, it closes ch when done.
+func (s *ChatService) respond(ctx context.Context, userID uuid.UUID, sessionID *uuid.UUID, messages []ai.Message, passages []chatPassage, ch chan<- ai.ChatCompletionChunk) (string, []ai.Citation, error) {
+	if err := ai.CheckQuota(ctx, ai.CapabilityChat); errors.Is(err, ai.ErrQuotaExceeded) {
+		// Answer with a notice instead of failing the stream, so the client shows why.
+		defer close(ch)
+		select {
+		case ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: chatQuotaNotice}}}}:
+		case <-ctx.Done():
+		}
+		return chatQuotaNotice, nil, nil
+	}
+	caller, ok := ai.AsToolCaller(s.aiProvider)
+	if s.toolbox == nil || !ok {
+		return s.forwardStream(ctx, messages, passages, ch)
+	}
+	messages[0].Content += chatToolInstructions
+	return s.runAgent(ctx, caller, userID, sessionID, messages, passages, ch)
+}
+
+// runAgent lets the model call tools until it answers. Read tools run straight away and their
+// results are streamed as widgets; write tools are turned into pending actions with a confirmation
+// widget. The model's text is streamed as it arrives, followed by the answer's citations; providers
+// that cannot stream a turn with tools send each turn's text at once.
+func (s *ChatService) runAgent(ctx context.Context, caller ai.ToolCaller, userID uuid.UUID, sessionID *uuid.UUID, messages []ai.Message, passages []chatPassage, ch chan<- ai.ChatCompletionChunk) (string, []ai.Citation, error) {
+	defer close(ch)
+	send := func(delta ai.DeltaContent) {
+		select {
+		case ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Index: 0, Delta: delta}}}:
+		case <-ctx.Done():
+		}
+	}
+
+	env := chatToolEnv{UserID: userID, Passages: &passages}
+	tools := s.toolbox.Definitions()
+	var answer strings.Builder
+	for step := 0; ; step++ {
+		if step == chatToolMaxSteps {
+			tools = nil // Out of steps: the model has to answer with what it has
+		}
+		resp, err := s.streamTurn(ctx, caller, messages, tools, func() int { return len(passages) }, send)
+		if err != nil {
+			return "", nil, err
+		}
+		answer.WriteString(resp.Content)
+		if len(resp.ToolCalls) == 0 {
+			break
+		}
+
+		messages = append(messages, ai.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
+		for _, call := range resp.ToolCalls {
+			result, widget, err := s.toolbox.Execute(ctx, env, sessionID, call)
+			if err != nil {
+				return "", nil, err
+			}
+			if widget != nil {
+				send(ai.DeltaContent{Widget: widget})
+			}
+			messages = append(messages, ai.Message{Role: "tool", Content: result, ToolCallID: call.ID, ToolName: call.Name})
+		}
+	}
+
+	citations := extractCitations(answer.String(), passages)
+	if len(citations) > 0 {
+		select {
+		case ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Index: 0}}, Citations: citations}:
+		case <-ctx.Done():
+		}
+	}
+	return answer.String(), citations, nil
+}
+
+// streamTurn runs one model turn, passing its text to send as it arrives with widget blocks turned
+// into widget deltas and markers citing none of the passageCount() passages removed. The returned
+// Content is the text as streamed.
+func (s *ChatService) streamTurn(ctx context.Context, caller ai.ToolCaller, messages []ai.Message, tools []ai.ToolDefinition, passageCount func() int, send func(ai.DeltaContent)) (ai.ToolChatResponse, error) {
+	inner := make(chan ai.ChatCompletionChunk)
+	var resp ai.ToolChatResponse
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		resp, err = ai.StreamChatWithTools(ctx, caller, messages, tools, inner)
+		errCh <- err
+	}()
+
+	content := newAnswerStream(passageCount)
+	for chunk := range inner {
+		for _, choice := range chunk.Choices {
+			for _, delta := range content.Feed(choice.Delta.Content) {
+				send(delta)
+			}
+		}
+	}
+	if err := <-errCh; err != nil {
+		return resp, err
+	}
+	for _, delta := range content.Flush() {
+		send(delta)
+	}
+	resp.Content = content.String()
+	return resp, nil
+}
+
+// ConfirmToolAction runs a write action the Copilot proposed and returns its result widget.
+func (s *ChatService) ConfirmToolAction(ctx context.Context, userID, actionID uuid.UUID) (*model.ChatToolAction, *ai.WidgetData, error) {
+	if s.toolbox == nil {
+		return nil, nil, ErrChatToolsDisabled
+	}
+	action, widget, err := s.toolbox.Confirm(ctx, userID, actionID)
+	if action != nil {
+		s.recordToolOutcome(ctx, userID, action)
+	}
+	return action, widget, err
+}
+
+// RejectToolAction discards a write action the Copilot proposed.
+func (s *ChatService) RejectToolAction(ctx context.Context, userID, actionID uuid.UUID) (*model.ChatToolAction, error) {
+	if s.toolbox == nil {
+		return nil, ErrChatToolsDisabled
+	}
+	action, err := s.toolbox.Reject(ctx, userID, actionID)
+	if err != nil {
+		return nil, err
+	}
+	s.recordToolOutcome(ctx, userID, action)
+	return action, nil
+}
+
+// recordToolOutcome notes in the action's session whether it was carried out, so that the model
+// knows on the next turn. This is best effort; the action itself is already resolved.
+func (s *ChatService) recordToolOutcome(ctx context.Context, userID uuid.UUID, action *model.ChatToolAction) {
+	if s.sessions == nil || action.SessionID == nil {
+		return
+	}
+	session, err := s.sessions.Get(ctx, userID, *action.SessionID)
+	if err != nil {
+		return
+	}
+
+	var note string
+	switch action.Status {
+	case model.ChatToolActionConfirmed:
+		note = fmt.Sprintf("Done: %s.", action.Summary)
+	case model.ChatToolActionRejected:
+		note = fmt.Sprintf("The user declined: %s.", action.Summary)
+	default:
+		note = fmt.Sprintf("Failed: %s (%s).", action.Summary, action.Error)
+	}
+	_, _ = s.sessions.AppendMessage(ctx, session, "assistant", note, nil)
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// scriptedToolProvider answers ChatWithTools with a fixed sequence of model turns.
+type scriptedToolProvider struct {
+	MockAIProvider
+	turns []ai.ToolChatResponse
+	seen  [][]ai.Message
+}
+
+func (p *scriptedToolProvider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	p.seen = append(p.seen, append([]ai.Message(nil), messages...))
+	turn := p.turns[0]
+	p.turns = p.turns[1:]
+	return turn, nil
+}
+
+// streamingToolProvider streams the text of its scripted turns word by word.
+type streamingToolProvider struct {
+	scriptedToolProvider
+}
+
+func (p *streamingToolProvider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	defer close(ch)
+	turn, err := p.ChatWithTools(ctx, messages, tools)
+	for _, word := range strings.SplitAfter(turn.Content, " ") {
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: word}}}}
+	}
+	return turn, err
+}
+
+func setupChatToolTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:chat_tools?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.ChatToolAction{}, &model.Task{}, &model.ChatSession{}, &model.ChatMessage{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM chat_tool_actions")
+		db.Exec("DELETE FROM tasks")
+		db.Exec("DELETE FROM chat_messages")
+		db.Exec("DELETE FROM chat_sessions")
+	})
+	return db
+}
+
+func collectChunks(t *testing.T, run func(ch chan<- ai.ChatCompletionChunk) error) []ai.ChatCompletionChunk {
+	ch := make(chan ai.ChatCompletionChunk, 32)
+	require.NoError(t, run(ch))
+	var chunks []ai.ChatCompletionChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+func TestChatService_ToolLoopRunsReadTools(t *testing.T) {
+	db := setupChatToolTestDB(t)
+	userID := uuid.New()
+	emailID := uuid.New()
+
+	searcher := new(MockContextSearcher)
+	searcher.On("Search", mock.Anything, userID, "contract renewal", mock.Anything, 15).Return([]SearchResult{
+		{EmailID: emailID, Subject: "Renewal", Sender: "acme@example.com", Date: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Snippet: "We will renew the contract in April."},
+	}, nil)
+
+	provider := &scriptedToolProvider{turns: []ai.ToolChatResponse{
+		{ToolCalls: []ai.ToolCall{{ID: "call_1", Name: "search_emails", Arguments: `{"query":"contract renewal"}`}}},
+		{Content: "Acme will renew in April. [1]"},
+	}}
+	svc := NewChatService(provider, nil, nil)
+	svc.SetToolbox(NewChatToolbox(db, ChatToolDeps{Search: searcher}))
+
+	chunks := collectChunks(t, func(ch chan<- ai.ChatCompletionChunk) error {
+		return svc.StreamChat(context.Background(), userID, []ai.Message{{Role: "user", Content: "When does Acme renew?"}}, nil, ch)
+	})
+
+	require.Len(t, chunks, 3)
+	widget := chunks[0].Choices[0].Delta.Widget
+	require.NotNil(t, widget)
+	assert.Equal(t, "email_list", widget.Type)
+	assert.Equal(t, "Acme will renew in April. [1]", chunks[1].Choices[0].Delta.Content)
+	require.Len(t, chunks[2].Citations, 1, "emails found by a tool can be cited")
+	assert.Equal(t, emailID.String(), chunks[2].Citations[0].EmailID)
+
+	// The tool result is fed back to the model, paired with its call.
+	second := provider.seen[1]
+	last := second[len(second)-1]
+	assert.Equal(t, "tool", last.Role)
+	assert.Equal(t, "call_1", last.ToolCallID)
+	assert.Contains(t, last.Content, `"ref":1`)
+	assert.Contains(t, second[0].Content, "search_emails", "tool instructions are added to the system prompt")
+}
+
+func TestChatService_ToolLoopStreamsTheAnswer(t *testing.T) {
+	db := setupChatToolTestDB(t)
+	provider := &streamingToolProvider{scriptedToolProvider{turns: []ai.ToolChatResponse{
+		{ToolCalls: []ai.ToolCall{{ID: "call_1", Name: "list_tasks", Arguments: `{}`}}},
+		{Content: "You have no open tasks."},
+	}}}
+	svc := NewChatService(provider, nil, nil)
+	svc.SetToolbox(NewChatToolbox(db, ChatToolDeps{}))
+
+	chunks := collectChunks(t, func(ch chan<- ai.ChatCompletionChunk) error {
+		return svc.StreamChat(context.Background(), uuid.New(), []ai.Message{{Role: "user", Content: "What's on my list?"}}, nil, ch)
+	})
+
+	var text []string
+	for _, chunk := range chunks {
+		if content := chunk.Choices[0].Delta.Content; content != "" {
+			text = append(text, content)
+		}
+	}
+	// Trailing spaces wait for the next delta in case a bogus citation marker follows.
+	assert.Equal(t, []string{"You", " have", " no", " open", " tasks."}, text, "the answer is streamed as it arrives")
+}
+
+func TestChatService_WriteToolsNeedConfirmation(t *testing.T) {
+	db := setupChatToolTestDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	sessions := NewChatSessionService(db, nil)
+	session, err := sessions.Create(ctx, userID, "Planning")
+	require.NoError(t, err)
+
+	provider := &scriptedToolProvider{turns: []ai.ToolChatResponse{
+		{ToolCalls: []ai.ToolCall{
+			{ID: "call_1", Name: "create_task", Arguments: `{"title":"Send the contract","due_date":"2025-04-01"}`},
+			{ID: "call_2", Name: "create_task", Arguments: `{"title":"Follow up","due_date":"tomorrow"}`},
+		}},
+		{Content: "I've prepared the task for you to confirm."},
+	}}
+	svc := NewChatService(provider, nil, nil)
+	svc.SetSessions(sessions)
+	svc.SetToolbox(NewChatToolbox(db, ChatToolDeps{Tasks: NewTaskService(db)}))
+
+	chunks := collectChunks(t, func(ch chan<- ai.ChatCompletionChunk) error {
+		return svc.StreamSessionChat(ctx, userID, session.ID, model.ChatMessageInput{Content: "Remind me to send the contract"}, ch)
+	})
+
+	require.Len(t, chunks, 2, "one confirmation widget for the valid call, then the answer")
+	widget := chunks[0].Choices[0].Delta.Widget
+	require.NotNil(t, widget)
+	assert.Equal(t, "tool_confirmation", widget.Type)
+	assert.Equal(t, `Create task "Send the contract" due 2025-04-01`, widget.Data["summary"])
+
+	var taskCount int64
+	db.Model(&model.Task{}).Count(&taskCount)
+	assert.Zero(t, taskCount, "nothing is written before confirmation")
+
+	results := provider.seen[1][len(provider.seen[1])-2:]
+	assert.Contains(t, results[0].Content, "awaiting_confirmation")
+	assert.Contains(t, results[1].Content, "due_date must be", "invalid arguments are reported to the model")
+
+	actionID, err := uuid.Parse(widget.Data["action_id"].(string))
+	require.NoError(t, err)
+
+	_, _, err = svc.ConfirmToolAction(ctx, uuid.New(), actionID)
+	assert.ErrorIs(t, err, ErrChatToolActionNotFound, "other users cannot confirm the action")
+
+	action, result, err := svc.ConfirmToolAction(ctx, userID, actionID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ChatToolActionConfirmed, action.Status)
+	assert.Equal(t, "task_card", result.Type)
+	assert.Equal(t, "2025-04-01", result.Data["due"])
+
+	var task model.Task
+	require.NoError(t, db.Where("user_id = ?", userID).First(&task).Error)
+	assert.Equal(t, "Send the contract", task.Title)
+
+	_, _, err = svc.ConfirmToolAction(ctx, userID, actionID)
+	assert.ErrorIs(t, err, ErrChatToolActionResolved, "an action runs at most once")
+	_, err = svc.RejectToolAction(ctx, userID, actionID)
+	assert.ErrorIs(t, err, ErrChatToolActionResolved)
+
+	resumed, err := sessions.GetWithMessages(ctx, userID, session.ID)
+	require.NoError(t, err)
+	require.Len(t, resumed.Messages, 3)
+	assert.Equal(t, `Done: Create task "Send the contract" due 2025-04-01.`, resumed.Messages[2].Content)
+}
+
+func TestChatToolbox_RejectAndExpiry(t *testing.T) {
+	db := setupChatToolTestDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	toolbox := NewChatToolbox(db, ChatToolDeps{Tasks: NewTaskService(db)})
+	env := chatToolEnv{UserID: userID}
+
+	_, widget, err := toolbox.Execute(ctx, env, nil, ai.ToolCall{Name: "create_task", Arguments: `{"title":"Call Bob"}`})
+	require.NoError(t, err)
+	rejected, err := toolbox.Reject(ctx, userID, uuid.MustParse(widget.Data["action_id"].(string)))
+	require.NoError(t, err)
+	assert.Equal(t, model.ChatToolActionRejected, rejected.Status)
+
+	_, widget, err = toolbox.Execute(ctx, env, nil, ai.ToolCall{Name: "create_task", Arguments: `{"title":"Call Carol"}`})
+	require.NoError(t, err)
+	expiredID := uuid.MustParse(widget.Data["action_id"].(string))
+	db.Model(&model.ChatToolAction{}).Where("id = ?", expiredID).Update("expires_at", time.Now().Add(-time.Minute))
+	_, _, err = toolbox.Confirm(ctx, userID, expiredID)
+	assert.ErrorIs(t, err, ErrChatToolActionResolved)
+
+	var action model.ChatToolAction
+	require.NoError(t, db.First(&action, "id = ?", expiredID).Error)
+	assert.Equal(t, model.ChatToolActionExpired, action.Status)
+
+	result, widget, err := toolbox.Execute(ctx, env, nil, ai.ToolCall{Name: "delete_everything", Arguments: `{}`})
+	require.NoError(t, err)
+	assert.Nil(t, widget)
+	var payload map[string]string
+	require.NoError(t, json.Unmarshal([]byte(result), &payload))
+	assert.Equal(t, `unknown tool "delete_everything"`, payload["error"])
+}
+
+func TestChatToolbox_WriteToolsCheckOwnership(t *testing.T) {
+	db := setupChatToolTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.Email{}, &model.Organization{}, &model.OrganizationMember{}, &model.Team{}, &model.TeamMember{}))
+	t.Cleanup(func() {
+		for _, table := range []string{"team_members", "teams", "organization_members", "organizations", "emails"} {
+			db.Exec("DELETE FROM " + table)
+		}
+	})
+	ctx := context.Background()
+	userID := uuid.New()
+	toolbox := NewChatToolbox(db, ChatToolDeps{Tasks: NewTaskService(db), Opportunities: NewOpportunityService(db)})
+	env := chatToolEnv{UserID: userID}
+	confirm := func(name, args string) error {
+		_, widget, err := toolbox.Execute(ctx, env, nil, ai.ToolCall{Name: name, Arguments: args})
+		require.NoError(t, err)
+		_, _, err = toolbox.Confirm(ctx, userID, uuid.MustParse(widget.Data["action_id"].(string)))
+		return err
+	}
+
+	othersEmail := model.Email{ID: uuid.New(), UserID: uuid.New(), MessageID: "theirs"}
+	require.NoError(t, db.Create(&othersEmail).Error)
+	err := confirm("create_task", `{"title":"Reply","source_email_id":"`+othersEmail.ID.String()+`"}`)
+	assert.ErrorContains(t, err, "source email not found")
+	err = confirm("create_opportunity", `{"title":"Renewal","company":"Acme"}`)
+	assert.ErrorContains(t, err, "the user is not in a team")
+
+	// Opportunities go to the first team the user joined in one of their organizations.
+	orgID, otherOrgID := uuid.New(), uuid.New()
+	joined := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	outside := model.Team{ID: uuid.New(), OrganizationID: otherOrgID, Name: "Outside"}
+	first := model.Team{ID: uuid.New(), OrganizationID: orgID, Name: "Sales"}
+	second := model.Team{ID: uuid.New(), OrganizationID: orgID, Name: "Support"}
+	require.NoError(t, db.Create(&model.OrganizationMember{OrganizationID: orgID, UserID: userID}).Error)
+	for i, team := range []model.Team{outside, first, second} {
+		require.NoError(t, db.Create(&team).Error)
+		require.NoError(t, db.Create(&model.TeamMember{TeamID: team.ID, UserID: userID, JoinedAt: joined.AddDate(0, 0, i)}).Error)
+	}
+	teamID, gotOrgID, err := toolbox.opportunityTeam(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, teamID)
+	assert.Equal(t, orgID, gotOrgID)
+}
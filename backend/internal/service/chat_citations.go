@@ -0,0 +1,251 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+const chatCitationQuoteRunes = 240
+
+// chatPassage is an email passage placed in the chat prompt. Its position (1-based) is the number
+// the model cites it by.
+type chatPassage struct {
+	EmailID uuid.UUID
+	Subject string
+	Sender  string
+	Date    time.Time
+	Content string
+}
+
+const chatCitationInstructions = `Each email above is numbered. When a statement relies on an email, cite it right after the statement with its number in square brackets, e.g. [1] or [1][3]. Only cite the numbered emails above, and never invent numbers.
+`
+
+var (
+	citationMarkerPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+	sentencePattern       = regexp.MustCompile(`[^.!?。！？\n]+[.!?。！？\n]*`)
+
+	leadingMarkerPattern = regexp.MustCompile(`^\[(\d+(?:\s*,\s*\d+)*)\]`)
+	partialMarkerPattern = regexp.MustCompile(`^\[[\d\s,]*$`) // What the next delta may complete into a marker
+	widgetBlockPattern   = regexp.MustCompile(`(?s)<widget\s[^>]*>.*?</widget>`)
+)
+
+// maxCitationMarkerBytes bounds how long a marker may grow before the text is treated as prose.
+const maxCitationMarkerBytes = 24
+
+// citationFilter removes the [n] markers that do not point at a supplied passage from streamed
+// text, so that neither the user nor the saved answer sees them. A marker like [1, 7] keeps its
+// valid numbers; one without any is dropped with the spaces before it. Markers may be split across
+// deltas.
+type citationFilter struct {
+	valid   func(n int) bool
+	pending string // Text that may still turn out to be part of a marker
+}
+
+// newCitationFilter returns a filter keeping the markers of passages 1 to passageCount().
+// The count is read as markers arrive, as the Copilot's tools can add passages mid-answer.
+func newCitationFilter(passageCount func() int) *citationFilter {
+	return &citationFilter{valid: func(n int) bool { return n >= 1 && n <= passageCount() }}
+}
+
+// Feed consumes the next text delta and returns the text that can be emitted so far.
+func (f *citationFilter) Feed(text string) string {
+	f.pending += text
+	var out strings.Builder
+	for {
+		i := strings.IndexByte(f.pending, '[')
+		if i < 0 {
+			// Hold back trailing spaces, which belong to a marker that is dropped.
+			kept := strings.TrimRight(f.pending, " ")
+			out.WriteString(kept)
+			f.pending = f.pending[len(kept):]
+			return out.String()
+		}
+		before := strings.TrimRight(f.pending[:i], " ")
+		out.WriteString(before)
+		spaces, rest := f.pending[len(before):i], f.pending[i:]
+
+		if m := leadingMarkerPattern.FindStringSubmatch(rest); m != nil {
+			if marker := f.rewrite(m[0], m[1]); marker != "" {
+				out.WriteString(spaces + marker)
+			}
+			f.pending = rest[len(m[0]):]
+			continue
+		}
+		if partialMarkerPattern.MatchString(rest) && len(rest) <= maxCitationMarkerBytes {
+			f.pending = spaces + rest
+			return out.String()
+		}
+		// Not a marker after all.
+		out.WriteString(spaces + "[")
+		f.pending = rest[1:]
+	}
+}
+
+// Flush ends the stream and returns whatever is still held back.
+func (f *citationFilter) Flush() string {
+	rest := f.pending
+	f.pending = ""
+	return rest
+}
+
+// rewrite returns marker with only its valid numbers, or "" if there are none.
+func (f *citationFilter) rewrite(marker, list string) string {
+	parts := strings.Split(list, ",")
+	var kept []string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if n, err := strconv.Atoi(part); err == nil && f.valid(n) {
+			kept = append(kept, part)
+		}
+	}
+	switch len(kept) {
+	case 0:
+		return ""
+	case len(parts):
+		return marker
+	}
+	return "[" + strings.Join(kept, ", ") + "]"
+}
+
+// answerStream turns a model's streamed text into deltas: widget blocks become widget deltas and
+// the text around them goes through a citationFilter. Block bodies bypass the filter, so that a
+// bracketed number in widget JSON is left alone.
+type answerStream struct {
+	parser  *ai.WidgetParser
+	markers *citationFilter
+	answer  strings.Builder // The answer as streamed, widgets written back as blocks
+}
+
+func newAnswerStream(passageCount func() int) *answerStream {
+	return &answerStream{parser: ai.NewWidgetParser(), markers: newCitationFilter(passageCount)}
+}
+
+// Feed consumes the next text delta and returns the deltas that can be emitted so far.
+func (a *answerStream) Feed(text string) []ai.DeltaContent {
+	return a.emit(a.parser.Feed(text), false)
+}
+
+// Flush ends the stream and returns whatever is still held back.
+func (a *answerStream) Flush() []ai.DeltaContent {
+	return a.emit(a.parser.Flush(), true)
+}
+
+// String returns the answer streamed so far.
+func (a *answerStream) String() string {
+	return a.answer.String()
+}
+
+func (a *answerStream) emit(deltas []ai.DeltaContent, final bool) []ai.DeltaContent {
+	var out []ai.DeltaContent
+	emitText := func(text string) {
+		if text != "" {
+			a.answer.WriteString(text)
+			out = append(out, ai.DeltaContent{Content: text})
+		}
+	}
+	for _, delta := range deltas {
+		if delta.Widget != nil {
+			// Text held back for a marker cannot continue into the block.
+			emitText(a.markers.Flush())
+			a.answer.WriteString(ai.WidgetBlock(delta.Widget))
+			out = append(out, delta)
+			continue
+		}
+		emitText(a.markers.Feed(delta.Content))
+	}
+	if final {
+		emitText(a.markers.Flush())
+	}
+	return out
+}
+
+// extractCitations resolves the [n] markers of an answer against the passages that were supplied.
+// Markers that point outside the supplied passages are bogus and dropped, as is anything inside a
+// widget block. Each citation quotes the
+// passage sentence that best supports the claim it is attached to, and appears once, in order of
+// first use.
+func extractCitations(answer string, passages []chatPassage) []ai.Citation {
+	if len(passages) == 0 {
+		return nil
+	}
+
+	var citations []ai.Citation
+	used := make(map[int]bool)
+	claim := ""
+	answer = widgetBlockPattern.ReplaceAllString(answer, "\n")
+	for _, sentence := range sentencePattern.FindAllString(answer, -1) {
+		markers := citationMarkerPattern.FindAllStringSubmatch(sentence, -1)
+		// A marker placed after the full stop belongs to the previous sentence.
+		if text := strings.TrimSpace(citationMarkerPattern.ReplaceAllString(sentence, "")); text != "" {
+			claim = text
+		}
+		for _, m := range markers {
+			for _, part := range strings.Split(m[1], ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil || n < 1 || n > len(passages) || used[n] {
+					continue
+				}
+				used[n] = true
+				p := passages[n-1]
+				citations = append(citations, ai.Citation{
+					Index:   n,
+					EmailID: p.EmailID.String(),
+					Subject: p.Subject,
+					Date:    p.Date.Format("2006-01-02"),
+					Quote:   supportingQuote(p.Content, claim),
+				})
+			}
+		}
+	}
+	return citations
+}
+
+// supportingQuote returns the passage sentence sharing the most terms with the claim.
+func supportingQuote(content, claim string) string {
+	claimTerms := make(map[string]bool)
+	for _, t := range tokenizeForRerank(claim) {
+		claimTerms[t] = true
+	}
+
+	best, bestScore := "", -1
+	for _, sentence := range sentencePattern.FindAllString(content, -1) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		score := 0
+		for _, t := range uniqueTerms(tokenizeForRerank(sentence)) {
+			if claimTerms[t] {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = sentence, score
+		}
+	}
+
+	if quote := []rune(best); len(quote) > chatCitationQuoteRunes {
+		return string(quote[:chatCitationQuoteRunes-1]) + "…"
+	}
+	return best
+}
+
+// citedEmailIDs lists the distinct emails cited, in citation order.
+func citedEmailIDs(citations []ai.Citation) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, c := range citations {
+		id, err := uuid.Parse(c.EmailID)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids
+}
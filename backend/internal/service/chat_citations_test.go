@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractCitations(t *testing.T) {
+	date := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	passages := []chatPassage{
+		{EmailID: uuid.New(), Subject: "Contract", Date: date, Content: "Thanks for the call. The signed contract is attached. Payment terms are net 30."},
+		{EmailID: uuid.New(), Subject: "Offsite", Date: date, Content: "The offsite moves to Friday."},
+	}
+
+	answer := "Bob sent the signed contract [1]. The offsite is on Friday.[2] Payment is due in 30 days [1, 2]. Revenue doubled [7]."
+	citations := extractCitations(answer, passages)
+
+	require.Len(t, citations, 2, "repeated markers are cited once and [7] is not in the context")
+	assert.Equal(t, 1, citations[0].Index)
+	assert.Equal(t, passages[0].EmailID.String(), citations[0].EmailID)
+	assert.Equal(t, "Contract", citations[0].Subject)
+	assert.Equal(t, "2024-03-04", citations[0].Date)
+	assert.Equal(t, "The signed contract is attached.", citations[0].Quote)
+	assert.Equal(t, 2, citations[1].Index)
+	assert.Equal(t, "The offsite moves to Friday.", citations[1].Quote)
+
+	assert.Empty(t, extractCitations("No markers here.", passages))
+	assert.Empty(t, extractCitations("Made up [1].", nil))
+}
+
+func TestExtractCitations_MarkerAfterFullStop(t *testing.T) {
+	passages := []chatPassage{{EmailID: uuid.New(), Content: "Lunch is at noon. The budget was approved by finance."}}
+
+	citations := extractCitations("Finance approved the budget. [1]", passages)
+	require.Len(t, citations, 1)
+	assert.Equal(t, "The budget was approved by finance.", citations[0].Quote)
+}
+
+func TestCitationFilter(t *testing.T) {
+	filter := newCitationFilter(func() int { return 2 })
+	var out strings.Builder
+	for _, delta := range []string{"Signed [1", "]. Moved [", "3]. Due soon [1,", " 2] and [2, 9]. See arr[x] and [12", "0"} {
+		out.WriteString(filter.Feed(delta))
+	}
+	out.WriteString(filter.Flush())
+	assert.Equal(t, "Signed [1]. Moved. Due soon [1, 2] and [2]. See arr[x] and [120", out.String(), "an unterminated marker is text")
+}
+
+func TestAnswerStream_LeavesWidgetBodiesAlone(t *testing.T) {
+	stream := newAnswerStream(func() int { return 1 })
+	var deltas []ai.DeltaContent
+	for _, part := range []string{"Drafted it [1] <widget type=\"email_draft\">", `{"body": "See item [`, `3] of the list."}</widget> Done [2].`} {
+		deltas = append(deltas, stream.Feed(part)...)
+	}
+	deltas = append(deltas, stream.Flush()...)
+
+	var text strings.Builder
+	var widgets []*ai.WidgetData
+	for _, delta := range deltas {
+		text.WriteString(delta.Content)
+		if delta.Widget != nil {
+			widgets = append(widgets, delta.Widget)
+		}
+	}
+	assert.Equal(t, "Drafted it [1]  Done.", text.String(), "the bogus [2] outside the block is removed")
+	require.Len(t, widgets, 1)
+	assert.Equal(t, "See item [3] of the list.", widgets[0].Data["body"])
+	assert.Contains(t, stream.String(), `"See item [3] of the list."`, "the saved answer keeps the block")
+
+	citations := extractCitations(stream.String(), []chatPassage{{EmailID: uuid.New(), Content: "Draft it."}})
+	require.Len(t, citations, 1)
+	assert.Equal(t, 1, citations[0].Index)
+}
+
+func TestChatService_StreamChat_Citations(t *testing.T) {
+	mockAI := new(MockAIProvider)
+	mockSearch := new(MockContextSearcher)
+	chatService := NewChatService(mockAI, mockSearch, nil)
+	chatService.SetReranker(nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	emailID := uuid.New()
+	mockSearch.On("Search", ctx, userID, "When is the offsite?", mock.Anything, chatRAGTopK).Return([]SearchResult{
+		{EmailID: emailID, Subject: "Offsite", Snippet: "The offsite moves to Friday.", Date: time.Now()},
+	}, nil).Once()
+	mockAI.On("StreamChat", ctx, mock.MatchedBy(func(msgs []ai.Message) bool {
+		return strings.Contains(msgs[0].Content, "[1] Subject: Offsite") && strings.Contains(msgs[0].Content, "square brackets")
+	}), mock.Anything).Return(nil).Run(replyWith("It is on Friday [1], not Monday [2].")).Once()
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	require.NoError(t, chatService.StreamChat(ctx, userID, []ai.Message{{Role: "user", Content: "When is the offsite?"}}, nil, ch))
+
+	var chunks []ai.ChatCompletionChunk
+	for chunk := range ch {
+		chunks = append(chunks, chunk)
+	}
+	require.Len(t, chunks, 2)
+	assert.Equal(t, "It is on Friday [1], not Monday.", chunks[0].Choices[0].Delta.Content, "the bogus [2] is removed")
+	require.Len(t, chunks[1].Citations, 1)
+	assert.Equal(t, emailID.String(), chunks[1].Citations[0].EmailID)
+	mockAI.AssertExpectations(t)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+const (
+	defaultChatRetrievalHops      = 2
+	defaultChatContextTokens      = 1500
+	chatRewriteMaxQueries         = 3 // Searches per retrieval round
+	chatRewriteHistoryMessages    = 6 // Latest turns shown to the rewriter
+	chatRewriteFollowUpCandidates = 8 // Passages shown when asking for follow-up searches
+)
+
+// ChatRetrievalOptions tunes how chat questions are turned into searches.
+type ChatRetrievalOptions struct {
+	QueryRewrite  bool // Rewrite the conversation into standalone queries with the chat model
+	MaxHops       int  // Retrieval rounds; later rounds follow up on what earlier ones found (default 2)
+	ContextTokens int  // Token budget for retrieved passages in the prompt (default 1500)
+}
+
+// RetrievalQuery is a standalone search derived from the conversation, with the hints the
+// rewriter pulled out of the question.
+type RetrievalQuery struct {
+	Query  string     `json:"query"`
+	Sender string     `json:"sender,omitempty"`
+	After  *time.Time `json:"after,omitempty"`
+	Before *time.Time `json:"before,omitempty"`
+}
+
+func (q RetrievalQuery) key() string {
+	return fmt.Sprintf("%s|%s|%v|%v", strings.ToLower(q.Query), strings.ToLower(q.Sender), q.After, q.Before)
+}
+
+func (q RetrievalQuery) filters() SearchFilters {
+	return SearchFilters{Sender: q.Sender, StartDate: q.After, EndDate: q.Before}
+}
+
+// SetRetrievalOptions configures query rewriting and multi-hop retrieval. Without QueryRewrite the
+// last user message is searched as is.
+func (s *ChatService) SetRetrievalOptions(opts ChatRetrievalOptions) {
+	if opts.MaxHops <= 0 {
+		opts.MaxHops = defaultChatRetrievalHops
+	}
+	if opts.ContextTokens <= 0 {
+		opts.ContextTokens = defaultChatContextTokens
+	}
+	s.retrieval = opts
+}
+
+const chatRewritePrompt = `You turn a user's question to their email assistant into search queries over their mailbox.
+Resolve pronouns and references ("he", "that meeting", "after that") using the conversation, so each query stands alone.
+Pull out hints: the sender's name or address if the question names one, and a date range if it mentions a time
+("last week", "in March", "after the offsite"). Today is %s.
+Reply with JSON only: {"queries": [{"query": "...", "sender": "...", "after": "YYYY-MM-DD", "before": "YYYY-MM-DD"}]}
+Use at most %d queries and omit hints you are unsure about.`
+
+const chatFollowUpPrompt = `You decide whether more searches are needed to answer a user's question from their email.
+You get the question and the emails found so far. If they are enough, or nothing more could help, reply {"queries": []}.
+Otherwise ask for what is still missing, e.g. the reply to a thread that was found or a message from a person it names.
+Today is %s. Reply with JSON only: {"queries": [{"query": "...", "sender": "...", "after": "YYYY-MM-DD", "before": "YYYY-MM-DD"}]}
+Use at most %d queries.`
+
+// multiHopRetrieve rewrites the conversation into standalone queries, runs them, optionally asks
+// for follow-up searches based on what was found, and merges everything into one ranked list
+// that fits the context token budget.
+func (s *ChatService) multiHopRetrieve(ctx context.Context, userID uuid.UUID, history []ai.Message, question string) ([]SearchResult, error) {
+	queries, err := s.rewriteQueries(ctx, history, question)
+	if err != nil || len(queries) == 0 {
+		// Rewriting is an optimization; search the question itself.
+		queries = []RetrievalQuery{{Query: question}}
+	}
+	standalone := queries[0].Query
+
+	seen := make(map[string]bool)
+	var pool []SearchResult
+	var lastErr error
+	for hop := 0; hop < s.retrieval.MaxHops && len(queries) > 0; hop++ {
+		for _, q := range queries {
+			if seen[q.key()] {
+				continue
+			}
+			seen[q.key()] = true
+			results, err := s.searchService.Search(ctx, userID, q.Query, q.filters(), chatRAGCandidates)
+			if err != nil {
+				lastErr = err
+				continue
+			}
+			pool = append(pool, results...)
+		}
+		if hop+1 < s.retrieval.MaxHops {
+			queries, _ = s.followUpQueries(ctx, question, CollapseByEmail(pool))
+		}
+	}
+	if len(pool) == 0 {
+		return nil, lastErr
+	}
+
+	merged := CollapseByEmail(pool)
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Score > merged[j].Score })
+	if s.reranker != nil {
+		if reranked, err := s.reranker.Rerank(ctx, standalone, merged, len(merged)); err == nil {
+			merged = reranked
+		}
+	}
+	return fitContextBudget(merged, s.retrieval.ContextTokens), nil
+}
+
+// rewriteQueries asks the chat model for standalone search queries.
+func (s *ChatService) rewriteQueries(ctx context.Context, history []ai.Message, question string) ([]RetrievalQuery, error) {
+	var prompt strings.Builder
+	if len(history) > chatRewriteHistoryMessages {
+		history = history[len(history)-chatRewriteHistoryMessages:]
+	}
+	if len(history) > 0 {
+		prompt.WriteString("Conversation:\n")
+		for _, m := range history {
+			prompt.WriteString(fmt.Sprintf("%s: %s\n", m.Role, m.Content))
+		}
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("Question: " + question)
+
+	return s.planQueries(ctx, fmt.Sprintf(chatRewritePrompt, time.Now().Format("2006-01-02"), chatRewriteMaxQueries), prompt.String())
+}
+
+// followUpQueries asks the chat model what is still missing after a retrieval round.
+func (s *ChatService) followUpQueries(ctx context.Context, question string, found []SearchResult) ([]RetrievalQuery, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Question: " + question + "\n\nEmails found:\n")
+	for i, r := range truncateResults(found, chatRewriteFollowUpCandidates) {
+		prompt.WriteString(fmt.Sprintf("[%d] %s | From: %s | %s\n%s\n", i+1, r.Date.Format("2006-01-02"), r.Sender, r.Subject, r.Snippet))
+	}
+	return s.planQueries(ctx, fmt.Sprintf(chatFollowUpPrompt, time.Now().Format("2006-01-02"), chatRewriteMaxQueries), prompt.String())
+}
+
+func (s *ChatService) planQueries(ctx context.Context, system, user string) ([]RetrievalQuery, error) {
+	reply, err := collectChatReply(ctx, s.aiProvider, []ai.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: user},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return parseRetrievalQueries(reply)
+}
+
+// parseRetrievalQueries reads the rewriter's JSON, dropping empty queries and unparseable dates.
+func parseRetrievalQueries(reply string) ([]RetrievalQuery, error) {
+	var parsed struct {
+		Queries []struct {
+			Query  string `json:"query"`
+			Sender string `json:"sender"`
+			After  string `json:"after"`
+			Before string `json:"before"`
+		} `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(extractJSONObject(reply)), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid query rewrite: %w", err)
+	}
+
+	var queries []RetrievalQuery
+	for _, q := range parsed.Queries {
+		query := strings.TrimSpace(q.Query)
+		if query == "" {
+			continue
+		}
+		rq := RetrievalQuery{Query: query, Sender: strings.TrimSpace(q.Sender)}
+		if day, err := time.Parse("2006-01-02", q.After); err == nil {
+			rq.After = &day
+		}
+		if day, err := time.Parse("2006-01-02", q.Before); err == nil {
+			// Inclusive: the whole "before" day counts.
+			end := day.Add(24*time.Hour - time.Nanosecond)
+			rq.Before = &end
+		}
+		queries = append(queries, rq)
+		if len(queries) == chatRewriteMaxQueries {
+			break
+		}
+	}
+	return queries, nil
+}
+
+// fitContextBudget keeps passages in order until the estimated token budget is spent. The first
+// passage is always kept.
+func fitContextBudget(results []SearchResult, tokens int) []SearchResult {
+	used := 0
+	for i, r := range results {
+		cost := (len(r.Subject) + len(r.Sender) + len(r.Snippet)) / 4
+		if i > 0 && used+cost > tokens {
+			return results[:i]
+		}
+		used += cost
+	}
+	return results
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// replyWith makes a mocked StreamChat call stream a single chunk and close the channel.
+func replyWith(content string) func(mock.Arguments) {
+	return func(args mock.Arguments) {
+		ch := args.Get(2).(chan<- ai.ChatCompletionChunk)
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: content}}}}
+		close(ch)
+	}
+}
+
+func systemPromptContains(fragment string) interface{} {
+	return mock.MatchedBy(func(msgs []ai.Message) bool {
+		return len(msgs) > 0 && strings.Contains(msgs[0].Content, fragment)
+	})
+}
+
+func TestParseRetrievalQueries(t *testing.T) {
+	queries, err := parseRetrievalQueries("```json\n" + `{"queries": [
+		{"query": "contract reply from Bob", "sender": "Bob", "after": "2024-03-04", "before": "2024-03-10"},
+		{"query": "  "},
+		{"query": "contract", "after": "next week"}
+	]}` + "\n```")
+	require.NoError(t, err)
+	require.Len(t, queries, 2, "empty queries are dropped")
+
+	assert.Equal(t, "Bob", queries[0].Sender)
+	require.NotNil(t, queries[0].After)
+	assert.Equal(t, "2024-03-04", queries[0].After.Format("2006-01-02"))
+	require.NotNil(t, queries[0].Before)
+	assert.True(t, queries[0].Before.After(time.Date(2024, 3, 10, 23, 0, 0, 0, time.UTC)), "before includes the whole day")
+	assert.Nil(t, queries[1].After, "unparseable dates are ignored")
+
+	_, err = parseRetrievalQueries("I cannot help with that")
+	assert.Error(t, err)
+}
+
+func TestFitContextBudget(t *testing.T) {
+	results := []SearchResult{
+		{Snippet: strings.Repeat("a", 400)}, // ~100 tokens
+		{Snippet: strings.Repeat("b", 400)},
+		{Snippet: strings.Repeat("c", 400)},
+	}
+	assert.Len(t, fitContextBudget(results, 250), 2)
+	assert.Len(t, fitContextBudget(results, 10), 1, "the best passage is always kept")
+	assert.Len(t, fitContextBudget(results, 1000), 3)
+}
+
+func TestChatService_MultiHopRetrieval(t *testing.T) {
+	mockAI := new(MockAIProvider)
+	mockSearch := new(MockContextSearcher)
+	chatService := NewChatService(mockAI, mockSearch, nil)
+	chatService.SetReranker(nil)
+	chatService.SetRetrievalOptions(ChatRetrievalOptions{QueryRewrite: true})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	messages := []ai.Message{
+		{Role: "user", Content: "Did Bob send the contract?"},
+		{Role: "assistant", Content: "Yes, on Monday."},
+		{Role: "user", Content: "and what did he say after that?"},
+	}
+
+	// Round 1: the follow-up is rewritten using the conversation, with a sender hint.
+	mockAI.On("StreamChat", mock.Anything, mock.MatchedBy(func(msgs []ai.Message) bool {
+		return len(msgs) == 2 && strings.Contains(msgs[0].Content, "search queries over their mailbox") &&
+			strings.Contains(msgs[1].Content, "Did Bob send the contract?")
+	}), mock.Anything).Return(nil).Run(replyWith(`{"queries": [{"query": "Bob contract follow-up", "sender": "Bob", "after": "2024-03-04"}]}`)).Once()
+	contractID, followUpID := uuid.New(), uuid.New()
+	mockSearch.On("Search", mock.Anything, userID, "Bob contract follow-up", mock.MatchedBy(func(f SearchFilters) bool {
+		return f.Sender == "Bob" && f.StartDate != nil && f.StartDate.Format("2006-01-02") == "2024-03-04"
+	}), chatRAGCandidates).Return([]SearchResult{
+		{EmailID: contractID, Subject: "Contract", Snippet: "Signed copy attached", Score: 0.7},
+	}, nil).Once()
+
+	// Round 2: the model asks for the reply it has not seen yet.
+	mockAI.On("StreamChat", mock.Anything, systemPromptContains("whether more searches are needed"), mock.Anything).
+		Return(nil).Run(replyWith(`{"queries": [{"query": "Bob payment terms reply"}]}`)).Once()
+	mockSearch.On("Search", mock.Anything, userID, "Bob payment terms reply", SearchFilters{}, chatRAGCandidates).Return([]SearchResult{
+		{EmailID: followUpID, Subject: "Re: Contract", Snippet: "Payment is due in 30 days", Score: 0.9},
+		{EmailID: contractID, Subject: "Contract", Snippet: "Signed copy attached", Score: 0.6},
+	}, nil).Once()
+
+	// The answer is grounded in both hops, best first.
+	mockAI.On("StreamChat", mock.Anything, mock.MatchedBy(func(msgs []ai.Message) bool {
+		prompt := msgs[0].Content
+		return len(msgs) == 4 && strings.Index(prompt, "Payment is due") < strings.Index(prompt, "Signed copy") &&
+			strings.Index(prompt, "Payment is due") >= 0 && strings.Count(prompt, "Signed copy") == 1
+	}), mock.Anything).Return(nil).Run(replyWith("He said payment is due in 30 days.")).Once()
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	require.NoError(t, chatService.StreamChat(ctx, userID, messages, nil, ch))
+
+	mockAI.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+}
+
+func TestChatService_MultiHopRetrieval_FallsBackToQuestion(t *testing.T) {
+	mockAI := new(MockAIProvider)
+	mockSearch := new(MockContextSearcher)
+	chatService := NewChatService(mockAI, mockSearch, nil)
+	chatService.SetReranker(nil)
+	chatService.SetRetrievalOptions(ChatRetrievalOptions{QueryRewrite: true, MaxHops: 1})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	mockAI.On("StreamChat", mock.Anything, systemPromptContains("search queries over their mailbox"), mock.Anything).
+		Return(nil).Run(replyWith("Sorry, I can't do that.")).Once()
+	mockSearch.On("Search", mock.Anything, userID, "Budget?", SearchFilters{}, chatRAGCandidates).Return([]SearchResult{}, nil).Once()
+	mockAI.On("StreamChat", mock.Anything, systemPromptContains("EchoMind Copilot"), mock.Anything).
+		Return(nil).Run(replyWith("I found nothing.")).Once()
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	require.NoError(t, chatService.StreamChat(ctx, userID, []ai.Message{{Role: "user", Content: "Budget?"}}, nil, ch))
+
+	mockAI.AssertExpectations(t)
+	mockSearch.AssertExpectations(t)
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatHistoryTokenBudget is the estimated size of the unsummarized history above which older
+	// turns are folded into the session summary.
+	chatHistoryTokenBudget = 3000
+	// chatHistoryKeepRecent is how many of the latest messages always stay verbatim.
+	chatHistoryKeepRecent = 6
+	chatSessionTitleRunes = 60
+)
+
+var ErrChatSessionNotFound = errors.New("chat session not found")
+
+// ChatSessionService stores chat sessions and keeps their history within the context window.
+type ChatSessionService struct {
+	db         *gorm.DB
+	summarizer ai.AIProvider // Folds old turns into the session summary; nil keeps only recent turns
+}
+
+func NewChatSessionService(db *gorm.DB, summarizer ai.AIProvider) *ChatSessionService {
+	return &ChatSessionService{db: db, summarizer: summarizer}
+}
+
+// Create starts an empty session. An empty title is filled in from the first message.
+func (s *ChatSessionService) Create(ctx context.Context, userID uuid.UUID, title string) (*model.ChatSession, error) {
+	session := &model.ChatSession{
+		ID:            uuid.New(),
+		UserID:        userID,
+		Title:         strings.TrimSpace(title),
+		LastMessageAt: time.Now(),
+	}
+	if err := s.db.WithContext(ctx).Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// List returns the user's sessions, most recently active first, without messages.
+func (s *ChatSessionService) List(ctx context.Context, userID uuid.UUID, limit, offset int) ([]model.ChatSession, error) {
+	var sessions []model.ChatSession
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("last_message_at DESC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+	if err := query.Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// Get returns one of the user's sessions without messages.
+func (s *ChatSessionService) Get(ctx context.Context, userID, id uuid.UUID) (*model.ChatSession, error) {
+	var session model.ChatSession
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetWithMessages returns a session and its full transcript, for resuming it in the UI.
+func (s *ChatSessionService) GetWithMessages(ctx context.Context, userID, id uuid.UUID) (*model.ChatSession, error) {
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Where("session_id = ?", id).Order("seq").Find(&session.Messages).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Rename changes a session's title.
+func (s *ChatSessionService) Rename(ctx context.Context, userID, id uuid.UUID, title string) (*model.ChatSession, error) {
+	session, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	session.Title = strings.TrimSpace(title)
+	if err := s.db.WithContext(ctx).Model(session).Update("title", session.Title).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// Delete removes a session and its messages.
+func (s *ChatSessionService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.ChatSession{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrChatSessionNotFound
+		}
+		return tx.Where("session_id = ?", id).Delete(&model.ChatMessage{}).Error
+	})
+}
+
+// AppendMessage adds a turn to the end of a session.
+func (s *ChatSessionService) AppendMessage(ctx context.Context, session *model.ChatSession, role, content string, citations []ai.Citation) (*model.ChatMessage, error) {
+	msg := &model.ChatMessage{
+		ID:        uuid.New(),
+		SessionID: session.ID,
+		Role:      role,
+		Content:   content,
+	}
+	if role == "assistant" {
+		// The answer was generated with ctx, so it resolves to the same chat prompt version.
+		msg.PromptVersion = ai.PromptRef(ctx, ai.PromptChatSystem)
+	}
+	if len(citations) > 0 {
+		msg.Citations = mustMarshalJSON(citations)
+		msg.CitedEmailIDs = mustMarshalJSON(citedEmailIDs(citations))
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&model.ChatMessage{}).Where("session_id = ?", session.ID).
+			Select("COALESCE(MAX(seq), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		msg.Seq = last + 1
+		if err := tx.Create(msg).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"message_count": msg.Seq, "last_message_at": time.Now()}
+		if session.Title == "" && role == "user" {
+			session.Title = chatSessionTitle(content)
+			updates["title"] = session.Title
+		}
+		return tx.Model(session).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save chat message: %w", err)
+	}
+	session.MessageCount = msg.Seq
+	return msg, nil
+}
+
+// History returns the session summary and the turns after it, compacting the history first if it has
+// outgrown the budget. The turns are returned oldest first, ready to be sent to the model.
+func (s *ChatSessionService) History(ctx context.Context, session *model.ChatSession) (string, []ai.Message, error) {
+	var messages []model.ChatMessage
+	if err := s.db.WithContext(ctx).Where("session_id = ? AND seq > ?", session.ID, session.SummarizedSeq).
+		Order("seq").Find(&messages).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to load chat history: %w", err)
+	}
+
+	if estimateMessageTokens(messages) > chatHistoryTokenBudget && len(messages) > chatHistoryKeepRecent {
+		older, recent := messages[:len(messages)-chatHistoryKeepRecent], messages[len(messages)-chatHistoryKeepRecent:]
+		// If summarizing fails the older turns are dropped for this request rather than overflowing
+		// the prompt; they stay unsummarized and compaction is retried on the next turn.
+		_ = s.compact(ctx, session, older)
+		messages = recent
+	}
+
+	history := make([]ai.Message, len(messages))
+	for i, m := range messages {
+		history[i] = ai.Message{Role: m.Role, Content: m.Content}
+	}
+	return session.Summary, history, nil
+}
+
+// compact folds older turns into the session summary.
+func (s *ChatSessionService) compact(ctx context.Context, session *model.ChatSession, older []model.ChatMessage) error {
+	if s.summarizer == nil {
+		return errors.New("no summarizer configured")
+	}
+
+	var transcript strings.Builder
+	if session.Summary != "" {
+		transcript.WriteString("Summary of the conversation so far:\n")
+		transcript.WriteString(session.Summary)
+		transcript.WriteString("\n\nLater turns:\n")
+	}
+	for _, m := range older {
+		transcript.WriteString(fmt.Sprintf("%s: %s\n", m.Role, m.Content))
+	}
+
+	summary, err := collectChatReply(ctx, s.summarizer, []ai.Message{
+		{Role: "system", Content: "Summarize this conversation between a user and their email assistant in under 200 words. " +
+			"Keep names, dates, decisions, open questions and any emails referred to. Reply with the summary only."},
+		{Role: "user", Content: transcript.String()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to summarize chat history: %w", err)
+	}
+
+	session.Summary = strings.TrimSpace(summary)
+	session.SummarizedSeq = older[len(older)-1].Seq
+	return s.db.WithContext(ctx).Model(session).Updates(map[string]interface{}{
+		"summary":        session.Summary,
+		"summarized_seq": session.SummarizedSeq,
+	}).Error
+}
+
+// estimateMessageTokens approximates token usage at four characters per token.
+func estimateMessageTokens(messages []model.ChatMessage) int {
+	chars := 0
+	for _, m := range messages {
+		chars += len(m.Content)
+	}
+	return chars / 4
+}
+
+// chatSessionTitle derives a title from the first question.
+func chatSessionTitle(content string) string {
+	title := []rune(strings.Join(strings.Fields(content), " "))
+	if len(title) > chatSessionTitleRunes {
+		return string(title[:chatSessionTitleRunes-1]) + "…"
+	}
+	return string(title)
+}
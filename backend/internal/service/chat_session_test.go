@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupChatSessionTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:chat_sessions?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.ChatSession{}, &model.ChatMessage{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM chat_messages")
+		db.Exec("DELETE FROM chat_sessions")
+	})
+	return db
+}
+
+func TestChatSessionService_Lifecycle(t *testing.T) {
+	db := setupChatSessionTestDB(t)
+	svc := NewChatSessionService(db, nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	session, err := svc.Create(ctx, userID, "")
+	require.NoError(t, err)
+
+	_, err = svc.AppendMessage(ctx, session, "user", "  What did   Alice say about the Q3 budget review that is scheduled for next week?", nil)
+	require.NoError(t, err)
+	emailID := uuid.New()
+	citations := []ai.Citation{{Index: 1, EmailID: emailID.String(), Subject: "Q3 budget", Quote: "Approved."}}
+	reply, err := svc.AppendMessage(ctx, session, "assistant", "She approved it. [1]", citations)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reply.Seq)
+	assert.Equal(t, 2, session.MessageCount)
+	assert.Equal(t, "What did Alice say about the Q3 budget review that is sched…", session.Title, "title comes from the first question")
+
+	resumed, err := svc.GetWithMessages(ctx, userID, session.ID)
+	require.NoError(t, err)
+	require.Len(t, resumed.Messages, 2)
+	assert.Equal(t, "user", resumed.Messages[0].Role)
+	var ids []uuid.UUID
+	require.NoError(t, json.Unmarshal(resumed.Messages[1].CitedEmailIDs, &ids))
+	assert.Equal(t, []uuid.UUID{emailID}, ids)
+	var stored []ai.Citation
+	require.NoError(t, json.Unmarshal(resumed.Messages[1].Citations, &stored))
+	assert.Equal(t, citations, stored)
+
+	_, err = svc.GetWithMessages(ctx, uuid.New(), session.ID)
+	assert.ErrorIs(t, err, ErrChatSessionNotFound, "other users cannot resume the session")
+
+	renamed, err := svc.Rename(ctx, userID, session.ID, "Budget")
+	require.NoError(t, err)
+	assert.Equal(t, "Budget", renamed.Title)
+
+	list, err := svc.List(ctx, userID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "Budget", list[0].Title)
+
+	require.NoError(t, svc.Delete(ctx, userID, session.ID))
+	assert.ErrorIs(t, svc.Delete(ctx, userID, session.ID), ErrChatSessionNotFound)
+	var remaining int64
+	db.Model(&model.ChatMessage{}).Where("session_id = ?", session.ID).Count(&remaining)
+	assert.Zero(t, remaining)
+}
+
+func TestChatSessionService_HistorySummarizesOlderTurns(t *testing.T) {
+	db := setupChatSessionTestDB(t)
+	summarizer := new(MockAIProvider)
+	svc := NewChatSessionService(db, summarizer)
+	ctx := context.Background()
+
+	session, err := svc.Create(ctx, uuid.New(), "Long chat")
+	require.NoError(t, err)
+	long := strings.Repeat("word ", chatHistoryTokenBudget/chatHistoryKeepRecent)
+	for i := 0; i < chatHistoryKeepRecent+4; i++ {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		_, err := svc.AppendMessage(ctx, session, role, long, nil)
+		require.NoError(t, err)
+	}
+
+	summarizer.On("StreamChat", mock.Anything, mock.MatchedBy(func(msgs []ai.Message) bool {
+		return len(msgs) == 2 && strings.Count(msgs[1].Content, "user:")+strings.Count(msgs[1].Content, "assistant:") == 4
+	}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		ch := args.Get(2).(chan<- ai.ChatCompletionChunk)
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: "Earlier they discussed the budget."}}}}
+		close(ch)
+	}).Once()
+
+	summary, history, err := svc.History(ctx, session)
+	require.NoError(t, err)
+	assert.Equal(t, "Earlier they discussed the budget.", summary)
+	assert.Len(t, history, chatHistoryKeepRecent)
+	summarizer.AssertExpectations(t)
+
+	// The summary is persisted, so the next turn does not summarize again.
+	stored, err := svc.Get(ctx, session.UserID, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, stored.SummarizedSeq)
+	summary, history, err = svc.History(ctx, stored)
+	require.NoError(t, err)
+	assert.Equal(t, "Earlier they discussed the budget.", summary)
+	assert.Len(t, history, chatHistoryKeepRecent)
+}
+
+func TestChatService_StreamSessionChat(t *testing.T) {
+	db := setupChatSessionTestDB(t)
+	mockAI := new(MockAIProvider)
+	mockSearch := new(MockContextSearcher)
+	sessions := NewChatSessionService(db, mockAI)
+	chatService := NewChatService(mockAI, mockSearch, nil)
+	chatService.SetReranker(nil)
+	chatService.SetSessions(sessions)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	session, err := sessions.Create(ctx, userID, "")
+	require.NoError(t, err)
+	_, err = sessions.AppendMessage(ctx, session, "user", "Who sent the offsite agenda?", nil)
+	require.NoError(t, err)
+	_, err = sessions.AppendMessage(ctx, session, "assistant", "Bob did.", nil)
+	require.NoError(t, err)
+
+	emailID := uuid.New()
+	mockSearch.On("Search", mock.Anything, userID, "When is it?", mock.Anything, chatRAGTopK).Return([]SearchResult{
+		{EmailID: emailID, Subject: "Offsite", Snippet: "Friday 10am", Date: time.Now()},
+	}, nil).Once()
+	mockAI.On("StreamChat", mock.Anything, mock.MatchedBy(func(msgs []ai.Message) bool {
+		// System prompt, stored history, then the new question
+		return len(msgs) == 4 && msgs[1].Content == "Who sent the offsite agenda?" && msgs[2].Content == "Bob did." &&
+			msgs[3].Content == "When is it?" && strings.Contains(msgs[0].Content, "Friday 10am")
+	}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		ch := args.Get(2).(chan<- ai.ChatCompletionChunk)
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: "Friday "}}}}
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: "at 10am. [1]"}}}}
+		close(ch)
+	}).Once()
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	err = chatService.StreamSessionChat(ctx, userID, session.ID, model.ChatMessageInput{Content: "When is it?"}, ch)
+	require.NoError(t, err)
+	var streamed []ai.ChatCompletionChunk
+	for chunk := range ch {
+		streamed = append(streamed, chunk)
+	}
+	require.Len(t, streamed, 3, "two content chunks, then the citations")
+	require.Len(t, streamed[2].Citations, 1)
+	assert.Equal(t, emailID.String(), streamed[2].Citations[0].EmailID)
+
+	resumed, err := sessions.GetWithMessages(ctx, userID, session.ID)
+	require.NoError(t, err)
+	require.Len(t, resumed.Messages, 4)
+	assert.Equal(t, "When is it?", resumed.Messages[2].Content)
+	assert.Equal(t, "Friday at 10am. [1]", resumed.Messages[3].Content)
+	var cited []uuid.UUID
+	require.NoError(t, json.Unmarshal(resumed.Messages[3].CitedEmailIDs, &cited))
+	assert.Equal(t, []uuid.UUID{emailID}, cited)
+
+	err = chatService.StreamSessionChat(ctx, uuid.New(), session.ID, model.ChatMessageInput{Content: "Hi"}, make(chan ai.ChatCompletionChunk, 1))
+	assert.ErrorIs(t, err, ErrChatSessionNotFound)
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -56,6 +57,19 @@ func (m *MockContextSearcher) Search(ctx context.Context, userID uuid.UUID, quer
 	return args.Get(0).([]SearchResult), args.Error(1)
 }
 
+// MockReranker mocks Reranker interface
+type MockReranker struct {
+	mock.Mock
+}
+
+func (m *MockReranker) Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error) {
+	args := m.Called(ctx, query, results, topN)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]SearchResult), args.Error(1)
+}
+
 // MockEmailRetriever mocks EmailRetriever interface
 type MockEmailRetriever struct {
 	mock.Mock
@@ -85,13 +99,13 @@ func TestChatService_StreamChat(t *testing.T) {
 		ch := make(chan ai.ChatCompletionChunk, 10)
 
 		// Expect Search to be called (Auto-Search Fallback) but return empty
-		mockSearch.On("Search", ctx, userID, "Hello", mock.Anything, 3).Return([]SearchResult{}, nil).Once()
+		mockSearch.On("Search", ctx, userID, "Hello", mock.Anything, chatRAGCandidates).Return([]SearchResult{}, nil).Once()
 
 		// Expect StreamChat to be called
 		mockAI.On("StreamChat", ctx, mock.MatchedBy(func(msgs []ai.Message) bool {
 			// Check if system prompt is generic (no context found)
 			return len(msgs) == 2 && msgs[0].Role == "system" && msgs[1].Content == "Hello"
-		}), (chan<- ai.ChatCompletionChunk)(ch)).Return(nil).Run(func(args mock.Arguments) {
+		}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 			chArg := args.Get(2).(chan<- ai.ChatCompletionChunk)
 			chArg <- ai.ChatCompletionChunk{ID: "1", Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: "Hi"}}}}
 			close(chArg)
@@ -132,7 +146,7 @@ func TestChatService_StreamChat(t *testing.T) {
 			return msgs[0].Role == "system" &&
 				strings.Contains(systemPrompt, "Test Email") &&
 				strings.Contains(systemPrompt, "This is a test email content")
-		}), (chan<- ai.ChatCompletionChunk)(ch)).Return(nil).Run(func(args mock.Arguments) {
+		}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 			chArg := args.Get(2).(chan<- ai.ChatCompletionChunk)
 			chArg <- ai.ChatCompletionChunk{ID: "1", Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: "Summary..."}}}}
 			close(chArg)
@@ -150,7 +164,7 @@ func TestChatService_StreamChat(t *testing.T) {
 		ch := make(chan ai.ChatCompletionChunk, 10)
 
 		// Mock Search returning results
-		mockSearch.On("Search", ctx, userID, "What about the budget?", mock.Anything, 3).Return([]SearchResult{
+		mockSearch.On("Search", ctx, userID, "What about the budget?", mock.Anything, chatRAGCandidates).Return([]SearchResult{
 			{
 				Subject: "Budget Report",
 				Snippet: "The budget is tight.",
@@ -163,7 +177,7 @@ func TestChatService_StreamChat(t *testing.T) {
 		mockAI.On("StreamChat", ctx, mock.MatchedBy(func(msgs []ai.Message) bool {
 			systemPrompt := msgs[0].Content
 			return strings.Contains(systemPrompt, "Budget Report") && strings.Contains(systemPrompt, "The budget is tight")
-		}), (chan<- ai.ChatCompletionChunk)(ch)).Return(nil).Run(func(args mock.Arguments) {
+		}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
 			chArg := args.Get(2).(chan<- ai.ChatCompletionChunk)
 			close(chArg)
 		}).Once()
@@ -174,4 +188,74 @@ func TestChatService_StreamChat(t *testing.T) {
 		mockSearch.AssertExpectations(t)
 		mockAI.AssertExpectations(t)
 	})
+
+	t.Run("Auto-Search Collapses and Reranks", func(t *testing.T) {
+		mockReranker := new(MockReranker)
+		rerankedService := NewChatService(mockAI, mockSearch, mockEmail)
+		rerankedService.SetReranker(mockReranker)
+
+		messages := []ai.Message{{Role: "user", Content: "Offsite agenda"}}
+		ch := make(chan ai.ChatCompletionChunk, 10)
+
+		offsiteID, lunchID := uuid.New(), uuid.New()
+		mockSearch.On("Search", ctx, userID, "Offsite agenda", mock.Anything, chatRAGCandidates).Return([]SearchResult{
+			{EmailID: offsiteID, Subject: "Offsite", Snippet: "chunk 1", Score: 0.8},
+			{EmailID: offsiteID, Subject: "Offsite", Snippet: "chunk 2", Score: 0.9},
+			{EmailID: lunchID, Subject: "Lunch", Snippet: "pizza", Score: 0.85},
+		}, nil).Once()
+
+		collapsed := []SearchResult{
+			{EmailID: offsiteID, Subject: "Offsite", Snippet: "chunk 2", Score: 0.9},
+			{EmailID: lunchID, Subject: "Lunch", Snippet: "pizza", Score: 0.85},
+		}
+		mockReranker.On("Rerank", ctx, "Offsite agenda", collapsed, chatRAGTopK).Return(collapsed[:1], nil).Once()
+
+		mockAI.On("StreamChat", ctx, mock.MatchedBy(func(msgs []ai.Message) bool {
+			systemPrompt := msgs[0].Content
+			return strings.Contains(systemPrompt, "chunk 2") && !strings.Contains(systemPrompt, "chunk 1") && !strings.Contains(systemPrompt, "pizza")
+		}), mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			close(args.Get(2).(chan<- ai.ChatCompletionChunk))
+		}).Once()
+
+		err := rerankedService.StreamChat(ctx, userID, messages, nil, ch)
+		assert.NoError(t, err)
+
+		mockSearch.AssertExpectations(t)
+		mockReranker.AssertExpectations(t)
+		mockAI.AssertExpectations(t)
+	})
+}
+
+func TestChatService_StreamChatParsesWidgets(t *testing.T) {
+	mockAI := new(MockAIProvider)
+	chatService := NewChatService(mockAI, nil, nil)
+	ctx := context.Background()
+
+	parts := []string{"Here you go: <wid", `get type="task_list">[{"title": "Send`, ` slides", "due": "2025-05-02"}]</wid`, "get> Anything else?"}
+	mockAI.On("StreamChat", ctx, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		chArg := args.Get(2).(chan<- ai.ChatCompletionChunk)
+		for i, part := range parts {
+			chArg <- ai.ChatCompletionChunk{ID: strconv.Itoa(i), Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: part}}}}
+		}
+		close(chArg)
+	}).Once()
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	err := chatService.StreamChat(ctx, uuid.New(), []ai.Message{{Role: "user", Content: "Make a task list"}}, nil, ch)
+	assert.NoError(t, err)
+
+	var text strings.Builder
+	var widgets []*ai.WidgetData
+	for chunk := range ch {
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		if w := chunk.Choices[0].Delta.Widget; w != nil {
+			widgets = append(widgets, w)
+		}
+	}
+	assert.Equal(t, "Here you go:  Anything else?", text.String())
+	if assert.Len(t, widgets, 1) {
+		assert.Equal(t, "task_list", widgets[0].Type)
+		assert.Equal(t, []interface{}{map[string]interface{}{"title": "Send slides", "due": "2025-05-02"}}, widgets[0].Data["tasks"])
+	}
+	mockAI.AssertExpectations(t)
 }
@@ -0,0 +1,742 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// chatToolActionTTL is how long a proposed write action can still be confirmed.
+	chatToolActionTTL = 24 * time.Hour
+	// chatToolEmailBodyRunes caps the email body handed back to the model by get_email.
+	chatToolEmailBodyRunes = 4000
+	chatToolSearchLimit    = 5
+)
+
+var (
+	ErrChatToolActionNotFound = errors.New("tool action not found")
+	ErrChatToolActionResolved = errors.New("tool action is no longer pending")
+)
+
+// DraftReplier generates reply drafts.
+type DraftReplier interface {
+	GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error)
+}
+
+// ChatToolDeps are the services the Copilot's tools act through.
+type ChatToolDeps struct {
+	Search        ContextSearcher
+	Emails        *EmailService
+	Tasks         *TaskService
+	Actions       *ActionService
+	Opportunities *OpportunityService
+	Drafts        DraftReplier
+}
+
+// chatToolEnv is what a tool run knows about the conversation it runs in.
+type chatToolEnv struct {
+	UserID uuid.UUID
+	// Passages collects emails the tool surfaced so that the answer can cite them. Nil outside a chat turn.
+	Passages *[]chatPassage
+}
+
+// chatTool is one function the model may call.
+type chatTool struct {
+	def ai.ToolDefinition
+	// describe validates the arguments of a write tool and summarizes what it will do, for confirmation.
+	// Read tools leave it nil and run straight away.
+	describe func(args json.RawMessage) (string, error)
+	run      func(ctx context.Context, env chatToolEnv, args json.RawMessage) (*ai.WidgetData, error)
+}
+
+func (t chatTool) write() bool { return t.describe != nil }
+
+// ChatToolbox holds the tools the Copilot can call and the write actions awaiting confirmation.
+type ChatToolbox struct {
+	db    *gorm.DB
+	deps  ChatToolDeps
+	tools map[string]chatTool
+	order []string
+}
+
+// NewChatToolbox registers the tools whose dependencies are available.
+func NewChatToolbox(db *gorm.DB, deps ChatToolDeps) *ChatToolbox {
+	t := &ChatToolbox{db: db, deps: deps, tools: make(map[string]chatTool)}
+	if deps.Search != nil {
+		t.register(t.searchEmailsTool())
+	}
+	if deps.Emails != nil {
+		t.register(t.getEmailTool())
+	}
+	if deps.Tasks != nil {
+		t.register(t.createTaskTool())
+		t.register(t.listTasksTool())
+	}
+	if deps.Actions != nil {
+		t.register(t.snoozeEmailTool())
+	}
+	if deps.Opportunities != nil {
+		t.register(t.createOpportunityTool())
+	}
+	if deps.Emails != nil && deps.Drafts != nil {
+		t.register(t.draftReplyTool())
+	}
+	return t
+}
+
+func (t *ChatToolbox) register(tool chatTool) {
+	t.tools[tool.def.Name] = tool
+	t.order = append(t.order, tool.def.Name)
+}
+
+// Definitions returns the tool declarations sent to the model.
+func (t *ChatToolbox) Definitions() []ai.ToolDefinition {
+	defs := make([]ai.ToolDefinition, 0, len(t.order))
+	for _, name := range t.order {
+		defs = append(defs, t.tools[name].def)
+	}
+	return defs
+}
+
+// Execute handles a tool call from the model. Read tools run immediately; write tools are stored as
+// a pending action and a confirmation widget is returned instead. The returned string is the tool
+// result for the model. Errors in the call itself are reported to the model rather than returned, so
+// it can correct itself; only storage failures are returned as errors.
+func (t *ChatToolbox) Execute(ctx context.Context, env chatToolEnv, sessionID *uuid.UUID, call ai.ToolCall) (string, *ai.WidgetData, error) {
+	tool, ok := t.tools[call.Name]
+	if !ok {
+		return toolError(fmt.Errorf("unknown tool %q", call.Name)), nil, nil
+	}
+	args := json.RawMessage(call.Arguments)
+	if strings.TrimSpace(call.Arguments) == "" {
+		args = json.RawMessage("{}")
+	}
+
+	if !tool.write() {
+		widget, err := tool.run(ctx, env, args)
+		if err != nil {
+			return toolError(err), nil, nil
+		}
+		return string(mustMarshalJSON(widget.Data)), widget, nil
+	}
+
+	summary, err := tool.describe(args)
+	if err != nil {
+		return toolError(err), nil, nil
+	}
+	action := &model.ChatToolAction{
+		ID:        uuid.New(),
+		UserID:    env.UserID,
+		SessionID: sessionID,
+		Tool:      call.Name,
+		Arguments: datatypes.JSON(args),
+		Summary:   summary,
+		Status:    model.ChatToolActionPending,
+		ExpiresAt: time.Now().Add(chatToolActionTTL),
+	}
+	if err := t.db.WithContext(ctx).Create(action).Error; err != nil {
+		return "", nil, fmt.Errorf("failed to save tool action: %w", err)
+	}
+
+	widget := &ai.WidgetData{Type: "tool_confirmation", Data: map[string]interface{}{
+		"action_id": action.ID.String(),
+		"tool":      action.Tool,
+		"arguments": args,
+		"summary":   summary,
+	}}
+	result := map[string]interface{}{
+		"status":    "awaiting_confirmation",
+		"action_id": action.ID.String(),
+		"message":   "The user has been asked to confirm this action. It has not been performed yet.",
+	}
+	return string(mustMarshalJSON(result)), widget, nil
+}
+
+// Confirm runs a pending action on the user's behalf and returns the result widget.
+func (t *ChatToolbox) Confirm(ctx context.Context, userID, actionID uuid.UUID) (*model.ChatToolAction, *ai.WidgetData, error) {
+	action, err := t.claim(ctx, userID, actionID, model.ChatToolActionConfirmed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tool, ok := t.tools[action.Tool]
+	if !ok {
+		err = fmt.Errorf("tool %q is not available", action.Tool)
+	} else {
+		var widget *ai.WidgetData
+		widget, err = tool.run(ctx, chatToolEnv{UserID: userID}, json.RawMessage(action.Arguments))
+		if err == nil {
+			action.Result = mustMarshalJSON(widget.Data)
+			if err := t.db.WithContext(ctx).Model(action).Update("result", action.Result).Error; err != nil {
+				return nil, nil, err
+			}
+			return action, widget, nil
+		}
+	}
+
+	action.Status = model.ChatToolActionFailed
+	action.Error = err.Error()
+	if dbErr := t.db.WithContext(ctx).Model(action).Updates(map[string]interface{}{
+		"status": action.Status,
+		"error":  action.Error,
+	}).Error; dbErr != nil {
+		return nil, nil, dbErr
+	}
+	return action, nil, fmt.Errorf("failed to run %s: %w", action.Tool, err)
+}
+
+// Reject discards a pending action.
+func (t *ChatToolbox) Reject(ctx context.Context, userID, actionID uuid.UUID) (*model.ChatToolAction, error) {
+	return t.claim(ctx, userID, actionID, model.ChatToolActionRejected)
+}
+
+// claim moves a pending action to status. The conditional update makes sure an action runs at most
+// once, even if it is confirmed twice concurrently.
+func (t *ChatToolbox) claim(ctx context.Context, userID, actionID uuid.UUID, status model.ChatToolActionStatus) (*model.ChatToolAction, error) {
+	var action model.ChatToolAction
+	if err := t.db.WithContext(ctx).Where("id = ? AND user_id = ?", actionID, userID).First(&action).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatToolActionNotFound
+		}
+		return nil, err
+	}
+	if action.Status != model.ChatToolActionPending {
+		return nil, ErrChatToolActionResolved
+	}
+	if time.Now().After(action.ExpiresAt) {
+		t.db.WithContext(ctx).Model(&action).Where("status = ?", model.ChatToolActionPending).
+			Update("status", model.ChatToolActionExpired)
+		return nil, ErrChatToolActionResolved
+	}
+
+	result := t.db.WithContext(ctx).Model(&model.ChatToolAction{}).
+		Where("id = ? AND status = ?", actionID, model.ChatToolActionPending).
+		Update("status", status)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrChatToolActionResolved
+	}
+	action.Status = status
+	return &action, nil
+}
+
+func toolError(err error) string {
+	return string(mustMarshalJSON(map[string]string{"error": err.Error()}))
+}
+
+func decodeToolArgs(args json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(args, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// parseToolDate accepts a date (YYYY-MM-DD) or an RFC 3339 timestamp.
+func parseToolDate(field, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be YYYY-MM-DD or an RFC 3339 timestamp", field)
+	}
+	return &t, nil
+}
+
+func parseToolUUID(field, value string) (*uuid.UUID, error) {
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid ID", field)
+	}
+	return &id, nil
+}
+
+func (t *ChatToolbox) searchEmailsTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "search_emails",
+			Description: "Search the user's emails. Returns the best matching emails with a ref number to cite them by.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"query":  {Type: "string", Description: "What to search for"},
+				"sender": {Type: "string", Description: "Only emails from this sender"},
+				"after":  {Type: "string", Description: "Only emails on or after this date, YYYY-MM-DD"},
+				"before": {Type: "string", Description: "Only emails on or before this date, YYYY-MM-DD"},
+				"limit":  {Type: "integer", Description: "Maximum number of emails, at most 10"},
+			}, Required: []string{"query"}},
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args struct {
+				Query  string `json:"query"`
+				Sender string `json:"sender"`
+				After  string `json:"after"`
+				Before string `json:"before"`
+				Limit  int    `json:"limit"`
+			}
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			if strings.TrimSpace(args.Query) == "" {
+				return nil, errors.New("query is required")
+			}
+			filters := SearchFilters{Sender: args.Sender}
+			var err error
+			if filters.StartDate, err = parseToolDate("after", args.After); err != nil {
+				return nil, err
+			}
+			if filters.EndDate, err = parseToolDate("before", args.Before); err != nil {
+				return nil, err
+			}
+			if filters.EndDate != nil {
+				end := filters.EndDate.AddDate(0, 0, 1) // Inclusive of the whole day
+				filters.EndDate = &end
+			}
+			if args.Limit <= 0 || args.Limit > 10 {
+				args.Limit = chatToolSearchLimit
+			}
+
+			results, err := t.deps.Search.Search(ctx, env.UserID, args.Query, filters, args.Limit*3)
+			if err != nil {
+				return nil, fmt.Errorf("search failed: %w", err)
+			}
+			results = truncateResults(CollapseByEmail(results), args.Limit)
+
+			emails := make([]map[string]interface{}, 0, len(results))
+			for _, r := range results {
+				email := map[string]interface{}{
+					"id":      r.EmailID.String(),
+					"subject": r.Subject,
+					"sender":  r.Sender,
+					"date":    r.Date.Format("2006-01-02"),
+					"snippet": r.Snippet,
+				}
+				if env.Passages != nil {
+					*env.Passages = append(*env.Passages, chatPassage{EmailID: r.EmailID, Subject: r.Subject, Sender: r.Sender, Date: r.Date, Content: r.Snippet})
+					email["ref"] = len(*env.Passages)
+				}
+				emails = append(emails, email)
+			}
+			return &ai.WidgetData{Type: "email_list", Data: map[string]interface{}{"query": args.Query, "emails": emails}}, nil
+		},
+	}
+}
+
+func (t *ChatToolbox) getEmailTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "get_email",
+			Description: "Read one of the user's emails in full.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"email_id": {Type: "string", Description: "ID of the email"},
+			}, Required: []string{"email_id"}},
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			email, err := t.toolEmail(ctx, env.UserID, raw)
+			if err != nil {
+				return nil, err
+			}
+			body := []rune(email.BodyText)
+			if len(body) > chatToolEmailBodyRunes {
+				body = append(body[:chatToolEmailBodyRunes-1], '…')
+			}
+			return &ai.WidgetData{Type: "email_card", Data: map[string]interface{}{
+				"id":      email.ID.String(),
+				"subject": email.Subject,
+				"sender":  email.Sender,
+				"date":    email.Date.Format("2006-01-02"),
+				"body":    string(body),
+			}}, nil
+		},
+	}
+}
+
+// toolEmail loads the email named by the email_id argument.
+func (t *ChatToolbox) toolEmail(ctx context.Context, userID uuid.UUID, raw json.RawMessage) (*model.Email, error) {
+	var args struct {
+		EmailID string `json:"email_id"`
+	}
+	if err := decodeToolArgs(raw, &args); err != nil {
+		return nil, err
+	}
+	id, err := parseToolUUID("email_id", args.EmailID)
+	if err != nil {
+		return nil, err
+	}
+	if id == nil {
+		return nil, errors.New("email_id is required")
+	}
+	email, err := t.deps.Emails.GetEmail(ctx, userID, *id)
+	if err != nil {
+		return nil, err
+	}
+	if email == nil {
+		return nil, errors.New("email not found")
+	}
+	return email, nil
+}
+
+// checkSourceEmail refuses a source email the user does not own. A nil ID is fine.
+func (t *ChatToolbox) checkSourceEmail(ctx context.Context, userID uuid.UUID, emailID *uuid.UUID) error {
+	if emailID == nil {
+		return nil
+	}
+	var count int64
+	if err := t.db.WithContext(ctx).Model(&model.Email{}).Where("id = ? AND user_id = ?", *emailID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("source email not found")
+	}
+	return nil
+}
+
+// opportunityTeam returns the team the user's opportunities are recorded under, the first one they
+// joined in an organization they belong to, and its organization.
+func (t *ChatToolbox) opportunityTeam(ctx context.Context, userID uuid.UUID) (teamID, orgID uuid.UUID, err error) {
+	var team model.Team
+	err = t.db.WithContext(ctx).
+		Joins("JOIN team_members ON team_members.team_id = teams.id").
+		Joins("JOIN organization_members ON organization_members.organization_id = teams.organization_id AND organization_members.user_id = team_members.user_id").
+		Where("team_members.user_id = ?", userID).
+		Order("team_members.joined_at").
+		Take(&team).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, uuid.Nil, errors.New("the user is not in a team, so opportunities cannot be recorded")
+	}
+	return team.ID, team.OrganizationID, err
+}
+
+type createTaskArgs struct {
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	DueDate       string `json:"due_date"`
+	SourceEmailID string `json:"source_email_id"`
+}
+
+func (a createTaskArgs) parse() (dueDate *time.Time, sourceEmailID *uuid.UUID, err error) {
+	if strings.TrimSpace(a.Title) == "" {
+		return nil, nil, errors.New("title is required")
+	}
+	if dueDate, err = parseToolDate("due_date", a.DueDate); err != nil {
+		return nil, nil, err
+	}
+	if sourceEmailID, err = parseToolUUID("source_email_id", a.SourceEmailID); err != nil {
+		return nil, nil, err
+	}
+	return dueDate, sourceEmailID, nil
+}
+
+func (t *ChatToolbox) createTaskTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "create_task",
+			Description: "Create a task for the user. The user confirms it before it is created.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"title":           {Type: "string", Description: "Short task title"},
+				"description":     {Type: "string", Description: "Details of the task"},
+				"due_date":        {Type: "string", Description: "Due date, YYYY-MM-DD"},
+				"source_email_id": {Type: "string", Description: "ID of the email the task comes from"},
+			}, Required: []string{"title"}},
+		},
+		describe: func(raw json.RawMessage) (string, error) {
+			var args createTaskArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return "", err
+			}
+			dueDate, _, err := args.parse()
+			if err != nil {
+				return "", err
+			}
+			if dueDate != nil {
+				return fmt.Sprintf("Create task %q due %s", args.Title, dueDate.Format("2006-01-02")), nil
+			}
+			return fmt.Sprintf("Create task %q", args.Title), nil
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args createTaskArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			dueDate, sourceEmailID, err := args.parse()
+			if err != nil {
+				return nil, err
+			}
+			if err := t.checkSourceEmail(ctx, env.UserID, sourceEmailID); err != nil {
+				return nil, err
+			}
+			task, err := t.deps.Tasks.CreateTask(ctx, env.UserID, args.Title, args.Description, sourceEmailID, dueDate)
+			if err != nil {
+				return nil, err
+			}
+			return &ai.WidgetData{Type: "task_card", Data: taskWidgetData(task)}, nil
+		},
+	}
+}
+
+func (t *ChatToolbox) listTasksTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "list_tasks",
+			Description: "List the user's tasks, soonest due first.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"status":   {Type: "string", Enum: []string{string(model.TaskStatusTodo), string(model.TaskStatusInProgress), string(model.TaskStatusDone)}},
+				"priority": {Type: "string", Enum: []string{string(model.TaskPriorityHigh), string(model.TaskPriorityMedium), string(model.TaskPriorityLow)}},
+				"limit":    {Type: "integer", Description: "Maximum number of tasks"},
+			}},
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args struct {
+				Status   string `json:"status"`
+				Priority string `json:"priority"`
+				Limit    int    `json:"limit"`
+			}
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			if args.Limit <= 0 || args.Limit > 50 {
+				args.Limit = 20
+			}
+			tasks, err := t.deps.Tasks.ListTasks(ctx, env.UserID, args.Status, args.Priority, args.Limit, 0)
+			if err != nil {
+				return nil, err
+			}
+			items := make([]map[string]interface{}, 0, len(tasks))
+			for i := range tasks {
+				items = append(items, taskWidgetData(&tasks[i]))
+			}
+			return &ai.WidgetData{Type: "task_list", Data: map[string]interface{}{"tasks": items}}, nil
+		},
+	}
+}
+
+func taskWidgetData(task *model.Task) map[string]interface{} {
+	data := map[string]interface{}{
+		"id":       task.ID.String(),
+		"title":    task.Title,
+		"status":   task.Status,
+		"priority": task.Priority,
+	}
+	if task.Description != "" {
+		data["description"] = task.Description
+	}
+	if task.DueDate != nil {
+		data["due"] = task.DueDate.Format("2006-01-02")
+	}
+	return data
+}
+
+type snoozeEmailArgs struct {
+	EmailID string `json:"email_id"`
+	Until   string `json:"until"`
+}
+
+func (a snoozeEmailArgs) parse() (uuid.UUID, time.Time, error) {
+	id, err := parseToolUUID("email_id", a.EmailID)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if id == nil {
+		return uuid.Nil, time.Time{}, errors.New("email_id is required")
+	}
+	until, err := parseToolDate("until", a.Until)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if until == nil {
+		return uuid.Nil, time.Time{}, errors.New("until is required")
+	}
+	return *id, *until, nil
+}
+
+func (t *ChatToolbox) snoozeEmailTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "snooze_email",
+			Description: "Hide an email from the inbox until a later time. The user confirms it first.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"email_id": {Type: "string", Description: "ID of the email"},
+				"until":    {Type: "string", Description: "When the email returns, YYYY-MM-DD or RFC 3339"},
+			}, Required: []string{"email_id", "until"}},
+		},
+		describe: func(raw json.RawMessage) (string, error) {
+			var args snoozeEmailArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return "", err
+			}
+			_, until, err := args.parse()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Snooze the email until %s", until.Format("2006-01-02 15:04")), nil
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args snoozeEmailArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			emailID, until, err := args.parse()
+			if err != nil {
+				return nil, err
+			}
+			if err := t.deps.Actions.SnoozeEmail(ctx, env.UserID, emailID, until); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, errors.New("email not found")
+				}
+				return nil, err
+			}
+			return &ai.WidgetData{Type: "snooze", Data: map[string]interface{}{
+				"email_id": emailID.String(),
+				"until":    until.Format(time.RFC3339),
+			}}, nil
+		},
+	}
+}
+
+type createOpportunityArgs struct {
+	Title         string `json:"title"`
+	Company       string `json:"company"`
+	Description   string `json:"description"`
+	Value         string `json:"value"`
+	Type          string `json:"type"`
+	Confidence    int    `json:"confidence"`
+	SourceEmailID string `json:"source_email_id"`
+}
+
+func (a *createOpportunityArgs) validate() error {
+	if strings.TrimSpace(a.Title) == "" || strings.TrimSpace(a.Company) == "" {
+		return errors.New("title and company are required")
+	}
+	switch model.OpportunityType(a.Type) {
+	case "":
+		a.Type = string(model.OpportunityTypeBuying)
+	case model.OpportunityTypeBuying, model.OpportunityTypePartnership, model.OpportunityTypeRenewal, model.OpportunityTypeStrategic:
+	default:
+		return fmt.Errorf("unknown opportunity type %q", a.Type)
+	}
+	if a.Confidence < 0 || a.Confidence > 100 {
+		return errors.New("confidence must be between 0 and 100")
+	}
+	_, err := parseToolUUID("source_email_id", a.SourceEmailID)
+	return err
+}
+
+func (t *ChatToolbox) createOpportunityTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "create_opportunity",
+			Description: "Record a business opportunity found in the user's email. The user confirms it first.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"title":       {Type: "string"},
+				"company":     {Type: "string"},
+				"description": {Type: "string"},
+				"value":       {Type: "string", Description: "Estimated value, e.g. \"$50k\""},
+				"type": {Type: "string", Enum: []string{
+					string(model.OpportunityTypeBuying), string(model.OpportunityTypePartnership),
+					string(model.OpportunityTypeRenewal), string(model.OpportunityTypeStrategic),
+				}},
+				"confidence":      {Type: "integer", Description: "0-100"},
+				"source_email_id": {Type: "string", Description: "ID of the email the opportunity comes from"},
+			}, Required: []string{"title", "company"}},
+		},
+		describe: func(raw json.RawMessage) (string, error) {
+			var args createOpportunityArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return "", err
+			}
+			if err := args.validate(); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("Record %s opportunity %q with %s", args.Type, args.Title, args.Company), nil
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args createOpportunityArgs
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			if err := args.validate(); err != nil {
+				return nil, err
+			}
+			emailID, _ := parseToolUUID("source_email_id", args.SourceEmailID)
+			if err := t.checkSourceEmail(ctx, env.UserID, emailID); err != nil {
+				return nil, err
+			}
+			teamID, orgID, err := t.opportunityTeam(ctx, env.UserID)
+			if err != nil {
+				return nil, err
+			}
+			var sourceEmailID *string
+			if args.SourceEmailID != "" {
+				sourceEmailID = &args.SourceEmailID
+			}
+			opp, err := t.deps.Opportunities.CreateOpportunity(ctx, env.UserID.String(), teamID.String(), orgID.String(),
+				args.Title, args.Description, args.Company, args.Value, model.OpportunityType(args.Type), args.Confidence, sourceEmailID)
+			if err != nil {
+				return nil, err
+			}
+			return &ai.WidgetData{Type: "opportunity_card", Data: map[string]interface{}{
+				"id":         opp.ID,
+				"title":      opp.Title,
+				"company":    opp.Company,
+				"value":      opp.Value,
+				"type":       opp.Type,
+				"confidence": opp.Confidence,
+			}}, nil
+		},
+	}
+}
+
+func (t *ChatToolbox) draftReplyTool() chatTool {
+	return chatTool{
+		def: ai.ToolDefinition{
+			Name:        "draft_reply",
+			Description: "Draft a reply to an email. The draft is shown to the user and not sent.",
+			Parameters: &ai.Schema{Type: "object", Properties: map[string]*ai.Schema{
+				"email_id":     {Type: "string", Description: "ID of the email to reply to"},
+				"instructions": {Type: "string", Description: "What the reply should say, and its tone"},
+			}, Required: []string{"email_id"}},
+		},
+		run: func(ctx context.Context, env chatToolEnv, raw json.RawMessage) (*ai.WidgetData, error) {
+			var args struct {
+				Instructions string `json:"instructions"`
+			}
+			if err := decodeToolArgs(raw, &args); err != nil {
+				return nil, err
+			}
+			email, err := t.toolEmail(ctx, env.UserID, raw)
+			if err != nil {
+				return nil, err
+			}
+			body, err := t.deps.Drafts.GenerateDraftReply(ctx, email.BodyText, args.Instructions)
+			if err != nil {
+				return nil, fmt.Errorf("failed to draft reply: %w", err)
+			}
+			subject := email.Subject
+			if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+				subject = "Re: " + subject
+			}
+			return &ai.WidgetData{Type: "email_draft", Data: map[string]interface{}{
+				"to":          email.Sender,
+				"subject":     subject,
+				"body":        body,
+				"in_reply_to": email.ID.String(),
+			}}, nil
+		},
+	}
+}
@@ -19,6 +19,11 @@ func NewContextService(db *gorm.DB) *ContextService {
 	return &ContextService{db: db}
 }
 
+// WithTx returns a ContextService that runs its queries in the transaction tx.
+func (s *ContextService) WithTx(tx *gorm.DB) *ContextService {
+	return &ContextService{db: tx}
+}
+
 // CreateContext creates a new context for a user.
 func (s *ContextService) CreateContext(userID uuid.UUID, input model.ContextInput) (*model.Context, error) {
 	keywordsJSON, err := json.Marshal(input.Keywords)
@@ -114,41 +119,67 @@ func (s *ContextService) MatchContexts(email *model.Email) ([]model.Context, err
 
 	var matches []model.Context
 	for _, ctx := range contexts {
-		matched := false
-
-		// Check Keywords
-		var keywords []string
-		_ = json.Unmarshal(ctx.Keywords, &keywords)
-		for _, kw := range keywords {
-			if strings.Contains(strings.ToLower(email.Subject), strings.ToLower(kw)) ||
-				strings.Contains(strings.ToLower(email.Snippet), strings.ToLower(kw)) {
-				matched = true
-				break
-			}
+		if emailMatchesContext(email, ctx) {
+			matches = append(matches, ctx)
 		}
+	}
 
-		if matched {
-			matches = append(matches, ctx)
+	return matches, nil
+}
+
+// emailMatchesContext reports whether an email matches a context's keyword or stakeholder rules.
+func emailMatchesContext(email *model.Email, ctx model.Context) bool {
+	// Check Keywords
+	var keywords []string
+	_ = json.Unmarshal(ctx.Keywords, &keywords)
+	for _, kw := range keywords {
+		if kw == "" {
 			continue
 		}
-
-		// Check Stakeholders
-		var stakeholders []string
-		_ = json.Unmarshal(ctx.Stakeholders, &stakeholders)
-		for _, sh := range stakeholders {
-			if strings.EqualFold(email.Sender, sh) {
-				matched = true
-				break
-			}
-			// Future: Check To/CC if available in email model as structured data
+		if strings.Contains(strings.ToLower(email.Subject), strings.ToLower(kw)) ||
+			strings.Contains(strings.ToLower(email.Snippet), strings.ToLower(kw)) {
+			return true
 		}
+	}
 
-		if matched {
-			matches = append(matches, ctx)
+	// Check Stakeholders
+	var stakeholders []string
+	_ = json.Unmarshal(ctx.Stakeholders, &stakeholders)
+	sender := senderAddress(email.Sender)
+	for _, sh := range stakeholders {
+		if sh != "" && senderAddress(sh) == sender {
+			return true
 		}
+		// Future: Check To/CC if available in email model as structured data
 	}
 
-	return matches, nil
+	return false
+}
+
+// BackfillContext scans all emails of the context owner and links the ones matching its rules.
+// It mirrors cmd/backfill_contexts but is scoped to a single context. Returns the number of emails matched.
+func (s *ContextService) BackfillContext(c *model.Context) (int, error) {
+	var emails []model.Email
+	matched := 0
+
+	result := s.db.Select("id, user_id, subject, snippet, sender").
+		Where("user_id = ?", c.UserID).
+		FindInBatches(&emails, 500, func(tx *gorm.DB, batch int) error {
+			for i := range emails {
+				if !emailMatchesContext(&emails[i], *c) {
+					continue
+				}
+				if err := s.AssignContextsToEmail(emails[i].ID, []uuid.UUID{c.ID}); err != nil {
+					return err
+				}
+				matched++
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return matched, result.Error
+	}
+	return matched, nil
 }
 
 // AssignContextsToEmail links contexts to an email.
@@ -0,0 +1,691 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSuggestionNotFound        = errors.New("context suggestion not found")
+	ErrSuggestionAlreadyResolved = errors.New("context suggestion already resolved")
+)
+
+// ContextSuggestionOptions tunes how unassigned emails are clustered into suggestions.
+type ContextSuggestionOptions struct {
+	SimilarityThreshold float64 // Minimum cosine similarity for an email to join a cluster
+	MinClusterSize      int     // Clusters smaller than this are discarded as noise
+	MaxSuggestions      int     // Upper bound on suggestions produced per run
+	EvidenceCount       int     // Number of closest emails kept as evidence
+	MaxEmails           int     // Most recent unassigned emails considered per run
+	RefineIterations    int     // k-means style reassignment passes after the greedy pass
+}
+
+// DefaultContextSuggestionOptions returns conservative defaults that favour precise topics.
+func DefaultContextSuggestionOptions() ContextSuggestionOptions {
+	return ContextSuggestionOptions{
+		SimilarityThreshold: 0.78,
+		MinClusterSize:      3,
+		MaxSuggestions:      5,
+		EvidenceCount:       5,
+		MaxEmails:           2000,
+		RefineIterations:    3,
+	}
+}
+
+// ContextSuggestionService proposes new contexts from clusters of emails that have no context.
+type ContextSuggestionService struct {
+	db             *gorm.DB
+	contextService *ContextService
+	opts           ContextSuggestionOptions
+}
+
+func NewContextSuggestionService(db *gorm.DB, contextService *ContextService, opts ContextSuggestionOptions) *ContextSuggestionService {
+	defaults := DefaultContextSuggestionOptions()
+	if opts.SimilarityThreshold <= 0 {
+		opts.SimilarityThreshold = defaults.SimilarityThreshold
+	}
+	if opts.MinClusterSize <= 0 {
+		opts.MinClusterSize = defaults.MinClusterSize
+	}
+	if opts.MaxSuggestions <= 0 {
+		opts.MaxSuggestions = defaults.MaxSuggestions
+	}
+	if opts.EvidenceCount <= 0 {
+		opts.EvidenceCount = defaults.EvidenceCount
+	}
+	if opts.MaxEmails <= 0 {
+		opts.MaxEmails = defaults.MaxEmails
+	}
+	if opts.RefineIterations < 0 {
+		opts.RefineIterations = defaults.RefineIterations
+	}
+	return &ContextSuggestionService{db: db, contextService: contextService, opts: opts}
+}
+
+// emailVector is the mean embedding of all chunks of one email.
+type emailVector struct {
+	EmailID uuid.UUID
+	Vector  []float32
+}
+
+// vectorCluster is a group of emails sharing a centroid.
+type vectorCluster struct {
+	Centroid []float32
+	Members  []int // Indexes into the clustered slice
+}
+
+// GenerateSuggestions clusters the user's unassigned emails and replaces any pending suggestions.
+func (s *ContextSuggestionService) GenerateSuggestions(ctx context.Context, userID uuid.UUID) ([]model.ContextSuggestion, error) {
+	vectors, err := s.loadUnassignedVectors(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := clusterEmailVectors(vectors, s.opts.SimilarityThreshold, s.opts.RefineIterations)
+
+	rejected, err := s.rejectedEvidence(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []model.ContextSuggestion
+	for _, cluster := range clusters {
+		if len(suggestions) >= s.opts.MaxSuggestions {
+			break
+		}
+		if len(cluster.Members) < s.opts.MinClusterSize {
+			continue
+		}
+
+		suggestion, err := s.buildSuggestion(ctx, userID, vectors, cluster)
+		if err != nil {
+			return nil, err
+		}
+		if overlapsRejected(suggestion, rejected) {
+			continue
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND status = ?", userID, model.ContextSuggestionPending).
+			Delete(&model.ContextSuggestion{}).Error; err != nil {
+			return err
+		}
+		if len(suggestions) == 0 {
+			return nil
+		}
+		return tx.Create(&suggestions).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save context suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// ListSuggestions returns the user's suggestions, optionally filtered by status.
+func (s *ContextSuggestionService) ListSuggestions(ctx context.Context, userID uuid.UUID, status string) ([]model.ContextSuggestion, error) {
+	var suggestions []model.ContextSuggestion
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Order("cluster_size desc, created_at desc").Find(&suggestions).Error; err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
+
+// AcceptSuggestion turns a suggestion into a real context, links its evidence emails and
+// backfills every other matching email, all in one transaction. The optional input overrides the
+// suggested fields. It returns the context and the number of emails linked to it.
+func (s *ContextSuggestionService) AcceptSuggestion(ctx context.Context, userID, suggestionID uuid.UUID, input *model.ContextInput) (*model.Context, int, error) {
+	suggestion, err := s.getPending(ctx, userID, suggestionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if input == nil {
+		input = &model.ContextInput{
+			Name:         suggestion.Name,
+			Keywords:     decodeStrings(suggestion.Keywords),
+			Stakeholders: decodeStrings(suggestion.Stakeholders),
+		}
+	}
+
+	var created *model.Context
+	var assigned int
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		contexts := s.contextService.WithTx(tx)
+		var err error
+		if created, err = contexts.CreateContext(userID, *input); err != nil {
+			return fmt.Errorf("failed to create context: %w", err)
+		}
+		if assigned, err = linkAndBackfill(tx, contexts, suggestion, created); err != nil {
+			return err
+		}
+		return resolve(tx, suggestion, model.ContextSuggestionAccepted, created.ID)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return created, assigned, nil
+}
+
+// MergeSuggestion folds a suggestion's keywords and stakeholders into an existing context, in one
+// transaction. It returns the context and the number of emails newly linked to it.
+func (s *ContextSuggestionService) MergeSuggestion(ctx context.Context, userID, suggestionID, contextID uuid.UUID) (*model.Context, int, error) {
+	suggestion, err := s.getPending(ctx, userID, suggestionID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	target, err := s.contextService.GetContext(contextID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var merged *model.Context
+	var assigned int
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		contexts := s.contextService.WithTx(tx)
+		var err error
+		merged, err = contexts.UpdateContext(target.ID, userID, model.ContextInput{
+			Name:         target.Name,
+			Color:        target.Color,
+			Keywords:     mergeStrings(decodeStrings(target.Keywords), decodeStrings(suggestion.Keywords)),
+			Stakeholders: mergeStrings(decodeStrings(target.Stakeholders), decodeStrings(suggestion.Stakeholders)),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to merge into context: %w", err)
+		}
+		if assigned, err = linkAndBackfill(tx, contexts, suggestion, merged); err != nil {
+			return err
+		}
+		return resolve(tx, suggestion, model.ContextSuggestionMerged, merged.ID)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return merged, assigned, nil
+}
+
+// RejectSuggestion marks a suggestion as rejected so similar clusters are not proposed again.
+func (s *ContextSuggestionService) RejectSuggestion(ctx context.Context, userID, suggestionID uuid.UUID) error {
+	suggestion, err := s.getPending(ctx, userID, suggestionID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Model(suggestion).Update("status", model.ContextSuggestionRejected).Error
+}
+
+func (s *ContextSuggestionService) getPending(ctx context.Context, userID, suggestionID uuid.UUID) (*model.ContextSuggestion, error) {
+	var suggestion model.ContextSuggestion
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", suggestionID, userID).First(&suggestion).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSuggestionNotFound
+		}
+		return nil, err
+	}
+	if suggestion.Status != model.ContextSuggestionPending {
+		return nil, ErrSuggestionAlreadyResolved
+	}
+	return &suggestion, nil
+}
+
+// resolve marks a pending suggestion as resolved into the context. It fails with
+// ErrSuggestionAlreadyResolved when another request resolved it first.
+func resolve(tx *gorm.DB, suggestion *model.ContextSuggestion, status model.ContextSuggestionStatus, contextID uuid.UUID) error {
+	result := tx.Model(suggestion).Where("status = ?", model.ContextSuggestionPending).Updates(map[string]interface{}{
+		"status":     status,
+		"context_id": contextID,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSuggestionAlreadyResolved
+	}
+	return nil
+}
+
+// linkAndBackfill assigns the evidence emails to the context, then backfills by its rules. It
+// returns the number of emails newly linked to the context.
+func linkAndBackfill(tx *gorm.DB, contexts *ContextService, suggestion *model.ContextSuggestion, c *model.Context) (int, error) {
+	before, err := countContextEmails(tx, c.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	var evidence []uuid.UUID
+	_ = json.Unmarshal(suggestion.EvidenceEmails, &evidence)
+	for _, emailID := range evidence {
+		if err := contexts.AssignContextsToEmail(emailID, []uuid.UUID{c.ID}); err != nil {
+			return 0, fmt.Errorf("failed to assign evidence email %s: %w", emailID, err)
+		}
+	}
+	if _, err := contexts.BackfillContext(c); err != nil {
+		return 0, fmt.Errorf("failed to backfill context: %w", err)
+	}
+
+	after, err := countContextEmails(tx, c.ID)
+	if err != nil {
+		return 0, err
+	}
+	return int(after - before), nil
+}
+
+func countContextEmails(tx *gorm.DB, contextID uuid.UUID) (int64, error) {
+	var count int64
+	err := tx.Model(&model.EmailContext{}).Where("context_id = ?", contextID).Count(&count).Error
+	return count, err
+}
+
+// loadUnassignedVectors loads per-email mean embeddings of non-spam emails without any context.
+func (s *ContextSuggestionService) loadUnassignedVectors(ctx context.Context, userID uuid.UUID) ([]emailVector, error) {
+	var rows []struct {
+		EmailID uuid.UUID
+		Vector  pgvector.Vector
+	}
+
+	// MaxEmails bounds the candidates themselves, so older unassigned emails are not crowded out by
+	// newer ones that are spam, already assigned or not embedded.
+	table := activeEmbeddingTable(ctx, s.db)
+	sql := `
+		SELECT ee.email_id, ee.vector
+		FROM ` + table + ` ee
+		WHERE ee.email_id IN (
+			SELECT e.id FROM emails e
+			WHERE e.user_id = ?
+				AND e.deleted_at IS NULL
+				AND (e.category IS NULL OR e.category <> 'Spam')
+				AND NOT EXISTS (SELECT 1 FROM email_contexts ec WHERE ec.email_id = e.id)
+				AND EXISTS (SELECT 1 FROM ` + table + ` ev WHERE ev.email_id = e.id)
+			ORDER BY e.date DESC
+			LIMIT ?
+		)
+	`
+	if err := s.db.WithContext(ctx).Raw(sql, userID, s.opts.MaxEmails).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load unassigned embeddings: %w", err)
+	}
+
+	// Average chunk vectors per email, preserving first-seen order for determinism.
+	index := make(map[uuid.UUID]int)
+	counts := make([]int, 0)
+	var vectors []emailVector
+	for _, row := range rows {
+		vec := row.Vector.Slice()
+		if len(vec) == 0 {
+			continue
+		}
+		i, ok := index[row.EmailID]
+		if !ok {
+			i = len(vectors)
+			index[row.EmailID] = i
+			vectors = append(vectors, emailVector{EmailID: row.EmailID, Vector: make([]float32, len(vec))})
+			counts = append(counts, 0)
+		}
+		if len(vectors[i].Vector) != len(vec) {
+			continue // Mixed dimensions cannot be averaged
+		}
+		for d, v := range vec {
+			vectors[i].Vector[d] += v
+		}
+		counts[i]++
+	}
+	for i := range vectors {
+		for d := range vectors[i].Vector {
+			vectors[i].Vector[d] /= float32(counts[i])
+		}
+		normalizeVector(vectors[i].Vector)
+	}
+
+	return vectors, nil
+}
+
+// rejectedEvidence returns the evidence sets of previously rejected suggestions.
+func (s *ContextSuggestionService) rejectedEvidence(ctx context.Context, userID uuid.UUID) ([]map[uuid.UUID]bool, error) {
+	var rejected []model.ContextSuggestion
+	if err := s.db.WithContext(ctx).Select("evidence_emails").
+		Where("user_id = ? AND status = ?", userID, model.ContextSuggestionRejected).
+		Find(&rejected).Error; err != nil {
+		return nil, err
+	}
+
+	sets := make([]map[uuid.UUID]bool, 0, len(rejected))
+	for _, r := range rejected {
+		var ids []uuid.UUID
+		_ = json.Unmarshal(r.EvidenceEmails, &ids)
+		set := make(map[uuid.UUID]bool, len(ids))
+		for _, id := range ids {
+			set[id] = true
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// overlapsRejected reports whether most of a suggestion's evidence was already rejected once.
+func overlapsRejected(suggestion model.ContextSuggestion, rejected []map[uuid.UUID]bool) bool {
+	var evidence []uuid.UUID
+	_ = json.Unmarshal(suggestion.EvidenceEmails, &evidence)
+	if len(evidence) == 0 {
+		return false
+	}
+	for _, set := range rejected {
+		overlap := 0
+		for _, id := range evidence {
+			if set[id] {
+				overlap++
+			}
+		}
+		if overlap*2 > len(evidence) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildSuggestion names a cluster and picks its keywords, stakeholders and evidence emails.
+func (s *ContextSuggestionService) buildSuggestion(ctx context.Context, userID uuid.UUID, vectors []emailVector, cluster vectorCluster) (model.ContextSuggestion, error) {
+	// Rank members by similarity to the centroid.
+	type ranked struct {
+		EmailID uuid.UUID
+		Sim     float64
+	}
+	members := make([]ranked, 0, len(cluster.Members))
+	var simSum float64
+	for _, idx := range cluster.Members {
+		sim := cosineSimilarity(vectors[idx].Vector, cluster.Centroid)
+		simSum += sim
+		members = append(members, ranked{EmailID: vectors[idx].EmailID, Sim: sim})
+	}
+	sort.SliceStable(members, func(i, j int) bool { return members[i].Sim > members[j].Sim })
+
+	ids := make([]uuid.UUID, len(members))
+	for i, m := range members {
+		ids[i] = m.EmailID
+	}
+
+	var emails []model.Email
+	if err := s.db.WithContext(ctx).Select("id, subject, sender").
+		Where("user_id = ? AND id IN ?", userID, ids).Find(&emails).Error; err != nil {
+		return model.ContextSuggestion{}, fmt.Errorf("failed to load cluster emails: %w", err)
+	}
+
+	keywords := topSubjectKeywords(emails, 5)
+	stakeholders := topStakeholders(emails, 3)
+
+	evidenceCount := s.opts.EvidenceCount
+	if evidenceCount > len(ids) {
+		evidenceCount = len(ids)
+	}
+
+	return model.ContextSuggestion{
+		ID:             uuid.New(),
+		UserID:         userID,
+		Name:           suggestionName(keywords, stakeholders),
+		Keywords:       mustMarshalJSON(keywords),
+		Stakeholders:   mustMarshalJSON(stakeholders),
+		EvidenceEmails: mustMarshalJSON(ids[:evidenceCount]),
+		ClusterSize:    len(members),
+		Cohesion:       simSum / float64(len(members)),
+		Status:         model.ContextSuggestionPending,
+	}, nil
+}
+
+// clusterEmailVectors runs a greedy leader pass followed by k-means style refinement.
+// Emails below the similarity threshold of every centroid are left unclustered.
+// Clusters are returned largest first.
+func clusterEmailVectors(vectors []emailVector, threshold float64, iterations int) []vectorCluster {
+	var clusters []vectorCluster
+
+	// Greedy pass: join the closest cluster above threshold or start a new one.
+	for i, v := range vectors {
+		best, bestSim := -1, threshold
+		for c := range clusters {
+			if sim := cosineSimilarity(v.Vector, clusters[c].Centroid); sim >= bestSim {
+				best, bestSim = c, sim
+			}
+		}
+		if best == -1 {
+			centroid := make([]float32, len(v.Vector))
+			copy(centroid, v.Vector)
+			clusters = append(clusters, vectorCluster{Centroid: centroid, Members: []int{i}})
+			continue
+		}
+		clusters[best].Members = append(clusters[best].Members, i)
+		clusters[best].Centroid = meanVector(vectors, clusters[best].Members)
+	}
+
+	// Refinement: reassign each email to its nearest centroid and recompute centroids.
+	for iter := 0; iter < iterations && len(clusters) > 0; iter++ {
+		next := make([]vectorCluster, len(clusters))
+		for c := range clusters {
+			next[c].Centroid = clusters[c].Centroid
+		}
+		for i, v := range vectors {
+			best, bestSim := -1, threshold
+			for c := range clusters {
+				if sim := cosineSimilarity(v.Vector, clusters[c].Centroid); sim >= bestSim {
+					best, bestSim = c, sim
+				}
+			}
+			if best >= 0 {
+				next[best].Members = append(next[best].Members, i)
+			}
+		}
+
+		clusters = clusters[:0]
+		for _, c := range next {
+			if len(c.Members) == 0 {
+				continue
+			}
+			c.Centroid = meanVector(vectors, c.Members)
+			clusters = append(clusters, c)
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Members) > len(clusters[j].Members)
+	})
+	return clusters
+}
+
+func meanVector(vectors []emailVector, members []int) []float32 {
+	if len(members) == 0 {
+		return nil
+	}
+	mean := make([]float32, len(vectors[members[0]].Vector))
+	for _, idx := range members {
+		for d, v := range vectors[idx].Vector {
+			if d < len(mean) {
+				mean[d] += v
+			}
+		}
+	}
+	for d := range mean {
+		mean[d] /= float32(len(members))
+	}
+	normalizeVector(mean)
+	return mean
+}
+
+func normalizeVector(v []float32) {
+	var sumSq float64
+	for _, x := range v {
+		sumSq += float64(x) * float64(x)
+	}
+	if sumSq == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sumSq))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+var suggestionStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "about": true,
+	"your": true, "you": true, "our": true, "this": true, "that": true, "are": true,
+	"was": true, "has": true, "have": true, "will": true, "please": true, "update": true,
+	"re": true, "fwd": true, "fw": true, "回复": true, "转发": true,
+}
+
+// topSubjectKeywords returns the subject terms shared by the most emails in a cluster.
+func topSubjectKeywords(emails []model.Email, limit int) []string {
+	docFreq := make(map[string]int)
+	for _, e := range emails {
+		seen := make(map[string]bool)
+		for _, word := range strings.FieldsFunc(strings.ToLower(e.Subject), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '-' && r != '#'
+		}) {
+			if len([]rune(word)) < 3 || suggestionStopWords[word] || seen[word] {
+				continue
+			}
+			seen[word] = true
+			docFreq[word]++
+		}
+	}
+
+	type kv struct {
+		Key   string
+		Value int
+	}
+	var terms []kv
+	for k, v := range docFreq {
+		if v >= 2 {
+			terms = append(terms, kv{k, v})
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Value != terms[j].Value {
+			return terms[i].Value > terms[j].Value
+		}
+		return terms[i].Key < terms[j].Key
+	})
+
+	keywords := make([]string, 0, limit)
+	for i := 0; i < len(terms) && i < limit; i++ {
+		keywords = append(keywords, terms[i].Key)
+	}
+	return keywords
+}
+
+// topStakeholders returns the most frequent sender addresses of a cluster.
+func topStakeholders(emails []model.Email, limit int) []string {
+	counts := make(map[string]int)
+	for _, e := range emails {
+		if addr := senderAddress(e.Sender); addr != "" {
+			counts[addr]++
+		}
+	}
+
+	addrs := make([]string, 0, len(counts))
+	for addr, n := range counts {
+		if n >= 2 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool {
+		if counts[addrs[i]] != counts[addrs[j]] {
+			return counts[addrs[i]] > counts[addrs[j]]
+		}
+		return addrs[i] < addrs[j]
+	})
+	if len(addrs) > limit {
+		addrs = addrs[:limit]
+	}
+	return addrs
+}
+
+// senderAddress extracts the lower-cased address from a "Name <addr>" sender string.
+func senderAddress(sender string) string {
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.TrimSpace(sender))
+}
+
+func suggestionName(keywords, stakeholders []string) string {
+	var name string
+	switch {
+	case len(keywords) >= 2:
+		name = titleCase(keywords[0]) + " / " + titleCase(keywords[1])
+	case len(keywords) == 1:
+		name = titleCase(keywords[0])
+	case len(stakeholders) > 0:
+		name = "Threads with " + stakeholders[0]
+	default:
+		name = "Suggested Topic"
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+func titleCase(word string) string {
+	runes := []rune(word)
+	if len(runes) == 0 {
+		return word
+	}
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func decodeStrings(raw datatypes.JSON) []string {
+	var values []string
+	_ = json.Unmarshal(raw, &values)
+	return values
+}
+
+// mergeStrings appends new values to base, skipping case-insensitive duplicates.
+func mergeStrings(base, extra []string) []string {
+	seen := make(map[string]bool, len(base)+len(extra))
+	merged := make([]string, 0, len(base)+len(extra))
+	for _, v := range append(append([]string{}, base...), extra...) {
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, v)
+	}
+	return merged
+}
+
+func mustMarshalJSON(v interface{}) datatypes.JSON {
+	b, _ := json.Marshal(v)
+	return datatypes.JSON(b)
+}
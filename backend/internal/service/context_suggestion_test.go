@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/pgvector/pgvector-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSuggestionTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:suggestions?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Email{}, &model.EmailEmbedding{}, &model.Context{}, &model.EmailContext{}, &model.ContextSuggestion{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM email_embeddings")
+		db.Exec("DELETE FROM email_contexts")
+		db.Exec("DELETE FROM contexts")
+		db.Exec("DELETE FROM context_suggestions")
+		db.Exec("DELETE FROM emails")
+	})
+	return db
+}
+
+// seedClusterEmail stores an email with a single embedding pointing mostly along axis.
+func seedClusterEmail(t *testing.T, db *gorm.DB, userID uuid.UUID, subject, sender string, axis int, jitter float32) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Create(&model.Email{
+		ID:        id,
+		UserID:    userID,
+		MessageID: id.String(),
+		Subject:   subject,
+		Sender:    sender,
+		Date:      time.Now(),
+	}).Error)
+
+	vec := make([]float32, 8)
+	vec[axis] = 1
+	vec[(axis+1)%len(vec)] = jitter
+	require.NoError(t, db.Create(&model.EmailEmbedding{EmailID: id, Vector: pgvector.NewVector(vec)}).Error)
+	return id
+}
+
+func TestClusterEmailVectors(t *testing.T) {
+	vec := func(x, y float32) []float32 { return []float32{x, y, 0} }
+	vectors := []emailVector{
+		{EmailID: uuid.New(), Vector: vec(1, 0.05)},
+		{EmailID: uuid.New(), Vector: vec(0.05, 1)},
+		{EmailID: uuid.New(), Vector: vec(1, 0.1)},
+		{EmailID: uuid.New(), Vector: vec(1, 0)},
+		{EmailID: uuid.New(), Vector: vec(0, 1)},
+	}
+
+	clusters := clusterEmailVectors(vectors, 0.9, 2)
+
+	require.Len(t, clusters, 2)
+	assert.ElementsMatch(t, []int{0, 2, 3}, clusters[0].Members)
+	assert.ElementsMatch(t, []int{1, 4}, clusters[1].Members)
+}
+
+func TestContextSuggestionService_GenerateAndAccept(t *testing.T) {
+	db := setupSuggestionTestDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewContextSuggestionService(db, NewContextService(db), DefaultContextSuggestionOptions())
+
+	var budgetIDs []uuid.UUID
+	for i := 0; i < 4; i++ {
+		budgetIDs = append(budgetIDs, seedClusterEmail(t, db, userID,
+			fmt.Sprintf("Budget review Q%d", i+1), "Finance <cfo@example.com>", 0, float32(i)*0.02))
+	}
+	seedClusterEmail(t, db, userID, "Lunch on Friday", "friend@example.com", 4, 0)
+
+	// Emails that already have a context are ignored.
+	assigned := seedClusterEmail(t, db, userID, "Budget review archive", "cfo@example.com", 0, 0)
+	require.NoError(t, db.Create(&model.EmailContext{EmailID: assigned, ContextID: uuid.New()}).Error)
+
+	suggestions, err := svc.GenerateSuggestions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+
+	s := suggestions[0]
+	assert.Equal(t, 4, s.ClusterSize)
+	assert.Equal(t, "Budget / Review", s.Name)
+	assert.Equal(t, []string{"cfo@example.com"}, decodeStrings(s.Stakeholders))
+	var evidence []uuid.UUID
+	require.NoError(t, json.Unmarshal(s.EvidenceEmails, &evidence))
+	assert.ElementsMatch(t, budgetIDs, evidence)
+
+	created, count, err := svc.AcceptSuggestion(ctx, userID, s.ID, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Budget / Review", created.Name)
+	assert.Equal(t, 5, count, "evidence plus the backfilled archive email, each once")
+
+	var linked int64
+	db.Model(&model.EmailContext{}).Where("context_id = ?", created.ID).Count(&linked)
+	assert.Equal(t, int64(5), linked, "evidence plus the backfilled archive email")
+
+	_, _, err = svc.AcceptSuggestion(ctx, userID, s.ID, nil)
+	assert.ErrorIs(t, err, ErrSuggestionAlreadyResolved)
+}
+
+func TestContextSuggestionService_AcceptRollsBack(t *testing.T) {
+	db := setupSuggestionTestDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewContextSuggestionService(db, NewContextService(db), DefaultContextSuggestionOptions())
+	for i := 0; i < 3; i++ {
+		seedClusterEmail(t, db, userID, "Hiring plan", "hr@example.com", 1, float32(i)*0.01)
+	}
+	suggestions, err := svc.GenerateSuggestions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+
+	// Linking the emails fails after the context was created.
+	require.NoError(t, db.Callback().Create().Before("gorm:create").Register("fail_email_contexts", func(tx *gorm.DB) {
+		if tx.Statement.Table == "email_contexts" {
+			_ = tx.AddError(errors.New("disk full"))
+		}
+	}))
+	_, _, err = svc.AcceptSuggestion(ctx, userID, suggestions[0].ID, nil)
+	require.ErrorContains(t, err, "disk full")
+
+	var contexts int64
+	db.Model(&model.Context{}).Where("user_id = ?", userID).Count(&contexts)
+	assert.Zero(t, contexts, "the context is not left behind")
+	var suggestion model.ContextSuggestion
+	require.NoError(t, db.First(&suggestion, "id = ?", suggestions[0].ID).Error)
+	assert.Equal(t, model.ContextSuggestionPending, suggestion.Status)
+}
+
+func TestContextSuggestionService_RejectSuppressesRegeneration(t *testing.T) {
+	db := setupSuggestionTestDB(t)
+	ctx := context.Background()
+	userID := uuid.New()
+	svc := NewContextSuggestionService(db, NewContextService(db), DefaultContextSuggestionOptions())
+
+	for i := 0; i < 3; i++ {
+		seedClusterEmail(t, db, userID, "Weekly newsletter digest", "news@example.com", 2, float32(i)*0.01)
+	}
+
+	suggestions, err := svc.GenerateSuggestions(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+
+	require.NoError(t, svc.RejectSuggestion(ctx, userID, suggestions[0].ID))
+
+	regenerated, err := svc.GenerateSuggestions(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, regenerated)
+}
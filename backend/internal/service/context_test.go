@@ -57,6 +57,12 @@ func TestMatchContexts(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Len(t, matches2, 1)
 
+	// A sender with a display name matches by address
+	email2.Sender = "The Boss <Boss@Example.com>"
+	matches2, err = svc.MatchContexts(email2)
+	assert.NoError(t, err)
+	assert.Len(t, matches2, 1)
+
 	// 4. Test No Match
 	email3 := &model.Email{
 		ID:      uuid.New(),
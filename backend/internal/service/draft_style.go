@@ -0,0 +1,286 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// styleProfileTTL is how long a learned style profile is used before it is learned again.
+	styleProfileTTL = 24 * time.Hour
+	// maxStyleSamples bounds the sent emails a style profile is learned from, newest first.
+	maxStyleSamples = 200
+	// maxStyleExamples and maxStyleExampleRunes bound the excerpts of sent mail kept as examples.
+	maxStyleExamples     = 2
+	maxStyleExampleRunes = 400
+)
+
+// quoteStart matches the lines where a reply starts quoting the message it answers.
+var quoteStart = regexp.MustCompile(`(?i)^(>|on .+ wrote:?$|-+ ?original message ?-+|from: |发件人[:：]|在.+写道[:：]?$|-+ ?原始邮件 ?-+)`)
+
+// stripQuoted returns the text an email's author wrote, without the quoted message it answers.
+func stripQuoted(body string) string {
+	lines := strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if quoteStart.MatchString(strings.TrimSpace(line)) {
+			lines = lines[:i]
+			break
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// wordCount counts the words of text, counting each Chinese, Japanese or Korean character as a word.
+func wordCount(text string) int {
+	count, inWord := 0, false
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			count++
+			inWord = false
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if !inWord {
+				count++
+			}
+			inWord = true
+		case r == '\'' || r == '’' || r == '-':
+			// Part of words such as "don't" and "follow-up"
+		default:
+			inWord = false
+		}
+	}
+	return count
+}
+
+var greetingWords = []string{"good morning", "good afternoon", "good evening", "hello", "hi", "hey", "dear", "greetings", "您好", "你好", "各位好", "大家好", "尊敬的"}
+
+var closingWords = []string{"best regards", "kind regards", "warm regards", "regards", "best", "cheers", "thanks", "thank you", "many thanks", "sincerely", "yours", "talk soon", "此致", "祝好", "顺祝", "谢谢", "多谢", "感谢"}
+
+// greeting returns the greeting an email opens with, such as "Hi" or "Dear", or "".
+func greeting(text string) string {
+	first := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	lower := strings.ToLower(first)
+	for _, g := range greetingWords {
+		if strings.HasPrefix(lower, g) {
+			rest := lower[len(g):]
+			if rest == "" || !unicode.IsLetter([]rune(rest)[0]) || unicode.Is(unicode.Han, []rune(g)[0]) {
+				return first[:len(g)]
+			}
+		}
+	}
+	return ""
+}
+
+// closing returns the sign-off line near the end of an email, such as "Best," or "Thanks!", or "".
+func closing(text string) string {
+	lines := strings.Split(text, "\n")
+	var tail []string
+	for i := len(lines) - 1; i >= 0 && len(tail) < 4; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			tail = append(tail, line)
+		}
+	}
+	for i := len(tail) - 1; i >= 0; i-- {
+		line := tail[i]
+		if len([]rune(line)) > 30 {
+			continue
+		}
+		lower := strings.ToLower(line)
+		for _, c := range closingWords {
+			if strings.HasPrefix(lower, c) {
+				return line
+			}
+		}
+	}
+	return ""
+}
+
+var (
+	formalMarkers = []string{"dear ", "regards", "sincerely", "kindly", "please find", "would you", "could you please", "at your earliest", "尊敬", "您", "此致", "敬礼", "烦请", "贵司"}
+	casualMarkers = []string{"hey", "cheers", "thx", "lol", "gonna", "n't", "'m ", "'re ", "'ll ", "!", "哈", "啦", "呀", "嘿", "哦"}
+)
+
+// formalityScore is positive for formal text and negative for casual text.
+func formalityScore(text string) int {
+	lower := strings.ToLower(text)
+	score := 0
+	for _, m := range formalMarkers {
+		if strings.Contains(lower, m) {
+			score++
+		}
+	}
+	for _, m := range casualMarkers {
+		if strings.Contains(lower, m) {
+			score--
+		}
+	}
+	return score
+}
+
+// sentenceCount counts the sentences of text by their terminating punctuation, and at least one.
+func sentenceCount(text string) int {
+	count := 0
+	for _, r := range text {
+		switch r {
+		case '.', '!', '?', '。', '！', '？':
+			count++
+		}
+	}
+	return max(count, 1)
+}
+
+// mostCommon returns the value seen most often, the earliest on ties, or "".
+func mostCommon(values []string) string {
+	counts := map[string]int{}
+	best := ""
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
+		counts[v]++
+		if counts[v] > counts[best] {
+			best = v
+		}
+	}
+	return best
+}
+
+// LearnStyleProfile derives a writing-style profile from the texts of emails a user sent, newest
+// first. Quoted messages are ignored.
+func LearnStyleProfile(userID uuid.UUID, sent []string) model.StyleProfile {
+	profile := model.StyleProfile{UserID: userID, Formality: model.FormalityNeutral, Examples: []byte("[]")}
+	var greetings, closings, languages []string
+	var lengths []int
+	var words, sentences, formality int
+	var examples []string
+	for _, text := range sent {
+		text = stripQuoted(text)
+		n := wordCount(text)
+		if n == 0 {
+			continue
+		}
+		profile.Samples++
+		lengths = append(lengths, n)
+		words += n
+		sentences += sentenceCount(text)
+		formality += formalityScore(text)
+		greetings = append(greetings, greeting(text))
+		closings = append(closings, closing(text))
+		languages = append(languages, ai.DetectLanguage(text))
+		if len(examples) < maxStyleExamples && n >= 20 {
+			examples = append(examples, truncateRunes(text, maxStyleExampleRunes))
+		}
+	}
+	if profile.Samples == 0 {
+		return profile
+	}
+
+	sort.Ints(lengths)
+	profile.MedianWords = lengths[len(lengths)/2]
+	profile.SentenceWords = float64(words) / float64(sentences)
+	profile.Greeting = mostCommon(greetings)
+	profile.Closing = mostCommon(closings)
+	profile.Language = mostCommon(languages)
+	switch average := float64(formality) / float64(profile.Samples); {
+	case average >= 1:
+		profile.Formality = model.FormalityFormal
+	case average <= -1:
+		profile.Formality = model.FormalityCasual
+	}
+	if data, err := json.Marshal(examples); err == nil && examples != nil {
+		profile.Examples = data
+	}
+	return profile
+}
+
+// truncateRunes cuts text to at most n runes.
+func truncateRunes(text string, n int) string {
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n]) + "…"
+	}
+	return text
+}
+
+// StyleProfile returns the user's writing-style profile, learning it again from their sent mail
+// when it is missing or older than a day, or when refresh is set.
+func (s *AIDraftService) StyleProfile(ctx context.Context, userID uuid.UUID, refresh bool) (*model.StyleProfile, error) {
+	var profile model.StyleProfile
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).Take(&profile).Error
+	if err == nil && !refresh && s.now().Sub(profile.UpdatedAt) < styleProfileTTL {
+		return &profile, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	sent, err := s.sentMail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	profile = LearnStyleProfile(userID, sent)
+	profile.UpdatedAt = s.now().UTC()
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// sentMail returns the bodies of the user's sent mail, newest first: the emails synced from their
+// sent folder, and stored emails sent from one of their addresses, such as messages to themselves.
+func (s *AIDraftService) sentMail(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	addresses, err := s.userAddresses(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	conditions := []string{"folder = ?"}
+	args := []any{model.FolderSent}
+	for _, address := range addresses {
+		conditions = append(conditions, `LOWER(sender) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+utils.EscapeLike(address)+"%")
+	}
+	var bodies []string
+	err = s.db.WithContext(ctx).Model(&model.Email{}).
+		Where("user_id = ?", userID).
+		Where(strings.Join(conditions, " OR "), args...).
+		Order("date DESC").Limit(maxStyleSamples).
+		Pluck("body_text", &bodies).Error
+	return bodies, err
+}
+
+// userAddresses returns the lower-cased addresses the user sends from: their login and connected accounts.
+func (s *AIDraftService) userAddresses(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var addresses []string
+	var users []model.User
+	if err := s.db.WithContext(ctx).Select("email").Where("id = ?", userID).Limit(1).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var accounts []string
+	if err := s.db.WithContext(ctx).Model(&model.EmailAccount{}).Where("user_id = ?", userID).Pluck("email", &accounts).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		accounts = append(accounts, user.Email)
+	}
+	seen := map[string]bool{}
+	for _, address := range accounts {
+		address = strings.ToLower(strings.TrimSpace(address))
+		if strings.Contains(address, "@") && !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses, nil
+}
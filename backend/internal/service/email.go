@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -10,9 +11,15 @@ import (
 	"github.com/hrygo/echomind/internal/model"
 )
 
+// SavedSearchFolders resolves saved searches shown as virtual folders.
+type SavedSearchFolders interface {
+	FolderEmailIDs(ctx context.Context, userID, id uuid.UUID) ([]uuid.UUID, error)
+}
+
 // EmailService handles email-related business logic and data access.
 type EmailService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	savedSearches SavedSearchFolders
 }
 
 // NewEmailService creates a new EmailService.
@@ -22,6 +29,11 @@ func NewEmailService(db *gorm.DB) *EmailService {
 	}
 }
 
+// SetSavedSearches enables "saved:<id>" folders in ListEmails.
+func (s *EmailService) SetSavedSearches(savedSearches SavedSearchFolders) {
+	s.savedSearches = savedSearches
+}
+
 // ListEmails retrieves a list of emails for a given user.
 func (s *EmailService) ListEmails(ctx context.Context, userID uuid.UUID, limit, offset int, contextID, folder, category, filter string) ([]model.Email, error) {
 	var emails []model.Email
@@ -32,21 +44,40 @@ func (s *EmailService) ListEmails(ctx context.Context, userID uuid.UUID, limit,
 		query = query.Joins("JOIN email_contexts ON emails.id = email_contexts.email_id").Where("email_contexts.context_id = ?", contextID)
 	}
 
+	// Saved searches act as virtual folders over the normal inbox view
+	if strings.HasPrefix(folder, SavedSearchFolderPrefix) {
+		if s.savedSearches == nil {
+			return nil, ErrSavedSearchNotFound
+		}
+		savedSearchID, err := uuid.Parse(strings.TrimPrefix(folder, SavedSearchFolderPrefix))
+		if err != nil {
+			return nil, ErrSavedSearchNotFound
+		}
+		ids, err := s.savedSearches.FolderEmailIDs(ctx, userID, savedSearchID)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return []model.Email{}, nil
+		}
+		query = query.Where("emails.id IN ?", ids)
+		folder = ""
+	}
+
 	// Apply Folder Filter
 	switch folder {
 	case "snoozed":
 		query = query.Where("snoozed_until > NOW()")
 	case "trash":
 		query = query.Where("deleted_at IS NOT NULL").Unscoped()
+	case "sent":
+		query = query.Where("folder = ?", model.FolderSent)
 	case "drafts":
 		// TODO: Implement drafts logic
 		fallthrough
-	case "sent":
-		// TODO: Implement sent logic (needs user email)
-		fallthrough
 	default:
-		// Normal inbox view: Hide snoozed
-		query = query.Where("snoozed_until IS NULL OR snoozed_until <= NOW()")
+		// Normal inbox view: Hide snoozed and sent mail
+		query = query.Where("snoozed_until IS NULL OR snoozed_until <= NOW()").Where("folder <> ?", model.FolderSent)
 	}
 
 	// Apply Category Filter
@@ -109,11 +140,23 @@ func (s *EmailService) UpdateEmail(ctx context.Context, email *model.Email) erro
 	return s.db.WithContext(ctx).Save(email).Error
 }
 
-// DeleteAllUserEmails deletes all emails and their associated embeddings for a given user.
+// DeleteAllUserEmails deletes all emails and their associated embeddings and translations for a given user.
 func (s *EmailService) DeleteAllUserEmails(ctx context.Context, userID uuid.UUID) error {
 	// Delete associated embeddings first (due to foreign key constraints with CASCADE might handle this, but explicit is safer)
-	if err := s.db.WithContext(ctx).Exec("DELETE FROM email_embeddings WHERE email_id IN (SELECT id FROM emails WHERE user_id = ?)", userID).Error; err != nil {
-		return fmt.Errorf("failed to delete embeddings for user %s: %w", userID, err)
+	for _, table := range embeddingTables(ctx, s.db) {
+		if err := s.db.WithContext(ctx).Exec("DELETE FROM "+table+" WHERE email_id IN (SELECT id FROM emails WHERE user_id = ?)", userID).Error; err != nil {
+			return fmt.Errorf("failed to delete embeddings for user %s: %w", userID, err)
+		}
+	}
+
+	// Translations hold the emails' content too
+	if err := s.db.WithContext(ctx).Where("email_id IN (?)", s.db.Model(&model.Email{}).Select("id").Where("user_id = ?", userID)).Delete(&model.EmailTranslation{}).Error; err != nil {
+		return fmt.Errorf("failed to delete translations for user %s: %w", userID, err)
+	}
+
+	// So does the style profile's examples; it is learned again from the mail synced next
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.StyleProfile{}).Error; err != nil {
+		return fmt.Errorf("failed to delete style profile for user %s: %w", userID, err)
 	}
 
 	// Then delete the emails themselves
@@ -9,6 +9,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/hrygo/echomind/internal/model"
 	"github.com/hrygo/echomind/internal/repository"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/imap"
 	echologger "github.com/hrygo/echomind/pkg/logger"
 	"gorm.io/datatypes"
 )
@@ -27,17 +29,40 @@ func NewEmailIngestor(emailRepo repository.EmailRepository, logger echologger.Lo
 }
 
 // Ingest fetches emails since the last sync time and saves them to the repository.
-// It returns the list of newly saved emails.
+// It returns the list of newly saved emails. The user's sent mail is saved too, so that drafts can
+// follow their style and see their side of a thread, but it is not returned for analysis.
 func (s *EmailIngestor) Ingest(ctx context.Context, session IMAPSession, account *model.EmailAccount, lastSyncTime time.Time) ([]model.Email, error) {
 	// 1. Fetch emails
 	// For simplicity, we fetch a fixed limit for now, or we could use UID search based on lastSyncTime
 	// The current fetcher implementation might need adjustment to support "since" properly if not already.
 	// Assuming FetchEmails gets recent emails.
-	emailDataList, err := session.FetchEmails("INBOX", 10) // Fetch top 10 for now
+	emailDataList, err := session.FetchEmails(model.FolderInbox, 10) // Fetch top 10 for now
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch emails: %w", err)
 	}
+	newEmails := s.save(ctx, account, emailDataList, model.FolderInbox, lastSyncTime)
 
+	// 2. Sent mail is best effort; the inbox is synced either way.
+	sentMailbox, err := session.SentMailbox()
+	if err != nil {
+		s.logger.Warnw("Failed to find the sent mailbox", "error", err)
+		return newEmails, nil
+	}
+	if sentMailbox == "" {
+		return newEmails, nil
+	}
+	sentList, err := session.FetchEmails(sentMailbox, 10)
+	if err != nil {
+		s.logger.Warnw("Failed to fetch sent emails", "mailbox", sentMailbox, "error", err)
+		return newEmails, nil
+	}
+	s.save(ctx, account, sentList, model.FolderSent, lastSyncTime)
+
+	return newEmails, nil
+}
+
+// save stores the emails not seen before in folder and returns them.
+func (s *EmailIngestor) save(ctx context.Context, account *model.EmailAccount, emailDataList []imap.EmailData, folder string, lastSyncTime time.Time) []model.Email {
 	var newEmails []model.Email
 	userID := *account.UserID
 
@@ -68,8 +93,11 @@ func (s *EmailIngestor) Ingest(ctx context.Context, session IMAPSession, account
 			BodyText:  data.BodyText,
 			BodyHTML:  data.BodyHTML,
 			MessageID: data.MessageID,
-			IsRead:    false, // Default to unread
-			Folder:    "INBOX",
+			IsRead:    folder == model.FolderSent, // New mail is unread; the user wrote their sent mail
+			Folder:    folder,
+			Language:  ai.DetectLanguage(data.Subject + "\n" + data.BodyText),
+
+			HasAttachments: data.HasAttachments,
 		}
 
 		if toJSON, err := json.Marshal(data.To); err == nil {
@@ -87,5 +115,5 @@ func (s *EmailIngestor) Ingest(ctx context.Context, session IMAPSession, account
 		newEmails = append(newEmails, email)
 	}
 
-	return newEmails, nil
+	return newEmails
 }
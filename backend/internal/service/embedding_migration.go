@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/tasks"
+	"github.com/hrygo/echomind/pkg/ai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SetEmbeddingSpaces enables per-model embedding tables. New vectors go to the space of the primary
+// embedder; queries go to the active space and are embedded with whichever registered embedder matches
+// it. Pass the previous provider in queryEmbedders while a migration is running so search keeps using
+// the old space until the new one is complete.
+func (s *SearchService) SetEmbeddingSpaces(spaces *EmbeddingSpaceService, queryEmbedders ...ai.EmbeddingProvider) {
+	s.spaces = spaces
+	s.queryEmbedders = make(map[string]ai.EmbeddingProvider)
+	s.previousEmbedders = nil
+	for _, e := range append([]ai.EmbeddingProvider{s.embedder}, queryEmbedders...) {
+		if e != nil {
+			s.queryEmbedders[EmbeddingSpaceKey(ai.EmbeddingModelName(e), e.GetDimensions())] = e
+			if e != s.embedder {
+				s.previousEmbedders = append(s.previousEmbedders, e)
+			}
+		}
+	}
+}
+
+// ensurePreviousSpaces registers the spaces of the previous embedders before the primary's, so that
+// after an upgrade email_embeddings, and the queries, stay with the model that wrote it.
+func (s *SearchService) ensurePreviousSpaces(ctx context.Context) error {
+	if s.previousEnsured.Load() {
+		return nil
+	}
+	for _, e := range s.previousEmbedders {
+		if _, err := s.spaces.Ensure(ctx, ai.EmbeddingModelName(e), e.GetDimensions()); err != nil {
+			return err
+		}
+	}
+	s.previousEnsured.Store(true)
+	return nil
+}
+
+// writeSpace resolves the table new embeddings from the primary embedder belong to.
+func (s *SearchService) writeSpace(ctx context.Context) (string, string, error) {
+	modelName := ai.EmbeddingModelName(s.embedder)
+	if s.spaces == nil {
+		return legacyEmbeddingTable, modelName, nil
+	}
+	if err := s.ensurePreviousSpaces(ctx); err != nil {
+		return "", "", err
+	}
+	space, err := s.spaces.Ensure(ctx, modelName, s.embedder.GetDimensions())
+	if err != nil {
+		return "", "", err
+	}
+	return space.Table, modelName, nil
+}
+
+// readSpace resolves the table and query embedder used for semantic search.
+func (s *SearchService) readSpace(ctx context.Context) (string, ai.EmbeddingProvider, error) {
+	if s.spaces == nil {
+		return legacyEmbeddingTable, s.embedder, nil
+	}
+	if err := s.ensurePreviousSpaces(ctx); err != nil {
+		return "", nil, err
+	}
+	active, err := s.spaces.Active(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if active != nil {
+		if embedder, ok := s.queryEmbedders[active.Key]; ok {
+			return active.Table, embedder, nil
+		}
+		// The active space's model is no longer configured: searching the partially migrated space
+		// beats comparing vectors from different models.
+		trace.SpanFromContext(ctx).AddEvent("embedding_space_fallback", trace.WithAttributes(
+			attribute.String("embedding.active_space", active.Key),
+		))
+	}
+	table, _, err := s.writeSpace(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return table, s.embedder, nil
+}
+
+var _ tasks.EmbeddingMigrator = (*SearchService)(nil)
+
+// EmbeddingMigrationNeeded reports whether the primary embedder's space is not the one serving queries.
+func (s *SearchService) EmbeddingMigrationNeeded(ctx context.Context) (bool, error) {
+	if s.spaces == nil {
+		return false, nil
+	}
+	table, _, err := s.writeSpace(ctx)
+	if err != nil {
+		return false, err
+	}
+	active, err := s.spaces.Active(ctx)
+	if err != nil {
+		return false, err
+	}
+	return active == nil || active.Table != table, nil
+}
+
+// MigrateEmbeddings embeds every received email missing from the primary embedder's space and, once the space
+// covers all emails, atomically switches queries over to it. Emails that fail are skipped for this run
+// and retried by the next one; those the provider rejects outright count as covered, so that a few
+// unembeddable emails do not hold the switch back forever.
+func (s *SearchService) MigrateEmbeddings(ctx context.Context, batchSize, chunkSize int) (tasks.EmbeddingMigrationResult, error) {
+	var report tasks.EmbeddingMigrationResult
+	if s.spaces == nil {
+		return report, fmt.Errorf("embedding spaces are not configured")
+	}
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	if err := s.ensurePreviousSpaces(ctx); err != nil {
+		return report, err
+	}
+	space, err := s.spaces.Ensure(ctx, ai.EmbeddingModelName(s.embedder), s.embedder.GetDimensions())
+	if err != nil {
+		return report, err
+	}
+	report.Space = space.Key
+
+	var cursor uuid.UUID
+	for {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		var batch []model.Email
+		err := s.db.WithContext(ctx).
+			Select("id, user_id, subject, snippet, body_text").
+			Where("id > ?", cursor).
+			Where("folder <> ?", model.FolderSent).
+			Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s ee WHERE ee.email_id = emails.id)", space.Table)).
+			Order("id").
+			Limit(batchSize).
+			Find(&batch).Error
+		if err != nil {
+			return report, fmt.Errorf("failed to load emails to embed: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			if err := s.GenerateAndSaveEmbedding(ctx, &batch[i], chunkSize); err != nil {
+				var apiErr *ai.APIError
+				if errors.As(err, &apiErr) && ai.ClassifyError(err) == ai.ErrorPermanent {
+					report.Skipped++
+				} else {
+					report.Failed++
+				}
+				continue
+			}
+			report.Embedded++
+		}
+		cursor = batch[len(batch)-1].ID
+	}
+
+	report.Covered, report.Total, err = s.spaces.Coverage(ctx, space)
+	if err != nil {
+		return report, err
+	}
+	if space.Status != model.EmbeddingSpaceActive && report.Covered+int64(report.Skipped) >= report.Total {
+		// Coverage was just checked, allowing for the skipped emails
+		if err := s.spaces.Activate(ctx, space.ID, true); err != nil {
+			return report, err
+		}
+		report.Activated = true
+	}
+	return report, nil
+}
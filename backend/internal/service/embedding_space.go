@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/database"
+	"gorm.io/gorm"
+)
+
+const (
+	// legacyEmbeddingTable is the original embeddings table with a fixed vector(1024) column.
+	legacyEmbeddingTable      = "email_embeddings"
+	legacyEmbeddingDimensions = 1024
+	// pgvector cannot build HNSW/IVFFlat indexes on vectors with more dimensions than this.
+	maxIndexableDimensions = 2000
+)
+
+var (
+	ErrEmbeddingSpaceNotFound   = errors.New("embedding space not found")
+	ErrEmbeddingCoverageMissing = errors.New("embedding space does not cover every email yet")
+)
+
+var unsafeTableChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// EmbeddingSpaceKey identifies the vector space of a model at a given output size.
+func EmbeddingSpaceKey(modelName string, dimensions int) string {
+	return fmt.Sprintf("%s@%d", modelName, dimensions)
+}
+
+// embeddingTableName derives a Postgres-safe table name (<= 63 bytes) for a space key.
+func embeddingTableName(key string) string {
+	slug := strings.Trim(unsafeTableChars.ReplaceAllString(strings.ToLower(key), "_"), "_")
+	sum := sha1.Sum([]byte(key))
+	suffix := hex.EncodeToString(sum[:])[:8]
+	if len(slug) > 40 {
+		slug = slug[:40]
+	}
+	return fmt.Sprintf("%s_%s_%s", legacyEmbeddingTable, slug, suffix)
+}
+
+// EmbeddingSpaceService keeps track of which table holds which model's vectors and which one serves queries.
+type EmbeddingSpaceService struct {
+	db          *gorm.DB
+	legacyModel string // Model that wrote the untagged vectors in email_embeddings, if known
+
+	mu           sync.RWMutex
+	active       *model.EmbeddingSpace
+	activeLoaded time.Time
+	cacheTTL     time.Duration
+}
+
+func NewEmbeddingSpaceService(db *gorm.DB) *EmbeddingSpaceService {
+	return &EmbeddingSpaceService{db: db, cacheTTL: 30 * time.Second}
+}
+
+// SetLegacyModel names the model that wrote the untagged vectors in email_embeddings, such as the
+// embedding_previous provider during an upgrade. Without it, the first model registered with
+// matching dimensions takes the table.
+func (s *EmbeddingSpaceService) SetLegacyModel(modelName string) {
+	s.legacyModel = modelName
+}
+
+// Ensure returns the space for a model, creating its table on first use. The legacy email_embeddings
+// table is adopted by the model its vectors came from when the dimensions fit. A space becomes
+// active immediately only when no other space is active and no other model's legacy vectors are
+// still waiting to be migrated.
+func (s *EmbeddingSpaceService) Ensure(ctx context.Context, modelName string, dimensions int) (*model.EmbeddingSpace, error) {
+	key := EmbeddingSpaceKey(modelName, dimensions)
+
+	var space model.EmbeddingSpace
+	err := s.db.WithContext(ctx).Where("space_key = ?", key).First(&space).Error
+	if err == nil {
+		return &space, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load embedding space: %w", err)
+	}
+
+	var activeCount, legacyClaimed int64
+	if err := s.db.WithContext(ctx).Model(&model.EmbeddingSpace{}).Where("status = ?", model.EmbeddingSpaceActive).Count(&activeCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count embedding spaces: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&model.EmbeddingSpace{}).Where("table_name = ?", legacyEmbeddingTable).Count(&legacyClaimed).Error; err != nil {
+		return nil, fmt.Errorf("failed to count embedding spaces: %w", err)
+	}
+
+	space = model.EmbeddingSpace{
+		ID:         uuid.New(),
+		Key:        key,
+		Model:      modelName,
+		Dimensions: dimensions,
+		Status:     model.EmbeddingSpaceBuilding,
+	}
+	var legacyOwner string
+	var legacyHasRows bool
+	if legacyClaimed == 0 {
+		legacyOwner, legacyHasRows = s.legacyOwner(ctx)
+	}
+	adoptLegacy := legacyClaimed == 0 && dimensions == legacyEmbeddingDimensions &&
+		(legacyOwner == "" || legacyOwner == modelName)
+	if activeCount == 0 && (adoptLegacy || !legacyHasRows) {
+		now := time.Now()
+		space.Status = model.EmbeddingSpaceActive
+		space.ActivatedAt = &now
+	}
+
+	if adoptLegacy {
+		space.Table = legacyEmbeddingTable
+		// Rows written before versioning existed belong to the model that claims the table.
+		if err := s.db.WithContext(ctx).Exec("UPDATE "+legacyEmbeddingTable+" SET model = ? WHERE model IS NULL OR model = ''", modelName).Error; err != nil {
+			return nil, fmt.Errorf("failed to tag legacy embeddings: %w", err)
+		}
+	} else {
+		space.Table = embeddingTableName(key)
+		if err := s.createTable(ctx, space.Table, dimensions); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Create(&space).Error; err != nil {
+		return nil, fmt.Errorf("failed to register embedding space: %w", err)
+	}
+	s.invalidate()
+	return &space, nil
+}
+
+// legacyOwner returns the model the vectors in email_embeddings came from: their own tag, else the
+// configured legacy model, else "" when unknown. hasRows is false when the table holds no vectors.
+func (s *EmbeddingSpaceService) legacyOwner(ctx context.Context) (owner string, hasRows bool) {
+	db := s.db.WithContext(ctx)
+	var tags []string
+	if err := db.Table(legacyEmbeddingTable).Where("model IS NOT NULL AND model <> ''").Limit(1).Pluck("model", &tags).Error; err == nil && len(tags) > 0 {
+		return tags[0], true
+	}
+	var ids []int64
+	if err := db.Table(legacyEmbeddingTable).Limit(1).Pluck("id", &ids).Error; err != nil || len(ids) == 0 {
+		return s.legacyModel, false
+	}
+	return s.legacyModel, true
+}
+
+// createTable creates an embeddings table with a vector column of the given size and its indexes.
+func (s *EmbeddingSpaceService) createTable(ctx context.Context, table string, dimensions int) error {
+	db := s.db.WithContext(ctx)
+	if db.Dialector.Name() != "postgres" {
+		// Other dialects (SQLite in tests) have no vector type; the struct layout is enough.
+		return db.Table(table).Migrator().CreateTable(&model.EmailEmbedding{})
+	}
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id bigserial PRIMARY KEY,
+			email_id uuid NOT NULL REFERENCES emails(id) ON DELETE CASCADE,
+			content text,
+			vector vector(%d),
+			dimensions bigint NOT NULL DEFAULT %d,
+			model varchar(200),
+			created_at timestamptz
+		)`, table, dimensions, dimensions),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_email_id ON %s (email_id)", table, table),
+	}
+	if dimensions <= maxIndexableDimensions {
+		indexSQL, err := database.CreateVectorIndexSQL(table+"_vector_idx", table, "vector", database.VectorIndexOptions{}, false)
+		if err != nil {
+			return err
+		}
+		statements = append(statements, indexSQL)
+	}
+
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create embedding table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Active returns the space that serves queries, or nil if none has been registered yet.
+func (s *EmbeddingSpaceService) Active(ctx context.Context) (*model.EmbeddingSpace, error) {
+	s.mu.RLock()
+	if time.Since(s.activeLoaded) < s.cacheTTL {
+		active := s.active
+		s.mu.RUnlock()
+		return active, nil
+	}
+	s.mu.RUnlock()
+
+	var space model.EmbeddingSpace
+	err := s.db.WithContext(ctx).Where("status = ?", model.EmbeddingSpaceActive).First(&space).Error
+	var active *model.EmbeddingSpace
+	switch {
+	case err == nil:
+		active = &space
+	case errors.Is(err, gorm.ErrRecordNotFound):
+	default:
+		return nil, fmt.Errorf("failed to load active embedding space: %w", err)
+	}
+
+	s.mu.Lock()
+	s.active = active
+	s.activeLoaded = time.Now()
+	s.mu.Unlock()
+	return active, nil
+}
+
+func (s *EmbeddingSpaceService) invalidate() {
+	s.mu.Lock()
+	s.activeLoaded = time.Time{}
+	s.mu.Unlock()
+}
+
+// ActiveTable returns the table serving queries, or email_embeddings if no space is registered yet.
+func (s *EmbeddingSpaceService) ActiveTable(ctx context.Context) string {
+	return activeEmbeddingTable(ctx, s.db)
+}
+
+// List returns all registered spaces, newest first.
+func (s *EmbeddingSpaceService) List(ctx context.Context) ([]model.EmbeddingSpace, error) {
+	var spaces []model.EmbeddingSpace
+	if err := s.db.WithContext(ctx).Order("created_at DESC").Find(&spaces).Error; err != nil {
+		return nil, fmt.Errorf("failed to list embedding spaces: %w", err)
+	}
+	return spaces, nil
+}
+
+// Coverage counts live received emails that have at least one embedding in the space, and all live
+// received emails. Sent mail is never embedded, so it does not count towards either.
+func (s *EmbeddingSpaceService) Coverage(ctx context.Context, space *model.EmbeddingSpace) (int64, int64, error) {
+	var embedded, total int64
+	db := s.db.WithContext(ctx)
+	if err := db.Raw(fmt.Sprintf(`
+		SELECT COUNT(DISTINCT ee.email_id) FROM %s ee
+		JOIN emails e ON e.id = ee.email_id
+		WHERE e.deleted_at IS NULL AND e.folder <> ?`, space.Table), model.FolderSent).Scan(&embedded).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to count embedded emails: %w", err)
+	}
+	if err := db.Model(&model.Email{}).Where("folder <> ?", model.FolderSent).Count(&total).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to count emails: %w", err)
+	}
+
+	if err := db.Model(space).Updates(map[string]interface{}{"embedded_emails": embedded, "total_emails": total}).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to record coverage: %w", err)
+	}
+	space.EmbeddedEmails, space.TotalEmails = embedded, total
+	return embedded, total, nil
+}
+
+// Activate switches queries to the space in one transaction. Unless force is set, the space must
+// cover every email so that search results do not silently shrink after the switch.
+func (s *EmbeddingSpaceService) Activate(ctx context.Context, spaceID uuid.UUID, force bool) error {
+	var space model.EmbeddingSpace
+	if err := s.db.WithContext(ctx).First(&space, "id = ?", spaceID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrEmbeddingSpaceNotFound
+		}
+		return err
+	}
+	if space.Status == model.EmbeddingSpaceActive {
+		return nil
+	}
+
+	if !force {
+		embedded, total, err := s.Coverage(ctx, &space)
+		if err != nil {
+			return err
+		}
+		if embedded < total {
+			return fmt.Errorf("%w: %d of %d emails embedded", ErrEmbeddingCoverageMissing, embedded, total)
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.EmbeddingSpace{}).
+			Where("status = ?", model.EmbeddingSpaceActive).
+			Update("status", model.EmbeddingSpaceRetired).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		return tx.Model(&space).Updates(map[string]interface{}{
+			"status":       model.EmbeddingSpaceActive,
+			"activated_at": &now,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to activate embedding space: %w", err)
+	}
+	s.invalidate()
+	return nil
+}
+
+// embeddingTables lists every table that may hold email embeddings. It tolerates a missing registry
+// (e.g. before the first migration) by falling back to the legacy table.
+func embeddingTables(ctx context.Context, db *gorm.DB) []string {
+	var tables []string
+	if err := db.WithContext(ctx).Model(&model.EmbeddingSpace{}).Pluck("table_name", &tables).Error; err != nil {
+		return []string{legacyEmbeddingTable}
+	}
+	for _, t := range tables {
+		if t == legacyEmbeddingTable {
+			return tables
+		}
+	}
+	return append(tables, legacyEmbeddingTable)
+}
+
+// activeEmbeddingTable returns the table of the active space, or the legacy table if none is registered.
+func activeEmbeddingTable(ctx context.Context, db *gorm.DB) string {
+	var tables []string
+	err := db.WithContext(ctx).Model(&model.EmbeddingSpace{}).
+		Where("status = ?", model.EmbeddingSpaceActive).
+		Limit(1).Pluck("table_name", &tables).Error
+	if err != nil || len(tables) == 0 {
+		return legacyEmbeddingTable
+	}
+	return tables[0]
+}
@@ -0,0 +1,292 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeEmbedder returns constant vectors of a fixed size under a fixed model name.
+type fakeEmbedder struct {
+	model string
+	dims  int
+}
+
+func (f *fakeEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vec := make([]float32, f.dims)
+	vec[0] = 1
+	return vec, nil
+}
+
+func (f *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i], _ = f.Embed(ctx, texts[i])
+	}
+	return vectors, nil
+}
+
+func (f *fakeEmbedder) GetDimensions() int     { return f.dims }
+func (f *fakeEmbedder) EmbeddingModel() string { return f.model }
+
+func setupEmbeddingSpaceTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+uuid.NewString()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Email{}, &model.EmailEmbedding{}, &model.EmbeddingSpace{}))
+	return db
+}
+
+func seedEmbeddingEmails(t *testing.T, db *gorm.DB, n int) []uuid.UUID {
+	userID := uuid.New()
+	ids := make([]uuid.UUID, n)
+	for i := range ids {
+		ids[i] = uuid.New()
+		require.NoError(t, db.Create(&model.Email{
+			ID:        ids[i],
+			UserID:    userID,
+			MessageID: ids[i].String(),
+			Subject:   "Quarterly report",
+			BodyText:  "Numbers attached.",
+			Date:      time.Now(),
+		}).Error)
+	}
+	return ids
+}
+
+func TestEmbeddingTableName(t *testing.T) {
+	name := embeddingTableName(EmbeddingSpaceKey("Pro/BAAI/bge-m3", 1024))
+	assert.Regexp(t, `^email_embeddings_pro_baai_bge_m3_1024_[0-9a-f]{8}$`, name)
+	assert.LessOrEqual(t, len(embeddingTableName(EmbeddingSpaceKey(string(make([]byte, 200)), 3072))), 63)
+	assert.NotEqual(t, name, embeddingTableName(EmbeddingSpaceKey("pro-baai-bge-m3", 1024)), "hash suffix keeps similar names apart")
+}
+
+func TestEmbeddingSpaceService_EnsureAndActivate(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	spaces := NewEmbeddingSpaceService(db)
+	emails := seedEmbeddingEmails(t, db, 2)
+
+	// Pre-versioning rows are adopted by the first 1024-dim model.
+	require.NoError(t, db.Create(&model.EmailEmbedding{EmailID: emails[0], Content: "old", Dimensions: 1024}).Error)
+
+	first, err := spaces.Ensure(ctx, "bge-m3", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, legacyEmbeddingTable, first.Table)
+	assert.Equal(t, model.EmbeddingSpaceActive, first.Status)
+	var tagged int64
+	db.Model(&model.EmailEmbedding{}).Where("model = ?", "bge-m3").Count(&tagged)
+	assert.Equal(t, int64(1), tagged)
+
+	again, err := spaces.Ensure(ctx, "bge-m3", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, again.ID)
+
+	second, err := spaces.Ensure(ctx, "text-embedding-3-large", 3072)
+	require.NoError(t, err)
+	assert.NotEqual(t, legacyEmbeddingTable, second.Table)
+	assert.Equal(t, model.EmbeddingSpaceBuilding, second.Status, "a second space waits for its backfill")
+	assert.True(t, db.Migrator().HasTable(second.Table))
+	assert.ElementsMatch(t, []string{first.Table, second.Table}, embeddingTables(ctx, db))
+
+	// Activation requires full coverage unless forced.
+	err = spaces.Activate(ctx, second.ID, false)
+	assert.ErrorIs(t, err, ErrEmbeddingCoverageMissing)
+
+	for _, id := range emails {
+		require.NoError(t, db.Table(second.Table).Create(&model.EmailEmbedding{EmailID: id, Dimensions: 3072}).Error)
+	}
+	require.NoError(t, spaces.Activate(ctx, second.ID, false))
+
+	active, err := spaces.Active(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, active)
+	assert.Equal(t, second.ID, active.ID)
+	assert.Equal(t, second.Table, spaces.ActiveTable(ctx))
+
+	var retired model.EmbeddingSpace
+	require.NoError(t, db.First(&retired, "id = ?", first.ID).Error)
+	assert.Equal(t, model.EmbeddingSpaceRetired, retired.Status)
+
+	assert.ErrorIs(t, spaces.Activate(ctx, uuid.New(), true), ErrEmbeddingSpaceNotFound)
+}
+
+func TestSearchService_MigrateEmbeddings(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	spaces := NewEmbeddingSpaceService(db)
+	emails := seedEmbeddingEmails(t, db, 3)
+
+	// Search currently runs on the old model, which embedded every email.
+	oldEmbedder := &fakeEmbedder{model: "old-model", dims: 1024}
+	oldService := NewSearchService(db, oldEmbedder, nil)
+	oldService.SetEmbeddingSpaces(spaces)
+	for _, id := range emails {
+		require.NoError(t, oldService.GenerateAndSaveEmbedding(ctx, &model.Email{ID: id, Subject: "Quarterly report"}, 100))
+	}
+	needed, err := oldService.EmbeddingMigrationNeeded(ctx)
+	require.NoError(t, err)
+	assert.False(t, needed)
+
+	// Switching the configured model keeps queries on the old space until the backfill completes.
+	newEmbedder := &fakeEmbedder{model: "new-model", dims: 768}
+	svc := NewSearchService(db, newEmbedder, nil)
+	svc.SetEmbeddingSpaces(spaces, oldEmbedder)
+
+	needed, err = svc.EmbeddingMigrationNeeded(ctx)
+	require.NoError(t, err)
+	assert.True(t, needed)
+	table, embedder, err := svc.readSpace(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, legacyEmbeddingTable, table)
+	assert.Same(t, oldEmbedder, embedder)
+
+	result, err := svc.MigrateEmbeddings(ctx, 2, 100)
+	require.NoError(t, err)
+	assert.Equal(t, EmbeddingSpaceKey("new-model", 768), result.Space)
+	assert.Equal(t, 3, result.Embedded)
+	assert.Equal(t, int64(3), result.Covered)
+	assert.Equal(t, int64(3), result.Total)
+	assert.True(t, result.Activated)
+
+	table, embedder, err = svc.readSpace(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, legacyEmbeddingTable, table)
+	assert.Same(t, newEmbedder, embedder)
+
+	var stored []model.EmailEmbedding
+	require.NoError(t, db.Table(table).Find(&stored).Error)
+	require.Len(t, stored, 3)
+	assert.Equal(t, "new-model", stored[0].Model)
+	assert.Equal(t, 768, stored[0].Dimensions)
+
+	// A second run has nothing left to do.
+	result, err = svc.MigrateEmbeddings(ctx, 2, 100)
+	require.NoError(t, err)
+	assert.Zero(t, result.Embedded)
+	assert.False(t, result.Activated)
+}
+
+func TestSearchService_MigrateEmbeddings_LeavesOutSentMail(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	emails := seedEmbeddingEmails(t, db, 2)
+	sent := uuid.New()
+	require.NoError(t, db.Create(&model.Email{
+		ID:        sent,
+		UserID:    uuid.New(),
+		MessageID: sent.String(),
+		Folder:    model.FolderSent,
+		Subject:   "Re: Quarterly report",
+		Date:      time.Now(),
+	}).Error)
+
+	svc := NewSearchService(db, &fakeEmbedder{model: "new-model", dims: 768}, nil)
+	svc.SetEmbeddingSpaces(NewEmbeddingSpaceService(db))
+	result, err := svc.MigrateEmbeddings(ctx, 10, 100)
+	require.NoError(t, err)
+	assert.Equal(t, len(emails), result.Embedded)
+	assert.Equal(t, int64(2), result.Covered)
+	assert.Equal(t, int64(2), result.Total, "sent mail is not analyzed, so it is never embedded")
+
+	table, _, err := svc.readSpace(ctx)
+	require.NoError(t, err)
+	var sentVectors int64
+	require.NoError(t, db.Table(table).Where("email_id = ?", sent).Count(&sentVectors).Error)
+	assert.Zero(t, sentVectors)
+}
+
+func TestEmbeddingSpaceService_LegacyTableStaysWithItsModel(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	spaces := NewEmbeddingSpaceService(db)
+	spaces.SetLegacyModel("bge-m3")
+	emails := seedEmbeddingEmails(t, db, 1)
+	require.NoError(t, db.Create(&model.EmailEmbedding{EmailID: emails[0], Content: "old", Dimensions: 1024}).Error)
+
+	// The new model of the same size registers first, but the untagged vectors are not its own.
+	upgraded, err := spaces.Ensure(ctx, "bge-large-zh", 1024)
+	require.NoError(t, err)
+	assert.NotEqual(t, legacyEmbeddingTable, upgraded.Table)
+	assert.Equal(t, model.EmbeddingSpaceBuilding, upgraded.Status, "the old vectors serve queries until the backfill")
+	var retagged int64
+	db.Model(&model.EmailEmbedding{}).Where("model = ?", "bge-large-zh").Count(&retagged)
+	assert.Zero(t, retagged)
+
+	previous, err := spaces.Ensure(ctx, "bge-m3", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, legacyEmbeddingTable, previous.Table)
+	assert.Equal(t, model.EmbeddingSpaceActive, previous.Status)
+}
+
+func TestSearchService_PreviousEmbedderKeepsLegacyTable(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	emails := seedEmbeddingEmails(t, db, 1)
+	require.NoError(t, db.Create(&model.EmailEmbedding{EmailID: emails[0], Content: "old", Dimensions: 1024}).Error)
+
+	// An upgrade to another model of the same size, with the old one kept as embedding_previous.
+	oldEmbedder := &fakeEmbedder{model: "old-model", dims: 1024}
+	newEmbedder := &fakeEmbedder{model: "new-model", dims: 1024}
+	svc := NewSearchService(db, newEmbedder, nil)
+	svc.SetEmbeddingSpaces(NewEmbeddingSpaceService(db), oldEmbedder)
+
+	// Writing a new email's vectors first must not hand the old vectors to the new model.
+	require.NoError(t, svc.GenerateAndSaveEmbedding(ctx, &model.Email{ID: emails[0], Subject: "Quarterly report"}, 100))
+	table, embedder, err := svc.readSpace(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, legacyEmbeddingTable, table)
+	assert.Same(t, oldEmbedder, embedder)
+	var tags []string
+	require.NoError(t, db.Model(&model.EmailEmbedding{}).Distinct().Pluck("model", &tags).Error)
+	assert.Equal(t, []string{"old-model"}, tags)
+}
+
+// rejectingEmbedder fails permanently for texts containing reject, like a provider refusing an input.
+type rejectingEmbedder struct {
+	fakeEmbedder
+	reject string
+}
+
+func (r *rejectingEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	for _, text := range texts {
+		if strings.Contains(text, r.reject) {
+			return nil, &ai.APIError{Provider: "fake", StatusCode: http.StatusBadRequest, Message: "input rejected"}
+		}
+	}
+	return r.fakeEmbedder.EmbedBatch(ctx, texts)
+}
+
+func TestSearchService_MigrateEmbeddings_SkipsRejectedEmails(t *testing.T) {
+	db := setupEmbeddingSpaceTestDB(t)
+	ctx := context.Background()
+	spaces := NewEmbeddingSpaceService(db)
+	emails := seedEmbeddingEmails(t, db, 3)
+	require.NoError(t, db.Model(&model.Email{}).Where("id = ?", emails[1]).Update("body_text", "unembeddable").Error)
+
+	oldEmbedder := &fakeEmbedder{model: "old-model", dims: 1024}
+	oldService := NewSearchService(db, oldEmbedder, nil)
+	oldService.SetEmbeddingSpaces(spaces)
+	for _, id := range emails {
+		require.NoError(t, oldService.GenerateAndSaveEmbedding(ctx, &model.Email{ID: id, Subject: "Quarterly report"}, 100))
+	}
+
+	svc := NewSearchService(db, &rejectingEmbedder{fakeEmbedder{model: "new-model", dims: 768}, "unembeddable"}, nil)
+	svc.SetEmbeddingSpaces(spaces, oldEmbedder)
+	result, err := svc.MigrateEmbeddings(ctx, 10, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Embedded)
+	assert.Equal(t, 1, result.Skipped)
+	assert.Zero(t, result.Failed)
+	assert.Equal(t, int64(2), result.Covered)
+	assert.True(t, result.Activated, "an email the provider rejects does not hold the switch back")
+}
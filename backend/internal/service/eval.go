@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+// EvalFixture is a labelled email of the evaluation corpus.
+type EvalFixture struct {
+	ID       string     `json:"id"`
+	Subject  string     `json:"subject"`
+	Sender   string     `json:"sender"`
+	Body     string     `json:"body"`
+	Expected EvalLabels `json:"expected"`
+}
+
+// EvalLabels are the expected analysis of a fixture.
+type EvalLabels struct {
+	Category    string   `json:"category"`
+	Urgency     string   `json:"urgency"`
+	Sentiment   string   `json:"sentiment"`
+	ActionItems []string `json:"action_items"`
+}
+
+// EvalAnalyzer produces the analysis of a fixture.
+type EvalAnalyzer interface {
+	Analyze(ctx context.Context, fixture EvalFixture) (ai.AnalysisResult, error)
+}
+
+// EvalCaseResult is the outcome of one fixture.
+type EvalCaseResult struct {
+	ID          string             `json:"id"`
+	Expected    EvalLabels         `json:"expected"`
+	Got         *ai.AnalysisResult `json:"got,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	LatencyMS   float64            `json:"latency_ms"`
+	CategoryOK  bool               `json:"category_ok"`
+	UrgencyOK   bool               `json:"urgency_ok"`
+	ItemsFound  int                `json:"action_items_found"` // Expected action items the analysis covers
+	MissedItems []string           `json:"missed_action_items,omitempty"`
+}
+
+// EvalMetrics are the aggregate scores of a run. Failed cases count as wrong answers.
+type EvalMetrics struct {
+	Cases            int                `json:"cases"`
+	Errors           int                `json:"errors"`
+	CategoryAccuracy float64            `json:"category_accuracy"`
+	UrgencyAccuracy  float64            `json:"urgency_accuracy"`
+	SentimentF1      float64            `json:"sentiment_f1"` // Macro average over the sentiment classes
+	SentimentF1By    map[string]float64 `json:"sentiment_f1_by_class"`
+	ActionItemRecall float64            `json:"action_item_recall"`
+	LatencyMeanMS    float64            `json:"latency_mean_ms"`
+	LatencyP50MS     float64            `json:"latency_p50_ms"`
+	LatencyP95MS     float64            `json:"latency_p95_ms"`
+}
+
+// EvalRun is a saved evaluation, so later runs can be compared with it.
+type EvalRun struct {
+	Name      string           `json:"name"`
+	Provider  string           `json:"provider"`
+	Corpus    string           `json:"corpus"`
+	StartedAt time.Time        `json:"started_at"`
+	Metrics   EvalMetrics      `json:"metrics"`
+	Results   []EvalCaseResult `json:"results"`
+}
+
+// LoadEvalCorpus reads a JSON Lines corpus, one fixture per line.
+func LoadEvalCorpus(path string) ([]EvalFixture, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var fixtures []EvalFixture
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "//") {
+			continue
+		}
+		var fixture EvalFixture
+		if err := json.Unmarshal([]byte(text), &fixture); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if fixture.ID == "" || fixture.Body == "" {
+			return nil, fmt.Errorf("%s:%d: fixture needs an id and a body", path, line)
+		}
+		if seen[fixture.ID] {
+			return nil, fmt.Errorf("%s:%d: duplicate fixture id %q", path, line, fixture.ID)
+		}
+		seen[fixture.ID] = true
+		fixtures = append(fixtures, fixture)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("%s: no fixtures", path)
+	}
+	return fixtures, nil
+}
+
+// RunEval analyzes every fixture in turn and scores the results. Analysis errors are recorded on
+// the case; only a cancelled context stops the run.
+func RunEval(ctx context.Context, analyzer EvalAnalyzer, fixtures []EvalFixture) (*EvalRun, error) {
+	run := &EvalRun{StartedAt: time.Now().UTC(), Results: make([]EvalCaseResult, 0, len(fixtures))}
+	for _, fixture := range fixtures {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		result, err := analyzer.Analyze(ctx, fixture)
+		latency := time.Since(start)
+		if err != nil && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		run.Results = append(run.Results, scoreEvalCase(fixture, result, err, latency))
+	}
+	run.Metrics = ComputeEvalMetrics(run.Results)
+	return run, nil
+}
+
+func scoreEvalCase(fixture EvalFixture, result ai.AnalysisResult, err error, latency time.Duration) EvalCaseResult {
+	c := EvalCaseResult{
+		ID:        fixture.ID,
+		Expected:  fixture.Expected,
+		LatencyMS: float64(latency.Microseconds()) / 1000,
+	}
+	if err != nil {
+		c.Error = err.Error()
+		c.MissedItems = fixture.Expected.ActionItems
+		return c
+	}
+	c.Got = &result
+	c.CategoryOK = strings.EqualFold(result.Category, fixture.Expected.Category)
+	c.UrgencyOK = strings.EqualFold(result.Urgency, fixture.Expected.Urgency)
+	for _, want := range fixture.Expected.ActionItems {
+		if actionItemCovered(want, result.ActionItems) {
+			c.ItemsFound++
+		} else {
+			c.MissedItems = append(c.MissedItems, want)
+		}
+	}
+	return c
+}
+
+// actionItemCovered reports whether some extracted item mentions at least half of the expected
+// item's words; models paraphrase, so exact matches would understate recall.
+func actionItemCovered(want string, got []string) bool {
+	wantTerms := uniqueTerms(tokenizeForRerank(want))
+	if len(wantTerms) == 0 {
+		return true
+	}
+	for _, item := range got {
+		terms := map[string]bool{}
+		for _, t := range tokenizeForRerank(item) {
+			terms[t] = true
+		}
+		matched := 0
+		for _, t := range wantTerms {
+			if terms[t] {
+				matched++
+			}
+		}
+		if 2*matched >= len(wantTerms) {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeEvalMetrics aggregates case results.
+func ComputeEvalMetrics(results []EvalCaseResult) EvalMetrics {
+	m := EvalMetrics{Cases: len(results), SentimentF1By: map[string]float64{}}
+	if len(results) == 0 {
+		return m
+	}
+
+	var categoryOK, urgencyOK, itemsWanted, itemsFound int
+	latencies := make([]float64, 0, len(results))
+	truePos, falsePos, falseNeg := map[string]int{}, map[string]int{}, map[string]int{}
+	for _, c := range results {
+		if c.Error != "" {
+			m.Errors++
+		}
+		if c.CategoryOK {
+			categoryOK++
+		}
+		if c.UrgencyOK {
+			urgencyOK++
+		}
+		itemsWanted += len(c.Expected.ActionItems)
+		itemsFound += c.ItemsFound
+		latencies = append(latencies, c.LatencyMS)
+
+		want := canonicalLabel(c.Expected.Sentiment, ai.AnalysisSentiments)
+		got := ""
+		if c.Got != nil {
+			got = canonicalLabel(c.Got.Sentiment, ai.AnalysisSentiments)
+		}
+		switch {
+		case want == got:
+			truePos[want]++
+		default:
+			falseNeg[want]++
+			if got != "" {
+				falsePos[got]++
+			}
+		}
+	}
+
+	n := float64(len(results))
+	m.CategoryAccuracy = float64(categoryOK) / n
+	m.UrgencyAccuracy = float64(urgencyOK) / n
+	m.ActionItemRecall = 1
+	if itemsWanted > 0 {
+		m.ActionItemRecall = float64(itemsFound) / float64(itemsWanted)
+	}
+
+	// Macro F1 over the classes that occur in the labels or the predictions.
+	var f1Sum float64
+	for _, class := range ai.AnalysisSentiments {
+		tp, fp, fn := truePos[class], falsePos[class], falseNeg[class]
+		if tp+fp+fn == 0 {
+			continue
+		}
+		f1 := 2 * float64(tp) / float64(2*tp+fp+fn)
+		m.SentimentF1By[class] = f1
+		f1Sum += f1
+	}
+	if len(m.SentimentF1By) > 0 {
+		m.SentimentF1 = f1Sum / float64(len(m.SentimentF1By))
+	}
+
+	sort.Float64s(latencies)
+	var total float64
+	for _, l := range latencies {
+		total += l
+	}
+	m.LatencyMeanMS = total / n
+	m.LatencyP50MS = percentile(latencies, 0.50)
+	m.LatencyP95MS = percentile(latencies, 0.95)
+	return m
+}
+
+// canonicalLabel returns the allowed spelling of a label, or the label itself if it is not one.
+func canonicalLabel(value string, allowed []string) string {
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSpace(value), a) {
+			return a
+		}
+	}
+	return value
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// EvalDelta is the change of one metric between two runs.
+type EvalDelta struct {
+	Metric    string
+	Base      float64
+	Head      float64
+	Regressed bool // Quality dropped by more than the tolerance
+}
+
+// CompareEvalRuns compares the quality metrics of head with base. A metric regresses when it
+// drops by more than tolerance (e.g. 0.02 for two percentage points). Latency is reported but never
+// counts as a regression, since it depends on the machine and the network.
+func CompareEvalRuns(base, head *EvalRun, tolerance float64) []EvalDelta {
+	quality := []struct {
+		name       string
+		base, head float64
+	}{
+		{"category_accuracy", base.Metrics.CategoryAccuracy, head.Metrics.CategoryAccuracy},
+		{"urgency_accuracy", base.Metrics.UrgencyAccuracy, head.Metrics.UrgencyAccuracy},
+		{"sentiment_f1", base.Metrics.SentimentF1, head.Metrics.SentimentF1},
+		{"action_item_recall", base.Metrics.ActionItemRecall, head.Metrics.ActionItemRecall},
+	}
+	deltas := make([]EvalDelta, 0, len(quality)+2)
+	for _, q := range quality {
+		deltas = append(deltas, EvalDelta{Metric: q.name, Base: q.base, Head: q.head, Regressed: q.base-q.head > tolerance})
+	}
+	deltas = append(deltas,
+		EvalDelta{Metric: "latency_p50_ms", Base: base.Metrics.LatencyP50MS, Head: head.Metrics.LatencyP50MS},
+		EvalDelta{Metric: "latency_p95_ms", Base: base.Metrics.LatencyP95MS, Head: head.Metrics.LatencyP95MS},
+	)
+	return deltas
+}
+
+// SaveEvalRun writes a run as indented JSON.
+func SaveEvalRun(path string, run *EvalRun) error {
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadEvalRun reads a run saved by SaveEvalRun.
+func LoadEvalRun(path string) (*EvalRun, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var run EvalRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &run, nil
+}
+
+// ProviderEvalAnalyzer analyzes fixtures with an AI provider, the way the analysis task does.
+type ProviderEvalAnalyzer struct {
+	Provider ai.AIProvider
+}
+
+func (a ProviderEvalAnalyzer) Analyze(ctx context.Context, fixture EvalFixture) (ai.AnalysisResult, error) {
+	return a.Provider.Summarize(ctx, fixture.Body)
+}
+
+// ErrNoRecording is returned when a replayed run has no result for a fixture.
+var ErrNoRecording = errors.New("no recorded result for fixture")
+
+// ReplayEvalAnalyzer answers with the results of a saved run, so the corpus and the scoring can be
+// checked in CI without calling a model. Recorded errors are replayed as errors.
+type ReplayEvalAnalyzer struct {
+	results map[string]EvalCaseResult
+}
+
+// NewReplayEvalAnalyzer replays a saved run.
+func NewReplayEvalAnalyzer(run *EvalRun) *ReplayEvalAnalyzer {
+	results := make(map[string]EvalCaseResult, len(run.Results))
+	for _, c := range run.Results {
+		results[c.ID] = c
+	}
+	return &ReplayEvalAnalyzer{results: results}
+}
+
+func (a *ReplayEvalAnalyzer) Analyze(ctx context.Context, fixture EvalFixture) (ai.AnalysisResult, error) {
+	c, ok := a.results[fixture.ID]
+	switch {
+	case !ok:
+		return ai.AnalysisResult{}, fmt.Errorf("%w %q", ErrNoRecording, fixture.ID)
+	case c.Error != "":
+		return ai.AnalysisResult{}, errors.New(c.Error)
+	case c.Got == nil:
+		return ai.AnalysisResult{}, fmt.Errorf("%w %q", ErrNoRecording, fixture.ID)
+	}
+	return *c.Got, nil
+}
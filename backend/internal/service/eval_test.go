@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubEvalAnalyzer map[string]ai.AnalysisResult
+
+func (s stubEvalAnalyzer) Analyze(ctx context.Context, fixture EvalFixture) (ai.AnalysisResult, error) {
+	result, ok := s[fixture.ID]
+	if !ok {
+		return ai.AnalysisResult{}, errors.New("model unavailable")
+	}
+	return result, nil
+}
+
+func TestRunEval_Metrics(t *testing.T) {
+	fixtures := []EvalFixture{
+		{ID: "a", Body: "x", Expected: EvalLabels{Category: "Work", Urgency: "High", Sentiment: "Negative", ActionItems: []string{"Pay invoice #4471 by Friday", "Call the bank"}}},
+		{ID: "b", Body: "x", Expected: EvalLabels{Category: "Personal", Urgency: "Low", Sentiment: "Positive"}},
+		{ID: "c", Body: "x", Expected: EvalLabels{Category: "Spam", Urgency: "Low", Sentiment: "Neutral", ActionItems: []string{"周三前审核合同"}}},
+		{ID: "d", Body: "x", Expected: EvalLabels{Category: "Newsletter", Urgency: "Low", Sentiment: "Neutral"}},
+	}
+	analyzer := stubEvalAnalyzer{
+		"a": {Category: "work", Urgency: "High", Sentiment: "Negative", ActionItems: []string{"Pay the overdue invoice 4471 before Friday"}},
+		"b": {Category: "Personal", Urgency: "Medium", Sentiment: "Neutral"},
+		"c": {Category: "Spam", Urgency: "Low", Sentiment: "Neutral", ActionItems: []string{"请在周三前审核合同"}},
+		// "d" fails
+	}
+
+	run, err := RunEval(context.Background(), analyzer, fixtures)
+	require.NoError(t, err)
+	m := run.Metrics
+	assert.Equal(t, 4, m.Cases)
+	assert.Equal(t, 1, m.Errors)
+	assert.Equal(t, 0.75, m.CategoryAccuracy, "matching ignores case; errors count as wrong")
+	assert.Equal(t, 0.5, m.UrgencyAccuracy)
+	assert.InDelta(t, 2.0/3, m.ActionItemRecall, 1e-9, "paraphrases count, the missing item does not")
+	assert.Equal(t, []string{"Call the bank"}, run.Results[0].MissedItems)
+	// Negative: tp 1 → 1. Positive: fn 1 → 0. Neutral: tp 1, fp 1 (b), fn 1 (d) → 0.5.
+	assert.Equal(t, map[string]float64{"Negative": 1, "Positive": 0, "Neutral": 0.5}, m.SentimentF1By)
+	assert.InDelta(t, 0.5, m.SentimentF1, 1e-9)
+	assert.Equal(t, "model unavailable", run.Results[3].Error)
+}
+
+func TestEvalRun_SaveReplayCompare(t *testing.T) {
+	fixtures := []EvalFixture{
+		{ID: "a", Body: "x", Expected: EvalLabels{Category: "Work", Urgency: "High", Sentiment: "Negative"}},
+		{ID: "b", Body: "x", Expected: EvalLabels{Category: "Personal", Urgency: "Low", Sentiment: "Positive"}},
+	}
+	good := stubEvalAnalyzer{
+		"a": {Category: "Work", Urgency: "High", Sentiment: "Negative"},
+		"b": {Category: "Personal", Urgency: "Low", Sentiment: "Positive"},
+	}
+	baseline, err := RunEval(context.Background(), good, fixtures)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	require.NoError(t, SaveEvalRun(path, baseline))
+
+	loaded, err := LoadEvalRun(path)
+	require.NoError(t, err)
+	replayed, err := RunEval(context.Background(), NewReplayEvalAnalyzer(loaded), fixtures)
+	require.NoError(t, err)
+	assert.Equal(t, baseline.Metrics.CategoryAccuracy, replayed.Metrics.CategoryAccuracy)
+	assert.Equal(t, baseline.Metrics.SentimentF1, replayed.Metrics.SentimentF1)
+	for _, d := range CompareEvalRuns(loaded, replayed, 0.02) {
+		assert.False(t, d.Regressed, d.Metric)
+	}
+
+	worse, err := RunEval(context.Background(), stubEvalAnalyzer{"a": good["a"]}, fixtures)
+	require.NoError(t, err)
+	regressed := map[string]bool{}
+	for _, d := range CompareEvalRuns(loaded, worse, 0.02) {
+		regressed[d.Metric] = d.Regressed
+	}
+	assert.True(t, regressed["category_accuracy"])
+	assert.False(t, regressed["latency_p95_ms"], "latency never counts as a regression")
+
+	_, err = NewReplayEvalAnalyzer(&EvalRun{}).Analyze(context.Background(), fixtures[0])
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestLoadEvalCorpus(t *testing.T) {
+	fixtures, err := LoadEvalCorpus("../../eval/corpus.jsonl")
+	require.NoError(t, err)
+	for _, f := range fixtures {
+		assert.Contains(t, ai.AnalysisCategories, f.Expected.Category, f.ID)
+		assert.Contains(t, ai.AnalysisUrgencies, f.Expected.Urgency, f.ID)
+		assert.Contains(t, ai.AnalysisSentiments, f.Expected.Sentiment, f.ID)
+	}
+
+	path := filepath.Join(t.TempDir(), "dup.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"id":"a","body":"x"}`+"\n"+`{"id":"a","body":"y"}`+"\n"), 0o644))
+	_, err = LoadEvalCorpus(path)
+	assert.ErrorContains(t, err, `duplicate fixture id "a"`)
+}
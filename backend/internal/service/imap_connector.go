@@ -16,6 +16,8 @@ import (
 type IMAPSession interface {
 	Logout() error
 	FetchEmails(mailbox string, limit int) ([]imap.EmailData, error)
+	// SentMailbox returns the name of the sent-mail folder, or "" if there is none.
+	SentMailbox() (string, error)
 }
 
 // DefaultIMAPSession wraps a go-imap client.
@@ -31,6 +33,10 @@ func (s *DefaultIMAPSession) FetchEmails(mailbox string, limit int) ([]imap.Emai
 	return imap.FetchEmails(s.client, mailbox, limit)
 }
 
+func (s *DefaultIMAPSession) SentMailbox() (string, error) {
+	return imap.FindSentMailbox(s.client)
+}
+
 // IMAPConnector handles establishing connections to IMAP servers.
 type IMAPConnector interface {
 	Connect(ctx context.Context, account *model.EmailAccount) (IMAPSession, error)
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPromptNotFound  = errors.New("unknown prompt")
+	ErrPromptInvalid   = errors.New("invalid prompt template")
+	ErrPromptForbidden = errors.New("prompts can only be changed by organization owners and admins")
+)
+
+// PromptVars are the variables prompt templates can use, e.g. {{.UserName}} or {{.Date}}.
+type PromptVars struct {
+	UserName string // Display name, or the email address when unset
+	Locale   string // e.g. "zh-CN"; empty when the user has not chosen one
+	Date     string // Today, YYYY-MM-DD
+	Role     string // executive, manager or dealmaker
+	OrgName  string // The user's primary organization
+}
+
+// PromptService is the registry of prompt templates. Every prompt has a default version from the
+// configuration; organizations add numbered versions and choose which of them serve their users.
+type PromptService struct {
+	db       *gorm.DB
+	defaults map[string]string
+	now      func() time.Time
+
+	templates sync.Map // template cache key -> *template.Template
+}
+
+// NewPromptService creates the registry with the configured prompts as default versions.
+func NewPromptService(db *gorm.DB, cfg configs.PromptConfig) *PromptService {
+	defaults := map[string]string{
+		ai.PromptSummary:       cfg.Summary,
+		ai.PromptClassify:      cfg.Classify,
+		ai.PromptSentiment:     cfg.Sentiment,
+		ai.PromptDraftReply:    cfg.DraftReply,
+		ai.PromptChatSystem:    cfg.ChatSystem,
+		ai.PromptSearchSummary: cfg.SearchSummary,
+	}
+	if defaults[ai.PromptChatSystem] == "" {
+		defaults[ai.PromptChatSystem] = chatSystemPrompt
+	}
+	if defaults[ai.PromptSearchSummary] == "" {
+		defaults[ai.PromptSearchSummary] = searchSummaryInstructions
+	}
+	return &PromptService{db: db, defaults: defaults, now: time.Now}
+}
+
+// Scope returns a context whose AI calls use the prompts serving userID's organization.
+func (s *PromptService) Scope(ctx context.Context, userID uuid.UUID) context.Context {
+	return ai.WithPromptResolver(ctx, &promptResolver{prompts: s, userID: userID})
+}
+
+// promptResolver resolves prompts for one user. It lives as long as a request or task and remembers
+// what it resolved, so every call in it uses the same versions.
+type promptResolver struct {
+	prompts *PromptService
+	userID  uuid.UUID
+
+	mu       sync.Mutex
+	loaded   bool
+	vars     PromptVars
+	orgID    *uuid.UUID
+	resolved map[string]ai.Prompt
+}
+
+func (r *promptResolver) ResolvePrompt(ctx context.Context, name string) (ai.Prompt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prompt, ok := r.resolved[name]; ok {
+		return prompt, nil
+	}
+	if !r.loaded {
+		vars, orgID, err := r.prompts.userVars(ctx, r.userID)
+		if err != nil {
+			return ai.Prompt{}, err
+		}
+		r.vars, r.orgID, r.loaded = vars, orgID, true
+	}
+	prompt, err := r.prompts.resolve(ctx, r.orgID, r.userID, name, r.vars)
+	if err != nil {
+		return ai.Prompt{}, err
+	}
+	if r.resolved == nil {
+		r.resolved = map[string]ai.Prompt{}
+	}
+	r.resolved[name] = prompt
+	return prompt, nil
+}
+
+// userVars loads the template variables for a user together with their primary organization.
+func (s *PromptService) userVars(ctx context.Context, userID uuid.UUID) (PromptVars, *uuid.UUID, error) {
+	vars := PromptVars{Date: s.now().Format("2006-01-02")}
+	var users []model.User
+	if err := s.db.WithContext(ctx).Where("id = ?", userID).Limit(1).Find(&users).Error; err != nil {
+		return vars, nil, err
+	}
+	if len(users) > 0 {
+		vars.UserName = users[0].Name
+		if vars.UserName == "" {
+			vars.UserName = users[0].Email
+		}
+		vars.Locale = users[0].Locale
+		vars.Role = users[0].Role
+	}
+
+	var members []model.OrganizationMember
+	err := s.db.WithContext(ctx).Preload("Organization").
+		Where("user_id = ?", userID).Order("joined_at ASC").Limit(1).Find(&members).Error
+	if err != nil || len(members) == 0 {
+		return vars, nil, err
+	}
+	vars.OrgName = members[0].Organization.Name
+	return vars, &members[0].OrganizationID, nil
+}
+
+// resolve renders the version of name serving orgID for userID: one of the organization's versions
+// with a weight, picked by a stable hash of the user so that they keep seeing the same one, or the
+// default version.
+func (s *PromptService) resolve(ctx context.Context, orgID *uuid.UUID, userID uuid.UUID, name string, vars PromptVars) (ai.Prompt, error) {
+	fallback, ok := s.defaults[name]
+	if !ok {
+		return ai.Prompt{}, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	var live []model.PromptTemplate
+	if orgID != nil {
+		err := s.db.WithContext(ctx).Where("org_id = ? AND name = ? AND weight > 0", *orgID, name).
+			Order("version ASC").Find(&live).Error
+		if err != nil {
+			return ai.Prompt{}, err
+		}
+	}
+	if len(live) == 0 {
+		return s.render(name, ai.PromptDefaultVersion, "default:"+name, fallback, vars)
+	}
+
+	total := 0
+	for _, v := range live {
+		total += v.Weight
+	}
+	h := fnv.New32a()
+	h.Write([]byte(userID.String() + "/" + name))
+	bucket := int(h.Sum32() % uint32(total))
+	chosen := live[len(live)-1]
+	for _, v := range live {
+		if bucket < v.Weight {
+			chosen = v
+			break
+		}
+		bucket -= v.Weight
+	}
+	return s.render(name, fmt.Sprintf("v%d", chosen.Version), chosen.ID.String(), chosen.Body, vars)
+}
+
+// render executes a template, caching the parsed template under key. A default prompt that is not a
+// valid template is used as plain text, since it predates templating.
+func (s *PromptService) render(name, version, key, body string, vars PromptVars) (ai.Prompt, error) {
+	prompt := ai.Prompt{Name: name, Version: version, Text: body}
+	var tmpl *template.Template
+	if cached, ok := s.templates.Load(key); ok {
+		tmpl = cached.(*template.Template)
+	} else {
+		parsed, err := parsePromptTemplate(name, body)
+		if err != nil {
+			if version == ai.PromptDefaultVersion {
+				return prompt, nil
+			}
+			return ai.Prompt{}, err
+		}
+		s.templates.Store(key, parsed)
+		tmpl = parsed
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, vars); err != nil {
+		if version == ai.PromptDefaultVersion {
+			return prompt, nil
+		}
+		return ai.Prompt{}, err
+	}
+	prompt.Text = text.String()
+	return prompt, nil
+}
+
+func parsePromptTemplate(name, body string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(body)
+}
+
+// PromptInfo describes a prompt and the versions an organization has of it.
+type PromptInfo struct {
+	Name     string                 `json:"name"`
+	Default  string                 `json:"default"`
+	Versions []model.PromptTemplate `json:"versions"`
+}
+
+// List returns every prompt with the organization's versions. Any member may look.
+func (s *PromptService) List(ctx context.Context, orgID, userID uuid.UUID) ([]PromptInfo, error) {
+	if _, err := s.member(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	var versions []model.PromptTemplate
+	if err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Order("name ASC, version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	byName := map[string][]model.PromptTemplate{}
+	for _, v := range versions {
+		byName[v.Name] = append(byName[v.Name], v)
+	}
+
+	names := make([]string, 0, len(s.defaults))
+	for name := range s.defaults {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	infos := make([]PromptInfo, 0, len(names))
+	for _, name := range names {
+		infos = append(infos, PromptInfo{Name: name, Default: s.defaults[name], Versions: byName[name]})
+	}
+	return infos, nil
+}
+
+// CreateVersion adds the next version of a prompt for an organization. The template must render
+// with the documented variables; with Activate it replaces whatever served before.
+func (s *PromptService) CreateVersion(ctx context.Context, orgID, userID uuid.UUID, name string, input model.PromptVersionInput) (*model.PromptTemplate, error) {
+	if err := s.checkAdmin(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	if _, ok := s.defaults[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+	if strings.TrimSpace(input.Body) == "" {
+		return nil, fmt.Errorf("%w: the template is empty", ErrPromptInvalid)
+	}
+	tmpl, err := parsePromptTemplate(name, input.Body)
+	if err == nil {
+		err = tmpl.Execute(&bytes.Buffer{}, PromptVars{UserName: "Ada", Locale: "en-US", Date: "2026-01-01", Role: "manager", OrgName: "Acme"})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPromptInvalid, err)
+	}
+
+	version := &model.PromptTemplate{
+		ID:        uuid.New(),
+		OrgID:     orgID,
+		Name:      name,
+		Body:      input.Body,
+		Note:      input.Note,
+		CreatedBy: userID,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&model.PromptTemplate{}).Where("org_id = ? AND name = ?", orgID, name).
+			Select("COALESCE(MAX(version), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		version.Version = last + 1
+		if input.Activate {
+			version.Weight = 100
+			if err := tx.Model(&model.PromptTemplate{}).Where("org_id = ? AND name = ?", orgID, name).
+				Update("weight", 0).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(version).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// SetRollout sets the weights of an organization's versions of a prompt. Versions not listed stop
+// serving, so an empty rollout rolls back to the default version.
+func (s *PromptService) SetRollout(ctx context.Context, orgID, userID uuid.UUID, name string, input model.PromptRolloutInput) ([]model.PromptTemplate, error) {
+	if err := s.checkAdmin(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	if _, ok := s.defaults[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
+	}
+
+	var versions []model.PromptTemplate
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("org_id = ? AND name = ?", orgID, name).Order("version ASC").Find(&versions).Error; err != nil {
+			return err
+		}
+		known := map[int]bool{}
+		for _, v := range versions {
+			known[v.Version] = true
+		}
+		for version, weight := range input.Weights {
+			if !known[version] {
+				return fmt.Errorf("%w: %s has no version %d", ErrPromptNotFound, name, version)
+			}
+			if weight < 0 {
+				return fmt.Errorf("%w: weights must not be negative", ErrPromptInvalid)
+			}
+		}
+		for i := range versions {
+			weight := input.Weights[versions[i].Version]
+			if versions[i].Weight == weight {
+				continue
+			}
+			versions[i].Weight = weight
+			if err := tx.Model(&versions[i]).Update("weight", weight).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (s *PromptService) member(ctx context.Context, orgID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).Take(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPromptForbidden
+	}
+	return &member, err
+}
+
+func (s *PromptService) checkAdmin(ctx context.Context, orgID, userID uuid.UUID) error {
+	member, err := s.member(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != model.OrgRoleOwner && member.Role != model.OrgRoleAdmin {
+		return ErrPromptForbidden
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func setupPromptTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:prompts?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Organization{}, &model.OrganizationMember{}, &model.PromptTemplate{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM prompt_templates")
+		db.Exec("DELETE FROM organization_members")
+		db.Exec("DELETE FROM organizations")
+		db.Exec("DELETE FROM users")
+	})
+	return db
+}
+
+func seedPromptTestUser(t *testing.T, db *gorm.DB, orgID uuid.UUID, name string, role model.OrganizationRole) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: id, Email: name + "@example.com", Name: name, Role: "manager", Locale: "en-US", WeChatOpenID: id.String()}).Error)
+	require.NoError(t, db.Omit(clause.Associations).Create(&model.OrganizationMember{OrganizationID: orgID, UserID: id, Role: role}).Error)
+	return id
+}
+
+func newTestPromptService(t *testing.T) (*PromptService, *gorm.DB, uuid.UUID) {
+	db := setupPromptTestDB(t)
+	orgID := uuid.New()
+	require.NoError(t, db.Create(&model.Organization{ID: orgID, Name: "Acme", Slug: "acme-" + orgID.String(), OwnerID: uuid.New()}).Error)
+	svc := NewPromptService(db, configs.PromptConfig{Summary: "Summarize for {{.UserName}}.", Classify: "Classify {"})
+	svc.now = func() time.Time { return time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC) }
+	return svc, db, orgID
+}
+
+func TestPromptService_ResolvesDefaultsAndVersions(t *testing.T) {
+	svc, _, orgID := newTestPromptService(t)
+	admin := seedPromptTestUser(t, svc.db, orgID, "Ada", model.OrgRoleOwner)
+	ctx := svc.Scope(context.Background(), admin)
+
+	prompt := ai.ResolvePrompt(ctx, ai.PromptSummary, "unused")
+	assert.Equal(t, "Summarize for Ada.", prompt.Text)
+	assert.Equal(t, "summary@default", prompt.Ref())
+	assert.Equal(t, "Classify {", ai.ResolvePrompt(ctx, ai.PromptClassify, "").Text, "defaults that are not templates are used verbatim")
+	assert.Contains(t, ai.ResolvePrompt(ctx, ai.PromptChatSystem, "").Text, "EchoMind Copilot")
+
+	_, err := svc.CreateVersion(context.Background(), orgID, admin, ai.PromptSummary, model.PromptVersionInput{Body: "{{.Nope}}"})
+	assert.ErrorIs(t, err, ErrPromptInvalid)
+	_, err = svc.CreateVersion(context.Background(), orgID, admin, "unknown", model.PromptVersionInput{Body: "x"})
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	v1, err := svc.CreateVersion(context.Background(), orgID, admin, ai.PromptSummary, model.PromptVersionInput{
+		Body: "[{{.OrgName}} {{.Role}} {{.Locale}} {{.Date}}] Summarize for {{.UserName}}.", Activate: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.Version)
+
+	// A new scope picks up the new version; the old one keeps what it resolved.
+	assert.Equal(t, "summary@default", ai.PromptRef(ctx, ai.PromptSummary))
+	fresh := svc.Scope(context.Background(), admin)
+	prompt = ai.ResolvePrompt(fresh, ai.PromptSummary, "")
+	assert.Equal(t, "summary@v1", prompt.Ref())
+	assert.Equal(t, "[Acme manager en-US 2026-05-04] Summarize for Ada.", prompt.Text)
+
+	// Users outside any organization get the default.
+	assert.Equal(t, "summary@default", ai.PromptRef(svc.Scope(context.Background(), uuid.New()), ai.PromptSummary))
+}
+
+func TestPromptService_RolloutAndRollback(t *testing.T) {
+	svc, _, orgID := newTestPromptService(t)
+	admin := seedPromptTestUser(t, svc.db, orgID, "Ada", model.OrgRoleAdmin)
+	member := seedPromptTestUser(t, svc.db, orgID, "Bob", model.OrgRoleMember)
+	ctx := context.Background()
+
+	_, err := svc.CreateVersion(ctx, orgID, member, ai.PromptSummary, model.PromptVersionInput{Body: "B"})
+	assert.ErrorIs(t, err, ErrPromptForbidden)
+
+	for _, body := range []string{"A", "B"} {
+		_, err := svc.CreateVersion(ctx, orgID, admin, ai.PromptSummary, model.PromptVersionInput{Body: body})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, "summary@default", ai.PromptRef(svc.Scope(ctx, admin), ai.PromptSummary), "new versions do not serve until rolled out")
+
+	_, err = svc.SetRollout(ctx, orgID, admin, ai.PromptSummary, model.PromptRolloutInput{Weights: map[int]int{3: 100}})
+	assert.ErrorIs(t, err, ErrPromptNotFound)
+
+	// A 50/50 split sends users to both versions, each user always to the same one.
+	_, err = svc.SetRollout(ctx, orgID, admin, ai.PromptSummary, model.PromptRolloutInput{Weights: map[int]int{1: 50, 2: 50}})
+	require.NoError(t, err)
+	seen := map[string]int{}
+	for i := 0; i < 40; i++ {
+		user := uuid.New()
+		ref := ai.PromptRef(svc.Scope(ctx, user), ai.PromptSummary)
+		assert.Equal(t, "summary@default", ref, "not a member of the organization")
+		prompt, err := svc.resolve(ctx, &orgID, user, ai.PromptSummary, PromptVars{})
+		require.NoError(t, err)
+		seen[prompt.Version]++
+		again, _ := svc.resolve(ctx, &orgID, user, ai.PromptSummary, PromptVars{})
+		assert.Equal(t, prompt.Version, again.Version)
+	}
+	assert.Len(t, seen, 2)
+
+	// Rolling back to the default.
+	versions, err := svc.SetRollout(ctx, orgID, admin, ai.PromptSummary, model.PromptRolloutInput{})
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Zero(t, versions[0].Weight+versions[1].Weight)
+	assert.Equal(t, "summary@default", ai.PromptRef(svc.Scope(ctx, admin), ai.PromptSummary))
+
+	prompts, err := svc.List(ctx, orgID, member)
+	require.NoError(t, err)
+	require.Len(t, prompts, 6)
+	for _, p := range prompts {
+		if p.Name == ai.PromptSummary {
+			assert.Len(t, p.Versions, 2)
+		}
+	}
+	_, err = svc.List(ctx, orgID, uuid.New())
+	assert.ErrorIs(t, err, ErrPromptForbidden)
+}
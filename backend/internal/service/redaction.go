@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRedactionForbidden is returned when a user reads the policy of an organization they are not
+	// in, or changes it or reads its audit log without being an owner or admin.
+	ErrRedactionForbidden = errors.New("redaction settings are only available to the organization's owners and admins")
+	// ErrRedactionInvalid is returned for a policy with unknown kinds.
+	ErrRedactionInvalid = errors.New("invalid redaction policy")
+)
+
+// DefaultRedactionPolicy returns the configured policy: enabled unless turned off, masking the
+// configured kinds or, by default, all of them. Unknown kinds are an error.
+func DefaultRedactionPolicy(cfg configs.RedactionConfig) (ai.RedactionPolicy, error) {
+	if cfg.Enabled != nil && !*cfg.Enabled {
+		return ai.RedactionPolicy{}, nil
+	}
+	kinds, err := parsePIIKinds(cfg.Kinds)
+	if err != nil {
+		return ai.RedactionPolicy{}, err
+	}
+	return ai.RedactionPolicy{Kinds: kinds}, nil
+}
+
+// parsePIIKinds validates kinds and returns them in detection order; none means all.
+func parsePIIKinds(names []string) ([]ai.PIIKind, error) {
+	if len(names) == 0 {
+		return ai.PIIKinds, nil
+	}
+	wanted := make(map[ai.PIIKind]bool, len(names))
+	for _, name := range names {
+		kind := ai.PIIKind(name)
+		known := false
+		for _, k := range ai.PIIKinds {
+			known = known || k == kind
+		}
+		if !known {
+			return nil, fmt.Errorf("%w: unknown kind %q", ErrRedactionInvalid, name)
+		}
+		wanted[kind] = true
+	}
+	var kinds []ai.PIIKind
+	for _, k := range ai.PIIKinds {
+		if wanted[k] {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds, nil
+}
+
+// RedactionService applies each organization's PII redaction policy to the AI calls made for its
+// members and keeps the audit log. For calls made outside a user's scope it serves as the scope
+// itself, with the default policy.
+type RedactionService struct {
+	db       *gorm.DB
+	defaults ai.RedactionPolicy
+	now      func() time.Time
+}
+
+// NewRedactionService creates a RedactionService with the configured default policy.
+func NewRedactionService(db *gorm.DB, cfg configs.RedactionConfig) (*RedactionService, error) {
+	defaults, err := DefaultRedactionPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &RedactionService{db: db, defaults: defaults, now: time.Now}, nil
+}
+
+// Scope returns a context whose AI calls are redacted with the policy of userID's primary
+// organization.
+func (s *RedactionService) Scope(ctx context.Context, userID uuid.UUID) context.Context {
+	return ai.WithRedactionScope(ctx, &redactionScope{service: s, userID: userID})
+}
+
+// Policy returns the default policy, for calls made outside a user's scope.
+func (s *RedactionService) Policy(ctx context.Context) ai.RedactionPolicy {
+	return s.defaults
+}
+
+// Audit records a call made outside a user's scope.
+func (s *RedactionService) Audit(ctx context.Context, capability ai.Capability, counts map[ai.PIIKind]int) {
+	s.audit(ctx, nil, nil, capability, counts)
+}
+
+type redactionScope struct {
+	service *RedactionService
+	userID  uuid.UUID
+
+	once   sync.Once
+	orgID  *uuid.UUID
+	policy ai.RedactionPolicy
+}
+
+// Policy looks the policy up once per scope, i.e. per request or task.
+func (r *redactionScope) Policy(ctx context.Context) ai.RedactionPolicy {
+	r.once.Do(func() {
+		orgID, policy, err := r.service.userPolicy(ctx, r.userID)
+		if err != nil {
+			// Fail closed: mask everything rather than risk sending what the policy would mask.
+			fmt.Printf("Warning: failed to load redaction policy, masking all PII: %v\n", err)
+			policy = ai.RedactionPolicy{Kinds: ai.PIIKinds}
+		}
+		r.orgID, r.policy = orgID, policy
+	})
+	return r.policy
+}
+
+func (r *redactionScope) Audit(ctx context.Context, capability ai.Capability, counts map[ai.PIIKind]int) {
+	r.service.audit(ctx, r.orgID, &r.userID, capability, counts)
+}
+
+// userPolicy returns the user's primary organization, the first one they joined, and its policy.
+func (s *RedactionService) userPolicy(ctx context.Context, userID uuid.UUID) (*uuid.UUID, ai.RedactionPolicy, error) {
+	var members []model.OrganizationMember
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("joined_at ASC").Limit(1).Find(&members).Error; err != nil {
+		return nil, ai.RedactionPolicy{}, err
+	}
+	if len(members) == 0 {
+		return nil, s.defaults, nil
+	}
+	orgID := members[0].OrganizationID
+	policy, _, err := s.orgPolicy(ctx, orgID)
+	return &orgID, policy, err
+}
+
+// orgPolicy returns the organization's policy and whether it has one of its own.
+func (s *RedactionService) orgPolicy(ctx context.Context, orgID uuid.UUID) (ai.RedactionPolicy, *model.RedactionPolicy, error) {
+	var stored model.RedactionPolicy
+	err := s.db.WithContext(ctx).Where("org_id = ?", orgID).Take(&stored).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.defaults, nil, nil
+	}
+	if err != nil {
+		return ai.RedactionPolicy{}, nil, err
+	}
+	if !stored.Enabled {
+		return ai.RedactionPolicy{}, &stored, nil
+	}
+	var names []string
+	if len(stored.Kinds) > 0 {
+		if err := json.Unmarshal(stored.Kinds, &names); err != nil {
+			return ai.RedactionPolicy{}, nil, err
+		}
+	}
+	kinds, err := parsePIIKinds(names)
+	if err != nil {
+		return ai.RedactionPolicy{}, nil, err
+	}
+	return ai.RedactionPolicy{Kinds: kinds}, &stored, nil
+}
+
+func (s *RedactionService) audit(ctx context.Context, orgID, userID *uuid.UUID, capability ai.Capability, counts map[ai.PIIKind]int) {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	entry := model.RedactionAudit{
+		ID:         uuid.New(),
+		CreatedAt:  s.now().UTC(),
+		OrgID:      orgID,
+		UserID:     userID,
+		Capability: string(capability),
+		Email:      counts[ai.PIIEmail],
+		Phone:      counts[ai.PIIPhone],
+		NationalID: counts[ai.PIINationalID],
+		Card:       counts[ai.PIICard],
+		IBAN:       counts[ai.PIIIBAN],
+		Total:      total,
+	}
+	// The call goes ahead even if the request that made it is cancelled, so its audit entry must too.
+	if err := s.db.WithContext(context.WithoutCancel(ctx)).Create(&entry).Error; err != nil {
+		fmt.Printf("Warning: failed to record redaction audit: %v\n", err)
+	}
+}
+
+// RedactionPolicyInfo is an organization's effective policy.
+type RedactionPolicyInfo struct {
+	OrgID     uuid.UUID  `json:"org_id"`
+	Enabled   bool       `json:"enabled"`
+	Kinds     []string   `json:"kinds"`
+	Default   bool       `json:"default"` // The organization uses the configured default
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty"`
+}
+
+// GetPolicy returns the organization's policy to any of its members.
+func (s *RedactionService) GetPolicy(ctx context.Context, orgID, userID uuid.UUID) (*RedactionPolicyInfo, error) {
+	if _, err := s.member(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	policy, stored, err := s.orgPolicy(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	info := &RedactionPolicyInfo{OrgID: orgID, Enabled: policy.Enabled(), Kinds: []string{}, Default: stored == nil}
+	for _, k := range policy.Kinds {
+		info.Kinds = append(info.Kinds, string(k))
+	}
+	if stored != nil {
+		info.UpdatedAt, info.UpdatedBy = &stored.UpdatedAt, &stored.UpdatedBy
+	}
+	return info, nil
+}
+
+// SetPolicy replaces the organization's policy. Only owners and admins may change it.
+func (s *RedactionService) SetPolicy(ctx context.Context, orgID, userID uuid.UUID, input model.RedactionPolicyInput) (*RedactionPolicyInfo, error) {
+	if err := s.checkAdmin(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	kinds, err := parsePIIKinds(input.Kinds)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(kinds))
+	for i, k := range kinds {
+		names[i] = string(k)
+	}
+	kindsJSON, err := json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+	stored := model.RedactionPolicy{
+		OrgID:     orgID,
+		UpdatedAt: s.now().UTC(),
+		Enabled:   input.Enabled != nil && *input.Enabled,
+		Kinds:     datatypes.JSON(kindsJSON),
+		UpdatedBy: userID,
+	}
+	if err := s.db.WithContext(ctx).Save(&stored).Error; err != nil {
+		return nil, err
+	}
+	return s.GetPolicy(ctx, orgID, userID)
+}
+
+// RedactionAuditPage is a page of an organization's audit log with totals over the whole log.
+type RedactionAuditPage struct {
+	Entries []model.RedactionAudit `json:"entries"`
+	Totals  RedactionAuditTotals   `json:"totals"`
+}
+
+// RedactionAuditTotals sums the audit log.
+type RedactionAuditTotals struct {
+	Calls      int64 `json:"calls"` // Calls that masked anything
+	Email      int64 `json:"email"`
+	Phone      int64 `json:"phone"`
+	NationalID int64 `json:"national_id"`
+	Card       int64 `json:"card"`
+	IBAN       int64 `json:"iban"`
+	Total      int64 `json:"total"`
+}
+
+// AuditLog returns the organization's audit entries, newest first. Only owners and admins may read it.
+func (s *RedactionService) AuditLog(ctx context.Context, orgID, userID uuid.UUID, limit, offset int) (*RedactionAuditPage, error) {
+	if err := s.checkAdmin(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	page := &RedactionAuditPage{Entries: []model.RedactionAudit{}}
+	q := s.db.WithContext(ctx).Model(&model.RedactionAudit{}).Where("org_id = ?", orgID)
+	if err := q.Session(&gorm.Session{}).Order("created_at DESC").Limit(limit).Offset(offset).Find(&page.Entries).Error; err != nil {
+		return nil, err
+	}
+	err := q.Session(&gorm.Session{}).Select(`COUNT(*) AS calls,
+		COALESCE(SUM(email), 0) AS email, COALESCE(SUM(phone), 0) AS phone,
+		COALESCE(SUM(national_id), 0) AS national_id, COALESCE(SUM(card), 0) AS card,
+		COALESCE(SUM(iban), 0) AS iban, COALESCE(SUM(total), 0) AS total`).Scan(&page.Totals).Error
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *RedactionService) member(ctx context.Context, orgID, userID uuid.UUID) (*model.OrganizationMember, error) {
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).Take(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRedactionForbidden
+	}
+	return &member, err
+}
+
+func (s *RedactionService) checkAdmin(ctx context.Context, orgID, userID uuid.UUID) error {
+	member, err := s.member(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role != model.OrgRoleOwner && member.Role != model.OrgRoleAdmin {
+		return ErrRedactionForbidden
+	}
+	return nil
+}
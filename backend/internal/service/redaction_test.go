@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestRedactionService(t *testing.T, cfg configs.RedactionConfig) (*RedactionService, *gorm.DB, uuid.UUID) {
+	db, err := gorm.Open(sqlite.Open("file:redaction?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Organization{}, &model.OrganizationMember{}, &model.RedactionPolicy{}, &model.RedactionAudit{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM redaction_audits")
+		db.Exec("DELETE FROM redaction_policies")
+		db.Exec("DELETE FROM organization_members")
+		db.Exec("DELETE FROM organizations")
+		db.Exec("DELETE FROM users")
+	})
+	orgID := uuid.New()
+	require.NoError(t, db.Create(&model.Organization{ID: orgID, Name: "Acme", Slug: "acme-" + orgID.String(), OwnerID: uuid.New()}).Error)
+	svc, err := NewRedactionService(db, cfg)
+	require.NoError(t, err)
+	svc.now = func() time.Time { return time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC) }
+	return svc, db, orgID
+}
+
+func TestDefaultRedactionPolicy(t *testing.T) {
+	policy, err := DefaultRedactionPolicy(configs.RedactionConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, ai.PIIKinds, policy.Kinds, "everything is masked by default")
+
+	policy, err = DefaultRedactionPolicy(configs.RedactionConfig{Kinds: []string{"phone", "email"}})
+	require.NoError(t, err)
+	assert.Equal(t, []ai.PIIKind{ai.PIIEmail, ai.PIIPhone}, policy.Kinds)
+
+	off := false
+	policy, err = DefaultRedactionPolicy(configs.RedactionConfig{Enabled: &off})
+	require.NoError(t, err)
+	assert.False(t, policy.Enabled())
+
+	_, err = DefaultRedactionPolicy(configs.RedactionConfig{Kinds: []string{"passport"}})
+	assert.ErrorIs(t, err, ErrRedactionInvalid)
+}
+
+func TestRedactionService_PolicyPerOrganization(t *testing.T) {
+	svc, db, orgID := newTestRedactionService(t, configs.RedactionConfig{})
+	admin := seedPromptTestUser(t, db, orgID, "Ada", model.OrgRoleOwner)
+	member := seedPromptTestUser(t, db, orgID, "Bob", model.OrgRoleMember)
+	ctx := context.Background()
+
+	info, err := svc.GetPolicy(ctx, orgID, member)
+	require.NoError(t, err)
+	assert.True(t, info.Default)
+	assert.Equal(t, []string{"email", "iban", "national_id", "card", "phone"}, info.Kinds)
+
+	enabled := true
+	_, err = svc.SetPolicy(ctx, orgID, member, model.RedactionPolicyInput{Enabled: &enabled})
+	assert.ErrorIs(t, err, ErrRedactionForbidden)
+	_, err = svc.SetPolicy(ctx, orgID, admin, model.RedactionPolicyInput{Enabled: &enabled, Kinds: []string{"ssn"}})
+	assert.ErrorIs(t, err, ErrRedactionInvalid)
+	_, err = svc.GetPolicy(ctx, orgID, uuid.New())
+	assert.ErrorIs(t, err, ErrRedactionForbidden)
+
+	info, err = svc.SetPolicy(ctx, orgID, admin, model.RedactionPolicyInput{Enabled: &enabled, Kinds: []string{"card", "email"}})
+	require.NoError(t, err)
+	assert.False(t, info.Default)
+	assert.Equal(t, []string{"email", "card"}, info.Kinds)
+	assert.Equal(t, admin, *info.UpdatedBy)
+
+	scope, ok := ai.RedactionScopeFrom(svc.Scope(ctx, member))
+	require.True(t, ok)
+	assert.Equal(t, []ai.PIIKind{ai.PIIEmail, ai.PIICard}, scope.Policy(ctx).Kinds)
+
+	// Users outside any organization get the default policy.
+	outsider, ok := ai.RedactionScopeFrom(svc.Scope(ctx, uuid.New()))
+	require.True(t, ok)
+	assert.Equal(t, ai.PIIKinds, outsider.Policy(ctx).Kinds)
+
+	disabled := false
+	info, err = svc.SetPolicy(ctx, orgID, admin, model.RedactionPolicyInput{Enabled: &disabled})
+	require.NoError(t, err)
+	assert.False(t, info.Enabled)
+	assert.Empty(t, info.Kinds)
+	scope, _ = ai.RedactionScopeFrom(svc.Scope(ctx, member))
+	assert.False(t, scope.Policy(ctx).Enabled())
+}
+
+func TestRedactionService_FailsClosed(t *testing.T) {
+	svc, db, orgID := newTestRedactionService(t, configs.RedactionConfig{Kinds: []string{"email"}})
+	member := seedPromptTestUser(t, db, orgID, "Bob", model.OrgRoleMember)
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("fail_redaction_test", func(tx *gorm.DB) {
+		tx.AddError(errors.New("database is down"))
+	}))
+	t.Cleanup(func() { _ = db.Callback().Query().Remove("fail_redaction_test") })
+
+	scope, _ := ai.RedactionScopeFrom(svc.Scope(context.Background(), member))
+	assert.Equal(t, ai.PIIKinds, scope.Policy(context.Background()).Kinds, "an unknown policy masks everything")
+}
+
+func TestRedactionService_AuditLog(t *testing.T) {
+	svc, db, orgID := newTestRedactionService(t, configs.RedactionConfig{})
+	admin := seedPromptTestUser(t, db, orgID, "Ada", model.OrgRoleAdmin)
+	member := seedPromptTestUser(t, db, orgID, "Bob", model.OrgRoleMember)
+	ctx := context.Background()
+
+	scope, _ := ai.RedactionScopeFrom(svc.Scope(ctx, member))
+	scope.Policy(ctx)
+	scope.Audit(ctx, ai.CapabilitySummary, map[ai.PIIKind]int{ai.PIIEmail: 2, ai.PIIPhone: 1})
+	scope.Audit(ctx, ai.CapabilityChat, map[ai.PIIKind]int{ai.PIICard: 1})
+	svc.Audit(ctx, ai.CapabilitySummary, map[ai.PIIKind]int{ai.PIIEmail: 5}) // Unscoped: no organization
+
+	_, err := svc.AuditLog(ctx, orgID, member, 0, 0)
+	assert.ErrorIs(t, err, ErrRedactionForbidden)
+
+	page, err := svc.AuditLog(ctx, orgID, admin, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, page.Entries, 1)
+	assert.Equal(t, member, *page.Entries[0].UserID)
+	assert.Equal(t, RedactionAuditTotals{Calls: 2, Email: 2, Phone: 1, Card: 1, Total: 4}, page.Totals)
+
+	var all int64
+	require.NoError(t, db.Model(&model.RedactionAudit{}).Count(&all).Error)
+	assert.Equal(t, int64(3), all)
+}
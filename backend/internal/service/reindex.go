@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/tasks"
+	"github.com/hrygo/echomind/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultReindexBatchSize = 100
+	defaultReindexWorkers   = 4
+	reindexCheckpointTTL    = 7 * 24 * time.Hour
+)
+
+// ReindexCheckpointStore persists reindex progress between runs.
+type ReindexCheckpointStore interface {
+	// Load returns the checkpoint for a job, or nil if there is none.
+	Load(ctx context.Context, jobID string) (*model.ReindexCheckpoint, error)
+	Save(ctx context.Context, cp *model.ReindexCheckpoint) error
+	Delete(ctx context.Context, jobID string) error
+}
+
+// DBReindexCheckpointStore keeps checkpoints in the reindex_checkpoints table.
+type DBReindexCheckpointStore struct {
+	db *gorm.DB
+}
+
+func NewDBReindexCheckpointStore(db *gorm.DB) *DBReindexCheckpointStore {
+	return &DBReindexCheckpointStore{db: db}
+}
+
+func (s *DBReindexCheckpointStore) Load(ctx context.Context, jobID string) (*model.ReindexCheckpoint, error) {
+	var cp model.ReindexCheckpoint
+	err := s.db.WithContext(ctx).First(&cp, "job_id = ?", jobID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reindex checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func (s *DBReindexCheckpointStore) Save(ctx context.Context, cp *model.ReindexCheckpoint) error {
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(cp).Error; err != nil {
+		return fmt.Errorf("failed to save reindex checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *DBReindexCheckpointStore) Delete(ctx context.Context, jobID string) error {
+	return s.db.WithContext(ctx).Delete(&model.ReindexCheckpoint{}, "job_id = ?", jobID).Error
+}
+
+// RedisReindexCheckpointStore keeps checkpoints in Redis; they expire after a week of inactivity.
+type RedisReindexCheckpointStore struct {
+	redis *redis.Client
+}
+
+func NewRedisReindexCheckpointStore(client *redis.Client) *RedisReindexCheckpointStore {
+	return &RedisReindexCheckpointStore{redis: client}
+}
+
+func reindexCheckpointKey(jobID string) string {
+	return "reindex:checkpoint:" + jobID
+}
+
+func (s *RedisReindexCheckpointStore) Load(ctx context.Context, jobID string) (*model.ReindexCheckpoint, error) {
+	data, err := s.redis.Get(ctx, reindexCheckpointKey(jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load reindex checkpoint: %w", err)
+	}
+	var cp model.ReindexCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to decode reindex checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func (s *RedisReindexCheckpointStore) Save(ctx context.Context, cp *model.ReindexCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := s.redis.Set(ctx, reindexCheckpointKey(cp.JobID), data, reindexCheckpointTTL).Err(); err != nil {
+		return fmt.Errorf("failed to save reindex checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisReindexCheckpointStore) Delete(ctx context.Context, jobID string) error {
+	return s.redis.Del(ctx, reindexCheckpointKey(jobID)).Err()
+}
+
+// ReindexProgress is reported after every batch and at the end of a run.
+type ReindexProgress struct {
+	JobID     string        `json:"job_id"`
+	Matched   int64         `json:"matched"`   // Emails selected by the filters
+	Remaining int64         `json:"remaining"` // Emails after the checkpoint cursor when the run started
+	Processed int64         `json:"processed"` // Cumulative across resumed runs
+	Embedded  int64         `json:"embedded"`
+	Failed    int64         `json:"failed"`
+	Resumed   bool          `json:"resumed"`
+	Done      bool          `json:"done"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// ReindexService regenerates email embeddings in resumable batches with a rate-limited worker pool.
+type ReindexService struct {
+	db          *gorm.DB
+	search      *SearchService
+	checkpoints ReindexCheckpointStore
+	chunkSize   int
+	log         logger.Logger
+}
+
+var _ tasks.EmailReindexer = (*ReindexService)(nil)
+
+func NewReindexService(db *gorm.DB, search *SearchService, checkpoints ReindexCheckpointStore, chunkSize int, log logger.Logger) *ReindexService {
+	return &ReindexService{db: db, search: search, checkpoints: checkpoints, chunkSize: chunkSize, log: log}
+}
+
+// ReindexJobID derives a stable checkpoint name from the filters, so rerunning the same command resumes it.
+func ReindexJobID(p tasks.ReindexPayload) string {
+	if p.JobID != "" {
+		return p.JobID
+	}
+	filters, _ := json.Marshal(struct {
+		UserID      *uuid.UUID
+		Since       *time.Time
+		Until       *time.Time
+		MissingOnly bool
+	}{p.UserID, p.Since, p.Until, p.MissingOnly})
+	sum := sha256.Sum256(filters)
+	return "reindex-" + hex.EncodeToString(sum[:8])
+}
+
+// ResetCheckpoint discards the checkpoint of the job p selects, so that its next run starts over.
+func (s *ReindexService) ResetCheckpoint(ctx context.Context, p tasks.ReindexPayload) error {
+	if err := s.checkpoints.Delete(ctx, ReindexJobID(p)); err != nil {
+		return fmt.Errorf("failed to delete reindex checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Reindex implements tasks.EmailReindexer.
+func (s *ReindexService) Reindex(ctx context.Context, p tasks.ReindexPayload) (tasks.ReindexResult, error) {
+	progress, err := s.Run(ctx, p, nil)
+	return tasks.ReindexResult{
+		JobID:     progress.JobID,
+		Matched:   progress.Matched,
+		Processed: progress.Processed,
+		Embedded:  progress.Embedded,
+		Failed:    progress.Failed,
+		Done:      progress.Done,
+	}, err
+}
+
+// Run processes the selected emails in id order, checkpointing after each batch. A checkpoint is only
+// advanced once every email of the batch has been attempted, so an interrupted run resumes at the first
+// unfinished batch. Emails that fail are counted and skipped; rerun with MissingOnly to retry them.
+// In dry-run mode only the counts are computed.
+func (s *ReindexService) Run(ctx context.Context, p tasks.ReindexPayload, onBatch func(ReindexProgress)) (ReindexProgress, error) {
+	progress := ReindexProgress{JobID: ReindexJobID(p)}
+	start := time.Now()
+	if p.BatchSize <= 0 {
+		p.BatchSize = defaultReindexBatchSize
+	}
+	if p.Workers <= 0 {
+		p.Workers = defaultReindexWorkers
+	}
+
+	// Resolving the target space up front also creates it before workers race to do so.
+	table, _, err := s.search.writeSpace(ctx)
+	if err != nil {
+		return progress, err
+	}
+
+	cp := &model.ReindexCheckpoint{JobID: progress.JobID, StartedAt: start}
+	if !p.Restart {
+		existing, err := s.checkpoints.Load(ctx, progress.JobID)
+		if err != nil {
+			return progress, err
+		}
+		// A finished job starts over; an unfinished one picks up where it stopped.
+		if existing != nil && !existing.Done {
+			cp = existing
+			progress.Resumed = true
+		}
+	}
+	progress.Processed, progress.Embedded, progress.Failed = cp.Processed, cp.Embedded, cp.Failed
+
+	if err := s.selectEmails(ctx, p, table).Count(&progress.Matched).Error; err != nil {
+		return progress, fmt.Errorf("failed to count emails: %w", err)
+	}
+	if err := s.selectEmails(ctx, p, table).Where("id > ?", cp.Cursor).Count(&progress.Remaining).Error; err != nil {
+		return progress, fmt.Errorf("failed to count emails: %w", err)
+	}
+	if p.DryRun {
+		progress.Elapsed = time.Since(start)
+		return progress, nil
+	}
+
+	limit := rate.Inf
+	if p.RatePerSecond > 0 {
+		limit = rate.Limit(p.RatePerSecond)
+	}
+	limiter := rate.NewLimiter(limit, p.Workers)
+
+	for {
+		var batch []model.Email
+		err := s.selectEmails(ctx, p, table).
+			Select("id, user_id, subject, snippet, body_text").
+			Where("id > ?", cp.Cursor).
+			Order("id").
+			Limit(p.BatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return progress, fmt.Errorf("failed to load emails: %w", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		embedded, failed := s.embedBatch(ctx, batch, p.Workers, limiter)
+		if err := ctx.Err(); err != nil {
+			// Part of the batch was cut short; leave the checkpoint before it.
+			return progress, err
+		}
+
+		cp.Cursor = batch[len(batch)-1].ID
+		cp.Processed += int64(len(batch))
+		cp.Embedded += embedded
+		cp.Failed += failed
+		if err := s.checkpoints.Save(ctx, cp); err != nil {
+			return progress, err
+		}
+
+		progress.Processed, progress.Embedded, progress.Failed = cp.Processed, cp.Embedded, cp.Failed
+		progress.Elapsed = time.Since(start)
+		if onBatch != nil {
+			onBatch(progress)
+		}
+	}
+
+	cp.Done = true
+	if err := s.checkpoints.Save(ctx, cp); err != nil {
+		return progress, err
+	}
+	progress.Done = true
+	progress.Elapsed = time.Since(start)
+	return progress, nil
+}
+
+// selectEmails applies the job's filters to a query over received emails; sent mail is not embedded.
+func (s *ReindexService) selectEmails(ctx context.Context, p tasks.ReindexPayload, table string) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&model.Email{}).Where("folder <> ?", model.FolderSent)
+	if p.UserID != nil {
+		query = query.Where("user_id = ?", *p.UserID)
+	}
+	if p.Since != nil {
+		query = query.Where("date >= ?", *p.Since)
+	}
+	if p.Until != nil {
+		query = query.Where("date < ?", *p.Until)
+	}
+	if p.MissingOnly {
+		query = query.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s ee WHERE ee.email_id = emails.id)", table))
+	}
+	return query
+}
+
+// embedBatch embeds a batch with a pool of workers that share the provider rate limit.
+func (s *ReindexService) embedBatch(ctx context.Context, batch []model.Email, workers int, limiter *rate.Limiter) (int64, int64) {
+	var embedded, failed atomic.Int64
+	jobs := make(chan *model.Email)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for email := range jobs {
+				if err := limiter.Wait(ctx); err != nil {
+					failed.Add(1)
+					continue
+				}
+				if err := s.search.GenerateAndSaveEmbedding(ctx, email, s.chunkSize); err != nil {
+					failed.Add(1)
+					s.log.WarnContext(ctx, "Failed to reindex email",
+						logger.String("email_id", email.ID.String()),
+						logger.Error(err),
+						logger.String("component", "reindexer"))
+					continue
+				}
+				embedded.Add(1)
+			}
+		}()
+	}
+	for i := range batch {
+		jobs <- &batch[i]
+	}
+	close(jobs)
+	wg.Wait()
+	return embedded.Load(), failed.Load()
+}
@@ -0,0 +1,161 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/tasks"
+	"github.com/hrygo/echomind/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// hookEmbedder runs a hook before each batch, e.g. to simulate a crash mid-run.
+type hookEmbedder struct {
+	fakeEmbedder
+	calls  atomic.Int32
+	before func(call int32)
+}
+
+func (h *hookEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	call := h.calls.Add(1)
+	if h.before != nil {
+		h.before(call)
+	}
+	return h.fakeEmbedder.EmbedBatch(ctx, texts)
+}
+
+func setupReindexTest(t *testing.T, embedder *hookEmbedder) (*gorm.DB, *ReindexService, ReindexCheckpointStore) {
+	db := setupEmbeddingSpaceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&model.ReindexCheckpoint{}))
+	search := NewSearchService(db, embedder, nil)
+	store := NewDBReindexCheckpointStore(db)
+	return db, NewReindexService(db, search, store, 100, logger.GetDefaultLogger()), store
+}
+
+func TestReindexService_DryRunFilters(t *testing.T) {
+	db, svc, store := setupReindexTest(t, &hookEmbedder{fakeEmbedder: fakeEmbedder{model: "m", dims: 4}})
+	ctx := context.Background()
+	ids := seedEmbeddingEmails(t, db, 3)
+	var target model.Email
+	require.NoError(t, db.First(&target, "id = ?", ids[0]).Error)
+	old := time.Now().AddDate(-1, 0, 0)
+	require.NoError(t, db.Model(&model.Email{}).Where("id = ?", ids[1]).Update("date", old).Error)
+
+	progress, err := svc.Run(ctx, tasks.ReindexPayload{DryRun: true}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), progress.Matched)
+	assert.Equal(t, int64(3), progress.Remaining)
+	assert.Zero(t, progress.Processed)
+
+	since := time.Now().AddDate(0, -1, 0)
+	progress, err = svc.Run(ctx, tasks.ReindexPayload{DryRun: true, UserID: &target.UserID, Since: &since}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), progress.Matched)
+
+	cp, err := store.Load(ctx, progress.JobID)
+	require.NoError(t, err)
+	assert.Nil(t, cp, "dry runs do not checkpoint")
+}
+
+func TestReindexService_ResumesFromCheckpoint(t *testing.T) {
+	embedder := &hookEmbedder{fakeEmbedder: fakeEmbedder{model: "m", dims: 4}}
+	db, svc, store := setupReindexTest(t, embedder)
+	seedEmbeddingEmails(t, db, 5)
+
+	// Crash while embedding the second batch.
+	ctx, cancel := context.WithCancel(context.Background())
+	embedder.before = func(call int32) {
+		if call == 3 {
+			cancel()
+		}
+	}
+	payload := tasks.ReindexPayload{BatchSize: 2, Workers: 1}
+	var batches int
+	_, err := svc.Run(ctx, payload, func(ReindexProgress) { batches++ })
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, batches)
+
+	cp, err := store.Load(context.Background(), ReindexJobID(payload))
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, int64(2), cp.Processed)
+	assert.False(t, cp.Done)
+
+	embedder.before = nil
+	progress, err := svc.Run(context.Background(), payload, nil)
+	require.NoError(t, err)
+	assert.True(t, progress.Resumed)
+	assert.True(t, progress.Done)
+	assert.Equal(t, int64(3), progress.Remaining)
+	assert.Equal(t, int64(5), progress.Processed)
+	assert.Equal(t, int64(5), progress.Embedded+progress.Failed)
+
+	var covered int64
+	require.NoError(t, db.Raw("SELECT COUNT(DISTINCT email_id) FROM email_embeddings").Scan(&covered).Error)
+	assert.Equal(t, int64(5), covered)
+
+	// Nothing is missing any more, and a finished job starts over instead of resuming.
+	progress, err = svc.Run(context.Background(), tasks.ReindexPayload{MissingOnly: true, DryRun: true}, nil)
+	require.NoError(t, err)
+	assert.Zero(t, progress.Matched)
+	progress, err = svc.Run(context.Background(), payload, nil)
+	require.NoError(t, err)
+	assert.False(t, progress.Resumed)
+	assert.Equal(t, int64(5), progress.Processed)
+
+	// Enqueued restarts reset the checkpoint once, up front.
+	require.NoError(t, svc.ResetCheckpoint(context.Background(), payload))
+	cp, err = store.Load(context.Background(), ReindexJobID(payload))
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestReindexService_ParallelWorkers(t *testing.T) {
+	embedder := &hookEmbedder{fakeEmbedder: fakeEmbedder{model: "m", dims: 4}}
+	db, svc, _ := setupReindexTest(t, embedder)
+	seedEmbeddingEmails(t, db, 7)
+
+	progress, err := svc.Run(context.Background(), tasks.ReindexPayload{BatchSize: 3, Workers: 3, RatePerSecond: 1000}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), progress.Processed)
+	assert.Equal(t, int32(7), embedder.calls.Load())
+}
+
+func TestRedisReindexCheckpointStore(t *testing.T) {
+	_, client := setupTestRedis(t)
+	defer client.Close()
+	store := NewRedisReindexCheckpointStore(client)
+	ctx := context.Background()
+
+	cp, err := store.Load(ctx, "job")
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+
+	cursor := uuid.New()
+	require.NoError(t, store.Save(ctx, &model.ReindexCheckpoint{JobID: "job", Cursor: cursor, Processed: 10}))
+	cp, err = store.Load(ctx, "job")
+	require.NoError(t, err)
+	require.NotNil(t, cp)
+	assert.Equal(t, cursor, cp.Cursor)
+	assert.Equal(t, int64(10), cp.Processed)
+
+	require.NoError(t, store.Delete(ctx, "job"))
+	cp, err = store.Load(ctx, "job")
+	require.NoError(t, err)
+	assert.Nil(t, cp)
+}
+
+func TestReindexJobID(t *testing.T) {
+	user := uuid.New()
+	a := ReindexJobID(tasks.ReindexPayload{UserID: &user, BatchSize: 10})
+	b := ReindexJobID(tasks.ReindexPayload{UserID: &user, BatchSize: 50, Workers: 8})
+	assert.Equal(t, a, b, "tuning knobs do not change the job")
+	assert.NotEqual(t, a, ReindexJobID(tasks.ReindexPayload{UserID: &user, MissingOnly: true}))
+	assert.Equal(t, "nightly", ReindexJobID(tasks.ReindexPayload{JobID: "nightly"}))
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/event"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/internal/tasks"
+	"github.com/hrygo/echomind/pkg/event/bus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// SavedSearchFolderPrefix marks a ListEmails folder that shows a saved search, e.g. "saved:<id>".
+	SavedSearchFolderPrefix = "saved:"
+
+	defaultSavedSearchMinScore = 0.5
+	savedSearchFolderLimit     = 500  // Matches shown in a virtual folder
+	savedSearchEvaluateLimit   = 1000 // Newly analyzed emails checked per saved search and run
+)
+
+var (
+	ErrSavedSearchNotFound = errors.New("saved search not found")
+	ErrSavedSearchEmpty    = errors.New("saved search needs a query or at least one filter")
+	ErrSavedSearchInvalid  = errors.New("invalid saved search")
+)
+
+// SavedSearchService stores searches, resolves them as virtual folders and alerts on new matches.
+type SavedSearchService struct {
+	db     *gorm.DB
+	search *SearchService
+	bus    *bus.Bus
+}
+
+var _ tasks.SavedSearchEvaluator = (*SavedSearchService)(nil)
+
+func NewSavedSearchService(db *gorm.DB, search *SearchService, eventBus *bus.Bus) *SavedSearchService {
+	return &SavedSearchService{db: db, search: search, bus: eventBus}
+}
+
+// Create validates and stores a saved search.
+func (s *SavedSearchService) Create(ctx context.Context, userID uuid.UUID, input model.SavedSearchInput) (*model.SavedSearch, error) {
+	saved := &model.SavedSearch{
+		ID:            uuid.New(),
+		UserID:        userID,
+		ShowInFolders: true,
+		MinScore:      defaultSavedSearchMinScore,
+	}
+	if err := applySavedSearchInput(saved, input); err != nil {
+		return nil, err
+	}
+	// Only mail analyzed from now on counts as new.
+	now := time.Now()
+	saved.LastEvaluatedAt = &now
+	if err := s.db.WithContext(ctx).Create(saved).Error; err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// List returns the user's saved searches, or with foldersOnly only those shown as folders.
+func (s *SavedSearchService) List(ctx context.Context, userID uuid.UUID, foldersOnly bool) ([]model.SavedSearch, error) {
+	var saved []model.SavedSearch
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if foldersOnly {
+		query = query.Where("show_in_folders = ?", true)
+	}
+	if err := query.Order("name").Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Get returns one of the user's saved searches.
+func (s *SavedSearchService) Get(ctx context.Context, userID, id uuid.UUID) (*model.SavedSearch, error) {
+	var saved model.SavedSearch
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&saved).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSavedSearchNotFound
+		}
+		return nil, err
+	}
+	return &saved, nil
+}
+
+// Update replaces the query, filters and options of a saved search.
+func (s *SavedSearchService) Update(ctx context.Context, userID, id uuid.UUID, input model.SavedSearchInput) (*model.SavedSearch, error) {
+	saved, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySavedSearchInput(saved, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Save(saved).Error; err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// Delete removes a saved search and its match history.
+func (s *SavedSearchService) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", id, userID).Delete(&model.SavedSearch{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSavedSearchNotFound
+		}
+		return tx.Where("saved_search_id = ?", id).Delete(&model.SavedSearchMatch{}).Error
+	})
+}
+
+func applySavedSearchInput(saved *model.SavedSearch, input model.SavedSearchInput) error {
+	input.Query = strings.TrimSpace(input.Query)
+	if _, err := ParseSearchQuery(input.Query); err != nil {
+		return fmt.Errorf("%w: %v", ErrSavedSearchInvalid, err)
+	}
+	if _, err := ParseSearchMode(input.Filters.Mode); err != nil {
+		return fmt.Errorf("%w: %v", ErrSavedSearchInvalid, err)
+	}
+	f := input.Filters
+	if input.Query == "" && f.Sender == "" && f.StartDate == nil && f.EndDate == nil && f.ContextID == nil {
+		return ErrSavedSearchEmpty
+	}
+
+	saved.Name = input.Name
+	saved.Query = input.Query
+	saved.Filters = mustMarshalJSON(input.Filters)
+	if input.ShowInFolders != nil {
+		saved.ShowInFolders = *input.ShowInFolders
+	}
+	if input.Notify != nil {
+		saved.Notify = *input.Notify
+	}
+	if input.MinScore != nil {
+		saved.MinScore = *input.MinScore
+	}
+	return nil
+}
+
+// searchFor turns a saved search into free text and SearchFilters.
+func (s *SavedSearchService) searchFor(saved *model.SavedSearch) (string, SearchFilters, error) {
+	parsed, err := ParseSearchQuery(saved.Query)
+	if err != nil {
+		return "", SearchFilters{}, err
+	}
+	var stored model.SavedSearchFilters
+	if len(saved.Filters) > 0 {
+		if err := json.Unmarshal(saved.Filters, &stored); err != nil {
+			return "", SearchFilters{}, fmt.Errorf("invalid saved search filters: %w", err)
+		}
+	}
+	mode, err := ParseSearchMode(stored.Mode)
+	if err != nil {
+		return "", SearchFilters{}, err
+	}
+	return parsed.Text, SearchFilters{
+		Sender:    stored.Sender,
+		StartDate: stored.StartDate,
+		EndDate:   stored.EndDate,
+		ContextID: stored.ContextID,
+		Mode:      mode,
+		Operators: parsed.Operators,
+	}, nil
+}
+
+// matches returns the emails a saved search matches, best first, optionally restricted to candidates.
+// Semantic hits must clear MinScore and are found exactly, not among the index's approximate nearest
+// neighbours. The cache is bypassed: results have to reflect the latest mail.
+func (s *SavedSearchService) matches(ctx context.Context, saved *model.SavedSearch, candidates []uuid.UUID, limit int) ([]uuid.UUID, error) {
+	text, filters, err := s.searchFor(saved)
+	if err != nil {
+		return nil, err
+	}
+	filters.EmailIDs = candidates
+
+	var rankings [][]SearchResult
+	if text == "" {
+		results, err := s.search.filterSearch(ctx, saved.UserID, filters, limit)
+		if err != nil {
+			return nil, err
+		}
+		rankings = append(rankings, results)
+	} else {
+		if filters.Mode == SearchModeKeyword || filters.Mode == SearchModeHybrid {
+			results, err := s.search.keywordSearch(ctx, saved.UserID, text, filters, limit)
+			if err != nil {
+				return nil, err
+			}
+			rankings = append(rankings, results)
+		}
+		if filters.Mode != SearchModeKeyword {
+			results, err := s.search.semanticMatches(ctx, saved.UserID, text, filters, saved.MinScore, limit)
+			if err != nil {
+				return nil, err
+			}
+			rankings = append(rankings, results)
+		}
+	}
+
+	seen := make(map[uuid.UUID]bool)
+	var ids []uuid.UUID
+	for _, ranking := range rankings {
+		for _, r := range ranking {
+			if !seen[r.EmailID] {
+				seen[r.EmailID] = true
+				ids = append(ids, r.EmailID)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// FolderEmailIDs resolves a saved search used as a virtual folder.
+func (s *SavedSearchService) FolderEmailIDs(ctx context.Context, userID, id uuid.UUID) ([]uuid.UUID, error) {
+	saved, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.matches(ctx, saved, nil, savedSearchFolderLimit)
+}
+
+// EvaluateSavedSearches checks every saved search with alerts enabled against emails analyzed since
+// its last run and publishes a SavedSearchMatchedEvent for emails that match for the first time.
+// It returns the number of new matches.
+func (s *SavedSearchService) EvaluateSavedSearches(ctx context.Context) (int, error) {
+	var saved []model.SavedSearch
+	if err := s.db.WithContext(ctx).Where("notify = ?", true).Find(&saved).Error; err != nil {
+		return 0, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+
+	total := 0
+	var errs []error
+	for i := range saved {
+		n, err := s.evaluate(ctx, &saved[i])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("saved search %s: %w", saved[i].ID, err))
+			continue
+		}
+		total += n
+	}
+	return total, errors.Join(errs...)
+}
+
+func (s *SavedSearchService) evaluate(ctx context.Context, saved *model.SavedSearch) (int, error) {
+	since := saved.CreatedAt
+	if saved.LastEvaluatedAt != nil {
+		since = *saved.LastEvaluatedAt
+	}
+	now := time.Now()
+
+	// Analysis writes the summary, so only analyzed emails are considered. Emails are paged on
+	// (updated_at, id), so that a run stopping at the limit does not skip those updated at the same time.
+	query := s.db.WithContext(ctx).Model(&model.Email{}).
+		Select("id, updated_at").
+		Where("user_id = ? AND summary <> ''", saved.UserID)
+	if saved.LastEvaluatedEmailID != nil {
+		query = query.Where("(updated_at > ? OR (updated_at = ? AND id > ?))", since, since, *saved.LastEvaluatedEmailID)
+	} else {
+		query = query.Where("updated_at > ?", since)
+	}
+	var rows []struct {
+		ID        uuid.UUID
+		UpdatedAt time.Time
+	}
+	err := query.Order("updated_at, id").Limit(savedSearchEvaluateLimit).Scan(&rows).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load new emails: %w", err)
+	}
+	candidates := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		candidates[i] = row.ID
+	}
+	var lastEmailID *uuid.UUID
+	if len(rows) == savedSearchEvaluateLimit {
+		// More is waiting; continue after the last email checked on the next run.
+		last := rows[len(rows)-1]
+		now, lastEmailID = last.UpdatedAt, &last.ID
+	}
+
+	var fresh []uuid.UUID
+	if len(candidates) > 0 {
+		matched, err := s.matches(ctx, saved, candidates, len(candidates))
+		if err != nil {
+			return 0, err
+		}
+		fresh, err = s.recordMatches(ctx, saved.ID, matched)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	updates := map[string]interface{}{"last_evaluated_at": now, "last_evaluated_email_id": lastEmailID}
+	if len(fresh) > 0 {
+		updates["last_matched_at"] = now
+		updates["match_count"] = gorm.Expr("match_count + ?", len(fresh))
+	}
+	if err := s.db.WithContext(ctx).Model(saved).Updates(updates).Error; err != nil {
+		return 0, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	if len(fresh) > 0 && s.bus != nil {
+		if err := s.bus.Publish(ctx, event.SavedSearchMatchedEvent{
+			UserID:        saved.UserID,
+			SavedSearchID: saved.ID,
+			SearchName:    saved.Name,
+			EmailIDs:      fresh,
+		}); err != nil {
+			return len(fresh), fmt.Errorf("failed to publish saved search matches: %w", err)
+		}
+	}
+	return len(fresh), nil
+}
+
+// recordMatches stores matches and returns the emails that had not been reported before, e.g. when
+// an already matched email is re-analyzed.
+func (s *SavedSearchService) recordMatches(ctx context.Context, savedSearchID uuid.UUID, emailIDs []uuid.UUID) ([]uuid.UUID, error) {
+	if len(emailIDs) == 0 {
+		return nil, nil
+	}
+	var known []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&model.SavedSearchMatch{}).
+		Where("saved_search_id = ? AND email_id IN ?", savedSearchID, emailIDs).
+		Pluck("email_id", &known).Error; err != nil {
+		return nil, fmt.Errorf("failed to load previous matches: %w", err)
+	}
+	reported := make(map[uuid.UUID]bool, len(known))
+	for _, id := range known {
+		reported[id] = true
+	}
+
+	var fresh []uuid.UUID
+	var rows []model.SavedSearchMatch
+	for _, id := range emailIDs {
+		if !reported[id] {
+			fresh = append(fresh, id)
+			rows = append(rows, model.SavedSearchMatch{SavedSearchID: savedSearchID, EmailID: id})
+		}
+	}
+	if len(rows) > 0 {
+		if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to record matches: %w", err)
+		}
+	}
+	return fresh, nil
+}
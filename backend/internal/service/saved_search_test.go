@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/event"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/event/bus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupSavedSearchTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:saved_searches?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.Email{}, &model.SavedSearch{}, &model.SavedSearchMatch{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM saved_search_matches")
+		db.Exec("DELETE FROM saved_searches")
+		db.Exec("DELETE FROM emails")
+	})
+	return db
+}
+
+func seedSavedSearchEmail(t *testing.T, db *gorm.DB, userID uuid.UUID, subject string, read bool, updatedAt time.Time) uuid.UUID {
+	id := uuid.New()
+	require.NoError(t, db.Create(&model.Email{
+		ID:        id,
+		UserID:    userID,
+		MessageID: id.String(),
+		Subject:   subject,
+		Summary:   "analyzed",
+		Date:      updatedAt,
+		UpdatedAt: updatedAt,
+	}).Error)
+	if read {
+		require.NoError(t, db.Model(&model.Email{}).Where("id = ?", id).UpdateColumn("is_read", true).Error)
+	}
+	return id
+}
+
+func TestSavedSearchService_CRUD(t *testing.T) {
+	db := setupSavedSearchTestDB(t)
+	svc := NewSavedSearchService(db, NewSearchService(db, nil, nil), nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	_, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Empty"})
+	assert.ErrorIs(t, err, ErrSavedSearchEmpty)
+
+	_, err = svc.Create(ctx, userID, model.SavedSearchInput{Name: "Bad", Query: "has:nothing"})
+	assert.ErrorIs(t, err, ErrSavedSearchInvalid)
+
+	_, err = svc.Create(ctx, userID, model.SavedSearchInput{Name: "Bad mode", Query: "budget", Filters: model.SavedSearchFilters{Mode: "fuzzy"}})
+	assert.ErrorIs(t, err, ErrSavedSearchInvalid)
+
+	saved, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Unread", Query: "is:unread"})
+	require.NoError(t, err)
+	assert.True(t, saved.ShowInFolders)
+	assert.False(t, saved.Notify)
+	assert.Equal(t, defaultSavedSearchMinScore, saved.MinScore)
+
+	_, err = svc.Get(ctx, uuid.New(), saved.ID)
+	assert.ErrorIs(t, err, ErrSavedSearchNotFound, "other users cannot see the search")
+
+	notify := true
+	updated, err := svc.Update(ctx, userID, saved.ID, model.SavedSearchInput{Name: "Unread alerts", Query: "is:unread", Notify: &notify})
+	require.NoError(t, err)
+	assert.Equal(t, "Unread alerts", updated.Name)
+	assert.True(t, updated.Notify)
+
+	hidden := false
+	unfiled, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Budget", Query: "budget", ShowInFolders: &hidden})
+	require.NoError(t, err)
+	stored, err := svc.Get(ctx, userID, unfiled.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.ShowInFolders, "an explicit false is kept")
+
+	list, err := svc.List(ctx, userID, false)
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	folders, err := svc.List(ctx, userID, true)
+	require.NoError(t, err)
+	require.Len(t, folders, 1)
+	assert.Equal(t, saved.ID, folders[0].ID)
+
+	require.NoError(t, svc.Delete(ctx, userID, saved.ID))
+	assert.ErrorIs(t, svc.Delete(ctx, userID, saved.ID), ErrSavedSearchNotFound)
+}
+
+func TestSavedSearchService_FolderEmailIDs(t *testing.T) {
+	db := setupSavedSearchTestDB(t)
+	svc := NewSavedSearchService(db, NewSearchService(db, nil, nil), nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	unread := seedSavedSearchEmail(t, db, userID, "Unread", false, time.Now())
+	seedSavedSearchEmail(t, db, userID, "Read", true, time.Now())
+	seedSavedSearchEmail(t, db, uuid.New(), "Someone else's", false, time.Now())
+
+	saved, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Unread", Query: "is:unread"})
+	require.NoError(t, err)
+
+	ids, err := svc.FolderEmailIDs(ctx, userID, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{unread}, ids)
+
+	_, err = svc.FolderEmailIDs(ctx, uuid.New(), saved.ID)
+	assert.ErrorIs(t, err, ErrSavedSearchNotFound)
+}
+
+func TestSavedSearchService_EvaluateReportsNewMatchesOnce(t *testing.T) {
+	db := setupSavedSearchTestDB(t)
+	eventBus := bus.New()
+	var events []event.SavedSearchMatchedEvent
+	eventBus.Subscribe(event.SavedSearchMatchedEventName, bus.ListenerFunc(func(ctx context.Context, e bus.Event) error {
+		events = append(events, e.(event.SavedSearchMatchedEvent))
+		return nil
+	}))
+	svc := NewSavedSearchService(db, NewSearchService(db, nil, nil), eventBus)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	notify := true
+	saved, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Unread", Query: "is:unread", Notify: &notify})
+	require.NoError(t, err)
+
+	seedSavedSearchEmail(t, db, userID, "Before", false, saved.LastEvaluatedAt.Add(-time.Hour))
+	fresh := seedSavedSearchEmail(t, db, userID, "New", false, time.Now().Add(time.Second))
+	seedSavedSearchEmail(t, db, userID, "New but read", true, time.Now().Add(time.Second))
+
+	matches, err := svc.EvaluateSavedSearches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matches)
+	require.Len(t, events, 1)
+	assert.Equal(t, saved.ID, events[0].SavedSearchID)
+	assert.Equal(t, "Unread", events[0].SearchName)
+	assert.Equal(t, []uuid.UUID{fresh}, events[0].EmailIDs)
+
+	// Re-analysis bumps updated_at, but the email was already reported.
+	require.NoError(t, db.Model(&model.Email{}).Where("id = ?", fresh).UpdateColumn("updated_at", time.Now().Add(time.Hour)).Error)
+	matches, err = svc.EvaluateSavedSearches(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, matches)
+	assert.Len(t, events, 1)
+
+	stored, err := svc.Get(ctx, userID, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.MatchCount)
+	assert.NotNil(t, stored.LastMatchedAt)
+}
+
+func TestSavedSearchService_EvaluatePagesThroughTies(t *testing.T) {
+	db := setupSavedSearchTestDB(t)
+	svc := NewSavedSearchService(db, NewSearchService(db, nil, nil), nil)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	notify := true
+	saved, err := svc.Create(ctx, userID, model.SavedSearchInput{Name: "Unread", Query: "is:unread", Notify: &notify})
+	require.NoError(t, err)
+
+	// A bulk update leaves more emails with the same updated_at than one run checks.
+	analyzedAt := time.Now().Add(time.Second)
+	emails := make([]model.Email, savedSearchEvaluateLimit+1)
+	for i := range emails {
+		id := uuid.New()
+		emails[i] = model.Email{ID: id, UserID: userID, MessageID: id.String(), Summary: "analyzed", Date: analyzedAt, UpdatedAt: analyzedAt}
+	}
+	require.NoError(t, db.CreateInBatches(emails, 200).Error)
+
+	matches, err := svc.EvaluateSavedSearches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, savedSearchEvaluateLimit, matches)
+
+	matches, err = svc.EvaluateSavedSearches(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, matches, "the run continues after the last email checked")
+
+	stored, err := svc.Get(ctx, userID, saved.ID)
+	require.NoError(t, err)
+	assert.Equal(t, len(emails), stored.MatchCount)
+	assert.Nil(t, stored.LastEvaluatedEmailID, "a run that checked everything leaves no cursor")
+}
+
+func TestEmailService_ListEmails_UnknownSavedSearchFolder(t *testing.T) {
+	svc := NewEmailService(setupSavedSearchTestDB(t))
+	_, err := svc.ListEmails(context.Background(), uuid.New(), 10, 0, "", SavedSearchFolderPrefix+uuid.NewString(), "", "")
+	assert.ErrorIs(t, err, ErrSavedSearchNotFound)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,6 +27,17 @@ type SearchService struct {
 	embedder ai.EmbeddingProvider
 	metrics  *telemetry.SearchMetrics
 	cache    *SearchCache
+	ann      ANNTuning // Default ANN tuning, overridable per query via SearchFilters.ANN
+
+	// Optional embedding space registry; without it all vectors live in email_embeddings.
+	spaces         *EmbeddingSpaceService
+	queryEmbedders map[string]ai.EmbeddingProvider // Keyed by EmbeddingSpaceKey
+	// Embedders of earlier models, whose spaces are registered before the primary's
+	previousEmbedders []ai.EmbeddingProvider
+	previousEnsured   atomic.Bool
+
+	// Optional; without it cross-language searches use the query as given.
+	translator QueryTranslator
 }
 
 func NewSearchService(db *gorm.DB, embedder ai.EmbeddingProvider, cache *SearchCache) *SearchService {
@@ -44,13 +56,18 @@ func NewSearchService(db *gorm.DB, embedder ai.EmbeddingProvider, cache *SearchC
 	}
 }
 
+// SetANNTuning sets the default ef_search/probes used for semantic queries.
+func (s *SearchService) SetANNTuning(tuning ANNTuning) {
+	s.ann = tuning
+}
+
 type SearchResult struct {
 	EmailID uuid.UUID `json:"email_id"`
 	Subject string    `json:"subject"`
 	Snippet string    `json:"snippet"`
 	Sender  string    `json:"sender"`
 	Date    time.Time `json:"date"`
-	Score   float64   `json:"score"` // Similarity score (1 - distance)
+	Score   float64   `json:"score"` // Similarity (1 - distance), ts_rank, or fused RRF score depending on mode
 }
 
 type SearchFilters struct {
@@ -58,6 +75,12 @@ type SearchFilters struct {
 	StartDate *time.Time
 	EndDate   *time.Time
 	ContextID *uuid.UUID
+	Mode      SearchMode // Empty defaults to semantic search
+	Operators []SearchOperator
+	ANN       ANNTuning   // Per-query index tuning; zero fields use the service defaults
+	EmailIDs  []uuid.UUID // Restrict results to these emails, e.g. newly analyzed ones for saved-search alerts
+	// CrossLanguage also searches with the query translated into the other languages of the user's mail
+	CrossLanguage bool
 }
 
 func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, error) {
@@ -80,9 +103,23 @@ func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query stri
 		s.metrics.IncrementSearchRequests(ctx)
 	}
 
+	// The cache generation is read once, so that results computed while an Invalidate runs are
+	// stored under the generation they were computed for. Without it, the cache is skipped.
+	useCache := s.cache != nil
+	var generation int64
+	if useCache {
+		var err error
+		if generation, err = s.cache.Generation(ctx, userID); err != nil {
+			span.AddEvent("cache_error", trace.WithAttributes(
+				attribute.String("error", err.Error()),
+			))
+			useCache = false
+		}
+	}
+
 	// Check cache first
-	if s.cache != nil {
-		cachedResults, found, err := s.cache.Get(ctx, userID, query, filters, limit)
+	if useCache {
+		cachedResults, found, err := s.cache.Get(ctx, userID, generation, query, filters, limit)
 		if err != nil {
 			// Log cache error but continue with normal search
 			span.AddEvent("cache_error", trace.WithAttributes(
@@ -102,30 +139,115 @@ func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query stri
 			)
 			return cachedResults, nil
 		}
-		// Cache miss
+		span.AddEvent("cache_miss")
+	}
+
+	// Near-duplicate semantic queries can reuse cached results
+	var queryVector []float32
+	var space string
+	isSemantic := strings.TrimSpace(query) != "" && filters.Mode != SearchModeKeyword && filters.Mode != SearchModeHybrid
+	if isSemantic && useCache && s.cache.similarityEnabled() {
+		var similarResults []SearchResult
+		var found bool
+		similarResults, queryVector, space, found = s.similarCacheLookup(ctx, userID, generation, query, filters, limit)
+		if found {
+			if s.metrics != nil {
+				s.metrics.IncrementCacheHits(ctx)
+				s.metrics.RecordSearchLatency(ctx, time.Since(start))
+				s.metrics.RecordResultsReturned(ctx, len(similarResults))
+			}
+			span.SetStatus(codes.Ok, "search completed (similar query cached)")
+			span.SetAttributes(
+				attribute.Bool("cache.hit", true),
+				attribute.Bool("cache.similar", true),
+				attribute.Int("results.total", len(similarResults)),
+			)
+			return similarResults, nil
+		}
+	}
+	if useCache && s.metrics != nil {
+		s.metrics.IncrementCacheMisses(ctx)
+	}
+
+	// 1. Retrieve candidates according to the search mode
+	results, err := s.retrieve(ctx, userID, query, filters, limit)
+	if err == nil && filters.CrossLanguage && strings.TrimSpace(query) != "" {
+		results, err = s.crossLanguageSearch(ctx, userID, query, filters, limit, results)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "search failed")
 		if s.metrics != nil {
-			s.metrics.IncrementCacheMisses(ctx)
+			s.metrics.IncrementSearchErrors(ctx)
 		}
-		span.AddEvent("cache_miss")
+		return nil, err
 	}
 
+	// Store in cache
+	if useCache && len(results) > 0 {
+		if err := s.cache.Set(ctx, userID, generation, query, filters, limit, results); err != nil {
+			// Log cache error but don't fail the request
+			span.AddEvent("cache_set_error", trace.WithAttributes(
+				attribute.String("error", err.Error()),
+			))
+		} else if queryVector != nil {
+			if err := s.cache.SetSimilar(ctx, userID, generation, space, query, queryVector, filters, limit); err != nil {
+				span.AddEvent("cache_set_error", trace.WithAttributes(
+					attribute.String("error", err.Error()),
+				))
+			}
+		}
+	}
+
+	// Record overall metrics
+	if s.metrics != nil {
+		s.metrics.RecordSearchLatency(ctx, time.Since(start))
+		s.metrics.RecordResultsReturned(ctx, len(results))
+	}
+
+	span.SetStatus(codes.Ok, "search completed")
+	span.SetAttributes(
+		attribute.Bool("cache.hit", false),
+		attribute.Int("results.total", len(results)),
+	)
+
+	return results, nil
+}
+
+// retrieve ranks emails for query with the search mode of filters.
+func (s *SearchService) retrieve(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, error) {
+	switch {
+	case strings.TrimSpace(query) == "":
+		// Operator-only queries (e.g. "from:alice is:unread") have nothing to rank by.
+		return s.filterSearch(ctx, userID, filters, limit)
+	case filters.Mode == SearchModeKeyword:
+		return s.keywordSearch(ctx, userID, query, filters, limit)
+	case filters.Mode == SearchModeHybrid:
+		return s.hybridSearch(ctx, userID, query, filters, limit)
+	default:
+		return s.semanticSearch(ctx, userID, query, filters, limit)
+	}
+}
+
+// semanticSearch ranks email chunks by cosine similarity to the query embedding.
+func (s *SearchService) semanticSearch(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, error) {
 	// 1. Generate query embedding
 	embedStart := time.Now()
+	table, embedder, err := s.readSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
 	ctx, embedSpan := tracer.Start(ctx, "generate_query_embedding")
-	queryVector, err := s.embedder.Embed(ctx, query)
+	queryVector, err := s.embedQuery(ctx, embedder, query)
 	if err != nil {
 		embedSpan.RecordError(err)
 		embedSpan.SetStatus(codes.Error, "failed to embed query")
 		embedSpan.End()
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "search failed")
-		if s.metrics != nil {
-			s.metrics.IncrementSearchErrors(ctx)
-		}
 		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
 	embedSpan.SetAttributes(
 		attribute.Int("embedding.dimensions", len(queryVector)),
+		attribute.String("embedding.table", table),
 	)
 	embedSpan.End()
 	if s.metrics != nil {
@@ -146,33 +268,15 @@ func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query stri
 			e.sender,
 			e.date,
 			1 - (ee.vector <=> ?) as score
-		FROM email_embeddings ee
+		FROM ` + table + ` ee
 		JOIN emails e ON e.id = ee.email_id
 	`
 	args := []interface{}{pgvector.NewVector(queryVector)}
 
 	// Add joins and where clauses dynamically
-	var whereClauses []string
-	whereClauses = append(whereClauses, "e.user_id = ?")
-	args = append(args, userID)
-
-	if filters.ContextID != nil {
-		sql += " JOIN email_contexts ec ON e.id = ec.email_id"
-		whereClauses = append(whereClauses, "ec.context_id = ?")
-		args = append(args, filters.ContextID)
-	}
-	if filters.Sender != "" {
-		whereClauses = append(whereClauses, "e.sender ILIKE ?")
-		args = append(args, "%"+filters.Sender+"%")
-	}
-	if filters.StartDate != nil {
-		whereClauses = append(whereClauses, "e.date >= ?")
-		args = append(args, filters.StartDate)
-	}
-	if filters.EndDate != nil {
-		whereClauses = append(whereClauses, "e.date <= ?")
-		args = append(args, filters.EndDate)
-	}
+	joins, whereClauses, filterArgs := buildFilterClauses(userID, filters)
+	sql += joins
+	args = append(args, filterArgs...)
 
 	if len(whereClauses) > 0 {
 		sql += " WHERE " + strings.Join(whereClauses, " AND ")
@@ -182,16 +286,26 @@ func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query stri
 	sql += " ORDER BY ee.vector <=> ? LIMIT ?"
 	args = append(args, pgvector.NewVector(queryVector), limit)
 
-	err = s.db.WithContext(ctx).Raw(sql, args...).Scan(&results).Error
+	tuning := filters.ANN.Or(s.ann)
+	if tuning.IsZero() {
+		err = s.db.WithContext(ctx).Raw(sql, args...).Scan(&results).Error
+	} else {
+		// ANN settings are transaction-local so pooled connections are not left modified.
+		dbSpan.SetAttributes(
+			attribute.Int("ann.ef_search", tuning.EfSearch),
+			attribute.Int("ann.probes", tuning.Probes),
+		)
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := applyANNTuning(tx, tuning); err != nil {
+				return err
+			}
+			return tx.Raw(sql, args...).Scan(&results).Error
+		})
+	}
 	if err != nil {
 		dbSpan.RecordError(err)
 		dbSpan.SetStatus(codes.Error, "database query failed")
 		dbSpan.End()
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "search failed")
-		if s.metrics != nil {
-			s.metrics.IncrementSearchErrors(ctx)
-		}
 		return nil, fmt.Errorf("search query failed: %w", err)
 	}
 
@@ -203,28 +317,43 @@ func (s *SearchService) Search(ctx context.Context, userID uuid.UUID, query stri
 		s.metrics.RecordDBQueryLatency(ctx, time.Since(dbStart))
 	}
 
-	// Store in cache
-	if s.cache != nil && len(results) > 0 {
-		if err := s.cache.Set(ctx, userID, query, filters, limit, results); err != nil {
-			// Log cache error but don't fail the request
-			span.AddEvent("cache_set_error", trace.WithAttributes(
-				attribute.String("error", err.Error()),
-			))
-		}
-	}
+	return results, nil
+}
 
-	// Record overall metrics
-	if s.metrics != nil {
-		s.metrics.RecordSearchLatency(ctx, time.Since(start))
-		s.metrics.RecordResultsReturned(ctx, len(results))
+// semanticMatches returns every email with a chunk at least minScore similar to the query, most
+// similar first. Unlike semanticSearch it compares against all of the user's embeddings rather than
+// the approximate nearest neighbours from the index, so that no match is missed.
+func (s *SearchService) semanticMatches(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, minScore float64, limit int) ([]SearchResult, error) {
+	table, embedder, err := s.readSpace(ctx)
+	if err != nil {
+		return nil, err
+	}
+	queryVector, err := s.embedQuery(ctx, embedder, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
+	vector := pgvector.NewVector(queryVector)
 
-	span.SetStatus(codes.Ok, "search completed")
-	span.SetAttributes(
-		attribute.Bool("cache.hit", false),
-		attribute.Int("results.total", len(results)),
-	)
+	sql := `
+		SELECT e.id as email_id, e.subject, e.sender, e.date, MAX(1 - (ee.vector <=> ?)) as score
+		FROM ` + table + ` ee
+		JOIN emails e ON e.id = ee.email_id
+	`
+	args := []interface{}{vector}
+	joins, whereClauses, filterArgs := buildFilterClauses(userID, filters)
+	sql += joins
+	args = append(args, filterArgs...)
+	// A distance bound cannot use the ANN index, so every chunk is compared.
+	whereClauses = append(whereClauses, "(ee.vector <=> ?) <= ?")
+	args = append(args, vector, 1-minScore)
+	sql += " WHERE " + strings.Join(whereClauses, " AND ")
+	sql += " GROUP BY e.id, e.subject, e.sender, e.date ORDER BY score DESC LIMIT ?"
+	args = append(args, limit)
 
+	var results []SearchResult
+	if err := s.db.WithContext(ctx).Raw(sql, args...).Scan(&results).Error; err != nil {
+		return nil, fmt.Errorf("search query failed: %w", err)
+	}
 	return results, nil
 }
 
@@ -268,26 +397,42 @@ func (s *SearchService) GenerateAndSaveEmbedding(ctx context.Context, email *mod
 		return fmt.Errorf("no valid vectors generated")
 	}
 
-	// 4. Save to DB
+	// 4. Save to DB, in the table of the embedder's space
+	table, modelName, err := s.writeSpace(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Delete existing embeddings for this email first (to avoid duplicates on re-analysis)
-	if err := s.db.WithContext(ctx).Where("email_id = ?", email.ID).Delete(&model.EmailEmbedding{}).Error; err != nil {
+	if err := s.db.WithContext(ctx).Table(table).Where("email_id = ?", email.ID).Delete(&model.EmailEmbedding{}).Error; err != nil {
 		return fmt.Errorf("failed to delete old embeddings: %w", err)
 	}
 
 	var embeddings []model.EmailEmbedding
 	for i, vec := range vectors {
 		embeddings = append(embeddings, model.EmailEmbedding{
-			EmailID: email.ID,
-			Content: chunks[i], // Store the actual text chunk
-			Vector:  pgvector.NewVector(vec),
+			EmailID:    email.ID,
+			Content:    chunks[i], // Store the actual text chunk
+			Vector:     pgvector.NewVector(vec),
+			Dimensions: len(vec),
+			Model:      modelName,
 		})
 	}
 
 	if len(embeddings) > 0 {
-		if err := s.db.WithContext(ctx).Create(&embeddings).Error; err != nil {
+		if err := s.db.WithContext(ctx).Table(table).Create(&embeddings).Error; err != nil {
 			return fmt.Errorf("failed to save embeddings: %w", err)
 		}
 	}
 
+	// 5. Cached results for this user no longer reflect their mailbox
+	if s.cache != nil {
+		if err := s.cache.Invalidate(ctx, email.UserID); err != nil {
+			trace.SpanFromContext(ctx).AddEvent("cache_invalidate_error", trace.WithAttributes(
+				attribute.String("error", err.Error()),
+			))
+		}
+	}
+
 	return nil
 }
@@ -167,3 +167,33 @@ func benchmarkSearch(b *testing.B, count int) {
 		}
 	}
 }
+
+// BenchmarkANNRecall reports recall@10 of the vector index against exact search at several ef_search values.
+func BenchmarkANNRecall(b *testing.B) {
+	db := getBenchmarkDB(b)
+	db.Exec("CREATE EXTENSION IF NOT EXISTS vector")
+	if err := db.AutoMigrate(&model.Email{}, &model.EmailEmbedding{}); err != nil {
+		b.Fatalf("Failed to migrate: %v", err)
+	}
+
+	userID := setupBenchmarkData(b, db, 2000)
+	defer cleanupBenchmarkData(db, userID)
+
+	manager := service.NewVectorIndexManager(db)
+	ctx := context.Background()
+	for _, efSearch := range []int{10, 40, 100} {
+		b.Run(fmt.Sprintf("ef_search_%d", efSearch), func(b *testing.B) {
+			var report *service.RecallReport
+			var err error
+			for i := 0; i < b.N; i++ {
+				report, err = manager.MeasureRecall(ctx, 20, 10, service.ANNTuning{EfSearch: efSearch})
+				if err != nil {
+					b.Fatalf("MeasureRecall failed: %v", err)
+				}
+			}
+			b.ReportMetric(report.MeanRecall, "recall@10")
+			b.ReportMetric(float64(report.ANNLatencyP50.Microseconds()), "ann_p50_us")
+			b.ReportMetric(float64(report.ExactLatency.Microseconds()), "exact_p50_us")
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -24,6 +25,8 @@ type SearchCache struct {
 	ttl     time.Duration
 	metrics *telemetry.CacheMetrics
 	tracer  trace.Tracer
+
+	semantic SemanticCacheOptions
 }
 
 // NewSearchCache creates a new search cache instance with OTel instrumentation
@@ -46,26 +49,31 @@ func NewSearchCache(redisClient *redis.Client, ttl time.Duration) *SearchCache {
 	}
 }
 
-// generateCacheKey generates a unique cache key for search parameters with tracing
-func (c *SearchCache) generateCacheKey(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) string {
+// generateCacheKey generates a unique cache key for search parameters in the given cache generation with tracing
+func (c *SearchCache) generateCacheKey(ctx context.Context, userID uuid.UUID, generation int64, query string, filters SearchFilters, limit int) string {
 	ctx, span := c.tracer.Start(ctx, "generate_cache_key",
 		trace.WithSpanKind(trace.SpanKindInternal),
 	)
 	defer span.End()
 
 	// Create a deterministic string from search parameters
-	filterStr := fmt.Sprintf("%s|%v|%v|%v",
+	filterStr := fmt.Sprintf("%s|%v|%v|%v|%s|%v|%v|%v|%v",
 		filters.Sender,
 		filters.StartDate,
 		filters.EndDate,
 		filters.ContextID,
+		filters.Mode,
+		filters.Operators,
+		filters.ANN,
+		filters.EmailIDs,
+		filters.CrossLanguage,
 	)
 
 	keyData := fmt.Sprintf("search:%s:%s:%s:%d", userID.String(), query, filterStr, limit)
 
-	// Hash the key to keep it short
+	// Hash the key to keep it short; the user's generation makes Invalidate a single INCR
 	hash := sha256.Sum256([]byte(keyData))
-	key := "search:cache:" + hex.EncodeToString(hash[:16])
+	key := fmt.Sprintf("search:cache:%s:%d:%s", userID.String(), generation, hex.EncodeToString(hash[:16]))
 
 	// Record span attributes
 	span.SetAttributes(
@@ -83,8 +91,8 @@ func (c *SearchCache) generateCacheKey(ctx context.Context, userID uuid.UUID, qu
 	return key
 }
 
-// Get retrieves cached search results with full OTel instrumentation
-func (c *SearchCache) Get(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, bool, error) {
+// Get retrieves cached search results of a cache generation with full OTel instrumentation
+func (c *SearchCache) Get(ctx context.Context, userID uuid.UUID, generation int64, query string, filters SearchFilters, limit int) ([]SearchResult, bool, error) {
 	// Create span for cache get operation
 	ctx, span := c.tracer.Start(ctx, "SearchCache.Get",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -98,7 +106,7 @@ func (c *SearchCache) Get(ctx context.Context, userID uuid.UUID, query string, f
 	}
 
 	// Generate cache key with sub-span
-	key := c.generateCacheKey(ctx, userID, query, filters, limit)
+	key := c.generateCacheKey(ctx, userID, generation, query, filters, limit)
 	span.SetAttributes(
 		attribute.String("cache.key", key),
 		attribute.String("cache.operation", "get"),
@@ -163,8 +171,10 @@ func (c *SearchCache) Get(ctx context.Context, userID uuid.UUID, query string, f
 	return results, true, nil
 }
 
-// Set stores search results in cache with full OTel instrumentation
-func (c *SearchCache) Set(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int, results []SearchResult) error {
+// Set stores search results under a cache generation with full OTel instrumentation. Pass the
+// generation read before the search, so that results computed while an Invalidate ran are stored
+// where nobody looks them up.
+func (c *SearchCache) Set(ctx context.Context, userID uuid.UUID, generation int64, query string, filters SearchFilters, limit int, results []SearchResult) error {
 	// Create span for cache set operation
 	ctx, span := c.tracer.Start(ctx, "SearchCache.Set",
 		trace.WithSpanKind(trace.SpanKindInternal),
@@ -178,7 +188,7 @@ func (c *SearchCache) Set(ctx context.Context, userID uuid.UUID, query string, f
 	}
 
 	// Generate cache key with sub-span
-	key := c.generateCacheKey(ctx, userID, query, filters, limit)
+	key := c.generateCacheKey(ctx, userID, generation, query, filters, limit)
 	span.SetAttributes(
 		attribute.String("cache.key", key),
 		attribute.String("cache.operation", "set"),
@@ -229,7 +239,9 @@ func (c *SearchCache) Set(ctx context.Context, userID uuid.UUID, query string, f
 	return nil
 }
 
-// Invalidate removes cached results for a user with full OTel instrumentation
+// Invalidate drops every cached result for a user by moving them to a new cache generation.
+// Entries of older generations become unreachable at once and expire with their TTL, so this
+// stays O(1) however often new mail is embedded.
 func (c *SearchCache) Invalidate(ctx context.Context, userID uuid.UUID) error {
 	// Create span for invalidate operation
 	ctx, span := c.tracer.Start(ctx, "SearchCache.Invalidate",
@@ -250,55 +262,23 @@ func (c *SearchCache) Invalidate(ctx context.Context, userID uuid.UUID) error {
 		attribute.String("user.id", userID.String()),
 	)
 
-	// Delete all search cache entries for this user
-	pattern := fmt.Sprintf("search:cache:*%s*", userID.String())
-
-	var deletedCount int
-	var cursor uint64
-	for {
-		var keys []string
-		var err error
-
-		// Redis scan with sub-span
-		ctx2, scanSpan := c.tracer.Start(ctx, "redis_scan")
-		keys, cursor, err = c.redis.Scan(ctx2, cursor, pattern, 100).Result()
-		scanSpan.End()
-
-		if err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "redis scan error")
-			if c.metrics != nil {
-				c.metrics.IncrementErrors(ctx, "scan")
-			}
-			return fmt.Errorf("redis scan error: %w", err)
-		}
-
-		if len(keys) > 0 {
-			// Redis delete with sub-span
-			ctx3, delSpan := c.tracer.Start(ctx, "redis_del")
-			deleted, err := c.redis.Del(ctx3, keys...).Result()
-			delSpan.End()
+	ctx2, incrSpan := c.tracer.Start(ctx, "redis_incr")
+	generation, err := c.redis.Incr(ctx2, generationKey(userID)).Result()
+	incrSpan.End()
 
-			if err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "redis del error")
-				if c.metrics != nil {
-					c.metrics.IncrementErrors(ctx, "del")
-				}
-				return fmt.Errorf("redis del error: %w", err)
-			}
-			deletedCount += int(deleted)
-		}
-
-		if cursor == 0 {
-			break
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "redis incr error")
+		if c.metrics != nil {
+			c.metrics.IncrementErrors(ctx, "incr")
 		}
+		return fmt.Errorf("redis incr error: %w", err)
 	}
 
 	latency := time.Since(start)
 
 	// Record attributes and metrics
-	span.SetAttributes(attribute.Int("cache.keys_deleted", deletedCount))
+	span.SetAttributes(attribute.Int64("cache.generation", generation))
 
 	if c.metrics != nil {
 		c.metrics.RecordDeleteLatency(ctx, latency.Milliseconds())
@@ -308,6 +288,26 @@ func (c *SearchCache) Invalidate(ctx context.Context, userID uuid.UUID) error {
 	return nil
 }
 
+// generationKey holds the counter that Invalidate bumps for a user.
+func generationKey(userID uuid.UUID) string {
+	return "search:gen:" + userID.String()
+}
+
+// Generation returns the user's current cache generation, 0 until the first Invalidate.
+func (c *SearchCache) Generation(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if c.redis == nil {
+		return 0, nil
+	}
+	generation, err := c.redis.Get(ctx, generationKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis get error: %w", err)
+	}
+	return generation, nil
+}
+
 // InvalidateAll clears all search cache with full OTel instrumentation
 func (c *SearchCache) InvalidateAll(ctx context.Context) error {
 	// Create span for invalidate all operation
@@ -47,14 +47,14 @@ func TestSearchCache_Get_CacheHit(t *testing.T) {
 	}
 
 	// Pre-populate cache
-	key := cache.generateCacheKey(ctx, userID, query, filters, limit)
+	key := cache.generateCacheKey(ctx, userID, 0, query, filters, limit)
 	data, err := json.Marshal(expectedResults)
 	require.NoError(t, err)
 	err = redisClient.Set(ctx, key, data, 30*time.Minute).Err()
 	require.NoError(t, err)
 
 	// Execute
-	results, found, err := cache.Get(ctx, userID, query, filters, limit)
+	results, found, err := cache.Get(ctx, userID, 0, query, filters, limit)
 
 	// Assert
 	assert.NoError(t, err)
@@ -77,7 +77,7 @@ func TestSearchCache_Get_CacheMiss(t *testing.T) {
 	limit := 10
 
 	// Execute (cache is empty)
-	results, found, err := cache.Get(ctx, userID, query, filters, limit)
+	results, found, err := cache.Get(ctx, userID, 0, query, filters, limit)
 
 	// Assert
 	assert.NoError(t, err)
@@ -109,13 +109,13 @@ func TestSearchCache_Set(t *testing.T) {
 	}
 
 	// Execute
-	err := cache.Set(ctx, userID, query, filters, limit, results)
+	err := cache.Set(ctx, userID, 0, query, filters, limit, results)
 
 	// Assert
 	assert.NoError(t, err)
 
 	// Verify data was stored
-	key := cache.generateCacheKey(ctx, userID, query, filters, limit)
+	key := cache.generateCacheKey(ctx, userID, 0, query, filters, limit)
 	storedData, err := redisClient.Get(ctx, key).Bytes()
 	require.NoError(t, err)
 
@@ -140,9 +140,9 @@ func TestSearchCache_Invalidate(t *testing.T) {
 	// Populate cache with multiple entries
 	results := []SearchResult{{EmailID: uuid.New(), Subject: "Test"}}
 
-	_ = cache.Set(ctx, userID, "query1", SearchFilters{}, 10, results)
-	_ = cache.Set(ctx, userID, "query2", SearchFilters{}, 10, results)
-	_ = cache.Set(ctx, otherUserID, "query3", SearchFilters{}, 10, results)
+	_ = cache.Set(ctx, userID, 0, "query1", SearchFilters{}, 10, results)
+	_ = cache.Set(ctx, userID, 0, "query2", SearchFilters{}, 10, results)
+	_ = cache.Set(ctx, otherUserID, 0, "query3", SearchFilters{}, 10, results)
 
 	initialKeys := mr.Keys()
 	assert.GreaterOrEqual(t, len(initialKeys), 3)
@@ -152,7 +152,21 @@ func TestSearchCache_Invalidate(t *testing.T) {
 
 	// Assert
 	assert.NoError(t, err)
-	// Note: Actual validation would check specific keys deleted
+	generation, err := cache.Generation(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), generation)
+	_, found, err := cache.Get(ctx, userID, generation, "query1", SearchFilters{}, 10)
+	assert.NoError(t, err)
+	assert.False(t, found, "invalidated user's entries are gone")
+
+	// Results of a search that started before the invalidation stay in the old generation.
+	require.NoError(t, cache.Set(ctx, userID, 0, "query4", SearchFilters{}, 10, results))
+	_, found, err = cache.Get(ctx, userID, generation, "query4", SearchFilters{}, 10)
+	assert.NoError(t, err)
+	assert.False(t, found, "stale results are not served")
+	_, found, err = cache.Get(ctx, otherUserID, 0, "query3", SearchFilters{}, 10)
+	assert.NoError(t, err)
+	assert.True(t, found, "other users keep their entries")
 }
 
 // TestSearchCache_InvalidateAll tests global cache invalidation
@@ -168,8 +182,8 @@ func TestSearchCache_InvalidateAll(t *testing.T) {
 	// Populate cache
 	results := []SearchResult{{EmailID: uuid.New(), Subject: "Test"}}
 
-	_ = cache.Set(ctx, userID1, "query1", SearchFilters{}, 10, results)
-	_ = cache.Set(ctx, userID2, "query2", SearchFilters{}, 10, results)
+	_ = cache.Set(ctx, userID1, 0, "query1", SearchFilters{}, 10, results)
+	_ = cache.Set(ctx, userID2, 0, "query2", SearchFilters{}, 10, results)
 
 	initialKeys := mr.Keys()
 	assert.GreaterOrEqual(t, len(initialKeys), 2)
@@ -192,11 +206,11 @@ func TestSearchCache_NilRedis(t *testing.T) {
 	results := []SearchResult{{EmailID: uuid.New()}}
 
 	// All operations should succeed with nil redis
-	_, found, err := cache.Get(ctx, userID, "query", SearchFilters{}, 10)
+	_, found, err := cache.Get(ctx, userID, 0, "query", SearchFilters{}, 10)
 	assert.NoError(t, err)
 	assert.False(t, found)
 
-	err = cache.Set(ctx, userID, "query", SearchFilters{}, 10, results)
+	err = cache.Set(ctx, userID, 0, "query", SearchFilters{}, 10, results)
 	assert.NoError(t, err)
 
 	err = cache.Invalidate(ctx, userID)
@@ -206,6 +220,23 @@ func TestSearchCache_NilRedis(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestSearchCache_GenerationError tests that an unreadable generation is reported, not taken for 0
+func TestSearchCache_GenerationError(t *testing.T) {
+	mr, redisClient := setupTestRedis(t)
+	defer redisClient.Close()
+	cache := NewSearchCache(redisClient, 30*time.Minute)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	generation, err := cache.Generation(ctx, userID)
+	require.NoError(t, err)
+	assert.Zero(t, generation, "users start at generation 0")
+
+	mr.SetError("LOADING Redis is loading the dataset in memory")
+	_, err = cache.Generation(ctx, userID)
+	assert.Error(t, err)
+}
+
 // TestSearchCache_GenerateCacheKey tests cache key generation
 func TestSearchCache_GenerateCacheKey(t *testing.T) {
 	cache := NewSearchCache(nil, 30*time.Minute)
@@ -219,8 +250,8 @@ func TestSearchCache_GenerateCacheKey(t *testing.T) {
 	limit := 10
 
 	// Generate keys
-	key1 := cache.generateCacheKey(ctx, userID, query, filters, limit)
-	key2 := cache.generateCacheKey(ctx, userID, query, filters, limit)
+	key1 := cache.generateCacheKey(ctx, userID, 0, query, filters, limit)
+	key2 := cache.generateCacheKey(ctx, userID, 0, query, filters, limit)
 
 	// Same inputs should generate same key
 	assert.Equal(t, key1, key2)
@@ -228,7 +259,7 @@ func TestSearchCache_GenerateCacheKey(t *testing.T) {
 
 	// Different inputs should generate different keys
 	differentQuery := "different query"
-	key3 := cache.generateCacheKey(ctx, userID, differentQuery, filters, limit)
+	key3 := cache.generateCacheKey(ctx, userID, 0, differentQuery, filters, limit)
 	assert.NotEqual(t, key1, key3)
 }
 
@@ -255,13 +286,13 @@ func BenchmarkSearchCache_Get(b *testing.B) {
 
 	// Pre-populate cache
 	results := []SearchResult{{EmailID: uuid.New(), Subject: "Test"}}
-	key := cache.generateCacheKey(ctx, userID, query, filters, limit)
+	key := cache.generateCacheKey(ctx, userID, 0, query, filters, limit)
 	data, _ := json.Marshal(results)
 	_ = redisClient.Set(ctx, key, data, 30*time.Minute).Err()
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, _ = cache.Get(ctx, userID, query, filters, limit)
+		_, _, _ = cache.Get(ctx, userID, 0, query, filters, limit)
 	}
 }
 
@@ -290,6 +321,6 @@ func BenchmarkSearchCache_Set(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		query := "query" + string(rune(i))
-		_ = cache.Set(ctx, userID, query, filters, limit, results)
+		_ = cache.Set(ctx, userID, 0, query, filters, limit, results)
 	}
 }
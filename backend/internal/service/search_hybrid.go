@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SearchMode selects the retrieval strategy used by SearchService.Search.
+type SearchMode string
+
+const (
+	SearchModeSemantic SearchMode = "semantic" // pgvector cosine similarity over embedded chunks
+	SearchModeKeyword  SearchMode = "keyword"  // Postgres full-text ranking over subject, sender and body
+	SearchModeHybrid   SearchMode = "hybrid"   // Reciprocal rank fusion of semantic and keyword results
+)
+
+const (
+	// rrfK dampens the contribution of lower ranks in reciprocal rank fusion (Cormack et al. use 60).
+	rrfK = 60
+	// hybridCandidateFactor over-fetches each ranking so fusion has enough overlap to work with.
+	hybridCandidateFactor = 3
+)
+
+// ParseSearchMode validates a user-supplied mode. An empty string yields the default (semantic) mode.
+func ParseSearchMode(mode string) (SearchMode, error) {
+	switch m := SearchMode(strings.ToLower(strings.TrimSpace(mode))); m {
+	case "":
+		return SearchModeSemantic, nil
+	case SearchModeSemantic, SearchModeKeyword, SearchModeHybrid:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid search mode %q: must be semantic, keyword or hybrid", mode)
+	}
+}
+
+// buildFilterClauses returns the JOIN fragment, WHERE predicates and their arguments shared by all search modes.
+// The emails table must be aliased as "e". Sent mail is left out, as it is for semantic search, unless
+// an in: operator asks for a folder.
+func buildFilterClauses(userID uuid.UUID, filters SearchFilters) (string, []string, []interface{}) {
+	var joins string
+	whereClauses := []string{"e.user_id = ?"}
+	args := []interface{}{userID}
+	if !hasFolderOperator(filters.Operators) {
+		whereClauses = append(whereClauses, "e.folder <> ?")
+		args = append(args, model.FolderSent)
+	}
+
+	if filters.ContextID != nil {
+		joins += " JOIN email_contexts ec ON e.id = ec.email_id"
+		whereClauses = append(whereClauses, "ec.context_id = ?")
+		args = append(args, filters.ContextID)
+	}
+	if filters.Sender != "" {
+		whereClauses = append(whereClauses, "e.sender ILIKE ?")
+		args = append(args, "%"+filters.Sender+"%")
+	}
+	if filters.StartDate != nil {
+		whereClauses = append(whereClauses, "e.date >= ?")
+		args = append(args, filters.StartDate)
+	}
+	if filters.EndDate != nil {
+		whereClauses = append(whereClauses, "e.date <= ?")
+		args = append(args, filters.EndDate)
+	}
+	if len(filters.EmailIDs) > 0 {
+		whereClauses = append(whereClauses, "e.id IN ?")
+		args = append(args, filters.EmailIDs)
+	}
+	for _, op := range filters.Operators {
+		if clause, opArgs := searchOperatorClause(op); clause != "" {
+			whereClauses = append(whereClauses, clause)
+			args = append(args, opArgs...)
+		}
+	}
+
+	return joins, whereClauses, args
+}
+
+// keywordSearch ranks emails with Postgres full-text search against the generated search_vector column.
+// Subject matches weigh more than sender matches, which weigh more than body matches.
+func (s *SearchService) keywordSearch(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, error) {
+	dbStart := time.Now()
+	ctx, dbSpan := tracer.Start(ctx, "keyword_database_search")
+	defer dbSpan.End()
+
+	// websearch_to_tsquery accepts free-form input (quotes, OR, -term) without raising syntax errors.
+	// ts_rank_cd normalization 32 maps the rank into [0, 1) so scores are comparable across queries.
+	sql := `
+		SELECT
+			e.id as email_id,
+			e.subject,
+			e.snippet,
+			e.sender,
+			e.date,
+			ts_rank_cd(e.search_vector, q, 32) as score
+		FROM emails e
+		CROSS JOIN websearch_to_tsquery('simple', ?) q
+	`
+	args := []interface{}{query}
+
+	joins, whereClauses, filterArgs := buildFilterClauses(userID, filters)
+	sql += joins
+	args = append(args, filterArgs...)
+
+	whereClauses = append(whereClauses, "e.search_vector @@ q")
+	sql += " WHERE " + strings.Join(whereClauses, " AND ")
+	sql += " ORDER BY score DESC, e.date DESC LIMIT ?"
+	args = append(args, limit)
+
+	var results []SearchResult
+	if err := s.db.WithContext(ctx).Raw(sql, args...).Scan(&results).Error; err != nil {
+		dbSpan.RecordError(err)
+		dbSpan.SetStatus(codes.Error, "database query failed")
+		return nil, fmt.Errorf("keyword search query failed: %w", err)
+	}
+
+	dbSpan.SetAttributes(attribute.Int("results.count", len(results)))
+	if s.metrics != nil {
+		s.metrics.RecordDBQueryLatency(ctx, time.Since(dbStart))
+	}
+
+	return results, nil
+}
+
+// filterSearch lists the most recent emails matching the filters when the query has no free text.
+func (s *SearchService) filterSearch(ctx context.Context, userID uuid.UUID, filters SearchFilters, limit int) ([]SearchResult, error) {
+	ctx, dbSpan := tracer.Start(ctx, "filter_database_search")
+	defer dbSpan.End()
+
+	sql := `
+		SELECT
+			e.id as email_id,
+			e.subject,
+			e.snippet,
+			e.sender,
+			e.date,
+			1 as score
+		FROM emails e
+	`
+	joins, whereClauses, args := buildFilterClauses(userID, filters)
+	sql += joins
+	sql += " WHERE " + strings.Join(whereClauses, " AND ")
+	sql += " ORDER BY e.date DESC LIMIT ?"
+	args = append(args, limit)
+
+	var results []SearchResult
+	if err := s.db.WithContext(ctx).Raw(sql, args...).Scan(&results).Error; err != nil {
+		dbSpan.RecordError(err)
+		dbSpan.SetStatus(codes.Error, "database query failed")
+		return nil, fmt.Errorf("filter search query failed: %w", err)
+	}
+	dbSpan.SetAttributes(attribute.Int("results.count", len(results)))
+	return results, nil
+}
+
+// hybridSearch runs semantic and keyword retrieval and merges them with reciprocal rank fusion.
+func (s *SearchService) hybridSearch(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int) ([]SearchResult, error) {
+	ctx, span := tracer.Start(ctx, "hybrid_search")
+	defer span.End()
+
+	candidates := limit * hybridCandidateFactor
+
+	semantic, err := s.semanticSearch(ctx, userID, query, filters, candidates)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	keyword, err := s.keywordSearch(ctx, userID, query, filters, candidates)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	results := fuseRankings(rrfK, limit, semantic, keyword)
+	span.SetAttributes(
+		attribute.Int("results.semantic", len(semantic)),
+		attribute.Int("results.keyword", len(keyword)),
+		attribute.Int("results.fused", len(results)),
+	)
+	return results, nil
+}
+
+// fuseRankings merges ranked result lists using reciprocal rank fusion: each email scores the sum of
+// 1/(k+rank) over the lists it appears in. Only an email's best-ranked entry per list counts, and the
+// snippet is taken from the first list that contains the email.
+func fuseRankings(k, limit int, rankings ...[]SearchResult) []SearchResult {
+	type fused struct {
+		result SearchResult
+		score  float64
+	}
+	byEmail := make(map[uuid.UUID]*fused)
+	var order []uuid.UUID
+
+	for _, ranking := range rankings {
+		seen := make(map[uuid.UUID]bool)
+		rank := 0
+		for _, r := range ranking {
+			if seen[r.EmailID] {
+				continue
+			}
+			seen[r.EmailID] = true
+			rank++
+
+			f, ok := byEmail[r.EmailID]
+			if !ok {
+				f = &fused{result: r}
+				byEmail[r.EmailID] = f
+				order = append(order, r.EmailID)
+			}
+			f.score += 1.0 / float64(k+rank)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(order))
+	for _, id := range order {
+		f := byEmail[id]
+		f.result.Score = f.score
+		results = append(results, f.result)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuseRankings(t *testing.T) {
+	a, b, c, d := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	semantic := []SearchResult{
+		{EmailID: a, Snippet: "chunk a1", Score: 0.91},
+		{EmailID: a, Snippet: "chunk a2", Score: 0.90}, // second chunk of the same email is ignored
+		{EmailID: b, Snippet: "chunk b", Score: 0.80},
+		{EmailID: c, Snippet: "chunk c", Score: 0.70},
+	}
+	keyword := []SearchResult{
+		{EmailID: c, Snippet: "snippet c", Score: 0.5},
+		{EmailID: d, Snippet: "snippet d", Score: 0.4},
+		{EmailID: a, Snippet: "snippet a", Score: 0.1},
+	}
+
+	results := fuseRankings(60, 10, semantic, keyword)
+
+	require.Len(t, results, 4)
+	// a: 1/61 + 1/63, c: 1/63 + 1/61 (tie, a first by stable order), b: 1/62, d: 1/62
+	assert.Equal(t, a, results[0].EmailID)
+	assert.Equal(t, c, results[1].EmailID)
+	assert.InDelta(t, 1.0/61+1.0/63, results[0].Score, 1e-9)
+	assert.Equal(t, "chunk a1", results[0].Snippet, "snippet comes from the first ranking")
+	assert.ElementsMatch(t, []uuid.UUID{b, d}, []uuid.UUID{results[2].EmailID, results[3].EmailID})
+
+	limited := fuseRankings(60, 2, semantic, keyword)
+	assert.Len(t, limited, 2)
+}
+
+func TestParseSearchMode(t *testing.T) {
+	mode, err := ParseSearchMode("")
+	require.NoError(t, err)
+	assert.Equal(t, SearchModeSemantic, mode)
+
+	mode, err = ParseSearchMode(" Hybrid ")
+	require.NoError(t, err)
+	assert.Equal(t, SearchModeHybrid, mode)
+
+	_, err = ParseSearchMode("fuzzy")
+	assert.Error(t, err)
+}
+
+func TestBuildFilterClauses_SentMail(t *testing.T) {
+	userID := uuid.New()
+	_, where, args := buildFilterClauses(userID, SearchFilters{})
+	assert.Equal(t, []string{"e.user_id = ?", "e.folder <> ?"}, where)
+	assert.Equal(t, []interface{}{userID, model.FolderSent}, args)
+
+	// Asking for a folder searches it, sent mail included.
+	_, where, _ = buildFilterClauses(userID, SearchFilters{Operators: []SearchOperator{{Field: SearchFieldIn, Value: "sent"}}})
+	assert.NotContains(t, where, "e.folder <> ?")
+
+	_, where, _ = buildFilterClauses(userID, SearchFilters{Operators: []SearchOperator{{Field: SearchFieldIn, Value: "inbox", Negate: true}}})
+	assert.Contains(t, where, "e.folder <> ?")
+}
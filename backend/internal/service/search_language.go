@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxSearchLanguages bounds the translated queries a cross-language search runs.
+const maxSearchLanguages = 3
+
+// QueryTranslator translates search queries for cross-language search.
+type QueryTranslator interface {
+	TranslateQuery(ctx context.Context, query, language string) (string, error)
+}
+
+// SetQueryTranslator enables cross-language search.
+func (s *SearchService) SetQueryTranslator(translator QueryTranslator) {
+	s.translator = translator
+}
+
+// crossLanguageSearch runs the query again in each of the other main languages of the user's mail
+// and fuses the rankings with results, so that "budget" also finds mail about 预算. Translation is
+// best effort: a language whose translation fails is skipped.
+func (s *SearchService) crossLanguageSearch(ctx context.Context, userID uuid.UUID, query string, filters SearchFilters, limit int, results []SearchResult) ([]SearchResult, error) {
+	if s.translator == nil {
+		return results, nil
+	}
+	ctx, span := tracer.Start(ctx, "cross_language_search")
+	defer span.End()
+
+	languages, err := s.mailLanguages(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		return results, nil
+	}
+	rankings := [][]SearchResult{results}
+	for _, language := range translationTargets(ai.DetectLanguage(query), languages) {
+		translated, err := s.translator.TranslateQuery(ctx, query, language)
+		if err != nil {
+			span.AddEvent("translation_failed", trace.WithAttributes(
+				attribute.String("language", language),
+				attribute.String("error", err.Error()),
+			))
+			continue
+		}
+		if translated == "" || strings.EqualFold(translated, query) {
+			continue
+		}
+		more, err := s.retrieve(ctx, userID, translated, filters, limit)
+		if err != nil {
+			return nil, err
+		}
+		rankings = append(rankings, more)
+	}
+	span.SetAttributes(attribute.Int("search.languages", len(rankings)))
+	if len(rankings) == 1 {
+		return results, nil
+	}
+	return fuseRankings(rrfK, limit, rankings...), nil
+}
+
+// translationTargets returns the mail languages a query in queryLanguage is translated into: all but
+// its own. A query too short to tell its language, such as a single Latin word, is only translated
+// into languages with another script, as it is likely in one of the others.
+func translationTargets(queryLanguage string, languages []string) []string {
+	var targets []string
+	for _, language := range languages {
+		if language == queryLanguage || (queryLanguage == "" && ai.WrittenInLatin(language)) {
+			continue
+		}
+		targets = append(targets, language)
+	}
+	return targets
+}
+
+// mailLanguages returns the most common languages of the user's mail, most common first.
+func (s *SearchService) mailLanguages(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	var languages []string
+	err := s.db.WithContext(ctx).Raw(`
+		SELECT language FROM emails
+		WHERE user_id = ? AND language <> '' AND deleted_at IS NULL
+		GROUP BY language ORDER BY COUNT(*) DESC LIMIT ?`, userID, maxSearchLanguages).Scan(&languages).Error
+	return languages, err
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/hrygo/echomind/pkg/utils"
+)
+
+// SearchField identifies the email attribute a SearchOperator constrains.
+type SearchField string
+
+const (
+	SearchFieldFrom     SearchField = "from"
+	SearchFieldTo       SearchField = "to"
+	SearchFieldCc       SearchField = "cc"
+	SearchFieldSubject  SearchField = "subject"
+	SearchFieldText     SearchField = "text" // Quoted phrases and negated words, matched against subject and body
+	SearchFieldHas      SearchField = "has"
+	SearchFieldIs       SearchField = "is"
+	SearchFieldCategory SearchField = "category"
+	SearchFieldUrgency  SearchField = "urgency"
+	SearchFieldBefore   SearchField = "before"
+	SearchFieldAfter    SearchField = "after"
+	SearchFieldIn       SearchField = "in"
+)
+
+// SearchOperator is a single structured constraint parsed from a query, e.g. `-from:boss@example.com`.
+type SearchOperator struct {
+	Field  SearchField
+	Value  string
+	Negate bool
+}
+
+// ParsedSearchQuery splits a Gmail-style query into free text (sent to vector/full-text ranking)
+// and structured operators (compiled into SQL predicates).
+type ParsedSearchQuery struct {
+	Text      string
+	Operators []SearchOperator
+}
+
+var searchQueryDateLayouts = []string{time.DateOnly, "2006/01/02"}
+
+// ParseSearchQuery parses Gmail-like operators out of a raw query:
+//
+//	from: to: cc: subject: category: urgency: in:  substring / exact matches
+//	has:attachment  is:unread  is:read
+//	before:YYYY-MM-DD  after:YYYY-MM-DD            (YYYY/MM/DD is accepted too)
+//	"quoted phrase"                                 must appear in subject or body
+//	-term  -"phrase"  -from:x                       negation
+//
+// Unknown prefixes such as "re:" are kept as free text. Operator values may be quoted.
+func ParseSearchQuery(raw string) (ParsedSearchQuery, error) {
+	var parsed ParsedSearchQuery
+	var text []string
+
+	for _, token := range tokenizeSearchQuery(raw) {
+		negate := false
+		if len(token) > 1 && token[0] == '-' {
+			negate = true
+			token = token[1:]
+		}
+
+		// Quoted phrase: contributes to the ranking text and must also match literally.
+		if token[0] == '"' {
+			phrase := unquoteSearchValue(token)
+			if phrase == "" {
+				continue
+			}
+			if !negate {
+				text = append(text, phrase)
+			}
+			parsed.Operators = append(parsed.Operators, SearchOperator{Field: SearchFieldText, Value: phrase, Negate: negate})
+			continue
+		}
+
+		if field, value, ok := splitSearchOperator(token); ok {
+			if value == "" {
+				continue
+			}
+			op, err := newSearchOperator(field, value, negate)
+			if err != nil {
+				return ParsedSearchQuery{}, err
+			}
+			parsed.Operators = append(parsed.Operators, op)
+			continue
+		}
+
+		if negate {
+			parsed.Operators = append(parsed.Operators, SearchOperator{Field: SearchFieldText, Value: token, Negate: true})
+			continue
+		}
+		text = append(text, token)
+	}
+
+	parsed.Text = strings.Join(text, " ")
+	return parsed, nil
+}
+
+// tokenizeSearchQuery splits on whitespace outside of double quotes. Quotes are kept in the tokens.
+func tokenizeSearchQuery(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// splitSearchOperator recognizes `key:value` tokens whose key is a supported operator.
+func splitSearchOperator(token string) (SearchField, string, bool) {
+	idx := strings.IndexByte(token, ':')
+	if idx <= 0 {
+		return "", "", false
+	}
+	field := SearchField(strings.ToLower(token[:idx]))
+	switch field {
+	case SearchFieldFrom, SearchFieldTo, SearchFieldCc, SearchFieldSubject, SearchFieldHas, SearchFieldIs,
+		SearchFieldCategory, SearchFieldUrgency, SearchFieldBefore, SearchFieldAfter, SearchFieldIn:
+		return field, unquoteSearchValue(token[idx+1:]), true
+	default:
+		return "", "", false
+	}
+}
+
+// newSearchOperator validates and normalizes an operator value.
+func newSearchOperator(field SearchField, value string, negate bool) (SearchOperator, error) {
+	switch field {
+	case SearchFieldHas:
+		value = strings.ToLower(value)
+		if value != "attachment" {
+			return SearchOperator{}, fmt.Errorf("unsupported operator has:%s (supported: has:attachment)", value)
+		}
+	case SearchFieldIs:
+		value = strings.ToLower(value)
+		if value != "unread" && value != "read" {
+			return SearchOperator{}, fmt.Errorf("unsupported operator is:%s (supported: is:unread, is:read)", value)
+		}
+	case SearchFieldBefore, SearchFieldAfter:
+		t, err := parseSearchDate(value)
+		if err != nil {
+			return SearchOperator{}, fmt.Errorf("invalid date for %s: %q (expected YYYY-MM-DD)", field, value)
+		}
+		value = t.Format(time.DateOnly)
+	}
+	return SearchOperator{Field: field, Value: value, Negate: negate}, nil
+}
+
+func parseSearchDate(value string) (time.Time, error) {
+	var err error
+	for _, layout := range searchQueryDateLayouts {
+		var t time.Time
+		if t, err = time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+func unquoteSearchValue(value string) string {
+	return strings.TrimSpace(strings.Trim(value, `"`))
+}
+
+// searchOperatorClause compiles an operator into a SQL predicate over the emails table aliased as "e".
+func searchOperatorClause(op SearchOperator) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	// Values are matched literally: % and _ typed by the user are not wildcards.
+	value := utils.EscapeLike(op.Value)
+	like := "%" + value + "%"
+
+	switch op.Field {
+	case SearchFieldFrom:
+		clause, args = `COALESCE(e.sender, '') ILIKE ? ESCAPE '\'`, []interface{}{like}
+	case SearchFieldTo:
+		clause, args = `COALESCE(CAST(e."to" AS TEXT), '') ILIKE ? ESCAPE '\'`, []interface{}{like}
+	case SearchFieldCc:
+		clause, args = `COALESCE(CAST(e.cc AS TEXT), '') ILIKE ? ESCAPE '\'`, []interface{}{like}
+	case SearchFieldSubject:
+		clause, args = `COALESCE(e.subject, '') ILIKE ? ESCAPE '\'`, []interface{}{like}
+	case SearchFieldText:
+		clause, args = `(COALESCE(e.subject, '') ILIKE ? ESCAPE '\' OR COALESCE(e.body_text, '') ILIKE ? ESCAPE '\')`, []interface{}{like, like}
+	case SearchFieldHas:
+		clause = "e.has_attachments = TRUE"
+	case SearchFieldIs:
+		clause, args = "e.is_read = ?", []interface{}{op.Value == "read"}
+	case SearchFieldCategory:
+		clause, args = `COALESCE(e.category, '') ILIKE ? ESCAPE '\'`, []interface{}{value}
+	case SearchFieldUrgency:
+		clause, args = `COALESCE(e.urgency, '') ILIKE ? ESCAPE '\'`, []interface{}{value}
+	case SearchFieldIn:
+		clause, args = `COALESCE(e.folder, '') ILIKE ? ESCAPE '\'`, []interface{}{value}
+	case SearchFieldBefore:
+		day, _ := time.Parse(time.DateOnly, op.Value)
+		clause, args = "e.date < ?", []interface{}{day}
+	case SearchFieldAfter:
+		// Gmail semantics: after:2024-01-01 includes mail from that day onwards.
+		day, _ := time.Parse(time.DateOnly, op.Value)
+		clause, args = "e.date >= ?", []interface{}{day}
+	default:
+		return "", nil
+	}
+
+	if op.Negate {
+		clause = "NOT (" + clause + ")"
+	}
+	return clause, args
+}
+
+// hasFolderOperator reports whether the operators ask for a folder with a non-negated in:.
+func hasFolderOperator(ops []SearchOperator) bool {
+	for _, op := range ops {
+		if op.Field == SearchFieldIn && !op.Negate {
+			return true
+		}
+	}
+	return false
+}
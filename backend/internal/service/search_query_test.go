@@ -0,0 +1,72 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	parsed, err := ParseSearchQuery(`budget from:alice@example.com -in:trash "quarterly report" is:unread has:attachment after:2024/01/31 -draft re: plan`)
+	require.NoError(t, err)
+
+	assert.Equal(t, `budget quarterly report re: plan`, parsed.Text)
+	assert.Equal(t, []SearchOperator{
+		{Field: SearchFieldFrom, Value: "alice@example.com"},
+		{Field: SearchFieldIn, Value: "trash", Negate: true},
+		{Field: SearchFieldText, Value: "quarterly report"},
+		{Field: SearchFieldIs, Value: "unread"},
+		{Field: SearchFieldHas, Value: "attachment"},
+		{Field: SearchFieldAfter, Value: "2024-01-31"},
+		{Field: SearchFieldText, Value: "draft", Negate: true},
+	}, parsed.Operators)
+}
+
+func TestParseSearchQuery_QuotedOperatorValue(t *testing.T) {
+	parsed, err := ParseSearchQuery(`subject:"weekly sync" -"do not reply" CATEGORY:Work urgency:high`)
+	require.NoError(t, err)
+
+	assert.Empty(t, parsed.Text)
+	assert.Equal(t, []SearchOperator{
+		{Field: SearchFieldSubject, Value: "weekly sync"},
+		{Field: SearchFieldText, Value: "do not reply", Negate: true},
+		{Field: SearchFieldCategory, Value: "Work"},
+		{Field: SearchFieldUrgency, Value: "high"},
+	}, parsed.Operators)
+}
+
+func TestParseSearchQuery_PlainText(t *testing.T) {
+	parsed, err := ParseSearchQuery("project kickoff notes")
+	require.NoError(t, err)
+	assert.Equal(t, "project kickoff notes", parsed.Text)
+	assert.Nil(t, parsed.Operators)
+}
+
+func TestParseSearchQuery_Errors(t *testing.T) {
+	for _, q := range []string{"before:yesterday", "has:pdf", "is:starred"} {
+		_, err := ParseSearchQuery(q)
+		assert.Error(t, err, q)
+	}
+}
+
+func TestSearchOperatorClause(t *testing.T) {
+	clause, args := searchOperatorClause(SearchOperator{Field: SearchFieldTo, Value: "bob", Negate: true})
+	assert.Equal(t, `NOT (COALESCE(CAST(e."to" AS TEXT), '') ILIKE ? ESCAPE '\')`, clause)
+	assert.Equal(t, []interface{}{"%bob%"}, args)
+
+	// Wildcards in values are matched literally.
+	_, args = searchOperatorClause(SearchOperator{Field: SearchFieldSubject, Value: "100%_done"})
+	assert.Equal(t, []interface{}{`%100\%\_done%`}, args)
+	_, args = searchOperatorClause(SearchOperator{Field: SearchFieldIn, Value: "%"})
+	assert.Equal(t, []interface{}{`\%`}, args)
+
+	clause, args = searchOperatorClause(SearchOperator{Field: SearchFieldIs, Value: "unread"})
+	assert.Equal(t, "e.is_read = ?", clause)
+	assert.Equal(t, []interface{}{false}, args)
+
+	clause, args = searchOperatorClause(SearchOperator{Field: SearchFieldBefore, Value: "2024-03-01"})
+	assert.Equal(t, "e.date < ?", clause)
+	assert.Equal(t, []interface{}{time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, args)
+}
@@ -0,0 +1,311 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+// Reranker reorders retrieval candidates by relevance to the query and returns at most topN of them.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error)
+}
+
+const (
+	RerankerLexical = "lexical"
+	RerankerLLM     = "llm"
+	RerankerNone    = "none"
+)
+
+// NewReranker builds a reranker by name. An empty name selects the lexical reranker; "none" disables reranking.
+func NewReranker(name string, provider ai.AIProvider) (Reranker, error) {
+	switch strings.ToLower(name) {
+	case "", RerankerLexical:
+		return NewLexicalReranker(), nil
+	case RerankerLLM:
+		if provider == nil {
+			return nil, fmt.Errorf("llm reranker requires an AI provider")
+		}
+		return NewLLMReranker(provider), nil
+	case RerankerNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown reranker %q", name)
+	}
+}
+
+// CollapseByEmail keeps only the best-scoring chunk of each email, preserving the order of first appearance
+// of each email's best chunk. Vector search returns one row per chunk, so an email can otherwise fill
+// several slots of the context window with near-identical passages.
+func CollapseByEmail(results []SearchResult) []SearchResult {
+	best := make(map[uuid.UUID]int, len(results))
+	collapsed := make([]SearchResult, 0, len(results))
+	for _, r := range results {
+		if i, ok := best[r.EmailID]; ok {
+			if r.Score > collapsed[i].Score {
+				collapsed[i] = r
+			}
+			continue
+		}
+		best[r.EmailID] = len(collapsed)
+		collapsed = append(collapsed, r)
+	}
+	sort.SliceStable(collapsed, func(i, j int) bool {
+		return collapsed[i].Score > collapsed[j].Score
+	})
+	return collapsed
+}
+
+func truncateResults(results []SearchResult, topN int) []SearchResult {
+	if topN > 0 && len(results) > topN {
+		return results[:topN]
+	}
+	return results
+}
+
+// LexicalReranker blends a BM25 score of the query terms over subject and passage with the retrieval score.
+// It needs no network calls, which makes it a cheap default.
+type LexicalReranker struct {
+	// Weight of the lexical score in [0, 1]; the remainder goes to the normalized retrieval score.
+	LexicalWeight float64
+}
+
+func NewLexicalReranker() *LexicalReranker {
+	return &LexicalReranker{LexicalWeight: 0.5}
+}
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+func (r *LexicalReranker) Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+	queryTerms := uniqueTerms(tokenizeForRerank(query))
+	if len(queryTerms) == 0 {
+		return truncateResults(results, topN), nil
+	}
+
+	// Subject terms are repeated so a subject hit counts double.
+	docs := make([][]string, len(results))
+	df := make(map[string]int)
+	totalLen := 0
+	for i, res := range results {
+		subject := tokenizeForRerank(res.Subject)
+		docs[i] = append(append(subject, subject...), tokenizeForRerank(res.Snippet)...)
+		totalLen += len(docs[i])
+		for _, term := range uniqueTerms(docs[i]) {
+			df[term]++
+		}
+	}
+	avgLen := math.Max(float64(totalLen)/float64(len(docs)), 1)
+
+	lexical := make([]float64, len(results))
+	maxLexical, maxRetrieval := 0.0, 0.0
+	for i, doc := range docs {
+		tf := make(map[string]int)
+		for _, term := range doc {
+			tf[term]++
+		}
+		for _, term := range queryTerms {
+			if tf[term] == 0 {
+				continue
+			}
+			n := float64(len(docs))
+			idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+			f := float64(tf[term])
+			lexical[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(len(doc))/avgLen))
+		}
+		maxLexical = math.Max(maxLexical, lexical[i])
+		maxRetrieval = math.Max(maxRetrieval, results[i].Score)
+	}
+
+	reranked := make([]SearchResult, len(results))
+	copy(reranked, results)
+	for i := range reranked {
+		score := 0.0
+		if maxLexical > 0 {
+			score += r.LexicalWeight * lexical[i] / maxLexical
+		}
+		if maxRetrieval > 0 {
+			score += (1 - r.LexicalWeight) * results[i].Score / maxRetrieval
+		}
+		reranked[i].Score = score
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+
+	return truncateResults(reranked, topN), nil
+}
+
+// tokenizeForRerank lowercases text and splits it into words; each Han character is its own token
+// because Chinese text has no whitespace word boundaries.
+func tokenizeForRerank(text string) []string {
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	var unique []string
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
+
+// LLMReranker asks the chat model to grade each passage. If the model fails or returns unusable output
+// the Fallback reranker is used instead so retrieval never breaks because of the reranking stage.
+type LLMReranker struct {
+	provider ai.AIProvider
+	Fallback Reranker
+	// MaxCandidates bounds the prompt size; candidates beyond it keep their retrieval order.
+	MaxCandidates int
+}
+
+func NewLLMReranker(provider ai.AIProvider) *LLMReranker {
+	return &LLMReranker{
+		provider:      provider,
+		Fallback:      NewLexicalReranker(),
+		MaxCandidates: 20,
+	}
+}
+
+const llmRerankPrompt = `You grade how well passages from a user's emails answer a search query.
+Score every passage from 0 (irrelevant) to 10 (directly answers the query).
+Reply with JSON only, in the form {"scores":[{"index":1,"score":7}]}.`
+
+type llmRerankResponse struct {
+	Scores []struct {
+		Index int     `json:"index"`
+		Score float64 `json:"score"`
+	} `json:"scores"`
+}
+
+func (r *LLMReranker) Rerank(ctx context.Context, query string, results []SearchResult, topN int) ([]SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	candidates := results
+	if r.MaxCandidates > 0 && len(candidates) > r.MaxCandidates {
+		candidates = candidates[:r.MaxCandidates]
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Query: %s\n\n", query))
+	for i, res := range candidates {
+		prompt.WriteString(fmt.Sprintf("[%d] Subject: %s\n%s\n\n", i+1, res.Subject, res.Snippet))
+	}
+
+	reply, err := collectChatReply(ctx, r.provider, []ai.Message{
+		{Role: "system", Content: llmRerankPrompt},
+		{Role: "user", Content: prompt.String()},
+	})
+	if err == nil {
+		var scored []SearchResult
+		scored, err = applyLLMScores(reply, candidates)
+		if err == nil {
+			return truncateResults(append(scored, results[len(candidates):]...), topN), nil
+		}
+	}
+
+	if r.Fallback != nil {
+		return r.Fallback.Rerank(ctx, query, results, topN)
+	}
+	return nil, fmt.Errorf("llm rerank failed: %w", err)
+}
+
+// applyLLMScores reorders candidates by the model's scores; passages the model skipped sort last.
+func applyLLMScores(reply string, candidates []SearchResult) ([]SearchResult, error) {
+	var parsed llmRerankResponse
+	if err := json.Unmarshal([]byte(extractJSONObject(reply)), &parsed); err != nil {
+		return nil, fmt.Errorf("invalid rerank response: %w", err)
+	}
+	if len(parsed.Scores) == 0 {
+		return nil, fmt.Errorf("rerank response contained no scores")
+	}
+
+	scores := make([]float64, len(candidates))
+	for i := range scores {
+		scores[i] = -1
+	}
+	for _, s := range parsed.Scores {
+		if s.Index >= 1 && s.Index <= len(candidates) {
+			scores[s.Index-1] = s.Score / 10
+		}
+	}
+
+	scored := make([]SearchResult, len(candidates))
+	copy(scored, candidates)
+	for i := range scored {
+		scored[i].Score = scores[i]
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].Score > scored[j].Score
+	})
+	return scored, nil
+}
+
+// extractJSONObject strips markdown fences or prose around the first JSON object in a model reply.
+func extractJSONObject(reply string) string {
+	start := strings.Index(reply, "{")
+	end := strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return reply
+	}
+	return reply[start : end+1]
+}
+
+// collectChatReply drains a streamed chat completion into a single string.
+// Providers close the channel when StreamChat returns, successful or not.
+func collectChatReply(ctx context.Context, provider ai.AIProvider, messages []ai.Message) (string, error) {
+	ch := make(chan ai.ChatCompletionChunk, 32)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- provider.StreamChat(ctx, messages, ch)
+	}()
+
+	var reply strings.Builder
+	for chunk := range ch {
+		for _, choice := range chunk.Choices {
+			reply.WriteString(choice.Delta.Content)
+		}
+	}
+	if err := <-errCh; err != nil {
+		return "", err
+	}
+	return reply.String(), nil
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollapseByEmail(t *testing.T) {
+	a, b := uuid.New(), uuid.New()
+	results := CollapseByEmail([]SearchResult{
+		{EmailID: a, Snippet: "a1", Score: 0.7},
+		{EmailID: b, Snippet: "b1", Score: 0.75},
+		{EmailID: a, Snippet: "a2", Score: 0.9},
+	})
+
+	require.Len(t, results, 2)
+	assert.Equal(t, "a2", results[0].Snippet)
+	assert.Equal(t, "b1", results[1].Snippet)
+}
+
+func TestLexicalReranker_PromotesTermMatches(t *testing.T) {
+	budget, lunch := uuid.New(), uuid.New()
+	results := []SearchResult{
+		{EmailID: lunch, Subject: "Team lunch", Snippet: "Pizza on Friday", Score: 0.82},
+		{EmailID: budget, Subject: "Q3 budget approval", Snippet: "The budget needs sign-off", Score: 0.80},
+	}
+
+	reranked, err := NewLexicalReranker().Rerank(context.Background(), "budget approval", results, 1)
+	require.NoError(t, err)
+	require.Len(t, reranked, 1)
+	assert.Equal(t, budget, reranked[0].EmailID)
+}
+
+func TestLexicalReranker_HanTokens(t *testing.T) {
+	assert.Equal(t, []string{"预", "算", "q3", "review"}, tokenizeForRerank("预算 Q3-review"))
+}
+
+func TestLLMReranker(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	results := []SearchResult{
+		{EmailID: first, Subject: "Newsletter", Snippet: "Weekly digest", Score: 0.9},
+		{EmailID: second, Subject: "Contract renewal", Snippet: "Renewal due in May", Score: 0.8},
+	}
+
+	t.Run("Uses model scores", func(t *testing.T) {
+		provider := new(MockAIProvider)
+		provider.On("StreamChat", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+			ch := args.Get(2).(chan<- ai.ChatCompletionChunk)
+			ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: "```json\n{\"scores\":[{\"index\":1,\"score\":2},"}}}}
+			ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: "{\"index\":2,\"score\":9}]}\n```"}}}}
+			close(ch)
+		}).Once()
+
+		reranked, err := NewLLMReranker(provider).Rerank(context.Background(), "when is the contract renewal", results, 2)
+		require.NoError(t, err)
+		require.Len(t, reranked, 2)
+		assert.Equal(t, second, reranked[0].EmailID)
+		assert.InDelta(t, 0.9, reranked[0].Score, 1e-9)
+	})
+
+	t.Run("Falls back when the provider fails", func(t *testing.T) {
+		provider := new(MockAIProvider)
+		provider.On("StreamChat", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("rate limited")).Run(func(args mock.Arguments) {
+			close(args.Get(2).(chan<- ai.ChatCompletionChunk))
+		}).Once()
+
+		fallback := new(MockReranker)
+		fallback.On("Rerank", mock.Anything, "renewal", results, 1).Return(results[1:], nil).Once()
+
+		reranker := NewLLMReranker(provider)
+		reranker.Fallback = fallback
+		reranked, err := reranker.Rerank(context.Background(), "renewal", results, 1)
+		require.NoError(t, err)
+		assert.Equal(t, results[1:], reranked)
+		fallback.AssertExpectations(t)
+	})
+}
+
+func TestNewReranker(t *testing.T) {
+	r, err := NewReranker("", nil)
+	require.NoError(t, err)
+	assert.IsType(t, &LexicalReranker{}, r)
+
+	r, err = NewReranker(RerankerNone, nil)
+	require.NoError(t, err)
+	assert.Nil(t, r)
+
+	_, err = NewReranker(RerankerLLM, nil)
+	assert.Error(t, err)
+
+	_, err = NewReranker("cross-encoder", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SemanticCacheOptions tunes the embedding cache and near-duplicate query matching.
+type SemanticCacheOptions struct {
+	EmbeddingTTL        time.Duration // How long query embeddings are kept (default 24h)
+	SimilarityThreshold float64       // Cosine similarity above which a cached query's results are reused; 0 disables
+	MaxSimilarEntries   int           // Recent queries compared per user and filter set (default 50)
+}
+
+// SetSemanticOptions enables caching by query meaning in addition to the exact query string.
+func (c *SearchCache) SetSemanticOptions(opts SemanticCacheOptions) {
+	if opts.EmbeddingTTL <= 0 {
+		opts.EmbeddingTTL = 24 * time.Hour
+	}
+	if opts.MaxSimilarEntries <= 0 {
+		opts.MaxSimilarEntries = 50
+	}
+	c.semantic = opts
+}
+
+func (c *SearchCache) similarityEnabled() bool {
+	return c != nil && c.redis != nil && c.semantic.SimilarityThreshold > 0
+}
+
+// NormalizeQuery folds case and whitespace so trivially different spellings share a cache entry.
+func NormalizeQuery(query string) string {
+	return strings.Join(strings.Fields(strings.ToLower(query)), " ")
+}
+
+// embeddingCacheKey is shared across users: the same text embeds to the same vector under a given model.
+func embeddingCacheKey(space, query string) string {
+	hash := sha256.Sum256([]byte(space + "\x00" + NormalizeQuery(query)))
+	return "search:embed:" + hex.EncodeToString(hash[:16])
+}
+
+// GetEmbedding returns the cached embedding of a query under an embedding space key.
+func (c *SearchCache) GetEmbedding(ctx context.Context, space, query string) ([]float32, bool, error) {
+	if c.redis == nil {
+		return nil, false, nil
+	}
+	data, err := c.redis.Get(ctx, embeddingCacheKey(space, query)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get error: %w", err)
+	}
+	var vector []float32
+	if err := json.Unmarshal(data, &vector); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal cached embedding: %w", err)
+	}
+	return vector, true, nil
+}
+
+// SetEmbedding caches a query embedding.
+func (c *SearchCache) SetEmbedding(ctx context.Context, space, query string, vector []float32) error {
+	if c.redis == nil {
+		return nil
+	}
+	data, err := json.Marshal(vector)
+	if err != nil {
+		return err
+	}
+	ttl := c.semantic.EmbeddingTTL
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if err := c.redis.Set(ctx, embeddingCacheKey(space, query), data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set error: %w", err)
+	}
+	return nil
+}
+
+// similarEntry links a cached query vector to the result key it was stored under.
+type similarEntry struct {
+	Vector []float32 `json:"v"`
+	Key    string    `json:"k"`
+}
+
+// similarIndexKey groups recent queries that can stand in for each other: same user, cache
+// generation, embedding space, filters and limit.
+func (c *SearchCache) similarIndexKey(userID uuid.UUID, generation int64, space string, filters SearchFilters, limit int) string {
+	bucket := fmt.Sprintf("%s|%s|%v|%v|%v|%s|%v|%v|%v|%v|%d", space,
+		filters.Sender, filters.StartDate, filters.EndDate, filters.ContextID,
+		filters.Mode, filters.Operators, filters.ANN, filters.EmailIDs, filters.CrossLanguage, limit)
+	hash := sha256.Sum256([]byte(bucket))
+	return fmt.Sprintf("search:cache:%s:%d:similar:%s", userID.String(), generation, hex.EncodeToString(hash[:16]))
+}
+
+// GetSimilar returns the results of the most similar recent query of a cache generation if it
+// clears the threshold.
+func (c *SearchCache) GetSimilar(ctx context.Context, userID uuid.UUID, generation int64, space string, vector []float32, filters SearchFilters, limit int) ([]SearchResult, bool, error) {
+	if !c.similarityEnabled() {
+		return nil, false, nil
+	}
+	ctx, span := c.tracer.Start(ctx, "SearchCache.GetSimilar", trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	raw, err := c.redis.LRange(ctx, c.similarIndexKey(userID, generation, space, filters, limit), 0, -1).Result()
+	if err != nil {
+		return nil, false, fmt.Errorf("redis lrange error: %w", err)
+	}
+
+	best, bestKey := 0.0, ""
+	for _, item := range raw {
+		var entry similarEntry
+		if json.Unmarshal([]byte(item), &entry) != nil {
+			continue
+		}
+		if sim := cosineSimilarity(vector, entry.Vector); sim > best {
+			best, bestKey = sim, entry.Key
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("cache.similar_candidates", len(raw)),
+		attribute.Float64("cache.similarity", best),
+	)
+	if bestKey == "" || best < c.semantic.SimilarityThreshold {
+		return nil, false, nil
+	}
+
+	data, err := c.redis.Get(ctx, bestKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil // Result expired before its index entry
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("redis get error: %w", err)
+	}
+	var results []SearchResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal cached results: %w", err)
+	}
+	span.SetAttributes(attribute.Bool("cache.hit", true))
+	if c.metrics != nil {
+		c.metrics.IncrementHits(ctx)
+	}
+	return results, true, nil
+}
+
+// SetSimilar records the query vector so later near-duplicates can reuse the results cached by Set.
+func (c *SearchCache) SetSimilar(ctx context.Context, userID uuid.UUID, generation int64, space, query string, vector []float32, filters SearchFilters, limit int) error {
+	if !c.similarityEnabled() {
+		return nil
+	}
+	data, err := json.Marshal(similarEntry{Vector: vector, Key: c.generateCacheKey(ctx, userID, generation, query, filters, limit)})
+	if err != nil {
+		return err
+	}
+	indexKey := c.similarIndexKey(userID, generation, space, filters, limit)
+	pipe := c.redis.TxPipeline()
+	pipe.LPush(ctx, indexKey, data)
+	pipe.LTrim(ctx, indexKey, 0, int64(c.semantic.MaxSimilarEntries-1))
+	pipe.Expire(ctx, indexKey, c.ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("redis similar index error: %w", err)
+	}
+	return nil
+}
+
+// embedQuery embeds a search query, reusing cached embeddings of the same normalized text and model.
+func (s *SearchService) embedQuery(ctx context.Context, embedder ai.EmbeddingProvider, query string) ([]float32, error) {
+	if s.cache == nil {
+		return embedder.Embed(ctx, query)
+	}
+	span := trace.SpanFromContext(ctx)
+	space := EmbeddingSpaceKey(ai.EmbeddingModelName(embedder), embedder.GetDimensions())
+	vector, found, err := s.cache.GetEmbedding(ctx, space, query)
+	if err != nil {
+		span.AddEvent("embedding_cache_error", trace.WithAttributes(attribute.String("error", err.Error())))
+	} else if found {
+		span.SetAttributes(attribute.Bool("embedding.cache_hit", true))
+		return vector, nil
+	}
+
+	vector, err = embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.SetEmbedding(ctx, space, query, vector); err != nil {
+		span.AddEvent("embedding_cache_set_error", trace.WithAttributes(attribute.String("error", err.Error())))
+	}
+	return vector, nil
+}
+
+// similarCacheLookup embeds a semantic query and looks for cached results of a near-duplicate.
+// It returns the vector and space key so the caller can index the query after searching.
+func (s *SearchService) similarCacheLookup(ctx context.Context, userID uuid.UUID, generation int64, query string, filters SearchFilters, limit int) ([]SearchResult, []float32, string, bool) {
+	_, embedder, err := s.readSpace(ctx)
+	if err != nil {
+		return nil, nil, "", false
+	}
+	space := EmbeddingSpaceKey(ai.EmbeddingModelName(embedder), embedder.GetDimensions())
+	vector, err := s.embedQuery(ctx, embedder, query)
+	if err != nil {
+		return nil, nil, "", false
+	}
+	results, found, err := s.cache.GetSimilar(ctx, userID, generation, space, vector, filters, limit)
+	if err != nil {
+		trace.SpanFromContext(ctx).AddEvent("similar_cache_error", trace.WithAttributes(attribute.String("error", err.Error())))
+		return nil, vector, space, false
+	}
+	return results, vector, space, found
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, "budget report q3", NormalizeQuery("  Budget\tReport   Q3 "))
+}
+
+func TestSearchCache_EmbeddingCache(t *testing.T) {
+	_, client := setupTestRedis(t)
+	defer client.Close()
+	cache := NewSearchCache(client, time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, cache.SetEmbedding(ctx, "model-a@3", "Budget Report", []float32{1, 2, 3}))
+
+	vector, found, err := cache.GetEmbedding(ctx, "model-a@3", "  budget   report")
+	require.NoError(t, err)
+	assert.True(t, found, "normalized text shares the entry")
+	assert.Equal(t, []float32{1, 2, 3}, vector)
+
+	_, found, err = cache.GetEmbedding(ctx, "model-b@3", "budget report")
+	require.NoError(t, err)
+	assert.False(t, found, "embeddings are per model")
+}
+
+func TestSearchCache_SimilarQueries(t *testing.T) {
+	_, client := setupTestRedis(t)
+	defer client.Close()
+	cache := NewSearchCache(client, time.Minute)
+	cache.SetSemanticOptions(SemanticCacheOptions{SimilarityThreshold: 0.95})
+	ctx := context.Background()
+	userID := uuid.New()
+	results := []SearchResult{{EmailID: uuid.New(), Subject: "Q3 budget"}}
+
+	require.NoError(t, cache.Set(ctx, userID, 0, "budget report", SearchFilters{}, 10, results))
+	require.NoError(t, cache.SetSimilar(ctx, userID, 0, "m@3", "budget report", []float32{1, 0, 0}, SearchFilters{}, 10))
+
+	cached, found, err := cache.GetSimilar(ctx, userID, 0, "m@3", []float32{0.99, 0.05, 0}, SearchFilters{}, 10)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, results[0].EmailID, cached[0].EmailID)
+
+	_, found, _ = cache.GetSimilar(ctx, userID, 0, "m@3", []float32{0, 1, 0}, SearchFilters{}, 10)
+	assert.False(t, found, "unrelated query")
+	_, found, _ = cache.GetSimilar(ctx, userID, 0, "m@3", []float32{1, 0, 0}, SearchFilters{Sender: "alice"}, 10)
+	assert.False(t, found, "different filters")
+	_, found, _ = cache.GetSimilar(ctx, uuid.New(), 0, "m@3", []float32{1, 0, 0}, SearchFilters{}, 10)
+	assert.False(t, found, "different user")
+
+	require.NoError(t, cache.Invalidate(ctx, userID))
+	_, found, _ = cache.GetSimilar(ctx, userID, 1, "m@3", []float32{1, 0, 0}, SearchFilters{}, 10)
+	assert.False(t, found, "invalidation also drops the similarity index")
+}
+
+// countingEmbedder counts Embed calls and returns a fixed vector.
+type countingEmbedder struct {
+	fakeEmbedder
+	vector []float32
+	calls  int
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	c.calls++
+	return c.vector, nil
+}
+
+func TestSearchService_ServesNearDuplicateFromCache(t *testing.T) {
+	_, client := setupTestRedis(t)
+	defer client.Close()
+	cache := NewSearchCache(client, time.Minute)
+	cache.SetSemanticOptions(SemanticCacheOptions{SimilarityThreshold: 0.95})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	embedder := &countingEmbedder{fakeEmbedder: fakeEmbedder{model: "m", dims: 3}, vector: []float32{0.98, 0.1, 0}}
+	space := EmbeddingSpaceKey("m", 3)
+	results := []SearchResult{{EmailID: uuid.New(), Subject: "Q3 budget"}}
+	require.NoError(t, cache.Set(ctx, userID, 0, "budget report", SearchFilters{}, 10, results))
+	require.NoError(t, cache.SetSimilar(ctx, userID, 0, space, "budget report", []float32{1, 0, 0}, SearchFilters{}, 10))
+
+	// No database: a near-duplicate hit must not reach the vector search.
+	svc := NewSearchService(nil, embedder, cache)
+	got, err := svc.Search(ctx, userID, "the budget reports", SearchFilters{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, results, got)
+	assert.Equal(t, 1, embedder.calls)
+
+	// The query embedding itself is cached by normalized text.
+	_, err = svc.Search(ctx, userID, "The  Budget reports", SearchFilters{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, embedder.calls)
+}
+
+func TestSearchService_EmbeddingWriteInvalidatesUserCache(t *testing.T) {
+	_, client := setupTestRedis(t)
+	defer client.Close()
+	cache := NewSearchCache(client, time.Minute)
+	ctx := context.Background()
+	db := setupEmbeddingSpaceTestDB(t)
+	email := model.Email{ID: uuid.New(), UserID: uuid.New(), MessageID: "m1", Subject: "Offsite", Date: time.Now()}
+	require.NoError(t, db.Create(&email).Error)
+
+	require.NoError(t, cache.Set(ctx, email.UserID, 0, "offsite", SearchFilters{}, 10, []SearchResult{{Subject: "stale"}}))
+
+	svc := NewSearchService(db, &fakeEmbedder{model: "m", dims: 3}, cache)
+	require.NoError(t, svc.GenerateAndSaveEmbedding(ctx, &email, 100))
+
+	generation, err := cache.Generation(ctx, email.UserID)
+	require.NoError(t, err)
+	_, found, err := cache.Get(ctx, email.UserID, generation, "offsite", SearchFilters{}, 10)
+	require.NoError(t, err)
+	assert.False(t, found)
+}
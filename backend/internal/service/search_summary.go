@@ -2,12 +2,79 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/hrygo/echomind/pkg/ai"
 )
 
+// searchSummaryInstructions is the default version of the search_summary prompt.
+const searchSummaryInstructions = `Please provide:
+1. A one-sentence summary (30-50 words, or characters in Chinese)
+2. 3-5 key topics
+3. Important people (at most 5)
+4. Whether any email is urgent
+
+Reply in JSON, in this form:
+{
+  "summary": "the summary",
+  "topics": ["topic 1", "topic 2"],
+  "people": ["name 1", "name 2"],
+  "urgent_count": 0
+}`
+
+const searchSummarySystemPrompt = "You summarize the emails that matched a user's search."
+
+// searchSummaryText holds the texts the service writes itself.
+type searchSummaryText struct {
+	noResults string
+	basic     string // Emails, senders
+	quick     string // Emails, senders
+}
+
+// searchSummaryTexts are the service's texts by language. Other languages use English; the AI
+// summary is written in any of ai.Languages.
+var searchSummaryTexts = map[string]searchSummaryText{
+	"en": {
+		noResults: "No matching emails found.",
+		basic:     "Found %d matching emails from %d contacts, covering several topics.",
+		quick:     "Found %d matching emails from %d different senders.",
+	},
+	"zh": {
+		noResults: "未找到相关邮件。",
+		basic:     "找到 %d 封相关邮件，主要来自 %d 位联系人，涉及多个主题。",
+		quick:     "找到 %d 封相关邮件，来自 %d 位不同的发件人。",
+	},
+}
+
+// summaryLanguage is the language of a search summary: the user's preferred one, else the query's,
+// else that of the results.
+func summaryLanguage(ctx context.Context, query string, results []SearchResult) string {
+	if language := ai.OutputLanguage(ctx); language != "" {
+		return language
+	}
+	if language := ai.DetectLanguage(query); language != "" {
+		return language
+	}
+	var subjects strings.Builder
+	for _, r := range results {
+		subjects.WriteString(r.Subject)
+		subjects.WriteString("\n")
+	}
+	if language := ai.DetectLanguage(subjects.String()); language != "" {
+		return language
+	}
+	return "en"
+}
+
+func summaryTexts(language string) searchSummaryText {
+	if texts, ok := searchSummaryTexts[language]; ok {
+		return texts
+	}
+	return searchSummaryTexts["en"]
+}
+
 // SearchSummaryService generates AI-powered summaries for search results
 type SearchSummaryService struct {
 	aiProvider ai.AIProvider
@@ -27,40 +94,47 @@ type SearchResultsSummary struct {
 	ImportantPeople []string `json:"important_people"`
 	UrgentCount     int      `json:"urgent_count"`
 	ActionItems     []string `json:"action_items,omitempty"`
+	PromptVersion   string   `json:"prompt_version,omitempty"` // search_summary template version used
 }
 
 // GenerateSummary creates an AI-powered summary of search results
 func (s *SearchSummaryService) GenerateSummary(ctx context.Context, results []SearchResult, query string) (*SearchResultsSummary, error) {
+	language := summaryLanguage(ctx, query, results)
 	if len(results) == 0 {
 		return &SearchResultsSummary{
-			NaturalSummary: "未找到相关邮件。",
+			NaturalSummary: summaryTexts(language).noResults,
 		}, nil
 	}
+	if ai.OutputLanguage(ctx) == "" {
+		ctx = ai.WithOutputLanguage(ctx, language)
+	}
 
-	// Prepare context for AI
-	prompt := s.buildSummaryPrompt(results, query)
-
-	// Call AI provider using Summarize method
-	// The Summarize method is designed for text analysis and summary generation
-	analysisResult, err := s.aiProvider.Summarize(ctx, prompt)
-
+	// A plain completion: the prompt asks for its own JSON shape, which the email analysis schema
+	// enforced by Summarize would reject.
+	system := searchSummarySystemPrompt
+	if instruction := ai.OutputLanguageInstruction(ctx); instruction != "" {
+		system += "\n" + instruction
+	}
+	reply, err := collectChatReply(ctx, s.aiProvider, []ai.Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: s.buildSummaryPrompt(ctx, results, query)},
+	})
 	if err != nil {
 		return nil, fmt.Errorf("AI summary generation failed: %w", err)
 	}
 
-	// Parse AI response and extract structured data
-	// Use the summary from analysis result
-	summary := s.parseSummaryResponse(analysisResult.Summary, results)
+	summary := s.parseSummaryResponse(reply, results, language)
+	summary.PromptVersion = ai.PromptRef(ctx, ai.PromptSearchSummary)
 
 	return summary, nil
 }
 
 // buildSummaryPrompt constructs the prompt for AI
-func (s *SearchSummaryService) buildSummaryPrompt(results []SearchResult, query string) string {
+func (s *SearchSummaryService) buildSummaryPrompt(ctx context.Context, results []SearchResult, query string) string {
 	var sb strings.Builder
 
-	sb.WriteString(fmt.Sprintf("用户搜索查询: \"%s\"\n\n", query))
-	sb.WriteString(fmt.Sprintf("找到 %d 封相关邮件:\n\n", len(results)))
+	sb.WriteString(fmt.Sprintf("Search query: \"%s\"\n\n", query))
+	sb.WriteString(fmt.Sprintf("%d matching emails:\n\n", len(results)))
 
 	// Limit to top 10 results for context
 	limit := 10
@@ -70,37 +144,31 @@ func (s *SearchSummaryService) buildSummaryPrompt(results []SearchResult, query
 
 	for i := 0; i < limit; i++ {
 		result := results[i]
-		sb.WriteString(fmt.Sprintf("%d. 发件人: %s\n", i+1, result.Sender))
-		sb.WriteString(fmt.Sprintf("   主题: %s\n", result.Subject))
-		sb.WriteString(fmt.Sprintf("   摘要: %s\n", truncateText(result.Snippet, 100)))
-		sb.WriteString(fmt.Sprintf("   日期: %s\n\n", result.Date.Format("2006-01-02")))
+		sb.WriteString(fmt.Sprintf("%d. From: %s\n", i+1, result.Sender))
+		sb.WriteString(fmt.Sprintf("   Subject: %s\n", result.Subject))
+		sb.WriteString(fmt.Sprintf("   Snippet: %s\n", truncateText(result.Snippet, 100)))
+		sb.WriteString(fmt.Sprintf("   Date: %s\n\n", result.Date.Format("2006-01-02")))
 	}
 
 	if len(results) > limit {
-		sb.WriteString(fmt.Sprintf("(还有 %d 封邮件未列出)\n\n", len(results)-limit))
-	}
-
-	sb.WriteString("请提供:\n")
-	sb.WriteString("1. 一句话总结 (中文, 30-50字)\n")
-	sb.WriteString("2. 3-5个关键主题\n")
-	sb.WriteString("3. 重要联系人 (最多5人)\n")
-	sb.WriteString("4. 是否有紧急邮件\n")
-	sb.WriteString("\n请以JSON格式返回，格式如下:\n")
-	sb.WriteString("{\n")
-	sb.WriteString("  \"summary\": \"总结内容\",\n")
-	sb.WriteString("  \"topics\": [\"主题1\", \"主题2\"],\n")
-	sb.WriteString("  \"people\": [\"人名1\", \"人名2\"],\n")
-	sb.WriteString("  \"urgent_count\": 0\n")
-	sb.WriteString("}")
+		sb.WriteString(fmt.Sprintf("(%d more emails not listed)\n\n", len(results)-limit))
+	}
+
+	sb.WriteString(ai.ResolvePrompt(ctx, ai.PromptSearchSummary, searchSummaryInstructions).Text)
 
 	return sb.String()
 }
 
 // parseSummaryResponse parses AI response into structured summary
-func (s *SearchSummaryService) parseSummaryResponse(response string, results []SearchResult) *SearchResultsSummary {
-	// Simple parsing logic - in production, use proper JSON parsing
+func (s *SearchSummaryService) parseSummaryResponse(response string, results []SearchResult, language string) *SearchResultsSummary {
 	summary := &SearchResultsSummary{
-		NaturalSummary: response,
+		NaturalSummary: strings.TrimSpace(response),
+	}
+	// The reply is JSON in the form the prompt asks for; anything else is taken as the summary text
+	var reply aiSearchSummary
+	if err := json.Unmarshal([]byte(extractJSONObject(response)), &reply); err == nil {
+		summary.NaturalSummary = strings.TrimSpace(reply.Summary)
+		summary.UrgentCount = reply.UrgentCount
 	}
 
 	// Extract basic statistics from results
@@ -122,6 +190,12 @@ func (s *SearchSummaryService) parseSummaryResponse(response string, results []S
 		}
 	}
 
+	// Topics and people come from the AI when it gave them, else from the results
+	if len(reply.Topics) > 0 || len(reply.People) > 0 {
+		summary.KeyTopics, summary.ImportantPeople = reply.Topics, reply.People
+		return s.withBasicSummary(summary, results, len(senderMap), language)
+	}
+
 	// Get top senders as important people
 	type kv struct {
 		Key   string
@@ -166,18 +240,25 @@ func (s *SearchSummaryService) parseSummaryResponse(response string, results []S
 		summary.KeyTopics = append(summary.KeyTopics, wordList[i].Key)
 	}
 
-	// If AI didn't provide a good summary, create a basic one
-	if len(response) < 20 {
-		summary.NaturalSummary = fmt.Sprintf(
-			"找到 %d 封相关邮件，主要来自 %d 位联系人，涉及多个主题。",
-			len(results),
-			len(senderMap),
-		)
-	}
+	return s.withBasicSummary(summary, results, len(senderMap), language)
+}
 
+// withBasicSummary replaces a missing or too short AI summary with a basic one.
+func (s *SearchSummaryService) withBasicSummary(summary *SearchResultsSummary, results []SearchResult, senders int, language string) *SearchResultsSummary {
+	if len(summary.NaturalSummary) < 20 {
+		summary.NaturalSummary = fmt.Sprintf(summaryTexts(language).basic, len(results), senders)
+	}
 	return summary
 }
 
+// aiSearchSummary is the JSON reply the search_summary prompt asks for.
+type aiSearchSummary struct {
+	Summary     string   `json:"summary"`
+	Topics      []string `json:"topics"`
+	People      []string `json:"people"`
+	UrgentCount int      `json:"urgent_count"`
+}
+
 // truncateText truncates text to specified length
 func truncateText(text string, maxLen int) string {
 	if len(text) <= maxLen {
@@ -187,10 +268,11 @@ func truncateText(text string, maxLen int) string {
 }
 
 // GenerateQuickSummary generates a quick non-AI summary
-func (s *SearchSummaryService) GenerateQuickSummary(results []SearchResult) *SearchResultsSummary {
+func (s *SearchSummaryService) GenerateQuickSummary(ctx context.Context, results []SearchResult, query string) *SearchResultsSummary {
+	texts := summaryTexts(summaryLanguage(ctx, query, results))
 	if len(results) == 0 {
 		return &SearchResultsSummary{
-			NaturalSummary: "未找到相关邮件。",
+			NaturalSummary: texts.noResults,
 		}
 	}
 
@@ -204,7 +286,7 @@ func (s *SearchSummaryService) GenerateQuickSummary(results []SearchResult) *Sea
 
 	summary := &SearchResultsSummary{
 		NaturalSummary: fmt.Sprintf(
-			"找到 %d 封相关邮件，来自 %d 位不同的发件人。",
+			texts.quick,
 			len(results),
 			len(senderMap),
 		),
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchSummaryService_GenerateSummary(t *testing.T) {
+	provider := new(MockAIProvider)
+	svc := NewSearchSummaryService(provider)
+	results := []SearchResult{
+		{Subject: "Budget review", Sender: "ada@example.com"},
+		{Subject: "Budget sign-off", Sender: "bob@example.com"},
+	}
+
+	// The summary is a plain completion in the prompt's own JSON shape, not an email analysis.
+	provider.On("StreamChat", mock.Anything, systemPromptContains("Write all free text"), mock.Anything).Return(nil).
+		Run(replyWith("```json\n{\"summary\":\"Ada and Bob are finalizing the budget this week.\",\"topics\":[\"budget\"],\"people\":[\"Ada\",\"Bob\"],\"urgent_count\":1}\n```")).Once()
+	summary, err := svc.GenerateSummary(context.Background(), results, "budget review")
+	require.NoError(t, err)
+	provider.AssertNotCalled(t, "Summarize", mock.Anything, mock.Anything)
+
+	assert.Equal(t, "Ada and Bob are finalizing the budget this week.", summary.NaturalSummary)
+	assert.Equal(t, []string{"budget"}, summary.KeyTopics)
+	assert.Equal(t, []string{"Ada", "Bob"}, summary.ImportantPeople)
+	assert.Equal(t, 1, summary.UrgentCount)
+
+	// A reply that is not JSON is the summary text, with topics and people from the results.
+	provider.On("StreamChat", mock.Anything, mock.Anything, mock.Anything).Return(nil).
+		Run(replyWith("Two emails about the budget, from Ada and Bob.")).Once()
+	summary, err = svc.GenerateSummary(context.Background(), results, "budget review")
+	require.NoError(t, err)
+	assert.Equal(t, "Two emails about the budget, from Ada and Bob.", summary.NaturalSummary)
+	assert.Equal(t, []string{"budget"}, summary.KeyTopics)
+	assert.Len(t, summary.ImportantPeople, 2)
+}
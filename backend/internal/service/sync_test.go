@@ -27,6 +27,7 @@ import (
 type MockIMAPSession struct {
 	LogoutFunc      func() error
 	FetchEmailsFunc func(mailbox string, limit int) ([]imap.EmailData, error)
+	SentMailboxFunc func() (string, error)
 }
 
 func (m *MockIMAPSession) Logout() error {
@@ -43,6 +44,13 @@ func (m *MockIMAPSession) FetchEmails(mailbox string, limit int) ([]imap.EmailDa
 	return nil, nil
 }
 
+func (m *MockIMAPSession) SentMailbox() (string, error) {
+	if m.SentMailboxFunc != nil {
+		return m.SentMailboxFunc()
+	}
+	return "", nil
+}
+
 // MockIMAPConnector implements service.IMAPConnector
 type MockIMAPConnector struct {
 	ConnectFunc func(ctx context.Context, account *model.EmailAccount) (service.IMAPSession, error)
@@ -167,3 +175,41 @@ func TestSyncEmails(t *testing.T) {
 		t.Errorf("Expected contact interaction count 1, got %d", contact.InteractionCount)
 	}
 }
+
+func TestEmailIngestor_SavesSentMail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ingest_sent?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to db: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Email{}); err != nil {
+		t.Fatalf("Failed to auto migrate database: %v", err)
+	}
+	now := time.Now()
+	session := &MockIMAPSession{
+		SentMailboxFunc: func() (string, error) { return "Sent Items", nil },
+		FetchEmailsFunc: func(mailbox string, limit int) ([]imap.EmailData, error) {
+			if mailbox == "Sent Items" {
+				return []imap.EmailData{{Subject: "Re: Hello", Sender: "me@example.com", Date: now, MessageID: "<sent@example.com>", BodyText: "Hi!"}}, nil
+			}
+			return []imap.EmailData{{Subject: "Hello", Sender: "ada@example.com", Date: now, MessageID: "<inbox@example.com>", BodyText: "Hello?"}}, nil
+		},
+	}
+	userID := uuid.New()
+	account := &model.EmailAccount{ID: uuid.New(), UserID: &userID}
+	ingestor := service.NewEmailIngestor(repository.NewEmailRepository(db), logger.GetDefaultLogger())
+
+	newEmails, err := ingestor.Ingest(context.Background(), session, account, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	if len(newEmails) != 1 || newEmails[0].Folder != model.FolderInbox {
+		t.Fatalf("Expected only the inbox email to be returned for analysis, got %+v", newEmails)
+	}
+	var sent model.Email
+	if err := db.Where("user_id = ? AND folder = ?", userID, model.FolderSent).First(&sent).Error; err != nil {
+		t.Fatalf("Sent email not saved: %v", err)
+	}
+	if sent.Subject != "Re: Hello" || !sent.IsRead {
+		t.Errorf("Unexpected sent email: subject %q, read %v", sent.Subject, sent.IsRead)
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTranslationEmailNotFound = errors.New("email not found")
+	ErrTranslationLanguage      = errors.New("unsupported language")
+)
+
+const (
+	// maxTranslationRunes bounds the body sent for translation; longer emails are cut.
+	maxTranslationRunes = 12000
+	// maxQueryTranslations bounds the in-memory cache of translated search queries.
+	maxQueryTranslations = 2048
+)
+
+const translationPrompt = `You translate emails from %s into %s.
+Keep names, email addresses, phone numbers, amounts, dates, URLs and placeholders such as [EMAIL_1] exactly as they are.
+Keep the line breaks and the tone of the original.
+The user sends a JSON object with "subject" and "body". Reply with JSON only, in the same form, with both fields translated.`
+
+const queryTranslationPrompt = `Translate this email search query into %s. Keep names, email addresses and quoted phrases as they are.
+Reply with the translated query only, without quotes or explanations.`
+
+// TranslationService detects and translates the languages of mail. It also applies each user's
+// preferred language, from their locale, to the AI output made on their behalf.
+type TranslationService struct {
+	db       *gorm.DB
+	provider ai.AIProvider // Routed to the translate capability
+	now      func() time.Time
+
+	mu      sync.Mutex
+	queries map[string]string // language + query -> translation
+}
+
+// NewTranslationService creates a TranslationService that translates with provider.
+func NewTranslationService(db *gorm.DB, provider ai.AIProvider) *TranslationService {
+	return &TranslationService{db: db, provider: provider, now: time.Now, queries: map[string]string{}}
+}
+
+// Scope returns a context whose AI output is written in userID's preferred language.
+func (s *TranslationService) Scope(ctx context.Context, userID uuid.UUID) context.Context {
+	return ai.WithLanguageResolver(ctx, &userLanguage{db: s.db, userID: userID})
+}
+
+// userLanguage looks the user's locale up once per request or task, when AI output needs it.
+type userLanguage struct {
+	db     *gorm.DB
+	userID uuid.UUID
+
+	once     sync.Once
+	language string
+}
+
+func (l *userLanguage) OutputLanguage(ctx context.Context) string {
+	l.once.Do(func() {
+		var users []model.User
+		if err := l.db.WithContext(ctx).Select("locale").Where("id = ?", l.userID).Limit(1).Find(&users).Error; err != nil {
+			fmt.Printf("Warning: failed to load user locale: %v\n", err)
+			return
+		}
+		if len(users) > 0 {
+			l.language = ai.LocaleLanguage(users[0].Locale)
+		}
+	})
+	return l.language
+}
+
+// EmailTranslationResult is an email in the requested language.
+type EmailTranslationResult struct {
+	EmailID        uuid.UUID `json:"email_id"`
+	Language       string    `json:"language"`
+	SourceLanguage string    `json:"source_language"`
+	Subject        string    `json:"subject"`
+	Body           string    `json:"body"`
+	Translated     bool      `json:"translated"` // False when the email is already in the language
+	Cached         bool      `json:"cached"`
+}
+
+// TranslateEmail returns the user's email translated into language, a code such as "en" or a
+// locale such as "zh-CN"; without one, into the user's preferred language, or English. Translations
+// are stored, so each email is translated into each language once.
+func (s *TranslationService) TranslateEmail(ctx context.Context, userID, emailID uuid.UUID, language string) (*EmailTranslationResult, error) {
+	target, err := s.targetLanguage(ctx, language)
+	if err != nil {
+		return nil, err
+	}
+
+	var email model.Email
+	err = s.db.WithContext(ctx).Where("id = ? AND user_id = ?", emailID, userID).Take(&email).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTranslationEmailNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	body := email.BodyText
+	if body == "" {
+		body = email.Snippet
+	}
+	source := email.Language
+	if source == "" {
+		source = ai.DetectLanguage(email.Subject + "\n" + body)
+	}
+	result := &EmailTranslationResult{EmailID: emailID, Language: target, SourceLanguage: source}
+	if source == target {
+		result.Subject, result.Body = email.Subject, body
+		return result, nil
+	}
+
+	var cached model.EmailTranslation
+	err = s.db.WithContext(ctx).Where("email_id = ? AND language = ?", emailID, target).Take(&cached).Error
+	if err == nil {
+		result.Subject, result.Body, result.Translated, result.Cached = cached.Subject, cached.Body, true, true
+		return result, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if runes := []rune(body); len(runes) > maxTranslationRunes {
+		body = string(runes[:maxTranslationRunes])
+	}
+	subject, translatedBody, err := s.translate(ctx, source, target, email.Subject, body)
+	if err != nil {
+		return nil, err
+	}
+	translation := model.EmailTranslation{
+		ID:             uuid.New(),
+		CreatedAt:      s.now().UTC(),
+		EmailID:        emailID,
+		Language:       target,
+		SourceLanguage: source,
+		Subject:        subject,
+		Body:           translatedBody,
+	}
+	// A concurrent request may have stored the same translation; either one will do.
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&translation).Error; err != nil {
+		return nil, err
+	}
+	result.Subject, result.Body, result.Translated = subject, translatedBody, true
+	return result, nil
+}
+
+// targetLanguage validates the requested language, defaulting to the context's output language.
+func (s *TranslationService) targetLanguage(ctx context.Context, language string) (string, error) {
+	if strings.TrimSpace(language) == "" {
+		if target := ai.OutputLanguage(ctx); target != "" {
+			return target, nil
+		}
+		return "en", nil
+	}
+	target := ai.LocaleLanguage(strings.TrimSpace(language))
+	if target == "" {
+		return "", fmt.Errorf("%w: %q", ErrTranslationLanguage, language)
+	}
+	return target, nil
+}
+
+type translationPayload struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+func (s *TranslationService) translate(ctx context.Context, source, target, subject, body string) (string, string, error) {
+	sourceName := ai.LanguageName(source)
+	if sourceName == "" {
+		sourceName = "the original language"
+	}
+	input, err := json.Marshal(translationPayload{Subject: subject, Body: body})
+	if err != nil {
+		return "", "", err
+	}
+	reply, err := collectChatReply(ctx, s.provider, []ai.Message{
+		{Role: "system", Content: fmt.Sprintf(translationPrompt, sourceName, ai.LanguageName(target))},
+		{Role: "user", Content: string(input)},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("translation failed: %w", err)
+	}
+	var output translationPayload
+	if err := json.Unmarshal([]byte(extractJSONObject(reply)), &output); err != nil {
+		return "", "", fmt.Errorf("invalid translation response: %w", err)
+	}
+	if strings.TrimSpace(output.Body) == "" && strings.TrimSpace(body) != "" {
+		return "", "", errors.New("invalid translation response: empty body")
+	}
+	return output.Subject, output.Body, nil
+}
+
+// TranslateQuery translates a search query into language. Translations are kept in memory, so a
+// repeated query costs no AI call.
+func (s *TranslationService) TranslateQuery(ctx context.Context, query, language string) (string, error) {
+	name := ai.LanguageName(language)
+	if name == "" {
+		return "", fmt.Errorf("%w: %q", ErrTranslationLanguage, language)
+	}
+	key := language + "\x00" + query
+	s.mu.Lock()
+	translated, ok := s.queries[key]
+	s.mu.Unlock()
+	if ok {
+		return translated, nil
+	}
+
+	reply, err := collectChatReply(ctx, s.provider, []ai.Message{
+		{Role: "system", Content: fmt.Sprintf(queryTranslationPrompt, name)},
+		{Role: "user", Content: query},
+	})
+	if err != nil {
+		return "", fmt.Errorf("query translation failed: %w", err)
+	}
+	translated = strings.Trim(strings.TrimSpace(reply), `"“”`)
+
+	s.mu.Lock()
+	if len(s.queries) >= maxQueryTranslations {
+		s.queries = map[string]string{} // Start over rather than track recency
+	}
+	s.queries[key] = translated
+	s.mu.Unlock()
+	return translated, nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestTranslationService(t *testing.T) (*TranslationService, *MockAIProvider, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open("file:translation?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Email{}, &model.EmailTranslation{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM email_translations")
+		db.Exec("DELETE FROM emails")
+		db.Exec("DELETE FROM users")
+	})
+	provider := new(MockAIProvider)
+	svc := NewTranslationService(db, provider)
+	svc.now = func() time.Time { return time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC) }
+	return svc, provider, db
+}
+
+// translateOnce makes the provider stream reply to the next translation.
+func translateOnce(provider *MockAIProvider, reply string) {
+	provider.On("StreamChat", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(replyWith(reply)).Once()
+}
+
+func TestTranslationService_TranslateEmail(t *testing.T) {
+	svc, provider, db := newTestTranslationService(t)
+	userID := uuid.New()
+	email := model.Email{ID: uuid.New(), UserID: userID, MessageID: "m1", Subject: "预算审核", BodyText: "请在周五前审阅预算。", Language: "zh"}
+	require.NoError(t, db.Create(&email).Error)
+	ctx := context.Background()
+
+	provider.On("StreamChat", mock.Anything, systemPromptContains("from Chinese into English"), mock.Anything).Return(nil).
+		Run(replyWith("```json\n{\"subject\":\"Budget review\",\"body\":\"Please review the budget by Friday.\"}\n```")).Once()
+	result, err := svc.TranslateEmail(ctx, userID, email.ID, "en-US")
+	require.NoError(t, err)
+	assert.Equal(t, &EmailTranslationResult{
+		EmailID: email.ID, Language: "en", SourceLanguage: "zh",
+		Subject: "Budget review", Body: "Please review the budget by Friday.", Translated: true,
+	}, result)
+
+	// The second request is served from the stored translation.
+	result, err = svc.TranslateEmail(ctx, userID, email.ID, "en")
+	require.NoError(t, err)
+	assert.True(t, result.Cached)
+	assert.Equal(t, "Budget review", result.Subject)
+	provider.AssertNumberOfCalls(t, "StreamChat", 1)
+
+	// An email already in the language is returned as it is.
+	result, err = svc.TranslateEmail(ctx, userID, email.ID, "zh-CN")
+	require.NoError(t, err)
+	assert.False(t, result.Translated)
+	assert.Equal(t, "请在周五前审阅预算。", result.Body)
+	provider.AssertNumberOfCalls(t, "StreamChat", 1)
+
+	_, err = svc.TranslateEmail(ctx, userID, email.ID, "klingon")
+	assert.ErrorIs(t, err, ErrTranslationLanguage)
+	_, err = svc.TranslateEmail(ctx, uuid.New(), email.ID, "en")
+	assert.ErrorIs(t, err, ErrTranslationEmailNotFound)
+
+	translateOnce(provider, "Sorry, I cannot help with that.")
+	_, err = svc.TranslateEmail(ctx, userID, email.ID, "fr")
+	assert.ErrorContains(t, err, "invalid translation response")
+	var stored int64
+	require.NoError(t, db.Model(&model.EmailTranslation{}).Count(&stored).Error)
+	assert.Equal(t, int64(1), stored, "failed translations are not cached")
+}
+
+func TestTranslationService_DefaultsToPreferredLanguage(t *testing.T) {
+	svc, provider, db := newTestTranslationService(t)
+	userID := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: userID, Email: "ada@example.com", Name: "Ada", Locale: "zh-CN", WeChatOpenID: userID.String()}).Error)
+	email := model.Email{ID: uuid.New(), UserID: userID, MessageID: "m2", Subject: "Budget", BodyText: "Please review the budget."}
+	require.NoError(t, db.Create(&email).Error)
+
+	ctx := svc.Scope(context.Background(), userID)
+	assert.Equal(t, "zh", ai.OutputLanguage(ctx))
+	assert.Empty(t, ai.OutputLanguage(svc.Scope(context.Background(), uuid.New())), "users without a locale leave the choice to the model")
+
+	translateOnce(provider, `{"subject":"预算","body":"请审阅预算。"}`)
+	result, err := svc.TranslateEmail(ctx, userID, email.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, "zh", result.Language)
+	assert.Equal(t, "en", result.SourceLanguage, "detected when the email has no language yet")
+
+	// Without a preference, English.
+	_, err = svc.TranslateEmail(context.Background(), userID, email.ID, "")
+	require.NoError(t, err)
+	provider.AssertNumberOfCalls(t, "StreamChat", 1)
+}
+
+func TestTranslationService_TranslateQuery(t *testing.T) {
+	svc, provider, _ := newTestTranslationService(t)
+	translateOnce(provider, "\"预算 审核\"\n")
+
+	translated, err := svc.TranslateQuery(context.Background(), "budget review", "zh")
+	require.NoError(t, err)
+	assert.Equal(t, "预算 审核", translated)
+	translated, err = svc.TranslateQuery(context.Background(), "budget review", "zh")
+	require.NoError(t, err)
+	assert.Equal(t, "预算 审核", translated)
+	provider.AssertNumberOfCalls(t, "StreamChat", 1)
+
+	_, err = svc.TranslateQuery(context.Background(), "budget", "xx")
+	assert.ErrorIs(t, err, ErrTranslationLanguage)
+}
+
+func TestSearchSummaryLanguage(t *testing.T) {
+	results := []SearchResult{{Subject: "季度预算审核"}, {Subject: "会议纪要"}}
+	assert.Equal(t, "fr", summaryLanguage(ai.WithOutputLanguage(context.Background(), "fr"), "budget review", results))
+	assert.Equal(t, "en", summaryLanguage(context.Background(), "budget review", results))
+	assert.Equal(t, "zh", summaryLanguage(context.Background(), "budget", results), "a one-word query says too little")
+
+	svc := NewSearchSummaryService(nil)
+	quick := svc.GenerateQuickSummary(context.Background(), []SearchResult{{Subject: "Budget review", Sender: "ada@example.com"}}, "budget review")
+	assert.True(t, strings.HasPrefix(quick.NaturalSummary, "Found 1 matching emails"))
+	quick = svc.GenerateQuickSummary(context.Background(), nil, "预算")
+	assert.Equal(t, "未找到相关邮件。", quick.NaturalSummary)
+}
+
+func TestTranslationTargets(t *testing.T) {
+	mail := []string{"en", "zh", "fr"}
+	assert.Equal(t, []string{"zh", "fr"}, translationTargets("en", mail))
+	assert.Equal(t, []string{"en", "fr"}, translationTargets("zh", mail))
+	assert.Equal(t, []string{"zh"}, translationTargets("", mail), "a single Latin word is not translated into other Latin languages")
+	assert.Empty(t, translationTargets("en", []string{"en"}))
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"gorm.io/gorm"
+)
+
+// ErrUsageForbidden is returned when a user asks for the usage of an organization they do not administer.
+var ErrUsageForbidden = errors.New("organization usage is only available to its owners and admins")
+
+const (
+	usageDayFormat = "2006-01-02"
+
+	// How long a user's primary organization and the token totals quotas are checked against are
+	// cached. Memberships can change and other instances record usage too, so neither is kept longer.
+	usageOrgCacheTTL    = 5 * time.Minute
+	usageTotalsCacheTTL = 30 * time.Second
+)
+
+// UsageService records the tokens every AI call uses, enforces quotas and reports usage and cost.
+type UsageService struct {
+	db     *gorm.DB
+	quotas configs.UsageQuotaConfig
+	prices map[string]configs.ModelPrice
+	now    func() time.Time
+
+	mu     sync.Mutex
+	orgs   map[uuid.UUID]cachedOrg  // Primary organization by user
+	totals map[string]*cachedTotals // Tokens used by "<column>:<id>", e.g. "user_id:<uuid>"
+}
+
+type cachedOrg struct {
+	orgID    *uuid.UUID
+	loadedAt time.Time
+}
+
+// cachedTotals are the tokens a user or organization used today and this month.
+type cachedTotals struct {
+	day      string // Day the totals were loaded on; they are reloaded on the next day
+	dayUsed  int64
+	month    int64
+	loadedAt time.Time
+}
+
+// NewUsageService creates a UsageService with the configured quotas and price table.
+func NewUsageService(db *gorm.DB, cfg configs.UsageConfig) *UsageService {
+	prices := make(map[string]configs.ModelPrice, len(cfg.Prices))
+	for _, price := range cfg.Prices {
+		prices[price.Model] = price
+	}
+	return &UsageService{
+		db:     db,
+		quotas: cfg.Quotas,
+		prices: prices,
+		now:    time.Now,
+		orgs:   make(map[uuid.UUID]cachedOrg),
+		totals: make(map[string]*cachedTotals),
+	}
+}
+
+// Scope returns a context whose AI calls are accounted to userID and checked against their quotas.
+func (s *UsageService) Scope(ctx context.Context, userID uuid.UUID) context.Context {
+	return ai.WithUsageMeter(ctx, &usageMeter{usage: s, userID: userID})
+}
+
+type usageMeter struct {
+	usage  *UsageService
+	userID uuid.UUID
+}
+
+func (m *usageMeter) Allow(ctx context.Context, capability ai.Capability) error {
+	if capability == ai.CapabilityEmbedding {
+		return nil
+	}
+	return m.usage.checkQuota(ctx, m.userID)
+}
+
+func (m *usageMeter) Record(ctx context.Context, usage ai.Usage) {
+	// Usage is recorded even when the request that caused it was cancelled mid-stream.
+	if err := m.usage.record(context.WithoutCancel(ctx), m.userID, usage); err != nil {
+		fmt.Printf("Warning: failed to record AI usage: %v\n", err)
+	}
+}
+
+func (s *UsageService) record(ctx context.Context, userID uuid.UUID, usage ai.Usage) error {
+	orgID, err := s.primaryOrg(ctx, userID)
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	err = s.db.WithContext(ctx).Create(&model.AIUsage{
+		ID:               uuid.New(),
+		CreatedAt:        now,
+		UserID:           userID,
+		OrgID:            orgID,
+		Day:              now.Format(usageDayFormat),
+		Capability:       string(usage.Capability),
+		Provider:         usage.Provider,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		Estimated:        usage.Estimated,
+	}).Error
+	if err != nil {
+		return err
+	}
+	tokens := int64(usage.PromptTokens + usage.CompletionTokens)
+	s.addUsed("user_id", userID, tokens)
+	if orgID != nil {
+		s.addUsed("org_id", *orgID, tokens)
+	}
+	return nil
+}
+
+// primaryOrg returns the organization usage is attributed to: the first one the user joined,
+// which is their personal organization. It is cached for usageOrgCacheTTL so membership changes
+// are picked up; a user without one is not cached, as they are about to get one.
+func (s *UsageService) primaryOrg(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	s.mu.Lock()
+	cached, ok := s.orgs[userID]
+	s.mu.Unlock()
+	if ok && s.now().Sub(cached.loadedAt) < usageOrgCacheTTL {
+		return cached.orgID, nil
+	}
+	var members []model.OrganizationMember
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Order("joined_at ASC").Limit(1).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	orgID := &members[0].OrganizationID
+	s.mu.Lock()
+	s.orgs[userID] = cachedOrg{orgID: orgID, loadedAt: s.now()}
+	s.mu.Unlock()
+	return orgID, nil
+}
+
+// checkQuota returns an error wrapping ai.ErrQuotaExceeded if the user or their organization has
+// used up a daily or monthly quota. The totals come from a short-lived cache, and a failure to load
+// them lets the call through: AI features should not go down with the usage table.
+func (s *UsageService) checkQuota(ctx context.Context, userID uuid.UUID) error {
+	q := s.quotas
+	if q.UserDailyTokens <= 0 && q.UserMonthlyTokens <= 0 && q.OrgDailyTokens <= 0 && q.OrgMonthlyTokens <= 0 {
+		return nil
+	}
+
+	check := func(scope, column string, id uuid.UUID, dailyLimit, monthlyLimit int64) error {
+		if dailyLimit <= 0 && monthlyLimit <= 0 {
+			return nil
+		}
+		used, err := s.cachedUsed(ctx, column, id)
+		if err != nil {
+			fmt.Printf("Warning: failed to check AI quota, allowing the call: %v\n", err)
+			return nil
+		}
+		if dailyLimit > 0 && used.dayUsed >= dailyLimit {
+			return fmt.Errorf("%w: %s daily limit of %d tokens reached", ai.ErrQuotaExceeded, scope, dailyLimit)
+		}
+		if monthlyLimit > 0 && used.month >= monthlyLimit {
+			return fmt.Errorf("%w: %s monthly limit of %d tokens reached", ai.ErrQuotaExceeded, scope, monthlyLimit)
+		}
+		return nil
+	}
+
+	if err := check("user", "user_id", userID, q.UserDailyTokens, q.UserMonthlyTokens); err != nil {
+		return err
+	}
+	if q.OrgDailyTokens <= 0 && q.OrgMonthlyTokens <= 0 {
+		return nil
+	}
+	orgID, err := s.primaryOrg(ctx, userID)
+	if err != nil {
+		fmt.Printf("Warning: failed to check AI quota, allowing the call: %v\n", err)
+		return nil
+	}
+	if orgID == nil {
+		return nil
+	}
+	return check("organization", "org_id", *orgID, q.OrgDailyTokens, q.OrgMonthlyTokens)
+}
+
+// cachedUsed returns the tokens used today and this month where column equals id, loading them at
+// most every usageTotalsCacheTTL. Usage recorded by this instance is added as it happens.
+func (s *UsageService) cachedUsed(ctx context.Context, column string, id uuid.UUID) (cachedTotals, error) {
+	key := column + ":" + id.String()
+	today, _ := s.periods()
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.totals[key]
+	if ok && cached.day == today && now.Sub(cached.loadedAt) < usageTotalsCacheTTL {
+		totals := *cached
+		s.mu.Unlock()
+		return totals, nil
+	}
+	s.mu.Unlock()
+
+	dayUsed, month, err := s.used(ctx, column, id)
+	if err != nil {
+		return cachedTotals{}, err
+	}
+	totals := cachedTotals{day: today, dayUsed: dayUsed, month: month, loadedAt: now}
+	s.mu.Lock()
+	s.totals[key] = &totals
+	s.mu.Unlock()
+	return totals, nil
+}
+
+// addUsed adds tokens to the cached totals where column equals id, if they are loaded.
+func (s *UsageService) addUsed(column string, id uuid.UUID, tokens int64) {
+	today, _ := s.periods()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.totals[column+":"+id.String()]; ok && cached.day == today {
+		cached.dayUsed += tokens
+		cached.month += tokens
+	}
+}
+
+// used sums the tokens used today and this month where column equals id.
+func (s *UsageService) used(ctx context.Context, column string, id uuid.UUID) (int64, int64, error) {
+	today, monthStart := s.periods()
+	var used struct {
+		Day   int64
+		Month int64
+	}
+	err := s.db.WithContext(ctx).Model(&model.AIUsage{}).
+		Select("COALESCE(SUM(CASE WHEN day = ? THEN prompt_tokens + completion_tokens ELSE 0 END), 0) AS day, "+
+			"COALESCE(SUM(prompt_tokens + completion_tokens), 0) AS month", today).
+		Where(column+" = ? AND day >= ?", id, monthStart).
+		Scan(&used).Error
+	return used.Day, used.Month, err
+}
+
+// periods returns today and the first day of this month, both in UTC.
+func (s *UsageService) periods() (string, string) {
+	now := s.now().UTC()
+	return now.Format(usageDayFormat), time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).Format(usageDayFormat)
+}
+
+// UsageLine is the usage of one group of calls.
+type UsageLine struct {
+	Day              string     `json:"day,omitempty"`
+	UserID           *uuid.UUID `json:"user_id,omitempty"`
+	Capability       string     `json:"capability,omitempty"`
+	Provider         string     `json:"provider,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	Calls            int64      `json:"calls"`
+	PromptTokens     int64      `json:"prompt_tokens"`
+	CompletionTokens int64      `json:"completion_tokens"`
+	EstimatedCalls   int64      `json:"estimated_calls"` // Calls whose token counts were estimated
+	CostUSD          float64    `json:"cost_usd"`
+}
+
+// UsageReport totals usage over a date range.
+type UsageReport struct {
+	From             string       `json:"from"`
+	To               string       `json:"to"`
+	Calls            int64        `json:"calls"`
+	PromptTokens     int64        `json:"prompt_tokens"`
+	CompletionTokens int64        `json:"completion_tokens"`
+	CostUSD          float64      `json:"cost_usd"`
+	UnpricedModels   []string     `json:"unpriced_models,omitempty"` // Models missing from the price table, counted at no cost
+	Lines            []UsageLine  `json:"lines"`
+	Quota            *QuotaStatus `json:"quota,omitempty"`
+}
+
+// QuotaStatus compares current usage with the configured quotas; a limit of 0 means unlimited.
+type QuotaStatus struct {
+	DailyLimit   int64 `json:"daily_limit"`
+	DailyUsed    int64 `json:"daily_used"`
+	MonthlyLimit int64 `json:"monthly_limit"`
+	MonthlyUsed  int64 `json:"monthly_used"`
+}
+
+// UsageRange resolves optional YYYY-MM-DD bounds; the default is the current month up to today.
+func (s *UsageService) UsageRange(from, to string) (string, string, error) {
+	today, monthStart := s.periods()
+	if from == "" {
+		from = monthStart
+	}
+	if to == "" {
+		to = today
+	}
+	for _, day := range []string{from, to} {
+		if _, err := time.Parse(usageDayFormat, day); err != nil {
+			return "", "", fmt.Errorf("invalid date %q, expected YYYY-MM-DD", day)
+		}
+	}
+	if to < from {
+		return "", "", errors.New("to must not be before from")
+	}
+	return from, to, nil
+}
+
+// UserUsage reports a user's usage by capability and model between from and to (inclusive), with
+// their quota status.
+func (s *UsageService) UserUsage(ctx context.Context, userID uuid.UUID, from, to string) (*UsageReport, error) {
+	report, err := s.report(ctx, "user_id = ?", userID, from, to, "capability, provider, model")
+	if err != nil {
+		return nil, err
+	}
+
+	dayUsed, monthUsed, err := s.used(ctx, "user_id", userID)
+	if err != nil {
+		return nil, err
+	}
+	report.Quota = &QuotaStatus{
+		DailyLimit:   s.quotas.UserDailyTokens,
+		DailyUsed:    dayUsed,
+		MonthlyLimit: s.quotas.UserMonthlyTokens,
+		MonthlyUsed:  monthUsed,
+	}
+	return report, nil
+}
+
+// DailyUsage reports a user's usage per day between from and to (inclusive).
+func (s *UsageService) DailyUsage(ctx context.Context, userID uuid.UUID, from, to string) (*UsageReport, error) {
+	return s.report(ctx, "user_id = ?", userID, from, to, "day, model")
+}
+
+// OrgUsage reports an organization's usage per member and model. Only owners and admins may see it.
+func (s *UsageService) OrgUsage(ctx context.Context, orgID, requesterID uuid.UUID, from, to string) (*UsageReport, error) {
+	var member model.OrganizationMember
+	err := s.db.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, requesterID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUsageForbidden
+		}
+		return nil, err
+	}
+	if member.Role != model.OrgRoleOwner && member.Role != model.OrgRoleAdmin {
+		return nil, ErrUsageForbidden
+	}
+	return s.report(ctx, "org_id = ?", orgID, from, to, "user_id, model")
+}
+
+// report aggregates usage matching where between from and to, grouped by the given columns, and
+// prices it.
+func (s *UsageService) report(ctx context.Context, where string, id uuid.UUID, from, to, groupBy string) (*UsageReport, error) {
+	var rows []struct {
+		Day              string
+		UserID           *uuid.UUID
+		Capability       string
+		Provider         string
+		Model            string
+		Calls            int64
+		PromptTokens     int64
+		CompletionTokens int64
+		EstimatedCalls   int64
+	}
+	err := s.db.WithContext(ctx).Model(&model.AIUsage{}).
+		Select(groupBy+", COUNT(*) AS calls, SUM(prompt_tokens) AS prompt_tokens, "+
+			"SUM(completion_tokens) AS completion_tokens, SUM(CASE WHEN estimated THEN 1 ELSE 0 END) AS estimated_calls").
+		Where(where+" AND day BETWEEN ? AND ?", id, from, to).
+		Group(groupBy).
+		Order(groupBy).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UsageReport{From: from, To: to, Lines: make([]UsageLine, 0, len(rows))}
+	unpriced := map[string]bool{}
+	for _, row := range rows {
+		line := UsageLine{
+			Day:              row.Day,
+			UserID:           row.UserID,
+			Capability:       row.Capability,
+			Provider:         row.Provider,
+			Model:            row.Model,
+			Calls:            row.Calls,
+			PromptTokens:     row.PromptTokens,
+			CompletionTokens: row.CompletionTokens,
+			EstimatedCalls:   row.EstimatedCalls,
+		}
+		if price, ok := s.prices[row.Model]; ok {
+			line.CostUSD = (float64(row.PromptTokens)*price.PromptPerM + float64(row.CompletionTokens)*price.CompletionPerM) / 1e6
+		} else if row.Model != "" {
+			unpriced[row.Model] = true
+		}
+		report.Calls += line.Calls
+		report.PromptTokens += line.PromptTokens
+		report.CompletionTokens += line.CompletionTokens
+		report.CostUSD += line.CostUSD
+		report.Lines = append(report.Lines, line)
+	}
+	for m := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, m)
+	}
+	sort.Strings(report.UnpricedModels)
+	return report, nil
+}
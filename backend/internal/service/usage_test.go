@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func setupUsageTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:ai_usage?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.OrganizationMember{}, &model.AIUsage{}))
+	t.Cleanup(func() {
+		db.Exec("DELETE FROM ai_usages")
+		db.Exec("DELETE FROM organization_members")
+	})
+	return db
+}
+
+func addUsageTestMember(t *testing.T, db *gorm.DB, orgID, userID uuid.UUID, role model.OrganizationRole) {
+	require.NoError(t, db.Omit(clause.Associations).Create(&model.OrganizationMember{
+		OrganizationID: orgID, UserID: userID, Role: role,
+	}).Error)
+}
+
+func newTestUsageService(db *gorm.DB, cfg configs.UsageConfig, now time.Time) *UsageService {
+	s := NewUsageService(db, cfg)
+	s.now = func() time.Time { return now }
+	return s
+}
+
+func TestUsageService_RecordsAndEnforcesQuotas(t *testing.T) {
+	db := setupUsageTestDB(t)
+	orgID, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	addUsageTestMember(t, db, orgID, alice, model.OrgRoleOwner)
+	addUsageTestMember(t, db, orgID, bob, model.OrgRoleMember)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	svc := newTestUsageService(db, configs.UsageConfig{Quotas: configs.UsageQuotaConfig{
+		UserDailyTokens: 100, OrgMonthlyTokens: 250,
+	}}, now)
+	aliceCtx := svc.Scope(context.Background(), alice)
+	bobCtx := svc.Scope(context.Background(), bob)
+
+	require.NoError(t, ai.CheckQuota(aliceCtx, ai.CapabilitySummary))
+	ai.ReportUsage(ai.WithCapability(aliceCtx, ai.CapabilitySummary),
+		ai.Usage{Provider: "openai", Model: "gpt-4o-mini", PromptTokens: 80, CompletionTokens: 30})
+
+	var recorded model.AIUsage
+	require.NoError(t, db.First(&recorded).Error)
+	assert.Equal(t, "summary", recorded.Capability)
+	assert.Equal(t, "2026-03-10", recorded.Day)
+	require.NotNil(t, recorded.OrgID)
+	assert.Equal(t, orgID, *recorded.OrgID)
+
+	err := ai.CheckQuota(aliceCtx, ai.CapabilityChat)
+	assert.ErrorIs(t, err, ai.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "user daily limit")
+	assert.NoError(t, ai.CheckQuota(aliceCtx, ai.CapabilityEmbedding), "embeddings are never refused")
+	assert.NoError(t, ai.CheckQuota(bobCtx, ai.CapabilityChat))
+
+	// The next day alice's daily quota is fresh.
+	svc.now = func() time.Time { return now.AddDate(0, 0, 1) }
+	assert.NoError(t, ai.CheckQuota(aliceCtx, ai.CapabilityChat))
+
+	// Bob's usage counts towards the organization's monthly quota, which then stops both.
+	ai.ReportUsage(bobCtx, ai.Usage{Model: "gpt-4o-mini", PromptTokens: 150})
+	err = ai.CheckQuota(aliceCtx, ai.CapabilityChat)
+	assert.ErrorIs(t, err, ai.ErrQuotaExceeded)
+	assert.Contains(t, err.Error(), "organization monthly limit")
+}
+
+func TestUsageService_Reports(t *testing.T) {
+	db := setupUsageTestDB(t)
+	orgID, alice, bob := uuid.New(), uuid.New(), uuid.New()
+	addUsageTestMember(t, db, orgID, alice, model.OrgRoleAdmin)
+	addUsageTestMember(t, db, orgID, bob, model.OrgRoleMember)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	svc := newTestUsageService(db, configs.UsageConfig{
+		Quotas: configs.UsageQuotaConfig{UserDailyTokens: 10000},
+		Prices: []configs.ModelPrice{{Model: "gpt-4o-mini", PromptPerM: 0.15, CompletionPerM: 0.60}},
+	}, now)
+	ctx := context.Background()
+
+	chat := ai.WithCapability(svc.Scope(ctx, alice), ai.CapabilityChat)
+	ai.ReportUsage(chat, ai.Usage{Model: "gpt-4o-mini", PromptTokens: 1000, CompletionTokens: 500})
+	ai.ReportUsage(chat, ai.Usage{Model: "gpt-4o-mini", PromptTokens: 1000, CompletionTokens: 500, Estimated: true})
+	ai.ReportUsage(svc.Scope(ctx, alice), ai.Usage{Capability: ai.CapabilityEmbedding, Model: "local-embed", PromptTokens: 200})
+	ai.ReportUsage(svc.Scope(ctx, bob), ai.Usage{Model: "gpt-4o-mini", PromptTokens: 400})
+
+	from, to, err := svc.UsageRange("", "")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-01", from)
+	assert.Equal(t, "2026-03-10", to)
+	_, _, err = svc.UsageRange("2026-03-10", "2026-03-01")
+	assert.Error(t, err)
+
+	report, err := svc.UserUsage(ctx, alice, from, to)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, report.Calls)
+	require.Len(t, report.Lines, 2)
+	assert.Equal(t, "chat", report.Lines[0].Capability)
+	assert.EqualValues(t, 2, report.Lines[0].Calls)
+	assert.EqualValues(t, 1, report.Lines[0].EstimatedCalls)
+	assert.InDelta(t, (2000*0.15+1000*0.60)/1e6, report.CostUSD, 1e-12)
+	assert.Equal(t, []string{"local-embed"}, report.UnpricedModels)
+	require.NotNil(t, report.Quota)
+	assert.EqualValues(t, 3200, report.Quota.DailyUsed)
+
+	daily, err := svc.DailyUsage(ctx, alice, from, to)
+	require.NoError(t, err)
+	require.Len(t, daily.Lines, 2)
+	assert.Equal(t, "2026-03-10", daily.Lines[0].Day)
+
+	_, err = svc.OrgUsage(ctx, orgID, bob, from, to)
+	assert.ErrorIs(t, err, ErrUsageForbidden)
+	_, err = svc.OrgUsage(ctx, uuid.New(), alice, from, to)
+	assert.ErrorIs(t, err, ErrUsageForbidden)
+
+	org, err := svc.OrgUsage(ctx, orgID, alice, from, to)
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, org.Calls)
+	assert.EqualValues(t, 2600, org.PromptTokens)
+	assert.Len(t, org.Lines, 3, "alice's two models and bob's one")
+}
+
+func TestUsageService_QuotaCaches(t *testing.T) {
+	db := setupUsageTestDB(t)
+	firstOrg, secondOrg, alice := uuid.New(), uuid.New(), uuid.New()
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	svc := newTestUsageService(db, configs.UsageConfig{Quotas: configs.UsageQuotaConfig{UserDailyTokens: 100}}, now)
+	ctx := svc.Scope(context.Background(), alice)
+
+	// A user without an organization is not cached: the one they join next is used.
+	orgID, err := svc.primaryOrg(ctx, alice)
+	require.NoError(t, err)
+	assert.Nil(t, orgID)
+	addUsageTestMember(t, db, firstOrg, alice, model.OrgRoleOwner)
+	orgID, err = svc.primaryOrg(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, firstOrg, *orgID)
+
+	// Membership changes are picked up once the cached organization expires.
+	require.NoError(t, db.Where("user_id = ?", alice).Delete(&model.OrganizationMember{}).Error)
+	addUsageTestMember(t, db, secondOrg, alice, model.OrgRoleOwner)
+	svc.now = func() time.Time { return now.Add(usageOrgCacheTTL) }
+	orgID, err = svc.primaryOrg(ctx, alice)
+	require.NoError(t, err)
+	assert.Equal(t, secondOrg, *orgID)
+
+	// Usage recorded elsewhere is seen once the cached totals expire.
+	require.NoError(t, ai.CheckQuota(ctx, ai.CapabilityChat))
+	require.NoError(t, db.Create(&model.AIUsage{ID: uuid.New(), UserID: alice, Day: "2026-03-10", PromptTokens: 100}).Error)
+	assert.NoError(t, ai.CheckQuota(ctx, ai.CapabilityChat), "totals are cached")
+	svc.now = func() time.Time { return now.Add(usageOrgCacheTTL + usageTotalsCacheTTL) }
+	assert.ErrorIs(t, ai.CheckQuota(ctx, ai.CapabilityChat), ai.ErrQuotaExceeded)
+
+	// A failing usage table lets calls through.
+	require.NoError(t, db.Migrator().DropTable(&model.AIUsage{}))
+	t.Cleanup(func() { require.NoError(t, db.AutoMigrate(&model.AIUsage{})) })
+	svc.now = func() time.Time { return now.Add(usageOrgCacheTTL + 2*usageTotalsCacheTTL) }
+	assert.NoError(t, ai.CheckQuota(ctx, ai.CapabilityChat))
+}
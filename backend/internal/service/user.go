@@ -110,7 +110,7 @@ func (s *UserService) LoginUser(ctx context.Context, email, password string) (st
 }
 
 // UpdateUserProfile updates the profile (role, name) of a user.
-func (s *UserService) UpdateUserProfile(ctx context.Context, userID uuid.UUID, role, name string) error {
+func (s *UserService) UpdateUserProfile(ctx context.Context, userID uuid.UUID, role, name, locale string) error {
 	updates := make(map[string]interface{})
 	if role != "" {
 		updates["role"] = role
@@ -118,6 +118,9 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID uuid.UUID, r
 	if name != "" {
 		updates["name"] = name
 	}
+	if locale != "" {
+		updates["locale"] = locale
+	}
 
 	if len(updates) == 0 {
 		return nil
@@ -35,7 +35,7 @@ func TestUpdateUserProfile(t *testing.T) {
 	db.Create(user)
 
 	// 2. Update Name only
-	err := svc.UpdateUserProfile(ctx, user.ID, "", "New Name")
+	err := svc.UpdateUserProfile(ctx, user.ID, "", "New Name", "")
 	assert.NoError(t, err)
 
 	var updatedUser model.User
@@ -44,7 +44,7 @@ func TestUpdateUserProfile(t *testing.T) {
 	assert.Equal(t, "executive", updatedUser.Role) // Role unchanged
 
 	// 3. Update Role only
-	err = svc.UpdateUserProfile(ctx, user.ID, "manager", "")
+	err = svc.UpdateUserProfile(ctx, user.ID, "manager", "", "")
 	assert.NoError(t, err)
 
 	db.First(&updatedUser, "id = ?", user.ID)
@@ -52,10 +52,19 @@ func TestUpdateUserProfile(t *testing.T) {
 	assert.Equal(t, "manager", updatedUser.Role)
 
 	// 4. Update Both
-	err = svc.UpdateUserProfile(ctx, user.ID, "dealmaker", "Final Name")
+	err = svc.UpdateUserProfile(ctx, user.ID, "dealmaker", "Final Name", "")
 	assert.NoError(t, err)
 
 	db.First(&updatedUser, "id = ?", user.ID)
 	assert.Equal(t, "Final Name", updatedUser.Name)
 	assert.Equal(t, "dealmaker", updatedUser.Role)
+
+	// 5. Update Locale only
+	err = svc.UpdateUserProfile(ctx, user.ID, "", "", "zh-CN")
+	assert.NoError(t, err)
+
+	db.First(&updatedUser, "id = ?", user.ID)
+	assert.Equal(t, "Final Name", updatedUser.Name) // Name unchanged
+	assert.Equal(t, "dealmaker", updatedUser.Role)  // Role unchanged
+	assert.Equal(t, "zh-CN", updatedUser.Locale)
 }
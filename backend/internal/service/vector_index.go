@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hrygo/echomind/pkg/database"
+	"github.com/pgvector/pgvector-go"
+	"gorm.io/gorm"
+)
+
+// EmailEmbeddingsVectorIndex is the name of the ANN index on the legacy email_embeddings table.
+// Indexes of other embedding spaces follow the same "<table>_vector_idx" pattern.
+const EmailEmbeddingsVectorIndex = legacyEmbeddingTable + "_vector_idx"
+
+// ANNTuning holds per-query accuracy/speed knobs for approximate nearest-neighbour search.
+// Zero values leave the server defaults (hnsw.ef_search = 40, ivfflat.probes = 1) in place.
+type ANNTuning struct {
+	EfSearch int // HNSW: size of the dynamic candidate list; must be >= LIMIT for full result sets
+	Probes   int // IVFFlat: number of lists scanned
+}
+
+// IsZero reports whether no tuning is requested.
+func (t ANNTuning) IsZero() bool {
+	return t.EfSearch <= 0 && t.Probes <= 0
+}
+
+// Or returns t with unset fields taken from fallback.
+func (t ANNTuning) Or(fallback ANNTuning) ANNTuning {
+	if t.EfSearch <= 0 {
+		t.EfSearch = fallback.EfSearch
+	}
+	if t.Probes <= 0 {
+		t.Probes = fallback.Probes
+	}
+	return t
+}
+
+// applyANNTuning sets transaction-local ANN parameters. It must run inside a transaction.
+func applyANNTuning(tx *gorm.DB, tuning ANNTuning) error {
+	if tuning.EfSearch > 0 {
+		if err := tx.Exec("SELECT set_config('hnsw.ef_search', ?, true)", strconv.Itoa(tuning.EfSearch)).Error; err != nil {
+			return fmt.Errorf("failed to set hnsw.ef_search: %w", err)
+		}
+	}
+	if tuning.Probes > 0 {
+		if err := tx.Exec("SELECT set_config('ivfflat.probes', ?, true)", strconv.Itoa(tuning.Probes)).Error; err != nil {
+			return fmt.Errorf("failed to set ivfflat.probes: %w", err)
+		}
+	}
+	return nil
+}
+
+// VectorIndexInfo describes an ANN index on an embeddings table.
+type VectorIndexInfo struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+	SizeBytes  int64  `json:"size_bytes"`
+	Valid      bool   `json:"valid"` // False while a concurrent build is in progress or after it failed
+}
+
+// VectorIndexManager builds and maintains the pgvector index behind semantic search.
+type VectorIndexManager struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewVectorIndexManager manages the index of the legacy email_embeddings table.
+func NewVectorIndexManager(db *gorm.DB) *VectorIndexManager {
+	return &VectorIndexManager{db: db, table: legacyEmbeddingTable}
+}
+
+// ForTable returns a manager for another embedding space's table.
+func (m *VectorIndexManager) ForTable(table string) *VectorIndexManager {
+	return &VectorIndexManager{db: m.db, table: table}
+}
+
+// IndexName is the name of the managed ANN index.
+func (m *VectorIndexManager) IndexName() string {
+	return m.table + "_vector_idx"
+}
+
+// List returns every HNSW or IVFFlat index on the managed table.
+func (m *VectorIndexManager) List(ctx context.Context) ([]VectorIndexInfo, error) {
+	var indexes []VectorIndexInfo
+	err := m.db.WithContext(ctx).Raw(`
+		SELECT
+			i.indexname AS name,
+			i.indexdef AS definition,
+			pg_relation_size(c.oid) AS size_bytes,
+			x.indisvalid AS valid
+		FROM pg_indexes i
+		JOIN pg_class c ON c.relname = i.indexname
+		JOIN pg_index x ON x.indexrelid = c.oid
+		WHERE i.tablename = ?
+		  AND (i.indexdef ILIKE '%USING hnsw%' OR i.indexdef ILIKE '%USING ivfflat%')
+		ORDER BY i.indexname
+	`, m.table).Scan(&indexes).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vector indexes: %w", err)
+	}
+	return indexes, nil
+}
+
+// Rebuild builds a new index with the given options next to the current one and swaps it in,
+// so semantic search keeps an index to use for the whole operation.
+func (m *VectorIndexManager) Rebuild(ctx context.Context, opts database.VectorIndexOptions) error {
+	tmpName := m.IndexName() + "_new"
+	createSQL, err := database.CreateVectorIndexSQL(tmpName, m.table, "vector", opts, true)
+	if err != nil {
+		return err
+	}
+
+	db := m.db.WithContext(ctx)
+	steps := []string{
+		"DROP INDEX CONCURRENTLY IF EXISTS " + tmpName, // Leftover from an interrupted build
+		createSQL,
+		"DROP INDEX CONCURRENTLY IF EXISTS " + m.IndexName(),
+		fmt.Sprintf("ALTER INDEX %s RENAME TO %s", tmpName, m.IndexName()),
+	}
+	for _, step := range steps {
+		if err := db.Exec(step).Error; err != nil {
+			return fmt.Errorf("vector index rebuild failed at %q: %w", step, err)
+		}
+	}
+	return nil
+}
+
+// Reindex rebuilds the existing index in place, e.g. after heavy churn degraded an IVFFlat index.
+func (m *VectorIndexManager) Reindex(ctx context.Context) error {
+	if err := m.db.WithContext(ctx).Exec("REINDEX INDEX CONCURRENTLY " + m.IndexName()).Error; err != nil {
+		return fmt.Errorf("failed to reindex %s: %w", m.IndexName(), err)
+	}
+	return nil
+}
+
+// Drop removes the index, falling back to exact (sequential) search.
+func (m *VectorIndexManager) Drop(ctx context.Context) error {
+	if err := m.db.WithContext(ctx).Exec("DROP INDEX CONCURRENTLY IF EXISTS " + m.IndexName()).Error; err != nil {
+		return fmt.Errorf("failed to drop %s: %w", m.IndexName(), err)
+	}
+	return nil
+}
+
+// RecallReport compares ANN results against exact search for a sample of queries.
+type RecallReport struct {
+	Tuning        ANNTuning     `json:"tuning"`
+	K             int           `json:"k"`
+	Queries       int           `json:"queries"`
+	MeanRecall    float64       `json:"mean_recall"`
+	MinRecall     float64       `json:"min_recall"`
+	ANNLatencyP50 time.Duration `json:"ann_latency_p50"`
+	ANNLatencyP95 time.Duration `json:"ann_latency_p95"`
+	ExactLatency  time.Duration `json:"exact_latency_p50"`
+}
+
+// MeasureRecall samples stored embeddings as queries and reports recall@k of the index under the
+// given tuning, using a forced sequential scan as ground truth.
+func (m *VectorIndexManager) MeasureRecall(ctx context.Context, sampleSize, k int, tuning ANNTuning) (*RecallReport, error) {
+	if sampleSize <= 0 || k <= 0 {
+		return nil, fmt.Errorf("sample size and k must be positive")
+	}
+
+	var samples []struct{ Vector pgvector.Vector }
+	if err := m.db.WithContext(ctx).Raw("SELECT vector FROM "+m.table+" ORDER BY random() LIMIT ?", sampleSize).Scan(&samples).Error; err != nil {
+		return nil, fmt.Errorf("failed to sample query vectors: %w", err)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%s is empty", m.table)
+	}
+
+	report := &RecallReport{Tuning: tuning, K: k, Queries: len(samples), MinRecall: 1}
+	var annLatencies, exactLatencies []time.Duration
+	for _, sample := range samples {
+		approx, annLatency, err := m.nearest(ctx, sample.Vector, k, func(tx *gorm.DB) error {
+			return applyANNTuning(tx, tuning)
+		})
+		if err != nil {
+			return nil, err
+		}
+		exact, exactLatency, err := m.nearest(ctx, sample.Vector, k, func(tx *gorm.DB) error {
+			return tx.Exec("SELECT set_config('enable_indexscan', 'off', true)").Error
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		recall := recallAtK(approx, exact)
+		report.MeanRecall += recall
+		if recall < report.MinRecall {
+			report.MinRecall = recall
+		}
+		annLatencies = append(annLatencies, annLatency)
+		exactLatencies = append(exactLatencies, exactLatency)
+	}
+
+	report.MeanRecall /= float64(len(samples))
+	report.ANNLatencyP50 = percentileDuration(annLatencies, 0.50)
+	report.ANNLatencyP95 = percentileDuration(annLatencies, 0.95)
+	report.ExactLatency = percentileDuration(exactLatencies, 0.50)
+	return report, nil
+}
+
+// nearest runs a k-NN query inside a transaction after applying the given session settings.
+func (m *VectorIndexManager) nearest(ctx context.Context, vector pgvector.Vector, k int, configure func(tx *gorm.DB) error) ([]uint, time.Duration, error) {
+	var ids []uint
+	var elapsed time.Duration
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := configure(tx); err != nil {
+			return err
+		}
+		start := time.Now()
+		err := tx.Raw("SELECT id FROM "+m.table+" ORDER BY vector <=> ? LIMIT ?", vector, k).Scan(&ids).Error
+		elapsed = time.Since(start)
+		return err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("nearest neighbour query failed: %w", err)
+	}
+	return ids, elapsed, nil
+}
+
+// recallAtK is the fraction of the exact neighbours that the approximate search also returned.
+func recallAtK(approx, exact []uint) float64 {
+	if len(exact) == 0 {
+		return 1
+	}
+	found := make(map[uint]bool, len(approx))
+	for _, id := range approx {
+		found[id] = true
+	}
+	hits := 0
+	for _, id := range exact {
+		if found[id] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(exact))
+}
+
+func percentileDuration(durations []time.Duration, p float64) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p * float64(len(sorted)-1))
+	return sorted[idx]
+}
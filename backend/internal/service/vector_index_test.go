@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecallAtK(t *testing.T) {
+	assert.Equal(t, 1.0, recallAtK([]uint{1, 2, 3}, []uint{3, 2, 1}))
+	assert.InDelta(t, 2.0/3, recallAtK([]uint{1, 2, 9}, []uint{1, 2, 3}), 1e-9)
+	assert.Equal(t, 0.0, recallAtK(nil, []uint{1}))
+	assert.Equal(t, 1.0, recallAtK(nil, nil))
+}
+
+func TestANNTuning_Or(t *testing.T) {
+	defaults := ANNTuning{EfSearch: 80, Probes: 4}
+
+	assert.Equal(t, defaults, ANNTuning{}.Or(defaults))
+	assert.Equal(t, ANNTuning{EfSearch: 200, Probes: 4}, ANNTuning{EfSearch: 200}.Or(defaults))
+	assert.True(t, ANNTuning{}.Or(ANNTuning{}).IsZero())
+}
+
+func TestPercentileDuration(t *testing.T) {
+	durations := []time.Duration{5, 1, 4, 2, 3}
+	assert.Equal(t, time.Duration(3), percentileDuration(durations, 0.5))
+	assert.Equal(t, time.Duration(4), percentileDuration(durations, 0.95))
+	assert.Equal(t, time.Duration(0), percentileDuration(nil, 0.5))
+}
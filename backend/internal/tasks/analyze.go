@@ -87,6 +87,13 @@ func HandleEmailAnalyzeTask(ctx context.Context, t *asynq.Task, db *gorm.DB, sum
 		return fmt.Errorf("email %s not found for user %s: %v", p.EmailID, p.UserID, err)
 	}
 
+	// Emails stored before language detection get it here
+	languageDetected := false
+	if email.Language == "" {
+		email.Language = ai.DetectLanguage(email.Subject + "\n" + email.BodyText)
+		languageDetected = true
+	}
+
 	// 2. Check for Spam
 	spamFilter := spam.NewRuleBasedFilter()
 	isSpam, spamReason := spamFilter.IsSpam(&email)
@@ -116,38 +123,52 @@ func HandleEmailAnalyzeTask(ctx context.Context, t *asynq.Task, db *gorm.DB, sum
 		textToAnalyze = email.Snippet // Fallback
 	}
 
-	analysis, err := summarizer.GenerateSummary(ctx, textToAnalyze)
-	if err != nil {
-		return fmt.Errorf("failed to generate analysis for email %s (user %s): %v", p.EmailID, p.UserID, err)
+	// The analysis is written in the user's preferred language, or in the email's own without one
+	analysisCtx := ctx
+	if ai.OutputLanguage(ctx) == "" && email.Language != "" {
+		analysisCtx = ai.WithOutputLanguage(ctx, email.Language)
 	}
-
-	// 4. Update Email fields
-	email.Summary = analysis.Summary
-	email.Category = analysis.Category
-	email.Sentiment = analysis.Sentiment
-	email.Urgency = analysis.Urgency
-	email.ActionItems = datatypes.JSON(jsonRaw(analysis.ActionItems))
-	email.SmartActions = datatypes.JSON(jsonRaw(analysis.SmartActions))
-
-	// 5. Update Email, ensure it belongs to the user
-	if err := db.WithContext(ctx).Where("user_id = ?", p.UserID).Save(&email).Error; err != nil {
-		return fmt.Errorf("failed to save analysis for email %s (user %s): %v", p.EmailID, p.UserID, err)
-	}
-
-	log.InfoContext(ctx, "[Email Analyzed]",
-		logger.String("email_id", p.EmailID.String()),
-		logger.String("category", email.Category),
-		logger.String("sentiment", email.Sentiment),
-		logger.String("urgency", email.Urgency),
-		logger.String("component", "email_analyzer"))
-
-	// 6. Update Contact Statistics for the sender
-	if err := updateContactStats(ctx, db, p.UserID, email.Sender, email.Sentiment, email.Date, log); err != nil {
-		log.WarnContext(ctx, "Failed to update contact stats",
-			logger.String("sender", email.Sender),
+	analysis, err := summarizer.GenerateSummary(analysisCtx, textToAnalyze)
+	var analysisErr error // Returned once the email is matched and indexed
+	switch {
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		// The user is out of AI quota: the email stays unanalyzed but is still matched to contexts
+		// and indexed for search, which embeddings keep working for.
+		log.WarnContext(ctx, "Skipping AI analysis, usage quota exceeded",
+			logger.String("email_id", p.EmailID.String()),
 			logger.Error(err),
-			logger.String("component", "contact_updater"))
-		// Do not return error, as email analysis is complete, contact update can be retried or ignored
+			logger.String("component", "email_analyzer"))
+		if languageDetected {
+			err := db.WithContext(ctx).Model(&model.Email{}).Where("id = ? AND user_id = ?", email.ID, p.UserID).
+				Update("language", email.Language).Error
+			if err != nil {
+				return fmt.Errorf("failed to save language for email %s (user %s): %v", p.EmailID, p.UserID, err)
+			}
+		}
+	case errors.Is(err, ai.ErrInvalidAnalysis):
+		// Every model produced output that failed validation, even after a repair attempt. Record the
+		// failure instead of storing a guessed analysis; retrying is unlikely to help. The email is
+		// still matched and indexed, as with an exceeded quota.
+		if recErr := recordAnalysisFailure(ctx, db, &email, err, p); recErr != nil {
+			return recErr
+		}
+		log.WarnContext(ctx, "AI analysis failed validation",
+			logger.String("email_id", p.EmailID.String()),
+			logger.Error(err),
+			logger.String("component", "email_analyzer"))
+		analysisErr = fmt.Errorf("invalid analysis for email %s (user %s): %w: %w", p.EmailID, p.UserID, err, asynq.SkipRetry)
+	case err != nil:
+		// A request every provider rejected (e.g. too long) fails the same way on retry; anything
+		// else may be transient, so asynq retries it.
+		var apiErr *ai.APIError
+		if errors.As(err, &apiErr) && ai.ClassifyError(err) == ai.ErrorPermanent {
+			return fmt.Errorf("failed to generate analysis for email %s (user %s): %w: %w", p.EmailID, p.UserID, err, asynq.SkipRetry)
+		}
+		return fmt.Errorf("failed to generate analysis for email %s (user %s): %w", p.EmailID, p.UserID, err)
+	default:
+		if err := saveAnalysis(ctx, db, &email, analysis, p, log); err != nil {
+			return err
+		}
 	}
 
 	// 7. Match and Assign Smart Contexts
@@ -180,9 +201,72 @@ func HandleEmailAnalyzeTask(ctx context.Context, t *asynq.Task, db *gorm.DB, sum
 		// Ideally, this could be a separate task or retried.
 	}
 
+	return analysisErr
+}
+
+// saveAnalysis stores the AI analysis on the email and folds its sentiment into the sender's contact.
+func saveAnalysis(ctx context.Context, db *gorm.DB, email *model.Email, analysis ai.AnalysisResult, p EmailAnalyzePayload, log logger.Logger) error {
+	// 4. Update Email fields
+	email.Summary = analysis.Summary
+	email.Category = analysis.Category
+	email.Sentiment = analysis.Sentiment
+	email.Urgency = analysis.Urgency
+	email.ActionItems = datatypes.JSON(jsonRaw(analysis.ActionItems))
+	email.SmartActions = datatypes.JSON(jsonRaw(analysis.SmartActions))
+	email.PromptVersion = ai.PromptRef(ctx, ai.PromptSummary)
+	email.AnalysisStatus = model.AnalysisStatusAnalyzed
+
+	// 5. Update Email, ensure it belongs to the user
+	if err := db.WithContext(ctx).Where("user_id = ?", p.UserID).Save(email).Error; err != nil {
+		return fmt.Errorf("failed to save analysis for email %s (user %s): %v", p.EmailID, p.UserID, err)
+	}
+
+	log.InfoContext(ctx, "[Email Analyzed]",
+		logger.String("email_id", p.EmailID.String()),
+		logger.String("category", email.Category),
+		logger.String("sentiment", email.Sentiment),
+		logger.String("urgency", email.Urgency),
+		logger.String("component", "email_analyzer"))
+
+	// 6. Update Contact Statistics for the sender
+	if err := updateContactStats(ctx, db, p.UserID, email.Sender, email.Sentiment, email.Date, log); err != nil {
+		log.WarnContext(ctx, "Failed to update contact stats",
+			logger.String("sender", email.Sender),
+			logger.Error(err),
+			logger.String("component", "contact_updater"))
+		// Do not return error, as email analysis is complete, contact update can be retried or ignored
+	}
 	return nil
 }
 
+// recordAnalysisFailure marks the email's analysis as failed and keeps the rejected output. The
+// email's detected language is saved with it.
+func recordAnalysisFailure(ctx context.Context, db *gorm.DB, email *model.Email, cause error, p EmailAnalyzePayload) error {
+	failure := model.AnalysisFailure{
+		ID:            uuid.New(),
+		UserID:        p.UserID,
+		EmailID:       email.ID,
+		PromptVersion: ai.PromptRef(ctx, ai.PromptSummary),
+		Reason:        cause.Error(),
+	}
+	var analysisErr *ai.AnalysisError
+	if errors.As(cause, &analysisErr) {
+		failure.Reason = analysisErr.Reason
+		failure.Output = analysisErr.Output
+	}
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&failure).Error; err != nil {
+			return fmt.Errorf("failed to record analysis failure for email %s (user %s): %v", p.EmailID, p.UserID, err)
+		}
+		err := tx.Model(&model.Email{}).Where("id = ? AND user_id = ?", email.ID, p.UserID).
+			Updates(map[string]interface{}{"analysis_status": model.AnalysisStatusFailed, "language": email.Language}).Error
+		if err != nil {
+			return fmt.Errorf("failed to mark analysis failed for email %s (user %s): %v", p.EmailID, p.UserID, err)
+		}
+		return nil
+	})
+}
+
 func jsonRaw(v interface{}) []byte {
 	b, _ := json.Marshal(v)
 	return b
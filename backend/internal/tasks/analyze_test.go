@@ -3,6 +3,8 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -12,6 +14,7 @@ import (
 	"github.com/hrygo/echomind/pkg/ai"
 	"github.com/hrygo/echomind/pkg/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -23,10 +26,12 @@ type MockSummarizer struct {
 	SummaryError    error
 	SentimentError  error
 	CallCount       int
+	Language        string // Output language of the last call
 }
 
 func (m *MockSummarizer) GenerateSummary(ctx context.Context, text string) (ai.AnalysisResult, error) {
 	m.CallCount++
+	m.Language = ai.OutputLanguage(ctx)
 	return m.SummaryResult, m.SummaryError
 }
 
@@ -69,7 +74,7 @@ func setupTestDB(t *testing.T) *gorm.DB {
 	if err != nil {
 		t.Fatalf("Failed to connect to test database: %v", err)
 	}
-	if err := db.AutoMigrate(&model.Email{}, &model.Contact{}, &model.EmailEmbedding{}, &model.Context{}, &model.EmailContext{}); err != nil {
+	if err := db.AutoMigrate(&model.Email{}, &model.Contact{}, &model.EmailEmbedding{}, &model.Context{}, &model.EmailContext{}, &model.AnalysisFailure{}); err != nil {
 		t.Fatalf("Failed to auto migrate database: %v", err)
 	}
 	return db
@@ -189,6 +194,8 @@ func TestHandleEmailAnalyzeTask(t *testing.T) {
 	assert.Equal(t, "Personal", updatedEmail.Category)
 	assert.Equal(t, "Positive", updatedEmail.Sentiment)
 	assert.Equal(t, "Low", updatedEmail.Urgency)
+	assert.Equal(t, "en", updatedEmail.Language, "detected for emails stored without one")
+	assert.Equal(t, "en", mockSummarizer.Language, "without a preferred language the analysis follows the email's")
 
 	// Verify contact was updated
 	var contact model.Contact
@@ -206,6 +213,19 @@ func TestHandleEmailAnalyzeTask(t *testing.T) {
 	assert.Equal(t, 1, mockContextMatcher.AssignCount)
 }
 
+func TestHandleEmailAnalyzeTask_PreferredLanguage(t *testing.T) {
+	db := setupTestDB(t)
+	userID, emailID := uuid.New(), uuid.New()
+	db.Create(&model.Email{ID: emailID, UserID: userID, MessageID: "<zh>", Subject: "预算", BodyText: "请在周五前审阅预算。", Language: "zh"})
+	mockSummarizer := &MockSummarizer{SummaryResult: ai.AnalysisResult{Summary: "Budget review by Friday.", Category: "Work", Sentiment: "Neutral", Urgency: "Medium"}}
+
+	payload, _ := json.Marshal(EmailAnalyzePayload{EmailID: emailID, UserID: userID})
+	ctx := ai.WithOutputLanguage(context.Background(), "en")
+	err := HandleEmailAnalyzeTask(ctx, asynq.NewTask(TypeEmailAnalyze, payload), db, mockSummarizer, &MockEmbeddingGenerator{}, &MockContextMatcher{}, 1000, logger.GetDefaultLogger())
+	require.NoError(t, err)
+	assert.Equal(t, "en", mockSummarizer.Language)
+}
+
 func TestHandleEmailAnalyzeTask_Spam(t *testing.T) {
 	db := setupTestDB(t)
 	ctx := context.Background()
@@ -258,3 +278,83 @@ func TestHandleEmailAnalyzeTask_Spam(t *testing.T) {
 	// Verify Context Matcher was NOT called
 	assert.Equal(t, 0, mockContextMatcher.MatchCount)
 }
+
+func TestHandleEmailAnalyzeTask_ProviderErrors(t *testing.T) {
+	db := setupTestDB(t)
+	userID := uuid.New()
+	emailID := uuid.New()
+	db.Create(&model.Email{ID: emailID, UserID: userID, MessageID: "<provider-errors>", Sender: "a@example.com", BodyText: "Quarterly numbers attached."})
+	payload, _ := json.Marshal(EmailAnalyzePayload{EmailID: emailID, UserID: userID})
+	task := asynq.NewTask(TypeEmailAnalyze, payload)
+
+	for _, tc := range []struct {
+		name      string
+		err       error
+		skipRetry bool
+	}{
+		{"rejected request", &ai.APIError{Provider: "openai", StatusCode: 400, Message: "context length exceeded"}, true},
+		{"rate limited", &ai.APIError{Provider: "openai", StatusCode: 429, Message: "slow down"}, false},
+		{"all providers out of rotation", ai.ErrUnavailable, false},
+		{"malformed model output", errors.New("failed to parse analysis JSON"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			summarizer := &MockSummarizer{SummaryError: tc.err}
+			err := HandleEmailAnalyzeTask(context.Background(), task, db, summarizer, &MockEmbeddingGenerator{}, &MockContextMatcher{}, 1000, logger.GetDefaultLogger())
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.skipRetry, errors.Is(err, asynq.SkipRetry))
+		})
+	}
+}
+
+func TestHandleEmailAnalyzeTask_QuotaExceeded(t *testing.T) {
+	db := setupTestDB(t)
+	userID := uuid.New()
+	emailID := uuid.New()
+	db.Create(&model.Email{ID: emailID, UserID: userID, MessageID: "<quota>", Sender: "a@example.com", BodyText: "Lunch on Friday?"})
+	payload, _ := json.Marshal(EmailAnalyzePayload{EmailID: emailID, UserID: userID})
+
+	summarizer := &MockSummarizer{SummaryError: ai.ErrQuotaExceeded}
+	embedder := &MockEmbeddingGenerator{}
+	matcher := &MockContextMatcher{}
+	err := HandleEmailAnalyzeTask(context.Background(), asynq.NewTask(TypeEmailAnalyze, payload), db, summarizer, embedder, matcher, 1000, logger.GetDefaultLogger())
+	assert.NoError(t, err, "out of quota is not a task failure")
+
+	var email model.Email
+	db.First(&email, "id = ?", emailID)
+	assert.Empty(t, email.Summary)
+	assert.Equal(t, "en", email.Language, "the detected language is kept without an analysis")
+	assert.Equal(t, 1, matcher.MatchCount)
+	assert.Equal(t, 1, embedder.CallCount, "the email is still indexed for search")
+}
+
+func TestHandleEmailAnalyzeTask_InvalidAnalysis(t *testing.T) {
+	db := setupTestDB(t)
+	userID := uuid.New()
+	emailID := uuid.New()
+	db.Create(&model.Email{ID: emailID, UserID: userID, MessageID: "<invalid>", Sender: "a@example.com", BodyText: "Lunch on Friday?"})
+	payload, _ := json.Marshal(EmailAnalyzePayload{EmailID: emailID, UserID: userID})
+
+	invalid := &ai.AnalysisError{Reason: `"urgency" is "urgent"`, Output: `{"summary":"Lunch","urgency":"urgent"}`}
+	summarizer := &MockSummarizer{SummaryError: fmt.Errorf("gpt-4o: %w", invalid)}
+	embedder := &MockEmbeddingGenerator{}
+	matcher := &MockContextMatcher{}
+	err := HandleEmailAnalyzeTask(context.Background(), asynq.NewTask(TypeEmailAnalyze, payload), db, summarizer, embedder, matcher, 1000, logger.GetDefaultLogger())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, asynq.SkipRetry)
+
+	var email model.Email
+	db.First(&email, "id = ?", emailID)
+	assert.Equal(t, model.AnalysisStatusFailed, email.AnalysisStatus)
+	assert.Empty(t, email.Summary, "nothing is stored as if the analysis had succeeded")
+	assert.Empty(t, email.Category)
+	assert.Equal(t, "en", email.Language, "the detected language is kept")
+	assert.Equal(t, 1, matcher.MatchCount)
+	assert.Equal(t, 1, embedder.CallCount, "the email is still indexed for search")
+
+	var failure model.AnalysisFailure
+	require.NoError(t, db.First(&failure, "email_id = ?", emailID).Error)
+	assert.Equal(t, userID, failure.UserID)
+	assert.Equal(t, invalid.Reason, failure.Reason)
+	assert.Equal(t, invalid.Output, failure.Output)
+	assert.Equal(t, "summary@default", failure.PromptVersion)
+}
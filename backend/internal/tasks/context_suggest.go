@@ -0,0 +1,74 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/hrygo/echomind/internal/model"
+	"github.com/hrygo/echomind/pkg/logger"
+	"gorm.io/gorm"
+)
+
+const (
+	TypeContextSuggest = "context:suggest"
+)
+
+// ContextSuggestPayload targets a single user; a nil UserID means every user.
+type ContextSuggestPayload struct {
+	UserID *uuid.UUID
+}
+
+// ContextSuggester defines the interface for generating context suggestions.
+type ContextSuggester interface {
+	GenerateSuggestions(ctx context.Context, userID uuid.UUID) ([]model.ContextSuggestion, error)
+}
+
+// NewContextSuggestTask creates a task to cluster unassigned emails into context suggestions.
+func NewContextSuggestTask(userID *uuid.UUID) (*asynq.Task, error) {
+	payload, err := json.Marshal(ContextSuggestPayload{UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeContextSuggest, payload), nil
+}
+
+// HandleContextSuggestTask generates context suggestions for one user or for all users.
+func HandleContextSuggestTask(ctx context.Context, t *asynq.Task, db *gorm.DB, suggester ContextSuggester, log logger.Logger) error {
+	var p ContextSuggestPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	var userIDs []uuid.UUID
+	if p.UserID != nil {
+		userIDs = []uuid.UUID{*p.UserID}
+	} else if err := db.WithContext(ctx).Model(&model.User{}).Pluck("id", &userIDs).Error; err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	failed := 0
+	for _, userID := range userIDs {
+		suggestions, err := suggester.GenerateSuggestions(ctx, userID)
+		if err != nil {
+			failed++
+			log.WarnContext(ctx, "Failed to generate context suggestions",
+				logger.String("user_id", userID.String()),
+				logger.Error(err),
+				logger.String("component", "context_suggester"))
+			continue
+		}
+		log.InfoContext(ctx, "Context suggestions generated",
+			logger.String("user_id", userID.String()),
+			logger.Int("suggestions", len(suggestions)),
+			logger.String("component", "context_suggester"))
+	}
+
+	// Only retry when a single-user run fails; the sweep logs per-user failures instead.
+	if p.UserID != nil && failed > 0 {
+		return fmt.Errorf("context suggestion failed for user %s", p.UserID)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/hrygo/echomind/pkg/logger"
+)
+
+const (
+	TypeEmbeddingMigrate = "embedding:migrate"
+)
+
+// EmbeddingMigratePayload controls a re-embedding run into the current embedding model's space.
+type EmbeddingMigratePayload struct {
+	BatchSize int
+	ChunkSize int
+}
+
+// EmbeddingMigrationResult summarizes a migration run.
+type EmbeddingMigrationResult struct {
+	Space     string
+	Embedded  int
+	Failed    int // Retried by the next run
+	Skipped   int // Rejected by the provider; counted as covered
+	Covered   int64
+	Total     int64
+	Activated bool
+}
+
+// EmbeddingMigrator re-embeds emails that are missing from the current model's space.
+type EmbeddingMigrator interface {
+	MigrateEmbeddings(ctx context.Context, batchSize, chunkSize int) (EmbeddingMigrationResult, error)
+}
+
+// NewEmbeddingMigrateTask creates a migration task. Unique keeps restarts from queueing duplicate runs.
+func NewEmbeddingMigrateTask(batchSize, chunkSize int) (*asynq.Task, error) {
+	payload, err := json.Marshal(EmbeddingMigratePayload{BatchSize: batchSize, ChunkSize: chunkSize})
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeEmbeddingMigrate, payload, asynq.Unique(6*time.Hour), asynq.Timeout(6*time.Hour)), nil
+}
+
+// HandleEmbeddingMigrateTask backfills the new embedding space and activates it once complete.
+// Per-email failures do not fail the task; they are retried by the next run.
+func HandleEmbeddingMigrateTask(ctx context.Context, t *asynq.Task, migrator EmbeddingMigrator, log logger.Logger) error {
+	var p EmbeddingMigratePayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+
+	result, err := migrator.MigrateEmbeddings(ctx, p.BatchSize, p.ChunkSize)
+	if err != nil {
+		return fmt.Errorf("embedding migration failed: %w", err)
+	}
+
+	log.InfoContext(ctx, "Embedding migration finished",
+		logger.String("space", result.Space),
+		logger.Int("embedded", result.Embedded),
+		logger.Int("failed", result.Failed),
+		logger.Int("skipped", result.Skipped),
+		logger.Int64("covered", result.Covered),
+		logger.Int64("total", result.Total),
+		logger.Bool("activated", result.Activated),
+		logger.String("component", "embedding_migrator"))
+	return nil
+}
@@ -0,0 +1,81 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/hrygo/echomind/pkg/logger"
+)
+
+const (
+	TypeEmailReindex = "email:reindex"
+)
+
+// ReindexPayload selects the emails to re-embed and how fast to go. Zero values use the reindexer's defaults.
+type ReindexPayload struct {
+	JobID         string // Checkpoint name; derived from the filters when empty
+	UserID        *uuid.UUID
+	Since         *time.Time
+	Until         *time.Time
+	MissingOnly   bool // Only emails without embeddings in the current space
+	DryRun        bool
+	Restart       bool // Ignore an existing checkpoint; on the first attempt only, retries resume
+	BatchSize     int
+	Workers       int
+	RatePerSecond float64
+}
+
+// ReindexResult summarizes a reindex run.
+type ReindexResult struct {
+	JobID     string
+	Matched   int64
+	Processed int64
+	Embedded  int64
+	Failed    int64
+	Done      bool
+}
+
+// EmailReindexer regenerates embeddings for the emails selected by a payload.
+type EmailReindexer interface {
+	Reindex(ctx context.Context, p ReindexPayload) (ReindexResult, error)
+}
+
+// NewEmailReindexTask creates a reindex task. Retries resume from the job's checkpoint.
+func NewEmailReindexTask(p ReindexPayload) (*asynq.Task, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return asynq.NewTask(TypeEmailReindex, payload, asynq.Timeout(24*time.Hour), asynq.MaxRetry(5)), nil
+}
+
+// HandleEmailReindexTask runs a reindex job.
+func HandleEmailReindexTask(ctx context.Context, t *asynq.Task, reindexer EmailReindexer, log logger.Logger) error {
+	var p ReindexPayload
+	if err := json.Unmarshal(t.Payload(), &p); err != nil {
+		return fmt.Errorf("json.Unmarshal failed: %v: %w", err, asynq.SkipRetry)
+	}
+	// A restart must not throw away the progress of the attempts it was retried after.
+	if retried, ok := asynq.GetRetryCount(ctx); ok && retried > 0 {
+		p.Restart = false
+	}
+
+	result, err := reindexer.Reindex(ctx, p)
+	if err != nil {
+		return fmt.Errorf("reindex job %s failed: %w", result.JobID, err)
+	}
+
+	log.InfoContext(ctx, "Reindex job finished",
+		logger.String("job_id", result.JobID),
+		logger.Int64("matched", result.Matched),
+		logger.Int64("processed", result.Processed),
+		logger.Int64("embedded", result.Embedded),
+		logger.Int64("failed", result.Failed),
+		logger.Bool("dry_run", p.DryRun),
+		logger.String("component", "reindexer"))
+	return nil
+}
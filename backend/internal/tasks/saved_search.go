@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/hrygo/echomind/pkg/logger"
+)
+
+const (
+	TypeSavedSearchEvaluate = "saved_search:evaluate"
+)
+
+// SavedSearchEvaluator checks saved searches against newly analyzed emails.
+type SavedSearchEvaluator interface {
+	EvaluateSavedSearches(ctx context.Context) (int, error)
+}
+
+// NewSavedSearchEvaluateTask creates a task that evaluates all saved searches with alerts enabled.
+func NewSavedSearchEvaluateTask() *asynq.Task {
+	return asynq.NewTask(TypeSavedSearchEvaluate, nil)
+}
+
+// HandleSavedSearchEvaluateTask runs the evaluator. Searches that fail are retried on the next run
+// from their own last evaluation time, so one broken search does not block the others.
+func HandleSavedSearchEvaluateTask(ctx context.Context, t *asynq.Task, evaluator SavedSearchEvaluator, log logger.Logger) error {
+	matches, err := evaluator.EvaluateSavedSearches(ctx)
+	if err != nil {
+		log.WarnContext(ctx, "Some saved searches failed to evaluate",
+			logger.Error(err),
+			logger.String("component", "saved_search_evaluator"))
+	}
+	if matches > 0 {
+		log.InfoContext(ctx, "Saved searches matched new emails",
+			logger.Int("matches", matches),
+			logger.String("component", "saved_search_evaluator"))
+	}
+	if err != nil && matches == 0 {
+		return fmt.Errorf("saved search evaluation failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+)
+
+// UserScope applies per-user scopes, such as AI usage accounting and prompt selection, for the user
+// in a task's payload. Tasks without a single user, such as sweeps over every user, run unscoped.
+func UserScope(scopes ...func(ctx context.Context, userID uuid.UUID) context.Context) asynq.MiddlewareFunc {
+	return func(next asynq.Handler) asynq.Handler {
+		return asynq.HandlerFunc(func(ctx context.Context, t *asynq.Task) error {
+			var p struct {
+				UserID *uuid.UUID
+			}
+			if err := json.Unmarshal(t.Payload(), &p); err == nil && p.UserID != nil && *p.UserID != uuid.Nil {
+				for _, scope := range scopes {
+					ctx = scope(ctx, *p.UserID)
+				}
+			}
+			return next.ProcessTask(ctx, t)
+		})
+	}
+}
@@ -0,0 +1,19 @@
+-- Migration: Add full-text search column to emails
+-- Description: Supports keyword and hybrid (vector + full-text) search modes
+-- Date: 2026-10-19
+
+BEGIN;
+
+-- Weighted tsvector: subject (A) > sender (B) > body (C).
+-- The 'simple' configuration avoids language-specific stemming so mixed-language mailboxes behave consistently.
+ALTER TABLE emails
+ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(subject, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(sender, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(body_text, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_emails_search_vector ON emails USING GIN (search_vector);
+
+COMMIT;
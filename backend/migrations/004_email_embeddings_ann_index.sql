@@ -0,0 +1,20 @@
+-- Migration: Approximate nearest-neighbour index for email_embeddings
+-- Description: Replaces sequential scans in semantic search with a pgvector ANN index.
+--              Parameters match configs/config.example.yaml (database.vector_index).
+--              Prefer `go run cmd/vector_index/main.go -action rebuild` on live systems:
+--              it builds CONCURRENTLY and swaps the index without blocking writes.
+-- Date: 2026-10-19
+
+-- HNSW (default): better recall/latency trade-off, slower to build, no training step.
+DROP INDEX IF EXISTS email_embeddings_vector_idx;
+CREATE INDEX email_embeddings_vector_idx ON email_embeddings
+    USING hnsw (vector vector_cosine_ops) WITH (m = 16, ef_construction = 64);
+
+-- IVFFlat alternative: faster to build and smaller, but lists are trained on existing rows,
+-- so build it after data is loaded and rebuild when the table grows substantially.
+-- CREATE INDEX email_embeddings_vector_idx ON email_embeddings
+--     USING ivfflat (vector vector_cosine_ops) WITH (lists = 100);
+
+-- Query-time tuning (per transaction):
+--   SET LOCAL hnsw.ef_search = 100;   -- default 40
+--   SET LOCAL ivfflat.probes = 10;    -- default 1
@@ -0,0 +1,32 @@
+-- Migration: Embedding model versioning
+-- Description: Records which model produced each embedding and registers per-model embedding
+--              tables ("spaces"). Tables for new models are created on demand by the application
+--              with a vector column sized for that model; the worker re-embeds mail into the new
+--              space in the background and switches search over once every email is covered.
+-- Date: 2026-10-19
+
+ALTER TABLE email_embeddings ADD COLUMN IF NOT EXISTS model varchar(200);
+CREATE INDEX IF NOT EXISTS idx_email_embeddings_model ON email_embeddings (model);
+
+CREATE TABLE IF NOT EXISTS embedding_spaces (
+    id uuid PRIMARY KEY,
+    created_at timestamptz,
+    updated_at timestamptz,
+    space_key varchar(255) NOT NULL UNIQUE,
+    model varchar(200) NOT NULL,
+    dimensions bigint NOT NULL,
+    table_name varchar(63) NOT NULL UNIQUE,
+    status varchar(20) NOT NULL DEFAULT 'building',
+    embedded_emails bigint,
+    total_emails bigint,
+    activated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_embedding_spaces_status ON embedding_spaces (status);
+
+-- Inspect migration progress:
+--   SELECT space_key, status, embedded_emails, total_emails, activated_at FROM embedding_spaces;
+-- Force a switch before coverage is complete (not recommended):
+--   BEGIN;
+--   UPDATE embedding_spaces SET status = 'retired' WHERE status = 'active';
+--   UPDATE embedding_spaces SET status = 'active', activated_at = now() WHERE space_key = '<model>@<dims>';
+--   COMMIT;
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Allowed values of the enumerated AnalysisResult fields.
+var (
+	AnalysisCategories = []string{"Work", "Newsletter", "Notification", "Personal", "Spam"}
+	AnalysisSentiments = []string{"Positive", "Neutral", "Negative"}
+	AnalysisUrgencies  = []string{"High", "Medium", "Low"}
+	SmartActionTypes   = []string{"calendar_event", "create_task"}
+	// SmartActionFields are the keys a smart action's data may have.
+	SmartActionFields = []string{"title", "start", "end", "location", "deadline", "description"}
+)
+
+// ErrInvalidAnalysis is returned when a model's analysis still does not match the schema after a
+// repair attempt. ClassifyError reports it as ErrorInvalidOutput: another model may do better, so
+// the call fails over, but the provider's circuit breaker is not tripped.
+var ErrInvalidAnalysis = errors.New("ai: analysis does not match the schema")
+
+// AnalysisError describes an analysis that failed validation. It wraps ErrInvalidAnalysis.
+type AnalysisError struct {
+	Reason string // What was wrong with the last output
+	Output string // The last output, for the failure record
+}
+
+func (e *AnalysisError) Error() string { return "ai: invalid analysis: " + e.Reason }
+func (e *AnalysisError) Unwrap() error { return ErrInvalidAnalysis }
+
+// AnalysisSchemaName names the schema in APIs that require one.
+const AnalysisSchemaName = "email_analysis"
+
+// AnalysisJSONSchema returns the JSON schema of an analysis. It follows the rules of OpenAI's strict
+// mode: every property is required and no others are allowed, so smart action data has a fixed set
+// of keys and unused ones are left empty.
+func AnalysisJSONSchema() map[string]interface{} {
+	str := func() map[string]interface{} { return map[string]interface{}{"type": "string"} }
+	enum := func(values []string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "enum": values}
+	}
+	object := func(properties map[string]interface{}) map[string]interface{} {
+		required := make([]string, 0, len(properties))
+		for name := range properties {
+			required = append(required, name)
+		}
+		sort.Strings(required)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}
+	}
+
+	data := map[string]interface{}{}
+	for _, field := range SmartActionFields {
+		data[field] = str()
+	}
+	return object(map[string]interface{}{
+		"summary":      str(),
+		"category":     enum(AnalysisCategories),
+		"sentiment":    enum(AnalysisSentiments),
+		"urgency":      enum(AnalysisUrgencies),
+		"action_items": map[string]interface{}{"type": "array", "items": str()},
+		"smart_actions": map[string]interface{}{
+			"type": "array",
+			"items": object(map[string]interface{}{
+				"type":  enum(SmartActionTypes),
+				"label": str(),
+				"data":  object(data),
+			}),
+		},
+	})
+}
+
+// AnalysisSchemaInstruction asks for the schema in the prompt, for APIs that cannot enforce it.
+func AnalysisSchemaInstruction() string {
+	schema, _ := json.Marshal(AnalysisJSONSchema())
+	return "Respond with a single JSON object that matches this JSON schema, and nothing else:\n" + string(schema)
+}
+
+// ParseAnalysis decodes and validates a model's analysis. Enumerated values are matched without
+// regard to case and returned in their canonical spelling; empty smart action fields are dropped.
+func ParseAnalysis(output string) (AnalysisResult, error) {
+	var result AnalysisResult
+	if err := json.Unmarshal([]byte(stripCodeFence(output)), &result); err != nil {
+		return AnalysisResult{}, fmt.Errorf("not a JSON object: %v", err)
+	}
+
+	result.Summary = strings.TrimSpace(result.Summary)
+	if result.Summary == "" {
+		return AnalysisResult{}, errors.New(`"summary" is empty`)
+	}
+	var err error
+	if result.Category, err = canonical("category", result.Category, AnalysisCategories); err != nil {
+		return AnalysisResult{}, err
+	}
+	if result.Sentiment, err = canonical("sentiment", result.Sentiment, AnalysisSentiments); err != nil {
+		return AnalysisResult{}, err
+	}
+	if result.Urgency, err = canonical("urgency", result.Urgency, AnalysisUrgencies); err != nil {
+		return AnalysisResult{}, err
+	}
+	if result.ActionItems == nil {
+		result.ActionItems = []string{}
+	}
+	if result.SmartActions == nil {
+		result.SmartActions = []SmartAction{}
+	}
+	for i := range result.SmartActions {
+		action := &result.SmartActions[i]
+		if action.Type, err = canonical("smart_actions.type", action.Type, SmartActionTypes); err != nil {
+			return AnalysisResult{}, err
+		}
+		for key, value := range action.Data {
+			if strings.TrimSpace(value) == "" {
+				delete(action.Data, key)
+			}
+		}
+	}
+	return result, nil
+}
+
+func canonical(field, value string, allowed []string) (string, error) {
+	value = strings.TrimSpace(value)
+	for _, a := range allowed {
+		if strings.EqualFold(value, a) {
+			return a, nil
+		}
+	}
+	return "", fmt.Errorf("%q is %q, want one of %s", field, value, strings.Join(allowed, ", "))
+}
+
+// GenerateAnalysis runs an analysis with complete, which sends a chat history to the model with
+// whatever output constraints the provider supports. Output that fails validation is sent back once
+// with the problem for the model to repair; if that fails too, an *AnalysisError is returned. The
+// free text is written in the context's output language, if it has one.
+func GenerateAnalysis(ctx context.Context, systemPrompt, text string, complete func(ctx context.Context, messages []Message) (string, error)) (AnalysisResult, error) {
+	if instruction := OutputLanguageInstruction(ctx); instruction != "" {
+		systemPrompt += "\n\n" + instruction
+	}
+	messages := []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: text},
+	}
+	output, err := complete(ctx, messages)
+	if err != nil {
+		return AnalysisResult{}, err
+	}
+	result, invalid := ParseAnalysis(output)
+	if invalid == nil {
+		return result, nil
+	}
+
+	messages = append(messages,
+		Message{Role: "assistant", Content: output},
+		Message{Role: "user", Content: analysisRepairPrompt(invalid)},
+	)
+	output, err = complete(ctx, messages)
+	if err != nil {
+		return AnalysisResult{}, err
+	}
+	if result, invalid = ParseAnalysis(output); invalid != nil {
+		return AnalysisResult{}, &AnalysisError{Reason: invalid.Error(), Output: output}
+	}
+	return result, nil
+}
+
+func analysisRepairPrompt(invalid error) string {
+	return fmt.Sprintf(`Your reply is not a valid analysis: %v.
+Reply again with only the JSON object: "summary" (non-empty), "category" (%s), "sentiment" (%s), "urgency" (%s), "action_items" (array of strings) and "smart_actions" (array of {"type": %s, "label", "data"}).`,
+		invalid,
+		strings.Join(AnalysisCategories, " | "),
+		strings.Join(AnalysisSentiments, " | "),
+		strings.Join(AnalysisUrgencies, " | "),
+		strings.Join(SmartActionTypes, " | "))
+}
+
+// stripCodeFence removes a Markdown code fence around a reply.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") {
+		return text
+	}
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "```"), "json")
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), "```"))
+}
@@ -0,0 +1,95 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAnalysis(t *testing.T) {
+	result, err := ParseAnalysis("```json\n" + `{
+		"summary": " Invoice overdue ",
+		"category": "work",
+		"sentiment": "NEGATIVE",
+		"urgency": "High",
+		"smart_actions": [{"type": "create_task", "label": "Pay", "data": {"title": "Pay invoice", "start": ""}}]
+	}` + "\n```")
+	require.NoError(t, err)
+	assert.Equal(t, "Invoice overdue", result.Summary)
+	assert.Equal(t, "Work", result.Category)
+	assert.Equal(t, "Negative", result.Sentiment)
+	assert.Equal(t, []string{}, result.ActionItems, "missing lists are empty, not null")
+	assert.Equal(t, map[string]string{"title": "Pay invoice"}, result.SmartActions[0].Data, "empty fields are dropped")
+
+	for _, tc := range []struct{ output, want string }{
+		{"The email is about an invoice.", "not a JSON object"},
+		{`{"summary":"","category":"Work","sentiment":"Neutral","urgency":"Low"}`, `"summary" is empty`},
+		{`{"summary":"x","category":"Finance","sentiment":"Neutral","urgency":"Low"}`, `"category" is "Finance"`},
+		{`{"summary":"x","category":"Work","sentiment":"Neutral"}`, `"urgency" is ""`},
+		{`{"summary":"x","category":"Work","sentiment":"Neutral","urgency":"Low","smart_actions":[{"type":"reply"}]}`, `"smart_actions.type" is "reply"`},
+	} {
+		_, err := ParseAnalysis(tc.output)
+		if assert.Error(t, err, tc.output) {
+			assert.Contains(t, err.Error(), tc.want)
+		}
+	}
+}
+
+func TestAnalysisJSONSchema(t *testing.T) {
+	raw, err := json.Marshal(AnalysisJSONSchema())
+	require.NoError(t, err)
+	var schema struct {
+		Required   []string                   `json:"required"`
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &schema))
+	assert.Len(t, schema.Required, len(schema.Properties), "strict mode requires every property")
+	assert.Contains(t, string(schema.Properties["urgency"]), `"enum":["High","Medium","Low"]`)
+}
+
+func TestGenerateAnalysis(t *testing.T) {
+	valid := `{"summary":"Lunch moved","category":"Personal","sentiment":"Neutral","urgency":"Low","action_items":[],"smart_actions":[]}`
+
+	t.Run("repairs once", func(t *testing.T) {
+		var calls [][]Message
+		result, err := GenerateAnalysis(context.Background(), "Analyze.", "Lunch at 1pm", func(ctx context.Context, messages []Message) (string, error) {
+			calls = append(calls, messages)
+			if len(calls) == 1 {
+				return `{"summary":"Lunch moved","category":"Social"}`, nil
+			}
+			return valid, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Personal", result.Category)
+		require.Len(t, calls, 2)
+		repair := calls[1]
+		require.Len(t, repair, 4)
+		assert.Equal(t, Message{Role: "assistant", Content: `{"summary":"Lunch moved","category":"Social"}`}, repair[2])
+		assert.Contains(t, repair[3].Content, `"category" is "Social"`)
+	})
+
+	t.Run("fails after the repair", func(t *testing.T) {
+		calls := 0
+		_, err := GenerateAnalysis(context.Background(), "Analyze.", "Lunch at 1pm", func(ctx context.Context, messages []Message) (string, error) {
+			calls++
+			return "Lunch was moved to 1pm.", nil
+		})
+		assert.Equal(t, 2, calls)
+		assert.ErrorIs(t, err, ErrInvalidAnalysis)
+		var analysisErr *AnalysisError
+		require.True(t, errors.As(err, &analysisErr))
+		assert.Equal(t, "Lunch was moved to 1pm.", analysisErr.Output)
+	})
+
+	t.Run("provider errors pass through", func(t *testing.T) {
+		unavailable := &APIError{Provider: "openai", StatusCode: 503}
+		_, err := GenerateAnalysis(context.Background(), "Analyze.", "x", func(ctx context.Context, messages []Message) (string, error) {
+			return "", unavailable
+		})
+		assert.Equal(t, unavailable, err)
+	})
+}
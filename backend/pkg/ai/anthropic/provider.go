@@ -0,0 +1,302 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/registry"
+)
+
+const (
+	defaultBaseURL   = "https://api.anthropic.com"
+	defaultVersion   = "2023-06-01"
+	defaultMaxTokens = 1024
+)
+
+func init() {
+	registry.Register("anthropic", NewProvider)
+}
+
+// Provider talks to a Messages API (Anthropic, or any server that implements the same API).
+// It has no embeddings; route embedding to another provider.
+type Provider struct {
+	ai.TextTasks
+	client      *http.Client
+	baseURL     string
+	apiKey      string
+	version     string
+	model       string
+	maxTokens   int
+	temperature *float32
+}
+
+func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
+	apiKey, _ := settings["api_key"].(string)
+	model, _ := settings["model"].(string)
+	baseURL, _ := settings["base_url"].(string)
+	version, _ := settings["api_version"].(string)
+	if model == "" {
+		return nil, errors.New("anthropic: model is required")
+	}
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if version == "" {
+		version = defaultVersion
+	}
+	params := ai.ParseModelParams(settings)
+	maxTokens := defaultMaxTokens
+	if params.MaxTokens > 0 {
+		maxTokens = params.MaxTokens
+	}
+	timeout := 120 * time.Second
+	if v, ok := settings["timeout_seconds"].(int); ok {
+		timeout = time.Duration(v) * time.Second
+	}
+
+	p := &Provider{
+		client:      &http.Client{Timeout: timeout},
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		apiKey:      apiKey,
+		version:     version,
+		model:       model,
+		maxTokens:   maxTokens,
+		temperature: params.Temperature,
+	}
+	p.TextTasks = ai.TextTasks{Prompts: prompts, Complete: p.complete}
+	return p, nil
+}
+
+type message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type messagesRequest struct {
+	Model       string    `json:"model"`
+	MaxTokens   int       `json:"max_tokens"`
+	Temperature *float32  `json:"temperature,omitempty"`
+	System      string    `json:"system,omitempty"`
+	Messages    []message `json:"messages"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type messagesResponse struct {
+	ID      string `json:"id"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Usage usage `json:"usage"`
+}
+
+type usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+type errorBody struct {
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// streamEvent is the data payload of a streamed server-sent event.
+type streamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		ID    string `json:"id"`
+		Usage usage  `json:"usage"`
+	} `json:"message"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Usage usage `json:"usage"` // Cumulative output tokens, on message_delta
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// complete sends a conversation and returns the reply. The Messages API can neither enforce a
+// schema nor a JSON mode, so the output format is asked for in the system prompt; analyses are
+// validated and repaired like everywhere else.
+func (p *Provider) complete(ctx context.Context, messages []ai.Message, format ai.OutputFormat) (string, error) {
+	system, converted := toMessages(messages)
+	switch format {
+	case ai.FormatJSON:
+		system += "\n\nRespond with a single JSON object and nothing else."
+	case ai.FormatAnalysis:
+		system += "\n\n" + ai.AnalysisSchemaInstruction()
+	}
+	req := messagesRequest{
+		Model:       p.model,
+		MaxTokens:   p.maxTokens,
+		Temperature: p.temperature,
+		System:      system,
+		Messages:    converted,
+	}
+
+	resp, err := p.post(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body messagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("anthropic: invalid response: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range body.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	p.reportUsage(ctx, body.Usage, ai.MessagesText(messages), text.String())
+	if text.Len() == 0 {
+		return "", errors.New("no content returned from Messages API")
+	}
+	return text.String(), nil
+}
+
+func (p *Provider) reportUsage(ctx context.Context, u usage, prompt, completion string) {
+	ai.ReportUsage(ctx, ai.Usage{
+		Provider:         "anthropic",
+		Model:            p.model,
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+	}.WithEstimates(prompt, completion))
+}
+
+func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	defer close(ch)
+
+	system, converted := toMessages(messages)
+	if len(converted) == 0 {
+		return errors.New("no messages provided")
+	}
+	resp, err := p.post(ctx, messagesRequest{
+		Model:       p.model,
+		MaxTokens:   p.maxTokens,
+		Temperature: p.temperature,
+		System:      system,
+		Messages:    converted,
+		Stream:      true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var id string
+	var used usage
+	var completion strings.Builder
+	defer func() { p.reportUsage(ctx, used, ai.MessagesText(messages), completion.String()) }()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue // "event:" lines repeat the type that the data carries
+		}
+		var event streamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			return fmt.Errorf("anthropic: invalid stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "message_start":
+			id = event.Message.ID
+			used = event.Message.Usage
+		case "message_delta":
+			used.OutputTokens = event.Usage.OutputTokens
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			completion.WriteString(event.Delta.Text)
+			ch <- ai.ChatCompletionChunk{
+				ID:      id,
+				Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: event.Delta.Text}}},
+			}
+		case "message_stop":
+			return nil
+		case "error":
+			return &ai.APIError{Provider: "anthropic", StatusCode: http.StatusOK, Message: event.Error.Type + ": " + event.Error.Message}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("anthropic: stream ended before message_stop")
+}
+
+// post sends a Messages request and returns the response if it succeeded.
+func (p *Provider) post(ctx context.Context, req messagesRequest) (*http.Response, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", p.version)
+	if req.Stream {
+		httpReq.Header.Set("Accept", "text/event-stream")
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		message := strings.TrimSpace(string(raw))
+		var body errorBody
+		if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+			message = body.Error.Type + ": " + body.Error.Message
+		}
+		return nil, &ai.APIError{Provider: "anthropic", StatusCode: resp.StatusCode, Message: message}
+	}
+	return resp, nil
+}
+
+// toMessages maps chat messages to the Messages API: system messages move to the system prompt,
+// and consecutive turns of the same role are merged because the API requires alternating roles.
+func toMessages(messages []ai.Message) (string, []message) {
+	var system []string
+	var converted []message
+	for _, msg := range messages {
+		role := "user"
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+			continue
+		case "assistant":
+			role = "assistant"
+		}
+		if last := len(converted) - 1; last >= 0 && converted[last].Role == role {
+			converted[last].Content += "\n\n" + msg.Content
+			continue
+		}
+		converted = append(converted, message{Role: role, Content: msg.Content})
+	}
+	return strings.Join(system, "\n\n"), converted
+}
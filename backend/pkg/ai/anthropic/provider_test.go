@@ -0,0 +1,155 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T, handler http.HandlerFunc) *Provider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	p, err := NewProvider(context.Background(), configs.ProviderSettings{
+		"api_key":  "test-key",
+		"model":    "claude-test",
+		"base_url": server.URL,
+	}, map[string]string{"summary": "Analyze the email.", "classify": "Classify the email.", "sentiment": "Rate the sentiment."})
+	require.NoError(t, err)
+	return p.(*Provider)
+}
+
+func decodeRequest(t *testing.T, r *http.Request) messagesRequest {
+	assert.Equal(t, "/v1/messages", r.URL.Path)
+	assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+	assert.Equal(t, defaultVersion, r.Header.Get("anthropic-version"))
+	var req messagesRequest
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+	return req
+}
+
+func TestProvider_Complete(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		assert.Equal(t, "claude-test", req.Model)
+		assert.Equal(t, defaultMaxTokens, req.MaxTokens)
+		assert.False(t, req.Stream)
+		if strings.HasPrefix(req.System, "Rate") {
+			assert.Contains(t, req.System, "single JSON object", "JSON output is requested in the system prompt")
+			fmt.Fprint(w, `{"id":"msg_2","content":[{"type":"text","text":"`+"```json\\n"+`{\"sentiment\":\"Negative\",\"urgency\":\"High\"}`+"\\n```"+`"}]}`)
+			return
+		}
+		assert.Equal(t, []message{{Role: "user", Content: "Invoice overdue"}}, req.Messages)
+		fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"text","text":"Work"}]}`)
+	})
+
+	label, err := p.Classify(context.Background(), "Invoice overdue")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", label)
+
+	sentiment, err := p.AnalyzeSentiment(context.Background(), "Invoice overdue")
+	require.NoError(t, err)
+	assert.Equal(t, ai.SentimentResult{Sentiment: "Negative", Urgency: "High"}, sentiment)
+}
+
+func TestProvider_Summarize(t *testing.T) {
+	var turns [][]message
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		assert.Contains(t, req.System, `"enum":["Work","Newsletter","Notification","Personal","Spam"]`, "the schema is given in the system prompt")
+		turns = append(turns, req.Messages)
+		if len(turns) == 1 {
+			fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"text","text":"{\"summary\":\"Invoice overdue\",\"category\":\"Billing\"}"}]}`)
+			return
+		}
+		fmt.Fprint(w, `{"id":"msg_2","content":[{"type":"text","text":"{\"summary\":\"Invoice overdue\",\"category\":\"Work\",\"sentiment\":\"Negative\",\"urgency\":\"High\",\"action_items\":[\"Pay it\"],\"smart_actions\":[]}"}]}`)
+	})
+
+	result, err := p.Summarize(context.Background(), "Invoice overdue")
+	require.NoError(t, err)
+	assert.Equal(t, ai.AnalysisResult{
+		Summary: "Invoice overdue", Category: "Work", Sentiment: "Negative", Urgency: "High",
+		ActionItems: []string{"Pay it"}, SmartActions: []ai.SmartAction{},
+	}, result)
+	require.Len(t, turns, 2, "invalid output is sent back for one repair")
+	assert.Equal(t, "assistant", turns[1][1].Role)
+	assert.Contains(t, turns[1][2].Content, `"category" is "Billing"`)
+}
+
+func TestProvider_StreamChat(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		req := decodeRequest(t, r)
+		assert.True(t, req.Stream)
+		assert.Equal(t, "Be brief.", req.System)
+		assert.Equal(t, []message{
+			{Role: "user", Content: "Hi\n\nAre you there?"},
+			{Role: "assistant", Content: "Yes."},
+			{Role: "user", Content: "Summarize my inbox"},
+		}, req.Messages, "system turns move out and consecutive roles merge")
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_9"}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"ping"}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Three "}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"new emails."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_stop"}`,
+		} {
+			var typed struct {
+				Type string `json:"type"`
+			}
+			_ = json.Unmarshal([]byte(event), &typed)
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", typed.Type, event)
+		}
+	})
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	err := p.StreamChat(context.Background(), []ai.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "user", Content: "Are you there?"},
+		{Role: "assistant", Content: "Yes."},
+		{Role: "user", Content: "Summarize my inbox"},
+	}, ch)
+	require.NoError(t, err)
+
+	var text strings.Builder
+	for chunk := range ch {
+		assert.Equal(t, "msg_9", chunk.ID)
+		text.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, "Three new emails.", text.String())
+}
+
+func TestProvider_Errors(t *testing.T) {
+	p := newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	})
+	_, err := p.Classify(context.Background(), "x")
+	var apiErr *ai.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, "rate_limit_error: slow down", apiErr.Message)
+
+	p = newTestProvider(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+	err = p.StreamChat(context.Background(), []ai.Message{{Role: "user", Content: "Hi"}}, make(chan ai.ChatCompletionChunk, 1))
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "overloaded_error: Overloaded", apiErr.Message)
+
+	_, err = NewProvider(context.Background(), configs.ProviderSettings{"api_key": "k"}, nil)
+	assert.EqualError(t, err, "anthropic: model is required")
+}
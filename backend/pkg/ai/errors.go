@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// ErrUnavailable means no provider could take the call right now, e.g. because every circuit
+// breaker is open. It is retryable.
+var ErrUnavailable = errors.New("ai: no provider available")
+
+// APIError is a non-success HTTP response from a provider's API. Providers convert their client
+// library's errors to it so that failures can be classified the same way for all of them.
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: API error (status %d): %s", e.Provider, e.StatusCode, e.Message)
+}
+
+// ErrorClass says how a failed provider call should be handled.
+type ErrorClass int
+
+const (
+	// ErrorPermanent failures would fail again anywhere (bad request, misconfiguration); give up.
+	ErrorPermanent ErrorClass = iota
+	// ErrorRetryable failures are transient (rate limits, overload, timeouts, dropped connections);
+	// retry after a delay or try another provider.
+	ErrorRetryable
+	// ErrorProvider failures are specific to the provider (bad credentials, unknown model); retrying
+	// does not help but another provider may succeed.
+	ErrorProvider
+	// ErrorInvalidOutput failures are answers that failed validation. The provider itself is
+	// healthy, so it is not retried or counted against it, but another model may answer better.
+	ErrorInvalidOutput
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorRetryable:
+		return "retryable"
+	case ErrorProvider:
+		return "provider"
+	case ErrorInvalidOutput:
+		return "invalid_output"
+	default:
+		return "permanent"
+	}
+}
+
+// ClassifyError decides whether a provider error is worth retrying. Cancellation by the caller is
+// permanent; unrecognized errors are treated as permanent so that bugs are not retried.
+func ClassifyError(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ErrorPermanent
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode == http.StatusConflict,
+			apiErr.StatusCode == http.StatusTooEarly,
+			apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode >= http.StatusInternalServerError,
+			apiErr.StatusCode < http.StatusBadRequest: // Errors reported inside a successful stream
+			return ErrorRetryable
+		case apiErr.StatusCode == http.StatusUnauthorized,
+			apiErr.StatusCode == http.StatusPaymentRequired,
+			apiErr.StatusCode == http.StatusForbidden,
+			apiErr.StatusCode == http.StatusNotFound:
+			return ErrorProvider
+		default:
+			return ErrorPermanent
+		}
+	}
+
+	if errors.Is(err, ErrInvalidAnalysis) {
+		// The model did not follow the schema; a different one may.
+		return ErrorInvalidOutput
+	}
+
+	var netErr net.Error
+	if errors.Is(err, ErrUnavailable) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &netErr) {
+		return ErrorRetryable
+	}
+	return ErrorPermanent
+}
@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	apiErr := func(status int) error {
+		return fmt.Errorf("summarize: %w", &APIError{Provider: "openai", StatusCode: status, Message: "x"})
+	}
+	for _, tc := range []struct {
+		err  error
+		want ErrorClass
+	}{
+		{apiErr(http.StatusTooManyRequests), ErrorRetryable},
+		{apiErr(http.StatusBadGateway), ErrorRetryable},
+		{apiErr(http.StatusRequestTimeout), ErrorRetryable},
+		{apiErr(0), ErrorRetryable},
+		{apiErr(http.StatusUnauthorized), ErrorProvider},
+		{apiErr(http.StatusNotFound), ErrorProvider},
+		{apiErr(http.StatusBadRequest), ErrorPermanent},
+		{apiErr(http.StatusUnprocessableEntity), ErrorPermanent},
+		{context.DeadlineExceeded, ErrorRetryable},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), ErrorRetryable},
+		{io.ErrUnexpectedEOF, ErrorRetryable},
+		{ErrUnavailable, ErrorRetryable},
+		{&AnalysisError{Reason: "x"}, ErrorInvalidOutput},
+		{context.Canceled, ErrorPermanent},
+		{errors.New("invalid JSON"), ErrorPermanent},
+	} {
+		assert.Equal(t, tc.want, ClassifyError(tc.err), "%v", tc.err)
+	}
+}
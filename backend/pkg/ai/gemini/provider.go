@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 
 	"github.com/google/generative-ai-go/genai"
@@ -15,6 +14,7 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
@@ -31,6 +31,7 @@ type Provider struct {
 	embeddingModel string
 	dimensions     int
 	prompts        map[string]string
+	params         ai.ModelParams
 }
 
 func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
@@ -56,6 +57,7 @@ func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts
 		embeddingModel: embeddingModelName,
 		dimensions:     dimensions,
 		prompts:        prompts,
+		params:         ai.ParseModelParams(settings),
 	}, nil
 }
 
@@ -68,34 +70,21 @@ func (p *Provider) Summarize(ctx context.Context, text string) (ai.AnalysisResul
 	)
 	defer span.End()
 
-	systemPrompt := p.prompts["summary"]
+	systemPrompt := ai.ResolvePrompt(ctx, ai.PromptSummary, p.prompts["summary"]).Text
 	if systemPrompt == "" {
 		err := errors.New("summary prompt not configured")
 		span.RecordError(err)
 		return ai.AnalysisResult{}, err
 	}
 
-	model := p.client.GenerativeModel(p.model)
-	model.ResponseMIMEType = "application/json"
-	model.SystemInstruction = genai.NewUserContent(genai.Text(systemPrompt))
-
-	resp, err := model.GenerateContent(ctx, genai.Text(text))
+	result, err := ai.GenerateAnalysis(ctx, systemPrompt, text, p.completeAnalysis)
 	if err != nil {
+		var invalid *ai.AnalysisError
+		span.SetAttributes(attribute.Bool("json.validation.failed", errors.As(err, &invalid)))
 		span.RecordError(err)
 		return ai.AnalysisResult{}, err
 	}
 
-	response := extractText(resp)
-	cleaned := cleanMarkdown(response)
-
-	var result ai.AnalysisResult
-	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
-		span.SetAttributes(
-			attribute.Bool("json.parsing.failed", true),
-		)
-		return ai.AnalysisResult{Summary: response}, nil
-	}
-
 	span.SetAttributes(
 		attribute.String("result.category", result.Category),
 		attribute.Int("result.action_items", len(result.ActionItems)),
@@ -104,8 +93,72 @@ func (p *Provider) Summarize(ctx context.Context, text string) (ai.AnalysisResul
 	return result, nil
 }
 
+// completeAnalysis sends an analysis conversation, constrained to the analysis schema. The leading
+// system message becomes the system instruction.
+func (p *Provider) completeAnalysis(ctx context.Context, messages []ai.Message) (string, error) {
+	model := p.generativeModel(true)
+	if p.params.JSON() {
+		model.ResponseSchema = analysisSchema()
+	}
+	cs := model.StartChat()
+	for _, msg := range messages[:len(messages)-1] {
+		switch msg.Role {
+		case "system":
+			model.SystemInstruction = genai.NewUserContent(genai.Text(msg.Content))
+		case "assistant":
+			cs.History = append(cs.History, &genai.Content{Parts: []genai.Part{genai.Text(msg.Content)}, Role: "model"})
+		default:
+			cs.History = append(cs.History, &genai.Content{Parts: []genai.Part{genai.Text(msg.Content)}, Role: "user"})
+		}
+	}
+
+	resp, err := cs.SendMessage(ctx, genai.Text(messages[len(messages)-1].Content))
+	if err != nil {
+		return "", apiError(err)
+	}
+	response := extractText(resp)
+	p.reportUsage(ctx, resp.UsageMetadata, ai.MessagesText(messages), response)
+	return response, nil
+}
+
+// analysisSchema is ai.AnalysisJSONSchema in Gemini's schema dialect, which has no
+// additionalProperties; smart action data keys are optional instead.
+func analysisSchema() *genai.Schema {
+	str := &genai.Schema{Type: genai.TypeString}
+	enum := func(values []string) *genai.Schema {
+		return &genai.Schema{Type: genai.TypeString, Format: "enum", Enum: values}
+	}
+	data := &genai.Schema{Type: genai.TypeObject, Properties: map[string]*genai.Schema{}}
+	for _, field := range ai.SmartActionFields {
+		data.Properties[field] = str
+	}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"summary":      str,
+			"category":     enum(ai.AnalysisCategories),
+			"sentiment":    enum(ai.AnalysisSentiments),
+			"urgency":      enum(ai.AnalysisUrgencies),
+			"action_items": {Type: genai.TypeArray, Items: str},
+			"smart_actions": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"type":  enum(ai.SmartActionTypes),
+						"label": str,
+						"data":  data,
+					},
+					Required: []string{"type", "label", "data"},
+				},
+			},
+		},
+		Required: []string{"summary", "category", "sentiment", "urgency", "action_items", "smart_actions"},
+	}
+}
+
 func (p *Provider) Classify(ctx context.Context, text string) (string, error) {
-	systemPrompt := p.prompts["classify"]
+	systemPrompt := ai.ResolvePrompt(ctx, ai.PromptClassify, p.prompts["classify"]).Text
 	if systemPrompt == "" {
 		return "", errors.New("classify prompt not configured")
 	}
@@ -113,21 +166,21 @@ func (p *Provider) Classify(ctx context.Context, text string) (string, error) {
 }
 
 func (p *Provider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
-	systemPrompt := p.prompts["sentiment"]
+	systemPrompt := ai.ResolvePrompt(ctx, ai.PromptSentiment, p.prompts["sentiment"]).Text
 	if systemPrompt == "" {
 		return ai.SentimentResult{}, errors.New("sentiment prompt not configured")
 	}
 
-	model := p.client.GenerativeModel(p.model)
-	model.ResponseMIMEType = "application/json" // Force JSON mode for Gemini
+	model := p.generativeModel(true)
 
 	prompt := fmt.Sprintf("%s\n\nEmail Content:\n%s", systemPrompt, text)
 	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 	if err != nil {
-		return ai.SentimentResult{}, err
+		return ai.SentimentResult{}, apiError(err)
 	}
 
 	response := extractText(resp)
+	p.reportUsage(ctx, resp.UsageMetadata, prompt, response)
 
 	var result struct {
 		Sentiment string `json:"sentiment"`
@@ -148,7 +201,7 @@ func (p *Provider) AnalyzeSentiment(ctx context.Context, text string) (ai.Sentim
 }
 
 func (p *Provider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
-	systemPrompt := p.prompts["draft_reply"]
+	systemPrompt := ai.ResolvePrompt(ctx, ai.PromptDraftReply, p.prompts["draft_reply"]).Text
 	if systemPrompt == "" {
 		systemPrompt = "You are an email assistant. Generate a professional email reply based on the provided email content and user instructions."
 	}
@@ -165,22 +218,50 @@ func (p *Provider) GenerateDraftReply(ctx context.Context, emailContent, userPro
 	return p.generateContent(ctx, fullPrompt, "")
 }
 
-func (p *Provider) generateContent(ctx context.Context, systemPrompt, userContent string) (string, error) {
+// generativeModel returns the configured model with the route's parameters applied. Structured
+// calls ask for JSON unless JSON mode is turned off.
+func (p *Provider) generativeModel(structured bool) *genai.GenerativeModel {
 	model := p.client.GenerativeModel(p.model)
+	if p.params.Temperature != nil {
+		model.SetTemperature(*p.params.Temperature)
+	}
+	if p.params.MaxTokens > 0 {
+		model.SetMaxOutputTokens(int32(p.params.MaxTokens))
+	}
+	if structured && p.params.JSON() {
+		model.ResponseMIMEType = "application/json"
+	}
+	return model
+}
+
+func (p *Provider) generateContent(ctx context.Context, systemPrompt, userContent string) (string, error) {
+	model := p.generativeModel(false)
 	model.SystemInstruction = genai.NewUserContent(genai.Text(systemPrompt))
 
 	resp, err := model.GenerateContent(ctx, genai.Text(userContent))
 	if err != nil {
-		return "", err
+		return "", apiError(err)
 	}
 
-	return extractText(resp), nil
+	response := extractText(resp)
+	p.reportUsage(ctx, resp.UsageMetadata, systemPrompt+userContent, response)
+	return response, nil
+}
+
+// reportUsage reports the usage of a generation call, estimating counts the API did not return.
+func (p *Provider) reportUsage(ctx context.Context, meta *genai.UsageMetadata, prompt, completion string) {
+	usage := ai.Usage{Provider: "gemini", Model: p.model}
+	if meta != nil {
+		usage.PromptTokens = int(meta.PromptTokenCount)
+		usage.CompletionTokens = int(meta.CandidatesTokenCount)
+	}
+	ai.ReportUsage(ctx, usage.WithEstimates(prompt, completion))
 }
 
 func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
 	defer close(ch)
 
-	model := p.client.GenerativeModel(p.model)
+	model := p.generativeModel(false)
 	cs := model.StartChat()
 
 	// Convert history (excluding the last message which is the new prompt)
@@ -209,37 +290,35 @@ func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch cha
 	lastMsg := messages[len(messages)-1]
 	iter := cs.SendMessageStream(ctx, genai.Text(lastMsg.Content))
 
+	// The last response of a stream carries the usage of the whole call.
+	var usage *genai.UsageMetadata
+	var completion strings.Builder
+	defer func() { p.reportUsage(ctx, usage, ai.MessagesText(messages), completion.String()) }()
+
 	for i := 0; ; i++ {
 		resp, err := iter.Next()
 		if errors.Is(err, iterator.Done) {
 			return nil
 		}
 		if err != nil {
-			return err
+			return apiError(err)
+		}
+		if resp.UsageMetadata != nil {
+			usage = resp.UsageMetadata
 		}
 
-		// Use the new function to extract both content and widget data
-		content, widgetData := extractContentAndWidget(resp)
-
-		if content != "" || widgetData != nil { // Send if either content or widget is present
-			delta := ai.DeltaContent{}
-			if content != "" {
-				delta.Content = content
-			}
-			if widgetData != nil {
-				delta.Widget = widgetData
-			}
-
-			chunk := ai.ChatCompletionChunk{
+		// Widget blocks are left in the text; the chat service parses them the same way for every provider.
+		if content := extractText(resp); content != "" {
+			completion.WriteString(content)
+			ch <- ai.ChatCompletionChunk{
 				ID: fmt.Sprintf("chatcmpl-%d", i), // Simple ID, can be UUID
 				Choices: []ai.Choice{
 					{
 						Index: 0,
-						Delta: delta,
+						Delta: ai.DeltaContent{Content: content},
 					},
 				},
 			}
-			ch <- chunk
 		}
 	}
 }
@@ -273,35 +352,6 @@ func cleanMarkdown(text string) string {
 	return strings.TrimSpace(cleaned)
 }
 
-// extractContentAndWidget parses the AI response for potential widget data.
-// It returns the remaining text content and an optional WidgetData object.
-func extractContentAndWidget(resp *genai.GenerateContentResponse) (string, *ai.WidgetData) {
-	fullText := extractText(resp)
-
-	// Check for widget pattern: ```widget_TYPE\n{JSON_DATA}\n```
-	widgetPattern := regexp.MustCompile("```widget_([a-zA-Z0-9_]+)\\n([\\s\\S]*?)\\n```")
-	matches := widgetPattern.FindStringSubmatch(fullText)
-
-	if len(matches) == 3 {
-		widgetType := matches[1]
-		jsonData := strings.TrimSpace(matches[2])
-
-		var data map[string]interface{}
-		if err := json.Unmarshal([]byte(jsonData), &data); err == nil {
-			// Successfully parsed widget JSON
-			widget := &ai.WidgetData{
-				Type: widgetType,
-				Data: data,
-			}
-			// Remove the widget block from the fullText
-			cleanText := strings.Replace(fullText, matches[0], "", 1)
-			return strings.TrimSpace(cleanText), widget
-		}
-	}
-	// No widget found or failed to parse, return full text as content
-	return fullText, nil
-}
-
 // Embed generates a vector for a single text.
 func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
 	ctx, span := tracer.Start(ctx, "gemini.Embed",
@@ -317,8 +367,9 @@ func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
 	res, err := em.EmbedContent(ctx, genai.Text(text))
 	if err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, apiError(err)
 	}
+	p.reportEmbeddingUsage(ctx, text)
 
 	span.SetAttributes(
 		attribute.Int("embedding.dimensions", len(res.Embedding.Values)),
@@ -345,8 +396,9 @@ func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32,
 	res, err := em.BatchEmbedContents(ctx, batch)
 	if err != nil {
 		span.RecordError(err)
-		return nil, err
+		return nil, apiError(err)
 	}
+	p.reportEmbeddingUsage(ctx, texts...)
 	var embeddings [][]float32
 	for _, e := range res.Embeddings {
 		embeddings = append(embeddings, e.Values)
@@ -362,3 +414,26 @@ func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32,
 func (p *Provider) GetDimensions() int {
 	return p.dimensions
 }
+
+// reportEmbeddingUsage reports estimated usage; the embedding API does not return token counts.
+func (p *Provider) reportEmbeddingUsage(ctx context.Context, texts ...string) {
+	ai.ReportUsage(ctx, ai.Usage{
+		Capability: ai.CapabilityEmbedding,
+		Provider:   "gemini",
+		Model:      p.embeddingModel,
+	}.WithEstimates(strings.Join(texts, "\n"), ""))
+}
+
+// EmbeddingModel returns the name of the configured embedding model.
+func (p *Provider) EmbeddingModel() string {
+	return p.embeddingModel
+}
+
+// apiError converts Google API errors to ai.APIError so that callers can classify them.
+func apiError(err error) error {
+	var gErr *googleapi.Error
+	if errors.As(err, &gErr) {
+		return &ai.APIError{Provider: "gemini", StatusCode: gErr.Code, Message: gErr.Message}
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/hrygo/echomind/pkg/ai"
+)
+
+var _ ai.ToolCaller = (*Provider)(nil)
+
+// ChatWithTools runs one chat turn with Gemini function declarations. Gemini has no call IDs, so
+// tool results are matched to calls by function name.
+func (p *Provider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	if len(messages) == 0 {
+		return ai.ToolChatResponse{}, errors.New("no messages provided")
+	}
+
+	model := p.generativeModel(false)
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for _, tool := range tools {
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  toGenaiSchema(tool.Parameters),
+		})
+	}
+	if len(declarations) > 0 {
+		model.Tools = []*genai.Tool{{FunctionDeclarations: declarations}}
+	}
+
+	system, contents, err := toGenaiContents(messages)
+	if err != nil {
+		return ai.ToolChatResponse{}, err
+	}
+	if system != "" {
+		model.SystemInstruction = &genai.Content{Parts: []genai.Part{genai.Text(system)}}
+	}
+	if len(contents) == 0 {
+		return ai.ToolChatResponse{}, errors.New("no user or tool messages provided")
+	}
+
+	cs := model.StartChat()
+	cs.History = contents[:len(contents)-1]
+	resp, err := cs.SendMessage(ctx, contents[len(contents)-1].Parts...)
+	if err != nil {
+		return ai.ToolChatResponse{}, apiError(err)
+	}
+
+	var result ai.ToolChatResponse
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		p.reportUsage(ctx, resp.UsageMetadata, ai.MessagesText(messages), "")
+		return result, nil
+	}
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		switch v := part.(type) {
+		case genai.Text:
+			text.WriteString(string(v))
+		case genai.FunctionCall:
+			args, err := json.Marshal(v.Args)
+			if err != nil {
+				return ai.ToolChatResponse{}, fmt.Errorf("invalid function call arguments: %w", err)
+			}
+			result.ToolCalls = append(result.ToolCalls, ai.ToolCall{Name: v.Name, Arguments: string(args)})
+		}
+	}
+	result.Content = text.String()
+	p.reportUsage(ctx, resp.UsageMetadata, ai.MessagesText(messages), result.Content)
+	return result, nil
+}
+
+// toGenaiContents maps chat messages to Gemini turns. System messages become the system
+// instruction; consecutive tool results are grouped into one turn as Gemini expects.
+func toGenaiContents(messages []ai.Message) (string, []*genai.Content, error) {
+	var system []string
+	var contents []*genai.Content
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+		case "assistant":
+			content := &genai.Content{Role: "model"}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, genai.Text(msg.Content))
+			}
+			for _, call := range msg.ToolCalls {
+				args := map[string]any{}
+				if call.Arguments != "" {
+					if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+						return "", nil, fmt.Errorf("invalid arguments for %s: %w", call.Name, err)
+					}
+				}
+				content.Parts = append(content.Parts, genai.FunctionCall{Name: call.Name, Args: args})
+			}
+			contents = append(contents, content)
+		case "tool":
+			response := map[string]any{}
+			if err := json.Unmarshal([]byte(msg.Content), &response); err != nil {
+				// Function responses must be objects; wrap anything else.
+				response = map[string]any{"result": msg.Content}
+			}
+			part := genai.FunctionResponse{Name: msg.ToolName, Response: response}
+			if last := len(contents) - 1; last >= 0 && contents[last].Role == "function" {
+				contents[last].Parts = append(contents[last].Parts, part)
+				continue
+			}
+			contents = append(contents, &genai.Content{Role: "function", Parts: []genai.Part{part}})
+		default:
+			contents = append(contents, &genai.Content{Role: "user", Parts: []genai.Part{genai.Text(msg.Content)}})
+		}
+	}
+	return strings.Join(system, "\n\n"), contents, nil
+}
+
+func toGenaiSchema(s *ai.Schema) *genai.Schema {
+	if s == nil {
+		return nil
+	}
+	schema := &genai.Schema{
+		Type:        toGenaiType(s.Type),
+		Description: s.Description,
+		Required:    s.Required,
+		Enum:        s.Enum,
+		Items:       toGenaiSchema(s.Items),
+	}
+	if len(s.Enum) > 0 {
+		schema.Format = "enum"
+	}
+	if len(s.Properties) > 0 {
+		schema.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, prop := range s.Properties {
+			schema.Properties[name] = toGenaiSchema(prop)
+		}
+	}
+	return schema
+}
+
+func toGenaiType(t string) genai.Type {
+	switch t {
+	case "object":
+		return genai.TypeObject
+	case "array":
+		return genai.TypeArray
+	case "integer":
+		return genai.TypeInteger
+	case "number":
+		return genai.TypeNumber
+	case "boolean":
+		return genai.TypeBoolean
+	default:
+		return genai.TypeString
+	}
+}
@@ -0,0 +1,305 @@
+// Package hashing implements an embedding provider that runs entirely in process: no network, no
+// model files. Texts become bags of word features that are hashed and randomly projected to the
+// configured dimensions, so texts that share words get similar vectors. It is meant for development,
+// tests and offline use; it knows nothing of synonyms, so a hosted model ranks far better.
+package hashing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/registry"
+)
+
+const (
+	protocol          = "hashing"
+	defaultDimensions = 1024 // Fits the legacy embeddings table
+	// modelVersion names the feature extraction; change it whenever vectors would change, so that
+	// new vectors go to a new embedding space instead of mixing with old ones.
+	modelVersion = "hashing-v1"
+	// projections is how many coordinates each feature is spread over. More than one keeps two
+	// colliding features from looking identical.
+	projections = 8
+)
+
+// ErrEmbeddingOnly is returned by the chat methods; route chat capabilities to another provider.
+var ErrEmbeddingOnly = errors.New("hashing: provider only supports embeddings")
+
+func init() {
+	registry.Register(protocol, NewProvider)
+}
+
+// Provider computes embeddings locally.
+type Provider struct {
+	dimensions int
+	seed       uint64
+	model      string
+}
+
+// NewProvider creates a hashing embedder. Settings: embedding_dimensions (default 1024) and seed,
+// which selects a different projection (and so a different embedding space).
+func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
+	dimensions := defaultDimensions
+	if dim, ok := settings["embedding_dimensions"].(int); ok {
+		dimensions = dim
+	} else if dimFloat, ok := settings["embedding_dimensions"].(float64); ok {
+		dimensions = int(dimFloat)
+	}
+	if dimensions < projections {
+		return nil, fmt.Errorf("hashing: embedding_dimensions must be at least %d", projections)
+	}
+
+	var seed uint64
+	if v, ok := settings["seed"].(int); ok {
+		seed = uint64(v)
+	} else if v, ok := settings["seed"].(float64); ok {
+		seed = uint64(v)
+	}
+	model := modelVersion
+	if seed != 0 {
+		model = fmt.Sprintf("%s-s%d", modelVersion, seed)
+	}
+	return &Provider{dimensions: dimensions, seed: seed, model: model}, nil
+}
+
+func (p *Provider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
+	return ai.AnalysisResult{}, ErrEmbeddingOnly
+}
+
+func (p *Provider) Classify(ctx context.Context, text string) (string, error) {
+	return "", ErrEmbeddingOnly
+}
+
+func (p *Provider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
+	return ai.SentimentResult{}, ErrEmbeddingOnly
+}
+
+func (p *Provider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	return "", ErrEmbeddingOnly
+}
+
+func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	close(ch)
+	return ErrEmbeddingOnly
+}
+
+// Embed generates a vector for a single text.
+func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := p.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch generates vectors for multiple texts.
+func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = p.vector(text)
+	}
+	ai.ReportUsage(ctx, ai.Usage{
+		Capability: ai.CapabilityEmbedding,
+		Provider:   protocol,
+		Model:      p.model,
+	}.WithEstimates(strings.Join(texts, "\n"), ""))
+	return vectors, nil
+}
+
+// GetDimensions returns the dimension size of the vectors generated by this provider.
+func (p *Provider) GetDimensions() int {
+	return p.dimensions
+}
+
+// EmbeddingModel returns the model name, which includes the feature version and seed.
+func (p *Provider) EmbeddingModel() string {
+	return p.model
+}
+
+// vector projects the text's weighted features and normalizes the result to unit length.
+func (p *Provider) vector(text string) []float32 {
+	counts := features(text)
+	if len(counts) == 0 {
+		// A zero vector has no direction for cosine distance; empty texts all get the same one.
+		counts = map[string]float64{"\x00empty": 1}
+	}
+
+	acc := make([]float64, p.dimensions)
+	for feature, weight := range counts {
+		// Sublinear term frequency, so a word repeated ten times does not drown out the rest.
+		w := (1 + math.Log(weight)) / math.Sqrt(projections)
+		if weight < 1 {
+			w = weight / math.Sqrt(projections)
+		}
+		state := p.hash(feature)
+		for i := 0; i < projections; i++ {
+			r := splitmix64(&state)
+			index := int(r % uint64(p.dimensions))
+			if r&(1<<63) != 0 {
+				acc[index] -= w
+			} else {
+				acc[index] += w
+			}
+		}
+	}
+
+	var norm float64
+	for _, v := range acc {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	vec := make([]float32, p.dimensions)
+	if norm == 0 {
+		vec[0] = 1
+		return vec
+	}
+	for i, v := range acc {
+		vec[i] = float32(v / norm)
+	}
+	return vec
+}
+
+func (p *Provider) hash(feature string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	return h.Sum64() ^ p.seed
+}
+
+// splitmix64 advances state and returns the next pseudo-random number.
+func splitmix64(state *uint64) uint64 {
+	*state += 0x9e3779b97f4a7c15
+	z := *state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Feature weights relative to a single word.
+const (
+	bigramWeight    = 0.5 // Adjacent word pairs, for phrases like "credit card"
+	hanBigramWeight = 1.0 // Han character pairs, which are most Chinese words
+	hanCharWeight   = 0.3 // Single Han characters, which carry less meaning on their own
+)
+
+// features counts the text's weighted features: stemmed words without stop words and their
+// bigrams, and for Chinese, which has no spaces, single characters and character pairs.
+func features(text string) map[string]float64 {
+	counts := map[string]float64{}
+	var words []string
+	flushWords := func() {
+		for i, w := range words {
+			counts["w:"+w]++
+			if i > 0 {
+				counts["b:"+words[i-1]+" "+w] += bigramWeight
+			}
+		}
+		words = words[:0]
+	}
+
+	var han []rune
+	flushHan := func() {
+		for i, r := range han {
+			counts["h:"+string(r)] += hanCharWeight
+			if i > 0 {
+				counts["h:"+string(han[i-1:i+1])] += hanBigramWeight
+			}
+		}
+		han = han[:0]
+	}
+
+	var word strings.Builder
+	flushWord := func() {
+		if word.Len() == 0 {
+			return
+		}
+		w := word.String()
+		word.Reset()
+		if stopWords[w] {
+			return
+		}
+		words = append(words, stem(w))
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushHan()
+			word.WriteRune(r)
+		case r == '\'' || r == '’':
+			// Keep contractions together: "don't", "we'll"
+			if word.Len() > 0 {
+				word.WriteRune('\'')
+			}
+		default:
+			flushWord()
+			flushHan()
+			if unicode.IsPunct(r) {
+				// Sentence and clause breaks end phrases.
+				flushWords()
+			}
+		}
+	}
+	flushWord()
+	flushHan()
+	flushWords()
+	return counts
+}
+
+// stem strips common English suffixes so that "meetings", "meeting" and "meet" share a feature.
+// It is deliberately crude; only the agreement between forms of a word matters, not the result.
+func stem(w string) string {
+	w = strings.TrimSuffix(w, "'")
+	if len(w) <= 3 {
+		return w
+	}
+	// Plurals and third person, except for words that only look like them: "class", "status", "analysis"
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = strings.TrimSuffix(w, "ies") + "y"
+	case strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss") && !strings.HasSuffix(w, "us") && !strings.HasSuffix(w, "is"):
+		w = strings.TrimSuffix(w, "s")
+	}
+	// Verb and adverb endings: "scheduled", "scheduling", "shipped", "quickly"
+	for _, suffix := range []string{"ing", "ed", "ly"} {
+		if base := strings.TrimSuffix(w, suffix); base != w && len(base) >= 3 {
+			w = base
+			if n := len(w); w[n-1] == w[n-2] && !strings.ContainsRune("aeiouls", rune(w[n-1])) {
+				w = w[:n-1] // "shipp" → "ship", but "call", "pass" keep theirs
+			}
+			break
+		}
+	}
+	// "date" and "dated" → "dat", "invoice" and "invoices" → "invoic"
+	if len(w) > 3 {
+		w = strings.TrimSuffix(w, "e")
+	}
+	return w
+}
+
+// stopWords are frequent English words that carry no topic.
+var stopWords = func() map[string]bool {
+	words := strings.Fields(`a an the and or but if then than so of to in on at by for from with
+		about as into over after before is are was were be been being am do does did have has had
+		i me my we us our you your he him his she her it its they them their this that these those
+		there here what which who whom when where why how all any some no not can could will would
+		shall should may might must just also very too up out s t re ve ll d m don't can't won't
+		i'm it's that's please thanks thank hi hello dear regards best`)
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}()
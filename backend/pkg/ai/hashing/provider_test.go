@@ -0,0 +1,118 @@
+package hashing
+
+import (
+	"context"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T, settings configs.ProviderSettings) *Provider {
+	factory, err := registry.Get("hashing")
+	require.NoError(t, err)
+	p, err := factory(context.Background(), settings, nil)
+	require.NoError(t, err)
+	return p.(*Provider)
+}
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot // Vectors are unit length
+}
+
+func TestProvider_Vectors(t *testing.T) {
+	p := newTestProvider(t, configs.ProviderSettings{"embedding_dimensions": 256})
+	assert.Equal(t, 256, p.GetDimensions())
+	assert.Equal(t, "hashing-v1", ai.EmbeddingModelName(p))
+
+	ctx := context.Background()
+	vectors, err := p.EmbedBatch(ctx, []string{"Invoice overdue", "Invoice overdue", ""})
+	require.NoError(t, err)
+	assert.Equal(t, vectors[0], vectors[1], "embeddings are deterministic")
+	for _, v := range vectors {
+		require.Len(t, v, 256)
+		var norm float64
+		for _, x := range v {
+			norm += float64(x) * float64(x)
+		}
+		assert.InDelta(t, 1, math.Sqrt(norm), 1e-5, "vectors are unit length, even for empty text")
+	}
+
+	seeded := newTestProvider(t, configs.ProviderSettings{"embedding_dimensions": 256, "seed": 7})
+	assert.Equal(t, "hashing-v1-s7", seeded.EmbeddingModel(), "another projection is another embedding space")
+	other, err := seeded.Embed(ctx, "Invoice overdue")
+	require.NoError(t, err)
+	assert.NotEqual(t, vectors[0], other)
+
+	_, err = p.Summarize(ctx, "x")
+	assert.ErrorIs(t, err, ErrEmbeddingOnly)
+}
+
+func TestProvider_Ranking(t *testing.T) {
+	p := newTestProvider(t, configs.ProviderSettings{})
+	docs := map[string]string{
+		"invoice":  "Reminder: invoice #4471 is overdue. Please arrange the payment by Friday.",
+		"meeting":  "The design review meeting is rescheduled to Thursday afternoon in room 4B.",
+		"dinner":   "Are you free for dinner on Saturday? The new Thai place downtown looks good.",
+		"deploy":   "The production deployment failed; the database migration timed out.",
+		"contract": "附件是新版合作合同，请于本周三前审核并反馈修改意见。",
+		"party":    "下周六是我的生日，晚上在家里办个小聚会，你一定要来！",
+	}
+	ctx := context.Background()
+	rank := func(query string) []string {
+		q, err := p.Embed(ctx, query)
+		require.NoError(t, err)
+		type scored struct {
+			id    string
+			score float64
+		}
+		var results []scored
+		for id, text := range docs {
+			v, err := p.Embed(ctx, text)
+			require.NoError(t, err)
+			results = append(results, scored{id, cosine(q, v)})
+		}
+		sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+		ids := make([]string, len(results))
+		for i, r := range results {
+			ids[i] = r.id
+		}
+		return ids
+	}
+
+	for query, want := range map[string]string{
+		"overdue invoices":               "invoice",
+		"when are we meeting to review?": "meeting",
+		"dinner plans this weekend":      "dinner",
+		"failed deployments":             "deploy",
+		"合同审核":                           "contract",
+		"生日聚会":                           "party",
+	} {
+		assert.Equal(t, want, rank(query)[0], query)
+	}
+}
+
+func TestStem(t *testing.T) {
+	for _, group := range [][]string{
+		{"meeting", "meetings", "meet", "meets"},
+		{"invoice", "invoices"},
+		{"ship", "shipped", "shipping", "ships"},
+		{"schedule", "scheduled", "scheduling"},
+		{"reply", "replies"},
+	} {
+		for _, w := range group[1:] {
+			assert.Equal(t, stem(group[0]), stem(w), "%s / %s", group[0], w)
+		}
+	}
+	assert.Equal(t, "status", stem("status"))
+	assert.Equal(t, "class", stem("class"))
+}
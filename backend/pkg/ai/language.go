@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Languages maps the ISO 639-1 codes of the languages EchoMind detects and translates to their
+// English names.
+var Languages = map[string]string{
+	"zh": "Chinese",
+	"en": "English",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"fr": "French",
+	"de": "German",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"it": "Italian",
+	"ru": "Russian",
+	"ar": "Arabic",
+}
+
+// LanguageName returns the English name of a language code, or "" for codes not in Languages.
+func LanguageName(code string) string {
+	return Languages[code]
+}
+
+// LocaleLanguage returns the language of a locale such as "zh-CN" or "en_US", or "" if it is not
+// one of the Languages.
+func LocaleLanguage(locale string) string {
+	code := strings.ToLower(locale)
+	if i := strings.IndexAny(code, "-_"); i >= 0 {
+		code = code[:i]
+	}
+	if _, ok := Languages[code]; !ok {
+		return ""
+	}
+	return code
+}
+
+// latinStopWords tells apart the languages written in the Latin alphabet by their most common words.
+var latinStopWords = map[string][]string{
+	"en": {"the", "and", "to", "of", "is", "you", "for", "we", "please", "with", "this", "that", "are", "on", "will"},
+	"fr": {"le", "la", "les", "et", "des", "est", "vous", "pour", "nous", "une", "dans", "que", "sur", "avec", "merci"},
+	"de": {"der", "die", "das", "und", "ist", "sie", "wir", "nicht", "mit", "für", "ein", "eine", "zu", "bitte", "ich"},
+	"es": {"el", "los", "las", "y", "es", "por", "para", "que", "con", "una", "usted", "gracias", "del", "nos", "en"},
+	"pt": {"o", "os", "as", "e", "é", "não", "para", "com", "uma", "você", "obrigado", "do", "da", "em", "que"},
+	"it": {"il", "gli", "e", "è", "non", "per", "con", "una", "che", "grazie", "della", "sono", "di", "ci", "lo"},
+}
+
+// WrittenInLatin reports whether the language with code is written in the Latin alphabet.
+func WrittenInLatin(code string) bool {
+	_, ok := latinStopWords[code]
+	return ok
+}
+
+// DetectLanguage returns the code of the main language of text, or "" when it has too few letters
+// to tell. Scripts decide first: kana means Japanese, Hangul Korean, Han characters alone Chinese.
+// Mixed Chinese and English counts as Chinese when there are at least as many Han characters as
+// English words, as in a Chinese email quoting product names. Latin text is told apart by its most
+// common words and is English when nothing else stands out.
+func DetectLanguage(text string) string {
+	var han, kana, hangul, cyrillic, arabic int
+	latin := strings.Builder{}
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.Is(unicode.Hangul, r):
+			hangul++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+		case unicode.Is(unicode.Arabic, r):
+			arabic++
+		case unicode.IsLetter(r):
+			latin.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		latin.WriteRune(' ')
+	}
+	words := strings.Fields(latin.String())
+
+	switch {
+	case kana > 0 && kana+han >= len(words):
+		return "ja"
+	case hangul > 0 && hangul >= len(words):
+		return "ko"
+	case han > 0 && han >= len(words):
+		return "zh"
+	case cyrillic > 0 && cyrillic >= len(words):
+		return "ru"
+	case arabic > 0 && arabic >= len(words):
+		return "ar"
+	case len(words) < 2:
+		return ""
+	}
+
+	counts := map[string]int{}
+	for _, w := range words {
+		for lang, stops := range latinStopWords {
+			for _, s := range stops {
+				if w == s {
+					counts[lang]++
+				}
+			}
+		}
+	}
+	best := "en"
+	for _, lang := range []string{"fr", "de", "es", "pt", "it"} {
+		if counts[lang] > counts[best] {
+			best = lang
+		}
+	}
+	return best
+}
+
+// LanguageResolver picks the language AI output is written in for the calls made with a context,
+// typically the user's preferred one.
+type LanguageResolver interface {
+	OutputLanguage(ctx context.Context) string
+}
+
+type languageResolverKey struct{}
+
+// WithLanguageResolver returns a context whose AI output is written in the language resolver picks.
+func WithLanguageResolver(ctx context.Context, resolver LanguageResolver) context.Context {
+	return context.WithValue(ctx, languageResolverKey{}, resolver)
+}
+
+type fixedLanguage string
+
+func (l fixedLanguage) OutputLanguage(ctx context.Context) string { return string(l) }
+
+// WithOutputLanguage returns a context whose AI output is written in the language with code.
+func WithOutputLanguage(ctx context.Context, code string) context.Context {
+	return WithLanguageResolver(ctx, fixedLanguage(code))
+}
+
+// OutputLanguage returns the code of the language AI output should be written in, or "" when the
+// model may choose.
+func OutputLanguage(ctx context.Context) string {
+	if resolver, ok := ctx.Value(languageResolverKey{}).(LanguageResolver); ok {
+		return resolver.OutputLanguage(ctx)
+	}
+	return ""
+}
+
+// OutputLanguageInstruction asks for the context's output language, or returns "" when it has none.
+// Enumerated values such as categories stay in English.
+func OutputLanguageInstruction(ctx context.Context) string {
+	name := LanguageName(OutputLanguage(ctx))
+	if name == "" {
+		return ""
+	}
+	return "Write all free text (summaries, action items, labels) in " + name +
+		", whatever the language of the input. Keep enumerated values exactly as specified."
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Please review the attached budget before Friday.", "en"},
+		{"请在周五前审阅附件中的预算。", "zh"},
+		{"关于 Q3 budget review 的会议改到周五下午三点", "zh"},
+		{"Re: 会议", "zh"},
+		{"Can we move the Q3 review? 谢谢", "en"},
+		{"金曜日までに予算を確認してください。", "ja"},
+		{"금요일까지 예산을 검토해 주세요.", "ko"},
+		{"Пожалуйста, проверьте бюджет до пятницы.", "ru"},
+		{"Merci de vérifier le budget avant vendredi, nous avons une réunion.", "fr"},
+		{"Bitte prüfen Sie das Budget bis Freitag, wir haben ein Treffen.", "de"},
+		{"Por favor revise el presupuesto antes del viernes, gracias.", "es"},
+		{"budget", ""},
+		{"12345 !!", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, DetectLanguage(tt.text), tt.text)
+	}
+}
+
+func TestLocaleLanguage(t *testing.T) {
+	assert.Equal(t, "zh", LocaleLanguage("zh-CN"))
+	assert.Equal(t, "en", LocaleLanguage("en_US"))
+	assert.Equal(t, "ja", LocaleLanguage("JA"))
+	assert.Equal(t, "", LocaleLanguage("tlh"))
+	assert.Equal(t, "", LocaleLanguage(""))
+}
+
+func TestOutputLanguage(t *testing.T) {
+	ctx := context.Background()
+	assert.Empty(t, OutputLanguage(ctx))
+	assert.Empty(t, OutputLanguageInstruction(ctx))
+
+	ctx = WithOutputLanguage(ctx, "zh")
+	assert.Equal(t, "zh", OutputLanguage(ctx))
+	assert.Contains(t, OutputLanguageInstruction(ctx), "in Chinese")
+	assert.Empty(t, OutputLanguageInstruction(WithOutputLanguage(ctx, "xx")), "unknown codes ask for nothing")
+
+	var system string
+	_, err := GenerateAnalysis(ctx, "Analyze.", "text", func(ctx context.Context, messages []Message) (string, error) {
+		system = messages[0].Content
+		return `{"summary":"会议改期","category":"Work","sentiment":"Neutral","urgency":"Low","action_items":[],"smart_actions":[]}`, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Analyze.\n\n"+OutputLanguageInstruction(ctx), system)
+}
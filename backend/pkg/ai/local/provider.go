@@ -0,0 +1,465 @@
+// Package local implements providers for self-hosted inference servers: Ollama (native API) and
+// llama.cpp's llama-server (OpenAI-style endpoints, no API key required).
+package local
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/registry"
+)
+
+const (
+	flavorOllama   = "ollama"
+	flavorLlamaCpp = "llamacpp"
+)
+
+func init() {
+	registry.Register(flavorOllama, NewOllamaProvider)
+	registry.Register(flavorLlamaCpp, NewLlamaCppProvider)
+}
+
+// Provider serves chat and embeddings from a local inference server.
+type Provider struct {
+	ai.TextTasks
+	flavor         string
+	client         *http.Client
+	baseURL        string
+	apiKey         string // Optional; llama-server can be started with --api-key
+	model          string
+	embeddingModel string
+	dimensions     int
+	params         ai.ModelParams
+}
+
+// NewOllamaProvider creates a provider for Ollama's native API (default http://localhost:11434).
+func NewOllamaProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
+	return newProvider(flavorOllama, "http://localhost:11434", settings, prompts)
+}
+
+// NewLlamaCppProvider creates a provider for llama.cpp's llama-server (default http://localhost:8080).
+func NewLlamaCppProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
+	return newProvider(flavorLlamaCpp, "http://localhost:8080", settings, prompts)
+}
+
+func newProvider(flavor, defaultBaseURL string, settings configs.ProviderSettings, prompts map[string]string) (*Provider, error) {
+	apiKey, _ := settings["api_key"].(string)
+	model, _ := settings["model"].(string)
+	baseURL, _ := settings["base_url"].(string)
+	embeddingModel, _ := settings["embedding_model"].(string)
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	if embeddingModel == "" {
+		embeddingModel = model
+	}
+	dimensions := 768 // nomic-embed-text, the usual local embedding model
+	if dim, ok := settings["embedding_dimensions"].(int); ok {
+		dimensions = dim
+	} else if dimFloat, ok := settings["embedding_dimensions"].(float64); ok {
+		dimensions = int(dimFloat)
+	}
+	// Local models on CPUs can be slow; allow generous time by default.
+	timeout := 300 * time.Second
+	if v, ok := settings["timeout_seconds"].(int); ok {
+		timeout = time.Duration(v) * time.Second
+	}
+
+	p := &Provider{
+		flavor:         flavor,
+		client:         &http.Client{Timeout: timeout},
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+		apiKey:         apiKey,
+		model:          model,
+		embeddingModel: embeddingModel,
+		dimensions:     dimensions,
+		params:         ai.ParseModelParams(settings),
+	}
+	p.TextTasks = ai.TextTasks{Prompts: prompts, Complete: p.complete}
+	return p, nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaCounts are the token counts on Ollama's final chat response and on embed responses.
+type ollamaCounts struct {
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+// openAIUsage is the usage block of llama-server's OpenAI-compatible responses.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// reportUsage reports a call's usage, estimating counts the server did not return.
+func (p *Provider) reportUsage(ctx context.Context, usage ai.Usage, prompt, completion string) {
+	usage.Provider = p.flavor
+	if usage.Model == "" {
+		usage.Model = p.model
+	}
+	ai.ReportUsage(ctx, usage.WithEstimates(prompt, completion))
+}
+
+func toChatMessages(messages []ai.Message) []chatMessage {
+	converted := make([]chatMessage, 0, len(messages))
+	for _, msg := range messages {
+		role := msg.Role
+		if role == "tool" {
+			role = "user" // Tool turns only occur with function calling, which these servers are not used for
+		}
+		converted = append(converted, chatMessage{Role: role, Content: msg.Content})
+	}
+	return converted
+}
+
+func chatText(messages []chatMessage) string {
+	var text strings.Builder
+	for _, msg := range messages {
+		text.WriteString(msg.Content)
+		text.WriteString("\n")
+	}
+	return text.String()
+}
+
+// chatRequest builds a chat request body in the server's dialect, with the configured parameters.
+func (p *Provider) chatRequest(messages []chatMessage, stream bool, format ai.OutputFormat) map[string]interface{} {
+	req := map[string]interface{}{"model": p.model, "messages": messages, "stream": stream}
+	if !p.params.JSON() {
+		format = ai.FormatText
+	}
+
+	if p.flavor == flavorOllama {
+		options := map[string]interface{}{}
+		if p.params.Temperature != nil {
+			options["temperature"] = *p.params.Temperature
+		}
+		if p.params.MaxTokens > 0 {
+			options["num_predict"] = p.params.MaxTokens
+		}
+		if len(options) > 0 {
+			req["options"] = options
+		}
+		switch format {
+		case ai.FormatJSON:
+			req["format"] = "json"
+		case ai.FormatAnalysis:
+			req["format"] = ai.AnalysisJSONSchema()
+		}
+		return req
+	}
+
+	if p.params.Temperature != nil {
+		req["temperature"] = *p.params.Temperature
+	}
+	if p.params.MaxTokens > 0 {
+		req["max_tokens"] = p.params.MaxTokens
+	}
+	switch format {
+	case ai.FormatJSON:
+		req["response_format"] = map[string]interface{}{"type": "json_object"}
+	case ai.FormatAnalysis:
+		req["response_format"] = map[string]interface{}{"type": "json_object", "schema": ai.AnalysisJSONSchema()}
+	}
+	return req
+}
+
+// complete runs a non-streamed chat request. Both servers enforce JSON and the analysis schema with
+// a grammar.
+func (p *Provider) complete(ctx context.Context, chat []ai.Message, format ai.OutputFormat) (string, error) {
+	messages := toChatMessages(chat)
+	if p.flavor == flavorOllama {
+		req := p.chatRequest(messages, false, format)
+		var resp struct {
+			Message chatMessage `json:"message"`
+			ollamaCounts
+		}
+		if err := p.postJSON(ctx, "/api/chat", req, &resp); err != nil {
+			return "", err
+		}
+		p.reportUsage(ctx, ai.Usage{PromptTokens: resp.PromptEvalCount, CompletionTokens: resp.EvalCount},
+			chatText(messages), resp.Message.Content)
+		return resp.Message.Content, nil
+	}
+
+	req := p.chatRequest(messages, false, format)
+	var resp struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := p.postJSON(ctx, "/v1/chat/completions", req, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("no choices returned from llama-server")
+	}
+	content := resp.Choices[0].Message.Content
+	p.reportUsage(ctx, ai.Usage{PromptTokens: resp.Usage.PromptTokens, CompletionTokens: resp.Usage.CompletionTokens},
+		chatText(messages), content)
+	return content, nil
+}
+
+func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	defer close(ch)
+
+	converted := toChatMessages(messages)
+	if p.flavor == flavorOllama {
+		return p.streamOllama(ctx, converted, ch)
+	}
+	return p.streamLlamaCpp(ctx, converted, ch)
+}
+
+// streamOllama reads Ollama's newline-delimited JSON stream.
+func (p *Provider) streamOllama(ctx context.Context, messages []chatMessage, ch chan<- ai.ChatCompletionChunk) error {
+	resp, err := p.post(ctx, "/api/chat", p.chatRequest(messages, true, ai.FormatText))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var counts ollamaCounts
+	var completion strings.Builder
+	defer func() {
+		p.reportUsage(ctx, ai.Usage{PromptTokens: counts.PromptEvalCount, CompletionTokens: counts.EvalCount},
+			chatText(messages), completion.String())
+	}()
+
+	scanner := newLineScanner(resp.Body)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var event struct {
+			Message chatMessage `json:"message"`
+			Done    bool        `json:"done"`
+			Error   string      `json:"error"`
+			ollamaCounts
+		}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return fmt.Errorf("ollama: invalid stream line: %w", err)
+		}
+		if event.Error != "" {
+			return &ai.APIError{Provider: p.flavor, StatusCode: resp.StatusCode, Message: event.Error}
+		}
+		if event.Message.Content != "" {
+			completion.WriteString(event.Message.Content)
+			ch <- ai.ChatCompletionChunk{
+				ID:      "ollama",
+				Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: event.Message.Content}}},
+			}
+		}
+		if event.Done {
+			counts = event.ollamaCounts
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("ollama: stream ended before done")
+}
+
+// streamLlamaCpp reads llama-server's OpenAI-style server-sent events.
+func (p *Provider) streamLlamaCpp(ctx context.Context, messages []chatMessage, ch chan<- ai.ChatCompletionChunk) error {
+	resp, err := p.post(ctx, "/v1/chat/completions", p.chatRequest(messages, true, ai.FormatText))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var usage openAIUsage
+	var completion strings.Builder
+	defer func() {
+		p.reportUsage(ctx, ai.Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens},
+			chatText(messages), completion.String())
+	}()
+
+	scanner := newLineScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+		var event struct {
+			ID      string `json:"id"`
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *openAIUsage `json:"usage"` // On the last chunk, if the server reports it
+		}
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return fmt.Errorf("llamacpp: invalid stream event: %w", err)
+		}
+		if event.Usage != nil {
+			usage = *event.Usage
+		}
+		if len(event.Choices) > 0 && event.Choices[0].Delta.Content != "" {
+			completion.WriteString(event.Choices[0].Delta.Content)
+			ch <- ai.ChatCompletionChunk{
+				ID:      event.ID,
+				Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: event.Choices[0].Delta.Content}}},
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("llamacpp: stream ended before [DONE]")
+}
+
+// Embed generates a vector for a single text.
+func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
+	vectors, err := p.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// EmbedBatch generates vectors for multiple texts.
+func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	var promptTokens int
+	if p.flavor == flavorOllama {
+		var resp struct {
+			Embeddings [][]float32 `json:"embeddings"`
+			ollamaCounts
+		}
+		if err := p.postJSON(ctx, "/api/embed", map[string]interface{}{"model": p.embeddingModel, "input": texts}, &resp); err != nil {
+			return nil, err
+		}
+		promptTokens = resp.PromptEvalCount
+		vectors = resp.Embeddings
+	} else {
+		var resp struct {
+			Data []struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+			Usage openAIUsage `json:"usage"`
+		}
+		if err := p.postJSON(ctx, "/v1/embeddings", map[string]interface{}{"model": p.embeddingModel, "input": texts}, &resp); err != nil {
+			return nil, err
+		}
+		promptTokens = resp.Usage.PromptTokens
+		vectors = make([][]float32, len(resp.Data))
+		for _, d := range resp.Data {
+			if d.Index < 0 || d.Index >= len(vectors) {
+				return nil, fmt.Errorf("llamacpp: embedding index %d out of range", d.Index)
+			}
+			vectors[d.Index] = d.Embedding
+		}
+	}
+
+	p.reportUsage(ctx, ai.Usage{Capability: ai.CapabilityEmbedding, Model: p.embeddingModel, PromptTokens: promptTokens},
+		strings.Join(texts, "\n"), "")
+
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("%s: got %d embeddings for %d texts", p.flavor, len(vectors), len(texts))
+	}
+	for _, v := range vectors {
+		if len(v) != p.dimensions {
+			return nil, fmt.Errorf("%s: embedding has %d dimensions, configured %d", p.flavor, len(v), p.dimensions)
+		}
+	}
+	return vectors, nil
+}
+
+// GetDimensions returns the dimension size of the vectors generated by this provider.
+func (p *Provider) GetDimensions() int {
+	return p.dimensions
+}
+
+// EmbeddingModel returns the name of the configured embedding model.
+func (p *Provider) EmbeddingModel() string {
+	return p.embeddingModel
+}
+
+func (p *Provider) postJSON(ctx context.Context, path string, body interface{}, out interface{}) error {
+	resp, err := p.post(ctx, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s: invalid response: %w", p.flavor, err)
+	}
+	return nil
+}
+
+// post sends a JSON request and returns the response if it succeeded.
+func (p *Provider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, &ai.APIError{Provider: p.flavor, StatusCode: resp.StatusCode, Message: errorMessage(raw)}
+	}
+	return resp, nil
+}
+
+// errorMessage extracts the message from an Ollama ({"error": "..."}) or OpenAI-style
+// ({"error": {"message": "..."}}) error body.
+func errorMessage(raw []byte) string {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) == nil && len(body.Error) > 0 {
+		var text string
+		if json.Unmarshal(body.Error, &text) == nil {
+			return text
+		}
+		var nested struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body.Error, &nested) == nil && nested.Message != "" {
+			return nested.Message
+		}
+	}
+	return strings.TrimSpace(string(raw))
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return scanner
+}
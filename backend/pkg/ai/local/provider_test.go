@@ -0,0 +1,222 @@
+package local
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hrygo/echomind/configs"
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProvider(t *testing.T, protocol string, handler http.HandlerFunc) *Provider {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	settings := configs.ProviderSettings{
+		"base_url":             server.URL,
+		"model":                "llama3",
+		"embedding_model":      "nomic-embed-text",
+		"embedding_dimensions": 3,
+	}
+	prompts := map[string]string{"summary": "Summarize as JSON.", "classify": "Classify the email."}
+
+	var p ai.AIProvider
+	var err error
+	if protocol == flavorOllama {
+		p, err = NewOllamaProvider(context.Background(), settings, prompts)
+	} else {
+		p, err = NewLlamaCppProvider(context.Background(), settings, prompts)
+	}
+	require.NoError(t, err)
+	return p.(*Provider)
+}
+
+func decodeBody(t *testing.T, r *http.Request) map[string]interface{} {
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+	return body
+}
+
+func collect(t *testing.T, ch chan ai.ChatCompletionChunk) string {
+	var text strings.Builder
+	for chunk := range ch {
+		text.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	return text.String()
+}
+
+var chatMessages = []ai.Message{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "Hi"}}
+
+func TestOllama_Contract(t *testing.T) {
+	p := newTestProvider(t, flavorOllama, func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"), "no API key is sent unless configured")
+		body := decodeBody(t, r)
+		switch r.URL.Path {
+		case "/api/chat":
+			assert.Equal(t, "llama3", body["model"])
+			if body["stream"] == true {
+				assert.Equal(t, []interface{}{
+					map[string]interface{}{"role": "system", "content": "Be brief."},
+					map[string]interface{}{"role": "user", "content": "Hi"},
+				}, body["messages"])
+				fmt.Fprintln(w, `{"message":{"role":"assistant","content":"Hel"},"done":false}`)
+				fmt.Fprintln(w, `{"message":{"role":"assistant","content":"lo!"},"done":false}`)
+				fmt.Fprintln(w, `{"message":{"role":"assistant","content":""},"done":true,"eval_count":3}`)
+				return
+			}
+			format, ok := body["format"].(map[string]interface{})
+			require.True(t, ok, "analysis uses Ollama's format option with the schema")
+			assert.Contains(t, format["required"], "category")
+			if len(body["messages"].([]interface{})) == 2 {
+				fmt.Fprint(w, `{"message":{"role":"assistant","content":"{\"summary\":\"Lunch moved\",\"category\":\"Work\"}"},"done":true}`)
+				return
+			}
+			// The invalid reply comes back with what is wrong with it
+			messages := body["messages"].([]interface{})
+			require.Len(t, messages, 4)
+			assert.Contains(t, messages[3].(map[string]interface{})["content"], `"sentiment" is ""`)
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"{\"summary\":\"Lunch moved\",\"category\":\"work\",\"sentiment\":\"Neutral\",\"urgency\":\"Low\",\"action_items\":[],\"smart_actions\":[]}"},"done":true}`)
+		case "/api/embed":
+			assert.Equal(t, "nomic-embed-text", body["model"])
+			assert.Equal(t, []interface{}{"a", "b"}, body["input"])
+			fmt.Fprint(w, `{"embeddings":[[0.1,0.2,0.3],[0.4,0.5,0.6]]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	result, err := p.Summarize(ctx, "Lunch is at 1pm now")
+	require.NoError(t, err)
+	assert.Equal(t, "Lunch moved", result.Summary)
+	assert.Equal(t, "Work", result.Category, "enum values are canonicalized")
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	require.NoError(t, p.StreamChat(ctx, chatMessages, ch))
+	assert.Equal(t, "Hello!", collect(t, ch))
+
+	vectors, err := p.EmbedBatch(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}, vectors)
+	assert.Equal(t, "nomic-embed-text", ai.EmbeddingModelName(p))
+}
+
+func TestOllama_Errors(t *testing.T) {
+	p := newTestProvider(t, flavorOllama, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/embed" {
+			fmt.Fprint(w, `{"embeddings":[[0.1,0.2]]}`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"model \"llama3\" not found, try pulling it first"}`)
+	})
+
+	_, err := p.Classify(context.Background(), "x")
+	var apiErr *ai.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, `model "llama3" not found, try pulling it first`, apiErr.Message)
+
+	_, err = p.Embed(context.Background(), "a")
+	assert.EqualError(t, err, "ollama: embedding has 2 dimensions, configured 3")
+}
+
+func TestLlamaCpp_Contract(t *testing.T) {
+	p := newTestProvider(t, flavorLlamaCpp, func(w http.ResponseWriter, r *http.Request) {
+		body := decodeBody(t, r)
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			if body["stream"] == true {
+				w.Header().Set("Content-Type", "text/event-stream")
+				fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+				fmt.Fprint(w, "data: {\"id\":\"c1\",\"choices\":[{\"delta\":{\"content\":\"lo!\"}}]}\n\n")
+				fmt.Fprint(w, "data: [DONE]\n\n")
+				return
+			}
+			assert.Nil(t, body["response_format"])
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"Newsletter"}}]}`)
+		case "/v1/embeddings":
+			// Results may come back out of order; the index decides.
+			fmt.Fprint(w, `{"data":[{"index":1,"embedding":[0.4,0.5,0.6]},{"index":0,"embedding":[0.1,0.2,0.3]}]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+	ctx := context.Background()
+
+	label, err := p.Classify(ctx, "Weekly digest")
+	require.NoError(t, err)
+	assert.Equal(t, "Newsletter", label)
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	require.NoError(t, p.StreamChat(ctx, chatMessages, ch))
+	assert.Equal(t, "Hello!", collect(t, ch))
+
+	vectors, err := p.EmbedBatch(ctx, []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{0.1, 0.2, 0.3}, {0.4, 0.5, 0.6}}, vectors)
+}
+
+func TestLlamaCpp_APIKeyAndErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"code":401,"message":"Invalid API Key","type":"authentication_error"}}`)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"code":503,"message":"Loading model","type":"unavailable_error"}}`)
+	}))
+	defer server.Close()
+
+	for key, want := range map[string]*ai.APIError{
+		"":       {Provider: flavorLlamaCpp, StatusCode: http.StatusUnauthorized, Message: "Invalid API Key"},
+		"secret": {Provider: flavorLlamaCpp, StatusCode: http.StatusServiceUnavailable, Message: "Loading model"},
+	} {
+		p, err := NewLlamaCppProvider(context.Background(), configs.ProviderSettings{"base_url": server.URL, "api_key": key}, nil)
+		require.NoError(t, err)
+		err = p.StreamChat(context.Background(), chatMessages, make(chan ai.ChatCompletionChunk, 1))
+		var apiErr *ai.APIError
+		require.True(t, errors.As(err, &apiErr))
+		assert.Equal(t, want, apiErr)
+	}
+}
+
+func TestModelParams(t *testing.T) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodies = append(bodies, decodeBody(t, r))
+		if r.URL.Path == "/api/chat" {
+			fmt.Fprint(w, `{"message":{"role":"assistant","content":"{}"},"done":true}`)
+			return
+		}
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`)
+	}))
+	defer server.Close()
+	settings := configs.ProviderSettings{"base_url": server.URL, "temperature": 0.0, "max_tokens": 64, "json_mode": false}
+	prompts := map[string]string{"sentiment": "Rate the sentiment."}
+
+	ollama, err := NewOllamaProvider(context.Background(), settings, prompts)
+	require.NoError(t, err)
+	_, err = ollama.AnalyzeSentiment(context.Background(), "x")
+	require.NoError(t, err)
+	llamacpp, err := NewLlamaCppProvider(context.Background(), settings, prompts)
+	require.NoError(t, err)
+	_, err = llamacpp.AnalyzeSentiment(context.Background(), "x")
+	require.NoError(t, err)
+
+	require.Len(t, bodies, 2)
+	assert.Equal(t, map[string]interface{}{"temperature": 0.0, "num_predict": 64.0}, bodies[0]["options"])
+	assert.Nil(t, bodies[0]["format"], "json_mode: false leaves the format to the prompt")
+	assert.Equal(t, 0.0, bodies[1]["temperature"])
+	assert.Equal(t, 64.0, bodies[1]["max_tokens"])
+	assert.Nil(t, bodies[1]["response_format"])
+}
@@ -4,9 +4,15 @@ import (
 	"context"
 	"time"
 
+	"github.com/hrygo/echomind/configs"
 	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/ai/registry"
 )
 
+func init() {
+	registry.Register("mock", newFromSettings)
+}
+
 type MockProvider struct {
 	dimensions int
 }
@@ -19,6 +25,17 @@ func NewProviderWithDimensions(dimensions int) *MockProvider {
 	return &MockProvider{dimensions: dimensions}
 }
 
+// newFromSettings creates a mock provider from its configuration entry (protocol "mock").
+func newFromSettings(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
+	if dim, ok := settings["embedding_dimensions"].(int); ok {
+		return NewProviderWithDimensions(dim), nil
+	}
+	if dimFloat, ok := settings["embedding_dimensions"].(float64); ok {
+		return NewProviderWithDimensions(int(dimFloat)), nil
+	}
+	return NewProvider(), nil
+}
+
 // AIProvider implementation
 
 func (m *MockProvider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
@@ -111,3 +128,7 @@ func (m *MockProvider) EmbedBatch(ctx context.Context, texts []string) ([][]floa
 func (m *MockProvider) GetDimensions() int {
 	return m.dimensions
 }
+
+func (m *MockProvider) EmbeddingModel() string {
+	return "mock-embedding"
+}
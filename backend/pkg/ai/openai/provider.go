@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
+	"math"
 	"strings"
 
 	"github.com/hrygo/echomind/configs"
@@ -19,11 +19,13 @@ func init() {
 }
 
 type Provider struct {
+	ai.TextTasks
 	client         *openai.Client
 	model          string
 	embeddingModel string
 	dimensions     int
-	prompts        map[string]string
+	params         ai.ModelParams
+	jsonSchema     bool // Constrain analyses with a JSON schema rather than plain JSON mode
 }
 
 func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts map[string]string) (ai.AIProvider, error) {
@@ -45,120 +47,100 @@ func NewProvider(ctx context.Context, settings configs.ProviderSettings, prompts
 		embeddingModel = string(openai.SmallEmbedding3)
 	}
 
+	// Schema-constrained output is an OpenAI feature many compatible servers lack, so elsewhere it
+	// has to be switched on with json_schema.
+	jsonSchema := baseURL == "" || strings.Contains(baseURL, "api.openai.com")
+	if v, ok := settings["json_schema"].(bool); ok {
+		jsonSchema = v
+	}
+
 	config := openai.DefaultConfig(apiKey)
 	if baseURL != "" {
 		config.BaseURL = baseURL
 	}
-	return &Provider{
+	p := &Provider{
 		client:         openai.NewClientWithConfig(config),
 		model:          model,
 		embeddingModel: embeddingModel,
 		dimensions:     dimensions,
-		prompts:        prompts,
-	}, nil
-}
-
-func (p *Provider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
-	systemPrompt := p.prompts["summary"]
-	if systemPrompt == "" {
-		return ai.AnalysisResult{}, errors.New("summary prompt not configured")
+		params:         ai.ParseModelParams(settings),
+		jsonSchema:     jsonSchema,
 	}
+	p.TextTasks = ai.TextTasks{Prompts: prompts, Complete: p.complete}
+	return p, nil
+}
 
-	response, err := p.chatCompletion(ctx, systemPrompt, text, true)
-	if err != nil {
-		return ai.AnalysisResult{}, err
+// responseFormat maps format to the API's response format. Analyses are constrained to the schema,
+// or to any JSON where schemas are unsupported; nil leaves the output free.
+func (p *Provider) responseFormat(format ai.OutputFormat) *openai.ChatCompletionResponseFormat {
+	switch {
+	case format == ai.FormatText || !p.params.JSON():
+		return nil
+	case format == ai.FormatJSON || !p.jsonSchema:
+		return &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
 	}
-
-	cleaned := cleanMarkdown(response)
-	var result ai.AnalysisResult
-	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
-		// Fallback if JSON parsing fails
-		return ai.AnalysisResult{Summary: response}, nil
+	return &openai.ChatCompletionResponseFormat{
+		Type: openai.ChatCompletionResponseFormatTypeJSONSchema,
+		JSONSchema: &openai.ChatCompletionResponseFormatJSONSchema{
+			Name:   ai.AnalysisSchemaName,
+			Schema: jsonSchema(ai.AnalysisJSONSchema()),
+			Strict: true,
+		},
 	}
-
-	return result, nil
 }
 
-func (p *Provider) Classify(ctx context.Context, text string) (string, error) {
-	systemPrompt := p.prompts["classify"]
-	if systemPrompt == "" {
-		return "", errors.New("classify prompt not configured")
-	}
-	return p.chatCompletion(ctx, systemPrompt, text, false)
+// jsonSchema adapts a schema to the client's json.Marshaler field.
+type jsonSchema map[string]interface{}
+
+func (s jsonSchema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]interface{}(s))
 }
 
-func (p *Provider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
-	systemPrompt := p.prompts["sentiment"]
-	if systemPrompt == "" {
-		return ai.SentimentResult{}, errors.New("sentiment prompt not configured")
+// complete sends a chat history and returns the reply, in JSON mode or constrained to the analysis
+// schema as requested.
+func (p *Provider) complete(ctx context.Context, messages []ai.Message, format ai.OutputFormat) (string, error) {
+	req := openai.ChatCompletionRequest{Model: p.model, ResponseFormat: p.responseFormat(format)}
+	for _, msg := range messages {
+		req.Messages = append(req.Messages, openai.ChatCompletionMessage{Role: msg.Role, Content: msg.Content})
 	}
+	p.applyParams(&req)
 
-	response, err := p.chatCompletion(ctx, systemPrompt, text, true)
+	resp, err := p.client.CreateChatCompletion(ctx, req)
 	if err != nil {
-		return ai.SentimentResult{}, err
+		return "", apiError(err)
 	}
 
-	var result struct {
-		Sentiment string `json:"sentiment"`
-		Urgency   string `json:"urgency"`
-	}
-
-	// Clean markdown if present
-	cleaned := cleanMarkdown(response)
-
-	if err := json.Unmarshal([]byte(cleaned), &result); err != nil {
-		return ai.SentimentResult{Sentiment: "Neutral", Urgency: "Medium"}, nil
+	if len(resp.Choices) == 0 {
+		return "", errors.New("no choices returned from OpenAI API")
 	}
 
-	return ai.SentimentResult{
-		Sentiment: result.Sentiment,
-		Urgency:   result.Urgency,
-	}, nil
+	content := resp.Choices[0].Message.Content
+	p.reportUsage(ctx, resp.Usage, ai.MessagesText(messages), content)
+	return content, nil
 }
 
-func (p *Provider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
-	systemPrompt := p.prompts["draft_reply"]
-	if systemPrompt == "" {
-		systemPrompt = "You are an email assistant. Generate a professional email reply based on the provided email content and user instructions."
-	}
-
-	// Ensure we have valid content
-	if emailContent == "" {
-		emailContent = "No email content provided."
-	}
-	if userPrompt == "" {
-		userPrompt = "Generate a brief, professional email reply."
-	}
-
-	fullUserPrompt := fmt.Sprintf("Original Email:\n%s\n\nUser Instructions:\n%s", emailContent, userPrompt)
-	return p.chatCompletion(ctx, systemPrompt, fullUserPrompt, false)
+// reportUsage reports the usage of a chat call, estimating counts the server did not return.
+func (p *Provider) reportUsage(ctx context.Context, usage openai.Usage, prompt, completion string) {
+	ai.ReportUsage(ctx, ai.Usage{
+		Provider:         "openai",
+		Model:            p.model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+	}.WithEstimates(prompt, completion))
 }
 
-func (p *Provider) chatCompletion(ctx context.Context, systemPrompt, userContent string, jsonMode bool) (string, error) {
-	req := openai.ChatCompletionRequest{
-		Model: p.model,
-		Messages: []openai.ChatCompletionMessage{
-			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-			{Role: openai.ChatMessageRoleUser, Content: userContent},
-		},
-	}
-
-	if jsonMode {
-		req.ResponseFormat = &openai.ChatCompletionResponseFormat{
-			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+// applyParams sets the configured temperature and token limit on req.
+func (p *Provider) applyParams(req *openai.ChatCompletionRequest) {
+	if p.params.Temperature != nil {
+		req.Temperature = *p.params.Temperature
+		if req.Temperature == 0 {
+			// The client omits a zero temperature, which the API reads as its default of 1.
+			req.Temperature = math.SmallestNonzeroFloat32
 		}
 	}
-
-	resp, err := p.client.CreateChatCompletion(ctx, req)
-	if err != nil {
-		return "", err
+	if p.params.MaxTokens > 0 {
+		req.MaxTokens = p.params.MaxTokens
 	}
-
-	if len(resp.Choices) == 0 {
-		return "", errors.New("no choices returned from OpenAI API")
-	}
-
-	return resp.Choices[0].Message.Content, nil
 }
 
 func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
@@ -176,24 +158,35 @@ func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch cha
 		Model:    p.model,
 		Messages: openaiMessages,
 		Stream:   true,
+		// Ask for the token counts in a final chunk; servers that ignore it get estimated usage.
+		StreamOptions: &openai.StreamOptions{IncludeUsage: true},
 	}
+	p.applyParams(&req)
 
 	stream, err := p.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
-		return err
+		return apiError(err)
 	}
 	defer stream.Close()
 
+	var usage openai.Usage
+	var completion strings.Builder
+	defer func() { p.reportUsage(ctx, usage, ai.MessagesText(messages), completion.String()) }()
+
 	for {
 		response, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
 			return nil
 		}
 		if err != nil {
-			return err
+			return apiError(err)
+		}
+		if response.Usage != nil {
+			usage = *response.Usage
 		}
 
 		if len(response.Choices) > 0 {
+			completion.WriteString(response.Choices[0].Delta.Content)
 			ch <- ai.ChatCompletionChunk{
 				ID: response.ID,
 				Choices: []ai.Choice{
@@ -209,22 +202,6 @@ func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch cha
 	}
 }
 
-func cleanMarkdown(text string) string {
-	cleaned := text
-	if len(cleaned) > 7 && cleaned[:7] == "```json" {
-		cleaned = cleaned[7:]
-		if len(cleaned) > 3 && cleaned[len(cleaned)-3:] == "```" {
-			cleaned = cleaned[:len(cleaned)-3]
-		}
-	} else if len(cleaned) > 3 && cleaned[:3] == "```" {
-		cleaned = cleaned[3:]
-		if len(cleaned) > 3 && cleaned[len(cleaned)-3:] == "```" {
-			cleaned = cleaned[:len(cleaned)-3]
-		}
-	}
-	return strings.TrimSpace(cleaned)
-}
-
 // Embed generates a vector for a single text using the configured embedding model.
 func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
 	req := openai.EmbeddingRequest{
@@ -235,8 +212,9 @@ func (p *Provider) Embed(ctx context.Context, text string) ([]float32, error) {
 
 	resp, err := p.client.CreateEmbeddings(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, apiError(err)
 	}
+	p.reportEmbeddingUsage(ctx, resp.Usage, text)
 
 	if len(resp.Data) == 0 {
 		return nil, errors.New("no embedding data returned")
@@ -255,8 +233,9 @@ func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32,
 
 	resp, err := p.client.CreateEmbeddings(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, apiError(err)
 	}
+	p.reportEmbeddingUsage(ctx, resp.Usage, texts...)
 
 	var embeddings [][]float32
 	for _, data := range resp.Data {
@@ -266,7 +245,34 @@ func (p *Provider) EmbedBatch(ctx context.Context, texts []string) ([][]float32,
 	return embeddings, nil
 }
 
+func (p *Provider) reportEmbeddingUsage(ctx context.Context, usage openai.Usage, texts ...string) {
+	ai.ReportUsage(ctx, ai.Usage{
+		Capability:   ai.CapabilityEmbedding,
+		Provider:     "openai",
+		Model:        p.embeddingModel,
+		PromptTokens: usage.PromptTokens,
+	}.WithEstimates(strings.Join(texts, "\n"), ""))
+}
+
 // GetDimensions returns the dimension size of the vectors generated by this provider.
 func (p *Provider) GetDimensions() int {
 	return p.dimensions
 }
+
+// EmbeddingModel returns the name of the configured embedding model.
+func (p *Provider) EmbeddingModel() string {
+	return p.embeddingModel
+}
+
+// apiError converts go-openai's HTTP errors to ai.APIError so that callers can classify them.
+func apiError(err error) error {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return &ai.APIError{Provider: "openai", StatusCode: apiErr.HTTPStatusCode, Message: apiErr.Message}
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return &ai.APIError{Provider: "openai", StatusCode: reqErr.HTTPStatusCode, Message: reqErr.Error()}
+	}
+	return err
+}
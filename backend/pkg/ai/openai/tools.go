@@ -0,0 +1,146 @@
+package openai
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/hrygo/echomind/pkg/ai"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+var (
+	_ ai.ToolCaller   = (*Provider)(nil)
+	_ ai.ToolStreamer = (*Provider)(nil)
+)
+
+func (p *Provider) toolRequest(messages []ai.Message, tools []ai.ToolDefinition) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:    p.model,
+		Messages: toOpenAIMessages(messages),
+	}
+	p.applyParams(&req)
+	for _, tool := range tools {
+		req.Tools = append(req.Tools, openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.Parameters,
+			},
+		})
+	}
+	return req
+}
+
+// ChatWithTools runs one chat turn with OpenAI function calling.
+func (p *Provider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	resp, err := p.client.CreateChatCompletion(ctx, p.toolRequest(messages, tools))
+	if err != nil {
+		return ai.ToolChatResponse{}, apiError(err)
+	}
+	if len(resp.Choices) == 0 {
+		return ai.ToolChatResponse{}, errors.New("no choices returned from OpenAI API")
+	}
+
+	msg := resp.Choices[0].Message
+	p.reportUsage(ctx, resp.Usage, ai.MessagesText(messages), msg.Content)
+	result := ai.ToolChatResponse{Content: msg.Content}
+	for _, call := range msg.ToolCalls {
+		result.ToolCalls = append(result.ToolCalls, ai.ToolCall{
+			ID:        call.ID,
+			Name:      call.Function.Name,
+			Arguments: call.Function.Arguments,
+		})
+	}
+	return result, nil
+}
+
+// StreamChatWithTools runs one chat turn with OpenAI function calling, streaming the text as it
+// arrives. Tool calls arrive in fragments and are returned once complete.
+func (p *Provider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	defer close(ch)
+
+	req := p.toolRequest(messages, tools)
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := p.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return ai.ToolChatResponse{}, apiError(err)
+	}
+	defer stream.Close()
+
+	var usage openai.Usage
+	var content strings.Builder
+	defer func() { p.reportUsage(ctx, usage, ai.MessagesText(messages), content.String()) }()
+
+	var calls []ai.ToolCall
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return ai.ToolChatResponse{}, apiError(err)
+		}
+		if response.Usage != nil {
+			usage = *response.Usage
+		}
+		if len(response.Choices) == 0 {
+			continue
+		}
+
+		delta := response.Choices[0].Delta
+		for _, fragment := range delta.ToolCalls {
+			i := max(len(calls)-1, 0)
+			if fragment.Index != nil {
+				i = *fragment.Index
+			} else if fragment.ID != "" {
+				i = len(calls)
+			}
+			for len(calls) <= i {
+				calls = append(calls, ai.ToolCall{})
+			}
+			if fragment.ID != "" {
+				calls[i].ID = fragment.ID
+			}
+			calls[i].Name += fragment.Function.Name
+			calls[i].Arguments += fragment.Function.Arguments
+		}
+		if delta.Content != "" {
+			content.WriteString(delta.Content)
+			ch <- ai.ChatCompletionChunk{
+				ID:      response.ID,
+				Choices: []ai.Choice{{Index: 0, Delta: ai.DeltaContent{Content: delta.Content}}},
+			}
+		}
+	}
+	return ai.ToolChatResponse{Content: content.String(), ToolCalls: calls}, nil
+}
+
+func toOpenAIMessages(messages []ai.Message) []openai.ChatCompletionMessage {
+	converted := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for _, msg := range messages {
+		m := openai.ChatCompletionMessage{
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCallID: msg.ToolCallID,
+		}
+		if msg.Role == openai.ChatMessageRoleTool {
+			m.Name = msg.ToolName
+		}
+		for _, call := range msg.ToolCalls {
+			m.ToolCalls = append(m.ToolCalls, openai.ToolCall{
+				ID:   call.ID,
+				Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{
+					Name:      call.Name,
+					Arguments: call.Arguments,
+				},
+			})
+		}
+		converted = append(converted, m)
+	}
+	return converted
+}
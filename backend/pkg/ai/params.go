@@ -0,0 +1,74 @@
+package ai
+
+// Capability names a kind of AI call that can be routed to its own provider and model parameters.
+type Capability string
+
+const (
+	CapabilityChat          Capability = "chat"           // Copilot chat, tool calling and query rewriting
+	CapabilitySummary       Capability = "summary"        // Email analysis (Summarize)
+	CapabilityClassify      Capability = "classify"       // Email categorization
+	CapabilitySentiment     Capability = "sentiment"      // Sentiment and urgency
+	CapabilityDraft         Capability = "draft"          // Draft replies
+	CapabilitySearchSummary Capability = "search_summary" // Summaries of search results
+	CapabilityRerank        Capability = "rerank"         // LLM reranking of chat passages
+	CapabilityTranslate     Capability = "translate"      // Email and search query translation
+)
+
+// Capabilities lists every routable capability.
+var Capabilities = []Capability{
+	CapabilityChat, CapabilitySummary, CapabilityClassify, CapabilitySentiment,
+	CapabilityDraft, CapabilitySearchSummary, CapabilityRerank, CapabilityTranslate,
+}
+
+// ModelParams are generation settings a provider applies to every call. Unset fields keep the
+// model's defaults.
+type ModelParams struct {
+	Temperature *float32
+	MaxTokens   int
+	JSONMode    *bool // Native JSON output for structured calls; on unless set to false
+}
+
+// ParseModelParams reads "temperature", "max_tokens" and "json_mode" from provider settings.
+func ParseModelParams(settings map[string]interface{}) ModelParams {
+	var params ModelParams
+	switch v := settings["temperature"].(type) {
+	case float64:
+		t := float32(v)
+		params.Temperature = &t
+	case int:
+		t := float32(v)
+		params.Temperature = &t
+	}
+	switch v := settings["max_tokens"].(type) {
+	case int:
+		params.MaxTokens = v
+	case float64:
+		params.MaxTokens = int(v)
+	}
+	if v, ok := settings["json_mode"].(bool); ok {
+		params.JSONMode = &v
+	}
+	return params
+}
+
+// JSON reports whether structured calls should request JSON output from the API. Some
+// OpenAI-compatible servers reject response_format; setting json_mode to false leaves the format
+// to the prompt.
+func (p ModelParams) JSON() bool {
+	return p.JSONMode == nil || *p.JSONMode
+}
+
+// Settings returns the parameters as provider settings, for merging into a provider's own.
+func (p ModelParams) Settings() map[string]interface{} {
+	settings := map[string]interface{}{}
+	if p.Temperature != nil {
+		settings["temperature"] = float64(*p.Temperature)
+	}
+	if p.MaxTokens > 0 {
+		settings["max_tokens"] = p.MaxTokens
+	}
+	if p.JSONMode != nil {
+		settings["json_mode"] = *p.JSONMode
+	}
+	return settings
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// Prompt names. The first four are the providers' system prompts; the others are assembled by the
+// services that use them.
+const (
+	PromptSummary       = "summary"
+	PromptClassify      = "classify"
+	PromptSentiment     = "sentiment"
+	PromptDraftReply    = "draft_reply"
+	PromptChatSystem    = "chat_system"    // Copilot persona and widget instructions
+	PromptSearchSummary = "search_summary" // Instructions for summarizing search results
+)
+
+// PromptDefaultVersion is the version of a prompt that comes from the configuration.
+const PromptDefaultVersion = "default"
+
+// Prompt is a rendered prompt template.
+type Prompt struct {
+	Name    string
+	Version string // PromptDefaultVersion, or "v<n>" for a stored version
+	Text    string
+}
+
+// Ref identifies the template version the prompt was rendered from, e.g. "summary@v3". It is
+// recorded on AI results so that versions can be compared and rolled back.
+func (p Prompt) Ref() string {
+	return p.Name + "@" + p.Version
+}
+
+// PromptResolver picks and renders the prompt templates for the calls made with a context.
+type PromptResolver interface {
+	ResolvePrompt(ctx context.Context, name string) (Prompt, error)
+}
+
+type promptResolverKey struct{}
+
+// WithPromptResolver returns a context whose calls use the prompts resolver picks.
+func WithPromptResolver(ctx context.Context, resolver PromptResolver) context.Context {
+	return context.WithValue(ctx, promptResolverKey{}, resolver)
+}
+
+// ResolvePrompt returns the prompt the context's resolver picks for name. Without a resolver, or if
+// it fails, fallback is used as the default version.
+func ResolvePrompt(ctx context.Context, name, fallback string) Prompt {
+	if resolver, ok := ctx.Value(promptResolverKey{}).(PromptResolver); ok {
+		prompt, err := resolver.ResolvePrompt(ctx, name)
+		if err == nil {
+			return prompt
+		}
+		fmt.Printf("Warning: failed to resolve prompt %q, using the default: %v\n", name, err)
+	}
+	return Prompt{Name: name, Version: PromptDefaultVersion, Text: fallback}
+}
+
+// PromptRef returns the Ref of the prompt the calls made with ctx use for name.
+func PromptRef(ctx context.Context, name string) string {
+	return ResolvePrompt(ctx, name, "").Ref()
+}
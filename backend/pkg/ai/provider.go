@@ -33,15 +33,44 @@ type EmbeddingProvider interface {
 	GetDimensions() int
 }
 
+// EmbeddingModelNamer is implemented by embedding providers that can name the model behind their vectors.
+// Vectors from different models are not comparable even when their dimensions match.
+type EmbeddingModelNamer interface {
+	EmbeddingModel() string
+}
+
+// EmbeddingModelName returns the provider's embedding model name, or "unknown" if it does not report one.
+func EmbeddingModelName(p EmbeddingProvider) string {
+	if namer, ok := p.(EmbeddingModelNamer); ok && namer.EmbeddingModel() != "" {
+		return namer.EmbeddingModel()
+	}
+	return "unknown"
+}
+
 type Message struct {
-	Role    string `json:"role"` // "system", "user", "assistant"
+	Role    string `json:"role"` // "system", "user", "assistant", "tool"
 	Content string `json:"content"`
+
+	// Function calling: an assistant turn may request calls, a "tool" turn returns one result.
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	ToolName   string     `json:"tool_name,omitempty"`
 }
 
 // ChatCompletionChunk represents a chunk of the streamed chat completion response.
 type ChatCompletionChunk struct {
-	ID      string   `json:"id"`
-	Choices []Choice `json:"choices"`
+	ID        string     `json:"id"`
+	Choices   []Choice   `json:"choices"`
+	Citations []Citation `json:"citations,omitempty"` // Sent once the answer is complete
+}
+
+// Citation links a [n] marker in an answer to the email passage it is based on.
+type Citation struct {
+	Index   int    `json:"index"` // The n of the [n] marker
+	EmailID string `json:"email_id"`
+	Subject string `json:"subject"`
+	Date    string `json:"date"`  // YYYY-MM-DD
+	Quote   string `json:"quote"` // Span of the passage that supports the claim
 }
 
 type DeltaContent struct {
@@ -0,0 +1,343 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// PIIKind is a kind of personal data that can be masked before text leaves for a provider.
+type PIIKind string
+
+const (
+	PIIEmail      PIIKind = "email"
+	PIIPhone      PIIKind = "phone"
+	PIINationalID PIIKind = "national_id" // Chinese resident ID and US SSN
+	PIICard       PIIKind = "card"        // Payment card numbers that pass the Luhn check
+	PIIIBAN       PIIKind = "iban"
+)
+
+// PIIKinds lists every kind in the order they are detected: the stricter checksums go first, so a
+// resident ID is not taken for a card number, nor a card number for a phone number.
+var PIIKinds = []PIIKind{PIIEmail, PIIIBAN, PIINationalID, PIICard, PIIPhone}
+
+// RedactionPolicy selects the kinds of PII to mask; none means calls go out unchanged.
+type RedactionPolicy struct {
+	Kinds []PIIKind
+}
+
+// Enabled reports whether the policy masks anything.
+func (p RedactionPolicy) Enabled() bool { return len(p.Kinds) > 0 }
+
+// RedactionScope decides how the calls made on behalf of a user are redacted and keeps the audit
+// trail. Like UsageMeter, it travels in the context.
+type RedactionScope interface {
+	Policy(ctx context.Context) RedactionPolicy
+	// Audit records how many values of each kind were masked in one call, never the values.
+	Audit(ctx context.Context, capability Capability, counts map[PIIKind]int)
+}
+
+type redactionScopeKey struct{}
+
+// WithRedactionScope returns a context whose AI calls are redacted by scope.
+func WithRedactionScope(ctx context.Context, scope RedactionScope) context.Context {
+	return context.WithValue(ctx, redactionScopeKey{}, scope)
+}
+
+// RedactionScopeFrom returns the context's redaction scope.
+func RedactionScopeFrom(ctx context.Context) (RedactionScope, bool) {
+	scope, ok := ctx.Value(redactionScopeKey{}).(RedactionScope)
+	return scope, ok
+}
+
+// Redaction masks PII in the text of one provider call with placeholders such as [EMAIL_1] and
+// restores the originals in the reply. A value gets the same placeholder wherever it occurs, so
+// the model can still tell that two mentions are the same person.
+type Redaction struct {
+	kinds    []PIIKind
+	original map[string]string // placeholder -> value
+	assigned map[string]string // kind + value -> placeholder
+	counts   map[PIIKind]int   // values masked, per kind
+	next     map[PIIKind]int
+}
+
+// NewRedaction starts a redaction with policy.
+func NewRedaction(policy RedactionPolicy) *Redaction {
+	return &Redaction{
+		kinds:    policy.Kinds,
+		original: map[string]string{},
+		assigned: map[string]string{},
+		counts:   map[PIIKind]int{},
+		next:     map[PIIKind]int{},
+	}
+}
+
+// Counts returns the number of masked values per kind.
+func (r *Redaction) Counts() map[PIIKind]int { return r.counts }
+
+// Redact replaces the PII in text with placeholders.
+func (r *Redaction) Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	for _, kind := range PIIKinds {
+		if !r.masks(kind) {
+			continue
+		}
+		d := piiDetectors[kind]
+		text = d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			return r.placeholder(kind, match)
+		})
+	}
+	return text
+}
+
+func (r *Redaction) masks(kind PIIKind) bool {
+	for _, k := range r.kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redaction) placeholder(kind PIIKind, value string) string {
+	key := string(kind) + "\x00" + value
+	if p, ok := r.assigned[key]; ok {
+		return p
+	}
+	r.next[kind]++
+	r.counts[kind]++
+	p := fmt.Sprintf("[%s_%d]", piiDetectors[kind].label, r.next[kind])
+	r.assigned[key] = p
+	r.original[p] = value
+	return p
+}
+
+// placeholderPattern matches the placeholders Redact writes.
+var placeholderPattern = regexp.MustCompile(`\[(?:EMAIL|PHONE|ID|CARD|IBAN)_\d+\]`)
+
+// maxPlaceholderLen bounds how much streamed text may be held back waiting for a placeholder's end.
+const maxPlaceholderLen = 16
+
+// Restore puts the original values back in place of the placeholders in text. Placeholders the
+// model made up are left alone.
+func (r *Redaction) Restore(text string) string {
+	if len(r.original) == 0 || !strings.Contains(text, "[") {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if v, ok := r.original[p]; ok {
+			return v
+		}
+		return p
+	})
+}
+
+// RedactMessages returns a copy of messages with their text and tool arguments redacted.
+func (r *Redaction) RedactMessages(messages []Message) []Message {
+	redacted := make([]Message, len(messages))
+	for i, msg := range messages {
+		msg.Content = r.Redact(msg.Content)
+		if len(msg.ToolCalls) > 0 {
+			calls := make([]ToolCall, len(msg.ToolCalls))
+			for j, call := range msg.ToolCalls {
+				call.Arguments = r.Redact(call.Arguments)
+				calls[j] = call
+			}
+			msg.ToolCalls = calls
+		}
+		redacted[i] = msg
+	}
+	return redacted
+}
+
+// RestoreAnalysis restores every text field of an analysis.
+func (r *Redaction) RestoreAnalysis(result AnalysisResult) AnalysisResult {
+	result.Summary = r.Restore(result.Summary)
+	for i, item := range result.ActionItems {
+		result.ActionItems[i] = r.Restore(item)
+	}
+	for i := range result.SmartActions {
+		action := &result.SmartActions[i]
+		action.Label = r.Restore(action.Label)
+		for key, value := range action.Data {
+			action.Data[key] = r.Restore(value)
+		}
+	}
+	return result
+}
+
+// RestoreStream copies chunks from in to out, restoring placeholders in the streamed text. Text
+// that may be the start of a placeholder is held back until the next chunk shows how it ends.
+// out is closed when in is.
+func (r *Redaction) RestoreStream(in <-chan ChatCompletionChunk, out chan<- ChatCompletionChunk) {
+	defer close(out)
+	var pending strings.Builder
+	var last ChatCompletionChunk
+	emit := func(text string) {
+		if text == "" {
+			return
+		}
+		chunk := last
+		chunk.Citations = nil
+		chunk.Choices = []Choice{{Index: 0, Delta: DeltaContent{Content: r.Restore(text)}}}
+		out <- chunk
+	}
+
+	for chunk := range in {
+		last = chunk
+		text, other := splitChunk(chunk)
+		pending.WriteString(text)
+		buffered := pending.String()
+		cut := len(buffered)
+		if i := strings.LastIndex(buffered, "["); i >= 0 && !strings.Contains(buffered[i:], "]") && len(buffered)-i < maxPlaceholderLen {
+			cut = i
+		}
+		if other != nil {
+			cut = len(buffered) // Widgets and citations end a run of text
+		}
+		emit(buffered[:cut])
+		pending.Reset()
+		pending.WriteString(buffered[cut:])
+		if other != nil {
+			out <- *other
+		}
+	}
+	emit(pending.String())
+}
+
+// splitChunk separates a chunk's text from whatever else it carries.
+func splitChunk(chunk ChatCompletionChunk) (string, *ChatCompletionChunk) {
+	var text strings.Builder
+	var rest []Choice
+	for _, choice := range chunk.Choices {
+		text.WriteString(choice.Delta.Content)
+		if choice.Delta.Widget != nil {
+			choice.Delta.Content = ""
+			rest = append(rest, choice)
+		}
+	}
+	if len(rest) == 0 && len(chunk.Citations) == 0 {
+		return text.String(), nil
+	}
+	chunk.Choices = rest
+	return text.String(), &chunk
+}
+
+type piiDetector struct {
+	label   string // Placeholder prefix
+	pattern *regexp.Regexp
+	valid   func(match string) bool // Checksum or plausibility check, if any
+}
+
+var piiDetectors = map[PIIKind]piiDetector{
+	PIIEmail: {
+		label:   "EMAIL",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	},
+	PIIIBAN: {
+		label:   "IBAN",
+		pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		valid:   validIBAN,
+	},
+	PIICard: {
+		label:   "CARD",
+		pattern: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+		valid:   validCard,
+	},
+	PIINationalID: {
+		label:   "ID",
+		pattern: regexp.MustCompile(`\b\d{17}[\dXx]\b|\b\d{3}-\d{2}-\d{4}\b`),
+		valid:   validNationalID,
+	},
+	PIIPhone: {
+		label: "PHONE",
+		// International (+44 20 7946 0958), North American ((415) 555-0132) and Chinese mobile
+		// (138 0013 8000) formats.
+		pattern: regexp.MustCompile(`\+\d{1,3}[ .\-]?(?:\(\d{1,4}\)[ .\-]?)?\d{1,4}(?:[ .\-]?\d{2,4}){1,4}\b|\(\d{3}\) ?\d{3}[ .\-]\d{4}\b|\b\d{3}[.\-]\d{3}[.\-]\d{4}\b|\b1[3-9]\d[ \-]?\d{4}[ \-]?\d{4}\b`),
+		valid:   validPhone,
+	},
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// validCard applies the Luhn check to 13 to 19 digits.
+func validCard(match string) bool {
+	digits := digitsOf(match)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// validIBAN applies the ISO 13616 mod-97 check.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			numeric.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&numeric, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validNationalID checks the checksum of an 18-character Chinese resident ID, or the reserved
+// ranges of a US SSN.
+func validNationalID(match string) bool {
+	if strings.Contains(match, "-") {
+		area, group, serial := match[0:3], match[4:6], match[7:11]
+		return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(match[i]-'0') * w
+	}
+	check := "10X98765432"[sum%11]
+	last := match[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return last == check
+}
+
+// validPhone requires a plausible number of digits, so dates and amounts are not taken for phones.
+func validPhone(match string) bool {
+	n := len(digitsOf(match))
+	return n >= 8 && n <= 15
+}
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedaction_Detectors(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{"email", "Write to ada.lovelace+work@example.co.uk today", "Write to [EMAIL_1] today"},
+		{"international phone", "Call +44 20 7946 0958.", "Call [PHONE_1]."},
+		{"north american phone", "Call (415) 555-0132 or 415.555.0199", "Call [PHONE_1] or [PHONE_2]"},
+		{"chinese mobile", "手机 138 0013 8000", "手机 [PHONE_1]"},
+		{"card", "Card 4111 1111 1111 1111 exp 12/27", "Card [CARD_1] exp 12/27"},
+		{"card failing luhn", "Ref 4111 1111 1111 1112", "Ref 4111 1111 1111 1112"},
+		{"iban", "Pay GB82 WEST 1234 5698 7654 32 or DE89370400440532013000", "Pay [IBAN_1] or [IBAN_2]"},
+		{"iban failing mod-97", "Pay GB82 WEST 1234 5698 7654 33", "Pay GB82 WEST 1234 5698 7654 33"},
+		{"resident id", "身份证 11010519491231002X", "身份证 [ID_1]"},
+		{"resident id failing checksum", "编号 110105194912310021", "编号 110105194912310021"},
+		{"ssn", "SSN 123-45-6789", "SSN [ID_1]"},
+		{"ssn in reserved range", "SSN 000-45-6789", "SSN 000-45-6789"},
+		{"dates and amounts", "Due 2026-05-04, total 1,250.00, order 12345", "Due 2026-05-04, total 1,250.00, order 12345"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRedaction(RedactionPolicy{Kinds: PIIKinds})
+			got := r.Redact(tt.text)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.text, r.Restore(got))
+		})
+	}
+}
+
+func TestRedaction_PolicyAndPlaceholders(t *testing.T) {
+	r := NewRedaction(RedactionPolicy{Kinds: []PIIKind{PIIEmail}})
+	got := r.Redact("ada@example.com wrote to bob@example.com, cc ada@example.com, from +44 20 7946 0958")
+	assert.Equal(t, "[EMAIL_1] wrote to [EMAIL_2], cc [EMAIL_1], from +44 20 7946 0958", got,
+		"a value keeps its placeholder and kinds outside the policy are left alone")
+	assert.Equal(t, map[PIIKind]int{PIIEmail: 2}, r.Counts())
+
+	assert.Equal(t, "Reply to ada@example.com, not [EMAIL_9] or [NAME_1]", r.Restore("Reply to [EMAIL_1], not [EMAIL_9] or [NAME_1]"),
+		"placeholders the model made up stay as they are")
+
+	off := NewRedaction(RedactionPolicy{})
+	assert.Equal(t, "ada@example.com", off.Redact("ada@example.com"))
+	assert.False(t, RedactionPolicy{}.Enabled())
+}
+
+func TestRedaction_Messages(t *testing.T) {
+	r := NewRedaction(RedactionPolicy{Kinds: PIIKinds})
+	messages := []Message{
+		{Role: "user", Content: "Email ada@example.com"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "1", Name: "send", Arguments: `{"to":"ada@example.com"}`}}},
+	}
+	redacted := r.RedactMessages(messages)
+	assert.Equal(t, "Email [EMAIL_1]", redacted[0].Content)
+	assert.Equal(t, `{"to":"[EMAIL_1]"}`, redacted[1].ToolCalls[0].Arguments)
+	assert.Equal(t, "Email ada@example.com", messages[0].Content, "the caller's messages are not modified")
+	assert.Equal(t, `{"to":"ada@example.com"}`, messages[1].ToolCalls[0].Arguments)
+
+	result := r.RestoreAnalysis(AnalysisResult{
+		Summary:      "[EMAIL_1] asked for a call",
+		ActionItems:  []string{"Reply to [EMAIL_1]"},
+		SmartActions: []SmartAction{{Label: "Email [EMAIL_1]", Data: map[string]string{"to": "[EMAIL_1]"}}},
+	})
+	assert.Equal(t, "ada@example.com asked for a call", result.Summary)
+	assert.Equal(t, []string{"Reply to ada@example.com"}, result.ActionItems)
+	assert.Equal(t, "Email ada@example.com", result.SmartActions[0].Label)
+	assert.Equal(t, "ada@example.com", result.SmartActions[0].Data["to"])
+}
+
+func TestRedaction_RestoreStream(t *testing.T) {
+	r := NewRedaction(RedactionPolicy{Kinds: PIIKinds})
+	r.Redact("ada@example.com +44 20 7946 0958")
+
+	in := make(chan ChatCompletionChunk, 8)
+	out := make(chan ChatCompletionChunk, 16)
+	for _, text := range []string{"Write to [EMA", "IL_1] or call [PH", "ONE_1", "]. See [1] and [", "unclosed"} {
+		in <- ChatCompletionChunk{ID: "c", Choices: []Choice{{Delta: DeltaContent{Content: text}}}}
+	}
+	in <- ChatCompletionChunk{ID: "c", Citations: []Citation{{Index: 1, EmailID: "e1"}}}
+	close(in)
+	r.RestoreStream(in, out)
+
+	var text strings.Builder
+	var citations []Citation
+	for chunk := range out {
+		assert.Equal(t, "c", chunk.ID)
+		for _, choice := range chunk.Choices {
+			assert.NotContains(t, choice.Delta.Content, "[EMAIL_1]")
+			text.WriteString(choice.Delta.Content)
+		}
+		citations = append(citations, chunk.Citations...)
+	}
+	assert.Equal(t, "Write to ada@example.com or call +44 20 7946 0958. See [1] and [unclosed", text.String())
+	assert.Len(t, citations, 1)
+}
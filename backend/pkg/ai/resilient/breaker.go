@@ -0,0 +1,95 @@
+package resilient
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// breaker stops calls to a provider after consecutive failures. Once the cooldown has passed it lets
+// a single trial call through; its outcome closes the breaker again or re-opens it.
+type breaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	trial     bool // A half-open trial call is in flight
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+	onChange  func(state breakerState)
+}
+
+// allow reports whether a call may go to the provider now.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(stateHalfOpen)
+		b.trial = true
+		return true
+	case stateHalfOpen:
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	default:
+		return true
+	}
+}
+
+// success records that the provider answered.
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+	if b.state != stateClosed {
+		b.setState(stateClosed)
+	}
+}
+
+// failure records a provider failure and reports whether the breaker is now open.
+func (b *breaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		if b.state != stateOpen {
+			b.setState(stateOpen)
+		}
+		b.openedAt = b.now()
+	}
+	return b.state == stateOpen
+}
+
+func (b *breaker) setState(state breakerState) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
@@ -0,0 +1,347 @@
+// Package resilient wraps chat providers with retries, circuit breakers and an ordered fallback
+// chain, so that an outage or rate limit at one provider does not fail every AI call.
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/hrygo/echomind/pkg/telemetry"
+)
+
+// Options tune retries and circuit breaking. Zero values take the defaults.
+type Options struct {
+	MaxRetries       int           // Retries per provider for retryable errors; negative disables (default 2)
+	BaseDelay        time.Duration // Backoff before the first retry, doubled for each further retry (default 500ms)
+	MaxDelay         time.Duration // Backoff cap (default 8s)
+	BreakerThreshold int           // Consecutive failures that open a provider's breaker (default 5)
+	BreakerCooldown  time.Duration // How long an open breaker rejects calls before a trial call (default 30s)
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 2
+	} else if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.BaseDelay <= 0 {
+		o.BaseDelay = 500 * time.Millisecond
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = 8 * time.Second
+	}
+	if o.MaxDelay < o.BaseDelay {
+		o.MaxDelay = o.BaseDelay
+	}
+	if o.BreakerThreshold <= 0 {
+		o.BreakerThreshold = 5
+	}
+	if o.BreakerCooldown <= 0 {
+		o.BreakerCooldown = 30 * time.Second
+	}
+	return o
+}
+
+// Named is one provider in the fallback chain. The name labels metrics and errors.
+type Named struct {
+	Name     string
+	Provider ai.AIProvider
+}
+
+type member struct {
+	name     string
+	provider ai.AIProvider
+	breaker  *breaker
+}
+
+// Provider implements ai.AIProvider over an ordered chain of providers. Each call goes to the first
+// provider whose breaker is closed; retryable errors are retried there with exponential backoff and
+// jitter, then the call fails over to the next provider. Permanent errors are returned at once.
+type Provider struct {
+	members []*member
+	opts    Options
+	metrics *telemetry.AIResilienceMetrics
+
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(d time.Duration) time.Duration
+}
+
+// New builds a Provider over chain, tried in order.
+func New(chain []Named, opts Options) *Provider {
+	p := &Provider{
+		opts:  opts.withDefaults(),
+		sleep: sleep,
+		jitter: func(d time.Duration) time.Duration {
+			// Equal jitter: somewhere between half and all of the delay
+			half := int64(d / 2)
+			return time.Duration(half + rand.Int64N(half+1))
+		},
+	}
+	if metrics, err := telemetry.NewAIResilienceMetrics(context.Background()); err != nil {
+		fmt.Printf("Warning: failed to initialize AI resilience metrics: %v\n", err)
+	} else {
+		p.metrics = metrics
+	}
+
+	for _, named := range chain {
+		m := &member{name: named.Name, provider: named.Provider}
+		m.breaker = &breaker{
+			threshold: p.opts.BreakerThreshold,
+			cooldown:  p.opts.BreakerCooldown,
+			now:       time.Now,
+			onChange: func(state breakerState) {
+				if p.metrics != nil {
+					p.metrics.RecordBreakerTransition(context.Background(), m.name, state.String())
+				}
+			},
+		}
+		p.members = append(p.members, m)
+	}
+	return p
+}
+
+// Chain returns the providers in the order they are tried.
+func (p *Provider) Chain() []Named {
+	chain := make([]Named, len(p.members))
+	for i, m := range p.members {
+		chain[i] = Named{Name: m.name, Provider: m.provider}
+	}
+	return chain
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// backoff returns the delay before retry number attempt (0-based).
+func (p *Provider) backoff(attempt int) time.Duration {
+	d := p.opts.BaseDelay
+	for i := 0; i < attempt && d < p.opts.MaxDelay; i++ {
+		d *= 2
+	}
+	return p.jitter(min(d, p.opts.MaxDelay))
+}
+
+// streamStartedError marks a stream that failed after sending output. It cannot be retried or moved
+// to another provider without the caller seeing the answer twice.
+type streamStartedError struct{ err error }
+
+func (e *streamStartedError) Error() string { return e.err.Error() }
+func (e *streamStartedError) Unwrap() error { return e.err }
+
+func classify(err error) ai.ErrorClass {
+	var started *streamStartedError
+	if errors.As(err, &started) {
+		return ai.ErrorPermanent
+	}
+	return ai.ClassifyError(err)
+}
+
+// run calls fn against the chain until a provider succeeds. With needTools, providers that cannot
+// call tools are skipped.
+func (p *Provider) run(ctx context.Context, op string, needTools bool, fn func(context.Context, ai.AIProvider) error) error {
+	var lastErr error
+	var previous string
+	for _, m := range p.members {
+		if needTools && !supportsTools(m.provider) {
+			continue
+		}
+		if !m.breaker.allow() {
+			continue
+		}
+		if previous != "" && p.metrics != nil {
+			p.metrics.RecordFailover(ctx, previous, m.name, op)
+		}
+		previous = m.name
+
+		err := p.attempt(ctx, m, op, fn)
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("%s: %w", m.name, err)
+		if classify(err) == ai.ErrorPermanent || ctx.Err() != nil {
+			return lastErr
+		}
+	}
+	if lastErr == nil {
+		return ai.ErrUnavailable
+	}
+	return lastErr
+}
+
+// attempt calls fn on one provider, retrying retryable errors while its breaker stays closed.
+func (p *Provider) attempt(ctx context.Context, m *member, op string, fn func(context.Context, ai.AIProvider) error) error {
+	for retry := 0; ; retry++ {
+		err := fn(ai.WithProviderName(ctx, m.name), m.provider)
+		if err == nil {
+			m.breaker.success()
+			return nil
+		}
+		class := classify(err)
+		if p.metrics != nil {
+			p.metrics.RecordProviderError(ctx, m.name, op, class.String())
+		}
+		var started *streamStartedError
+		if errors.As(err, &started) {
+			m.breaker.failure()
+			return err
+		}
+		if class == ai.ErrorPermanent || class == ai.ErrorInvalidOutput {
+			// The provider answered; the request, or this one answer, was at fault.
+			m.breaker.success()
+			return err
+		}
+		if open := m.breaker.failure(); open || class != ai.ErrorRetryable || retry >= p.opts.MaxRetries {
+			return err
+		}
+		if sleepErr := p.sleep(ctx, p.backoff(retry)); sleepErr != nil {
+			return err
+		}
+		if p.metrics != nil {
+			p.metrics.RecordRetry(ctx, m.name, op)
+		}
+	}
+}
+
+func supportsTools(provider ai.AIProvider) bool {
+	_, ok := ai.AsToolCaller(provider)
+	return ok
+}
+
+func (p *Provider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
+	var result ai.AnalysisResult
+	err := p.run(ctx, "summarize", false, func(ctx context.Context, provider ai.AIProvider) (err error) {
+		result, err = provider.Summarize(ctx, text)
+		return err
+	})
+	return result, err
+}
+
+func (p *Provider) Classify(ctx context.Context, text string) (string, error) {
+	var label string
+	err := p.run(ctx, "classify", false, func(ctx context.Context, provider ai.AIProvider) (err error) {
+		label, err = provider.Classify(ctx, text)
+		return err
+	})
+	return label, err
+}
+
+func (p *Provider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
+	var result ai.SentimentResult
+	err := p.run(ctx, "analyze_sentiment", false, func(ctx context.Context, provider ai.AIProvider) (err error) {
+		result, err = provider.AnalyzeSentiment(ctx, text)
+		return err
+	})
+	return result, err
+}
+
+func (p *Provider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	var draft string
+	err := p.run(ctx, "generate_draft_reply", false, func(ctx context.Context, provider ai.AIProvider) (err error) {
+		draft, err = provider.GenerateDraftReply(ctx, emailContent, userPrompt)
+		return err
+	})
+	return draft, err
+}
+
+// StreamChat fails over only until the first chunk has been forwarded; after that an error ends
+// the stream.
+func (p *Provider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	defer close(ch)
+	err := p.run(ctx, "stream_chat", false, func(ctx context.Context, provider ai.AIProvider) error {
+		return streamOnce(ctx, provider, messages, ch)
+	})
+	var started *streamStartedError
+	if errors.As(err, &started) {
+		return started.err
+	}
+	return err
+}
+
+func streamOnce(ctx context.Context, provider ai.AIProvider, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	return forwardOnce(ctx, ch, func(inner chan<- ai.ChatCompletionChunk) error {
+		return provider.StreamChat(ctx, messages, inner)
+	})
+}
+
+// forwardOnce forwards the chunks stream sends to ch, wrapping an error that ends the stream after
+// the first chunk in a streamStartedError. stream must close its channel when done.
+func forwardOnce(ctx context.Context, ch chan<- ai.ChatCompletionChunk, stream func(chan<- ai.ChatCompletionChunk) error) error {
+	inner := make(chan ai.ChatCompletionChunk)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- stream(inner)
+	}()
+
+	sent := false
+	for {
+		select {
+		case chunk, ok := <-inner:
+			if !ok {
+				inner = nil // Wait for the result
+				continue
+			}
+			select {
+			case ch <- chunk:
+				sent = true
+			case <-ctx.Done():
+				// Keep draining so the provider can finish.
+			}
+		case err := <-errCh:
+			if err != nil && sent {
+				return &streamStartedError{err: err}
+			}
+			return err
+		}
+	}
+}
+
+func (p *Provider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	var resp ai.ToolChatResponse
+	err := p.run(ctx, "chat_with_tools", true, func(ctx context.Context, provider ai.AIProvider) error {
+		caller, _ := ai.AsToolCaller(provider)
+		var err error
+		resp, err = caller.ChatWithTools(ctx, messages, tools)
+		return err
+	})
+	return resp, err
+}
+
+// StreamChatWithTools fails over like StreamChat, only until the first chunk has been forwarded.
+func (p *Provider) StreamChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition, ch chan<- ai.ChatCompletionChunk) (ai.ToolChatResponse, error) {
+	defer close(ch)
+	var resp ai.ToolChatResponse
+	err := p.run(ctx, "chat_with_tools", true, func(ctx context.Context, provider ai.AIProvider) error {
+		caller, _ := ai.AsToolCaller(provider)
+		return forwardOnce(ctx, ch, func(inner chan<- ai.ChatCompletionChunk) (err error) {
+			resp, err = ai.StreamChatWithTools(ctx, caller, messages, tools, inner)
+			return err
+		})
+	})
+	var started *streamStartedError
+	if errors.As(err, &started) {
+		return resp, started.err
+	}
+	return resp, err
+}
+
+// SupportsTools reports whether any provider in the chain can call tools.
+func (p *Provider) SupportsTools() bool {
+	for _, m := range p.members {
+		if supportsTools(m.provider) {
+			return true
+		}
+	}
+	return false
+}
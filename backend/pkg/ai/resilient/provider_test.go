@@ -0,0 +1,280 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hrygo/echomind/pkg/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider answers Classify with its label, after failing with the queued errors.
+type fakeProvider struct {
+	label  string
+	errs   []error
+	calls  int
+	chunks []string
+	tools  bool
+}
+
+func (f *fakeProvider) next() error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *fakeProvider) Summarize(ctx context.Context, text string) (ai.AnalysisResult, error) {
+	return ai.AnalysisResult{Summary: f.label}, f.next()
+}
+
+func (f *fakeProvider) Classify(ctx context.Context, text string) (string, error) {
+	if err := f.next(); err != nil {
+		return "", err
+	}
+	return f.label, nil
+}
+
+func (f *fakeProvider) AnalyzeSentiment(ctx context.Context, text string) (ai.SentimentResult, error) {
+	return ai.SentimentResult{}, f.next()
+}
+
+func (f *fakeProvider) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	return f.label, f.next()
+}
+
+func (f *fakeProvider) StreamChat(ctx context.Context, messages []ai.Message, ch chan<- ai.ChatCompletionChunk) error {
+	defer close(ch)
+	for _, text := range f.chunks {
+		ch <- ai.ChatCompletionChunk{Choices: []ai.Choice{{Delta: ai.DeltaContent{Content: text}}}}
+	}
+	return f.next()
+}
+
+func (f *fakeProvider) ChatWithTools(ctx context.Context, messages []ai.Message, tools []ai.ToolDefinition) (ai.ToolChatResponse, error) {
+	return ai.ToolChatResponse{Content: f.label}, f.next()
+}
+
+func (f *fakeProvider) SupportsTools() bool { return f.tools }
+
+var (
+	errOverloaded   = &ai.APIError{Provider: "fake", StatusCode: http.StatusServiceUnavailable, Message: "overloaded"}
+	errBadKey       = &ai.APIError{Provider: "fake", StatusCode: http.StatusUnauthorized, Message: "invalid key"}
+	errBadRequest   = &ai.APIError{Provider: "fake", StatusCode: http.StatusBadRequest, Message: "context too long"}
+	errRateLimited  = &ai.APIError{Provider: "fake", StatusCode: http.StatusTooManyRequests, Message: "slow down"}
+	errUnrecognized = errors.New("unexpected reply")
+)
+
+// newTestProvider builds a Provider that records backoff delays instead of sleeping and has a clock
+// the test controls.
+func newTestProvider(opts Options, chain ...Named) (*Provider, *[]time.Duration, *time.Time) {
+	p := New(chain, opts)
+	var delays []time.Duration
+	p.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return ctx.Err()
+	}
+	p.jitter = func(d time.Duration) time.Duration { return d }
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, m := range p.members {
+		m.breaker.now = func() time.Time { return now }
+	}
+	return p, &delays, &now
+}
+
+func TestProvider_RetriesWithBackoff(t *testing.T) {
+	primary := &fakeProvider{label: "Work", errs: []error{errOverloaded, errRateLimited}}
+	p, delays, _ := newTestProvider(Options{BaseDelay: 100 * time.Millisecond, MaxDelay: 150 * time.Millisecond},
+		Named{"primary", primary})
+
+	label, err := p.Classify(context.Background(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", label)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 150 * time.Millisecond}, *delays, "doubling, capped at MaxDelay")
+}
+
+func TestProvider_FailsOver(t *testing.T) {
+	primary := &fakeProvider{errs: []error{errOverloaded, errOverloaded, errOverloaded}}
+	fallback := &fakeProvider{label: "Newsletter"}
+	p, _, _ := newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+
+	label, err := p.Classify(context.Background(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "Newsletter", label)
+	assert.Equal(t, 3, primary.calls, "retried MaxRetries times before failing over")
+
+	// Provider errors such as a bad key fail over without retrying.
+	primary = &fakeProvider{errs: []error{errBadKey}}
+	fallback = &fakeProvider{label: "Work"}
+	p, delays, _ := newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+	label, err = p.Classify(context.Background(), "x")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", label)
+	assert.Equal(t, 1, primary.calls)
+	assert.Empty(t, *delays)
+}
+
+func TestProvider_InvalidOutputFailsOverWithoutTrippingBreaker(t *testing.T) {
+	invalid := &ai.AnalysisError{Reason: `"urgency" is "urgent"`}
+	primary := &fakeProvider{label: "primary"}
+	fallback := &fakeProvider{label: "fallback"}
+	p, delays, _ := newTestProvider(Options{BreakerThreshold: 2}, Named{"primary", primary}, Named{"fallback", fallback})
+	ctx := context.Background()
+
+	primary.errs = []error{invalid, invalid, invalid}
+	for i := 0; i < 3; i++ {
+		result, err := p.Summarize(ctx, "x")
+		require.NoError(t, err)
+		assert.Equal(t, "fallback", result.Summary)
+	}
+	assert.Equal(t, 3, primary.calls, "not retried")
+	assert.Empty(t, *delays)
+	assert.Equal(t, stateClosed, p.members[0].breaker.state, "a bad answer says nothing about the provider's health")
+
+	label, err := p.Classify(ctx, "x")
+	require.NoError(t, err)
+	assert.Equal(t, "primary", label)
+}
+
+func TestProvider_PermanentErrorsStop(t *testing.T) {
+	for _, permanent := range []error{errBadRequest, errUnrecognized} {
+		primary := &fakeProvider{errs: []error{permanent}}
+		fallback := &fakeProvider{label: "Work"}
+		p, _, _ := newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+
+		_, err := p.Classify(context.Background(), "x")
+		assert.ErrorIs(t, err, permanent)
+		assert.Equal(t, 1, primary.calls)
+		assert.Zero(t, fallback.calls)
+	}
+}
+
+func TestProvider_ExhaustedChainReturnsLastError(t *testing.T) {
+	primary := &fakeProvider{errs: []error{errBadKey}}
+	fallback := &fakeProvider{errs: []error{errOverloaded, errOverloaded}}
+	p, _, _ := newTestProvider(Options{MaxRetries: 1}, Named{"primary", primary}, Named{"fallback", fallback})
+
+	_, err := p.Classify(context.Background(), "x")
+	assert.ErrorIs(t, err, errOverloaded)
+	assert.EqualError(t, err, "fallback: fake: API error (status 503): overloaded")
+	assert.Equal(t, ai.ErrorRetryable, ai.ClassifyError(err), "asynq should retry the task later")
+}
+
+func TestProvider_CircuitBreaker(t *testing.T) {
+	primary := &fakeProvider{}
+	fallback := &fakeProvider{label: "fallback"}
+	p, _, now := newTestProvider(Options{MaxRetries: -1, BreakerThreshold: 2, BreakerCooldown: time.Minute},
+		Named{"primary", primary}, Named{"fallback", fallback})
+	ctx := context.Background()
+
+	primary.errs = []error{errOverloaded, errOverloaded}
+	for i := 0; i < 2; i++ {
+		_, err := p.Classify(ctx, "x")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, stateOpen, p.members[0].breaker.state)
+
+	// While open, calls skip the primary entirely.
+	primary.label = "primary"
+	label, err := p.Classify(ctx, "x")
+	require.NoError(t, err)
+	assert.Equal(t, "fallback", label)
+	assert.Equal(t, 2, primary.calls)
+
+	// After the cooldown a failed trial call re-opens the breaker at once...
+	*now = now.Add(time.Minute)
+	primary.errs = []error{errOverloaded}
+	label, _ = p.Classify(ctx, "x")
+	assert.Equal(t, "fallback", label)
+	assert.Equal(t, 3, primary.calls)
+	assert.Equal(t, stateOpen, p.members[0].breaker.state)
+
+	// ...and a successful one closes it.
+	*now = now.Add(time.Minute)
+	label, _ = p.Classify(ctx, "x")
+	assert.Equal(t, "primary", label)
+	assert.Equal(t, stateClosed, p.members[0].breaker.state)
+
+	// With every breaker open nothing is called and the error is retryable.
+	fallback.errs = []error{errOverloaded, errOverloaded}
+	primary.errs = []error{errOverloaded, errOverloaded}
+	for i := 0; i < 2; i++ {
+		_, _ = p.Classify(ctx, "x")
+	}
+	_, err = p.Classify(ctx, "x")
+	assert.ErrorIs(t, err, ai.ErrUnavailable)
+	assert.Equal(t, ai.ErrorRetryable, ai.ClassifyError(err))
+}
+
+func TestProvider_StreamChat(t *testing.T) {
+	collect := func(p *Provider) (string, error) {
+		ch := make(chan ai.ChatCompletionChunk, 10)
+		err := p.StreamChat(context.Background(), nil, ch)
+		var text strings.Builder
+		for chunk := range ch {
+			text.WriteString(chunk.Choices[0].Delta.Content)
+		}
+		return text.String(), err
+	}
+
+	// A stream that fails before any output moves to the fallback.
+	primary := &fakeProvider{errs: []error{errBadKey}}
+	fallback := &fakeProvider{chunks: []string{"Hel", "lo"}}
+	p, _, _ := newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+	text, err := collect(p)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", text)
+
+	// Once output has been sent, an error ends the stream.
+	primary = &fakeProvider{chunks: []string{"Hel"}, errs: []error{errOverloaded}}
+	fallback = &fakeProvider{chunks: []string{"Hello"}}
+	p, _, _ = newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+	text, err = collect(p)
+	assert.ErrorIs(t, err, errOverloaded)
+	assert.Equal(t, "Hel", text)
+	assert.Equal(t, 1, primary.calls)
+	assert.Zero(t, fallback.calls)
+}
+
+func TestProvider_StreamChatWithTools(t *testing.T) {
+	// Providers that cannot stream tool turns send the text at once; failures before it fail over.
+	primary := &fakeProvider{tools: true, errs: []error{errBadKey}}
+	fallback := &fakeProvider{label: "Hello", tools: true}
+	p, _, _ := newTestProvider(Options{}, Named{"primary", primary}, Named{"fallback", fallback})
+
+	ch := make(chan ai.ChatCompletionChunk, 10)
+	resp, err := p.StreamChatWithTools(context.Background(), nil, nil, ch)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", resp.Content)
+	var text []string
+	for chunk := range ch {
+		text = append(text, chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, []string{"Hello"}, text)
+}
+
+func TestProvider_ToolsSkipProvidersWithoutThem(t *testing.T) {
+	plain := &fakeProvider{label: "plain"}
+	withTools := &fakeProvider{label: "tools", tools: true}
+	p, _, _ := newTestProvider(Options{}, Named{"plain", plain}, Named{"tools", withTools})
+
+	caller, ok := ai.AsToolCaller(p)
+	require.True(t, ok)
+	resp, err := caller.ChatWithTools(context.Background(), nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tools", resp.Content)
+	assert.Zero(t, plain.calls)
+
+	p, _, _ = newTestProvider(Options{}, Named{"plain", plain})
+	_, ok = ai.AsToolCaller(p)
+	assert.False(t, ok)
+}
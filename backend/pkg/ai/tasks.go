@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// OutputFormat constrains the output of a completion.
+type OutputFormat int
+
+const (
+	FormatText     OutputFormat = iota
+	FormatJSON                  // Any JSON object
+	FormatAnalysis              // An object matching AnalysisJSONSchema
+)
+
+// CompleteFunc sends a chat history to the model and returns its reply, constrained to format as
+// far as the provider supports it. Providers that cannot constrain the output ask for it in the
+// system prompt instead.
+type CompleteFunc func(ctx context.Context, messages []Message, format OutputFormat) (string, error)
+
+const defaultDraftReplyPrompt = "You are an email assistant. Generate a professional email reply based on the provided email content and user instructions."
+
+// TextTasks implements the prompt-driven AIProvider methods on top of a provider's CompleteFunc.
+// Providers embed it, so that only completing and streaming differ between them.
+type TextTasks struct {
+	Prompts  map[string]string // Configured system prompts by name, e.g. PromptSummary
+	Complete CompleteFunc
+}
+
+// Summarize generates a structured analysis of text, see GenerateAnalysis.
+func (t TextTasks) Summarize(ctx context.Context, text string) (AnalysisResult, error) {
+	systemPrompt := ResolvePrompt(ctx, PromptSummary, t.Prompts[PromptSummary]).Text
+	if systemPrompt == "" {
+		return AnalysisResult{}, errors.New("summary prompt not configured")
+	}
+	return GenerateAnalysis(ctx, systemPrompt, text, func(ctx context.Context, messages []Message) (string, error) {
+		return t.Complete(ctx, messages, FormatAnalysis)
+	})
+}
+
+func (t TextTasks) Classify(ctx context.Context, text string) (string, error) {
+	systemPrompt := ResolvePrompt(ctx, PromptClassify, t.Prompts[PromptClassify]).Text
+	if systemPrompt == "" {
+		return "", errors.New("classify prompt not configured")
+	}
+	return t.complete(ctx, systemPrompt, text, FormatText)
+}
+
+// AnalyzeSentiment falls back to a neutral, medium-urgency result if the reply is not valid JSON.
+func (t TextTasks) AnalyzeSentiment(ctx context.Context, text string) (SentimentResult, error) {
+	systemPrompt := ResolvePrompt(ctx, PromptSentiment, t.Prompts[PromptSentiment]).Text
+	if systemPrompt == "" {
+		return SentimentResult{}, errors.New("sentiment prompt not configured")
+	}
+
+	response, err := t.complete(ctx, systemPrompt, text, FormatJSON)
+	if err != nil {
+		return SentimentResult{}, err
+	}
+
+	var result SentimentResult
+	if err := json.Unmarshal([]byte(stripCodeFence(response)), &result); err != nil {
+		return SentimentResult{Sentiment: "Neutral", Urgency: "Medium"}, nil
+	}
+	return result, nil
+}
+
+func (t TextTasks) GenerateDraftReply(ctx context.Context, emailContent, userPrompt string) (string, error) {
+	systemPrompt := ResolvePrompt(ctx, PromptDraftReply, t.Prompts[PromptDraftReply]).Text
+	if systemPrompt == "" {
+		systemPrompt = defaultDraftReplyPrompt
+	}
+	if emailContent == "" {
+		emailContent = "No email content provided."
+	}
+	if userPrompt == "" {
+		userPrompt = "Generate a brief, professional email reply."
+	}
+
+	fullUserPrompt := fmt.Sprintf("Original Email:\n%s\n\nUser Instructions:\n%s", emailContent, userPrompt)
+	return t.complete(ctx, systemPrompt, fullUserPrompt, FormatText)
+}
+
+// complete runs a single-turn completion.
+func (t TextTasks) complete(ctx context.Context, systemPrompt, userContent string, format OutputFormat) (string, error) {
+	return t.Complete(ctx, []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: userContent},
+	}, format)
+}
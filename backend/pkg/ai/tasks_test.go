@@ -0,0 +1,55 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextTasks(t *testing.T) {
+	var formats []OutputFormat
+	var last []Message
+	reply := ""
+	tasks := TextTasks{
+		Prompts: map[string]string{PromptClassify: "Classify.", PromptSentiment: "Rate.", PromptSummary: "Analyze."},
+		Complete: func(ctx context.Context, messages []Message, format OutputFormat) (string, error) {
+			formats = append(formats, format)
+			last = messages
+			return reply, nil
+		},
+	}
+	ctx := context.Background()
+
+	reply = "Work"
+	label, err := tasks.Classify(ctx, "Quarterly numbers")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", label)
+	assert.Equal(t, []Message{{Role: "system", Content: "Classify."}, {Role: "user", Content: "Quarterly numbers"}}, last)
+
+	reply = "```json\n{\"sentiment\": \"Negative\", \"urgency\": \"High\"}\n```"
+	sentiment, err := tasks.AnalyzeSentiment(ctx, "Where is my refund?")
+	require.NoError(t, err)
+	assert.Equal(t, SentimentResult{Sentiment: "Negative", Urgency: "High"}, sentiment)
+
+	reply = "not JSON"
+	sentiment, err = tasks.AnalyzeSentiment(ctx, "Hello")
+	require.NoError(t, err)
+	assert.Equal(t, SentimentResult{Sentiment: "Neutral", Urgency: "Medium"}, sentiment, "unparseable replies fall back")
+
+	reply = `{"summary":"Refund","category":"Work","sentiment":"Negative","urgency":"High"}`
+	_, err = tasks.Summarize(ctx, "Where is my refund?")
+	require.NoError(t, err)
+
+	reply = "Thanks, will do."
+	_, err = tasks.GenerateDraftReply(ctx, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, defaultDraftReplyPrompt, last[0].Content, "the draft prompt has a default")
+	assert.Contains(t, last[1].Content, "No email content provided.")
+
+	assert.Equal(t, []OutputFormat{FormatText, FormatJSON, FormatJSON, FormatAnalysis, FormatText}, formats)
+
+	_, err = TextTasks{Complete: tasks.Complete}.Classify(ctx, "x")
+	assert.EqualError(t, err, "classify prompt not configured")
+}
@@ -0,0 +1,70 @@
+package ai
+
+import "context"
+
+// ToolCaller is implemented by providers that support function calling.
+type ToolCaller interface {
+	// ChatWithTools runs one model turn in which the model may answer or request tool calls.
+	ChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition) (ToolChatResponse, error)
+}
+
+// ToolStreamer is implemented by tool callers that can stream a turn's text as it is generated.
+type ToolStreamer interface {
+	// StreamChatWithTools runs one model turn like ChatWithTools, sending its text to ch as it
+	// arrives. It closes ch when done.
+	StreamChatWithTools(ctx context.Context, messages []Message, tools []ToolDefinition, ch chan<- ChatCompletionChunk) (ToolChatResponse, error)
+}
+
+// StreamChatWithTools runs one model turn through caller, streaming its text when caller is a
+// ToolStreamer and sending it as a single chunk otherwise. Like the providers, it closes ch when done.
+func StreamChatWithTools(ctx context.Context, caller ToolCaller, messages []Message, tools []ToolDefinition, ch chan<- ChatCompletionChunk) (ToolChatResponse, error) {
+	if streamer, ok := caller.(ToolStreamer); ok {
+		return streamer.StreamChatWithTools(ctx, messages, tools, ch)
+	}
+	defer close(ch)
+	resp, err := caller.ChatWithTools(ctx, messages, tools)
+	if err == nil && resp.Content != "" {
+		select {
+		case ch <- ChatCompletionChunk{Choices: []Choice{{Index: 0, Delta: DeltaContent{Content: resp.Content}}}}:
+		case <-ctx.Done():
+		}
+	}
+	return resp, err
+}
+
+// ToolSupporter is implemented by wrappers whose support for tools depends on the providers they wrap.
+type ToolSupporter interface {
+	SupportsTools() bool
+}
+
+// AsToolCaller returns p's function-calling interface if it can actually run tool calls.
+func AsToolCaller(p AIProvider) (ToolCaller, bool) {
+	caller, ok := p.(ToolCaller)
+	if !ok {
+		return nil, false
+	}
+	if s, ok := p.(ToolSupporter); ok && !s.SupportsTools() {
+		return nil, false
+	}
+	return caller, true
+}
+
+// ToolDefinition describes a function the model may call.
+type ToolDefinition struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Parameters  *Schema `json:"parameters"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID        string `json:"id"` // Empty for providers without call IDs; results are then matched by name
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"` // JSON object
+}
+
+// ToolChatResponse is the outcome of a model turn with tools: text, tool calls, or both.
+type ToolChatResponse struct {
+	Content   string
+	ToolCalls []ToolCall
+}
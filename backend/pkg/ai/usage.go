@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CapabilityEmbedding labels usage from embedding calls. Embeddings are not routed per capability
+// and are never refused for quota, since search depends on them.
+const CapabilityEmbedding Capability = "embedding"
+
+// ErrQuotaExceeded is returned instead of calling a provider once the caller's usage quota is spent.
+var ErrQuotaExceeded = errors.New("ai: usage quota exceeded")
+
+// Usage is the token count of one provider call.
+type Usage struct {
+	Capability       Capability
+	Provider         string // Configured provider name, e.g. "deepseek"; the protocol if unknown
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	Estimated        bool // The API reported no counts, so they were estimated from the text
+}
+
+// WithEstimates fills counts the API did not report with estimates from the prompt and completion.
+func (u Usage) WithEstimates(prompt, completion string) Usage {
+	if u.PromptTokens == 0 && prompt != "" {
+		u.PromptTokens = EstimateTokens(prompt)
+		u.Estimated = true
+	}
+	if u.CompletionTokens == 0 && completion != "" {
+		u.CompletionTokens = EstimateTokens(completion)
+		u.Estimated = true
+	}
+	return u
+}
+
+// UsageMeter accounts for the AI calls made on behalf of a user. It travels in the context so that
+// providers can report what they used without knowing who they work for.
+type UsageMeter interface {
+	// Allow returns an error wrapping ErrQuotaExceeded when no more calls of capability may be made.
+	Allow(ctx context.Context, capability Capability) error
+	// Record stores the usage of a completed call.
+	Record(ctx context.Context, usage Usage)
+}
+
+type usageMeterKey struct{}
+type capabilityKey struct{}
+type providerNameKey struct{}
+
+// WithUsageMeter returns a context whose AI calls are accounted to meter.
+func WithUsageMeter(ctx context.Context, meter UsageMeter) context.Context {
+	return context.WithValue(ctx, usageMeterKey{}, meter)
+}
+
+// WithCapability labels the calls made with ctx.
+func WithCapability(ctx context.Context, capability Capability) context.Context {
+	return context.WithValue(ctx, capabilityKey{}, capability)
+}
+
+// WithProviderName records which configured provider serves the calls made with ctx.
+func WithProviderName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, providerNameKey{}, name)
+}
+
+// CheckQuota asks the context's meter whether a call of capability may be made. Without a meter
+// every call is allowed.
+func CheckQuota(ctx context.Context, capability Capability) error {
+	meter, ok := ctx.Value(usageMeterKey{}).(UsageMeter)
+	if !ok {
+		return nil
+	}
+	return meter.Allow(ctx, capability)
+}
+
+// ReportUsage hands usage to the context's meter, labelled with the context's capability and
+// provider name. Providers call it after every API call.
+func ReportUsage(ctx context.Context, usage Usage) {
+	meter, ok := ctx.Value(usageMeterKey{}).(UsageMeter)
+	if !ok {
+		return
+	}
+	if capability, ok := ctx.Value(capabilityKey{}).(Capability); ok && usage.Capability == "" {
+		usage.Capability = capability
+	}
+	if usage.Capability == "" {
+		usage.Capability = CapabilityChat
+	}
+	if name, ok := ctx.Value(providerNameKey{}).(string); ok && name != "" {
+		usage.Provider = name
+	}
+	meter.Record(ctx, usage)
+}
+
+// EstimateTokens approximates how many tokens texts take: about four characters per token for
+// Latin scripts and one token per character for CJK scripts.
+func EstimateTokens(texts ...string) int {
+	var latin, cjk int
+	for _, text := range texts {
+		for _, r := range text {
+			if r >= utf8.RuneSelf && (unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+				unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)) {
+				cjk++
+			} else {
+				latin++
+			}
+		}
+	}
+	return cjk + (latin+3)/4
+}
+
+// MessagesText joins the contents of a chat history, for estimating its prompt tokens.
+func MessagesText(messages []Message) string {
+	var text strings.Builder
+	for _, msg := range messages {
+		text.WriteString(msg.Content)
+		text.WriteString("\n")
+	}
+	return text.String()
+}
@@ -0,0 +1,42 @@
+package ai
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMeter struct {
+	usages []Usage
+	allow  error
+}
+
+func (m *recordingMeter) Allow(ctx context.Context, capability Capability) error { return m.allow }
+func (m *recordingMeter) Record(ctx context.Context, usage Usage)                { m.usages = append(m.usages, usage) }
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, EstimateTokens(""))
+	assert.Equal(t, 3, EstimateTokens("Hello world!"))
+	assert.Equal(t, 4, EstimateTokens("你好世界"), "one token per CJK character")
+	assert.Equal(t, 4, EstimateTokens("会议 at 3pm"))
+}
+
+func TestReportUsage(t *testing.T) {
+	// Without a meter nothing happens and every call is allowed.
+	ReportUsage(context.Background(), Usage{PromptTokens: 1})
+	assert.NoError(t, CheckQuota(context.Background(), CapabilityChat))
+
+	meter := &recordingMeter{allow: ErrQuotaExceeded}
+	ctx := WithUsageMeter(context.Background(), meter)
+	assert.ErrorIs(t, CheckQuota(ctx, CapabilityChat), ErrQuotaExceeded)
+
+	ReportUsage(ctx, Usage{Provider: "openai", PromptTokens: 1})
+	ReportUsage(WithProviderName(WithCapability(ctx, CapabilityDraft), "deepseek"),
+		Usage{Provider: "openai"}.WithEstimates("Hello world!", ""))
+
+	assert.Equal(t, []Usage{
+		{Capability: CapabilityChat, Provider: "openai", PromptTokens: 1},
+		{Capability: CapabilityDraft, Provider: "deepseek", PromptTokens: 3, Estimated: true},
+	}, meter.usages)
+}
@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	widgetOpenPrefix = "<widget"
+	widgetCloseTag   = "</widget>"
+	// maxWidgetTagBytes bounds how long an opening tag may be before the text is treated as prose.
+	maxWidgetTagBytes = 128
+	// maxWidgetBodyBytes bounds a block's body; a block that never closes is given up on after this.
+	maxWidgetBodyBytes = 64 << 10
+)
+
+var widgetOpenTagPattern = regexp.MustCompile(`^<widget\s+type\s*=\s*["']([A-Za-z0-9_-]+)["']\s*>$`)
+
+// widgetSpec describes the payload of a widget type the model may emit.
+type widgetSpec struct {
+	schema *Schema
+	// wrap is the property a bare JSON array is stored under, for types whose payload is a list.
+	wrap string
+	// check validates what the schema cannot express.
+	check func(data map[string]interface{}) error
+}
+
+var widgetSpecs = map[string]widgetSpec{
+	"task_list": {
+		schema: &Schema{Type: "object", Required: []string{"tasks"}, Properties: map[string]*Schema{
+			"tasks": {Type: "array", Items: &Schema{Type: "object", Required: []string{"title"}, Properties: map[string]*Schema{
+				"title":    {Type: "string"},
+				"due":      {Type: "string"},
+				"priority": {Type: "string"},
+			}}},
+		}},
+		wrap: "tasks",
+		check: func(data map[string]interface{}) error {
+			tasks := data["tasks"].([]interface{})
+			if len(tasks) == 0 {
+				return errors.New("tasks is empty")
+			}
+			for i, t := range tasks {
+				task := t.(map[string]interface{})
+				if strings.TrimSpace(task["title"].(string)) == "" {
+					return fmt.Errorf("tasks[%d].title is empty", i)
+				}
+				if due, ok := task["due"].(string); ok && due != "" {
+					if _, err := parseWidgetTime(due); err != nil {
+						return fmt.Errorf("tasks[%d].due: %w", i, err)
+					}
+				}
+			}
+			return nil
+		},
+	},
+	"email_draft": {
+		schema: &Schema{Type: "object", Required: []string{"body"}, Properties: map[string]*Schema{
+			"to":      {Type: "string"},
+			"subject": {Type: "string"},
+			"body":    {Type: "string"},
+		}},
+		check: func(data map[string]interface{}) error {
+			if strings.TrimSpace(data["body"].(string)) == "" {
+				return errors.New("body is empty")
+			}
+			return nil
+		},
+	},
+	"calendar_event": {
+		schema: &Schema{Type: "object", Required: []string{"title", "start"}, Properties: map[string]*Schema{
+			"title":       {Type: "string"},
+			"start":       {Type: "string"},
+			"end":         {Type: "string"},
+			"location":    {Type: "string"},
+			"description": {Type: "string"},
+		}},
+		check: func(data map[string]interface{}) error {
+			start, err := parseWidgetTime(data["start"].(string))
+			if err != nil {
+				return fmt.Errorf("start: %w", err)
+			}
+			if end, ok := data["end"].(string); ok && end != "" {
+				endTime, err := parseWidgetTime(end)
+				if err != nil {
+					return fmt.Errorf("end: %w", err)
+				}
+				if endTime.Before(start) {
+					return errors.New("end is before start")
+				}
+			}
+			return nil
+		},
+	},
+}
+
+var widgetTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+func parseWidgetTime(value string) (time.Time, error) {
+	for _, layout := range widgetTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date or time", value)
+}
+
+// ParseWidget validates the JSON body of a widget block against the schema of its type. List
+// payloads given as a bare array are stored under their property, e.g. {"tasks": [...]}.
+func ParseWidget(widgetType, body string) (*WidgetData, error) {
+	spec, ok := widgetSpecs[widgetType]
+	if !ok {
+		return nil, fmt.Errorf("unknown widget type %q", widgetType)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &value); err != nil {
+		return nil, fmt.Errorf("invalid %s widget JSON: %w", widgetType, err)
+	}
+	if list, ok := value.([]interface{}); ok && spec.wrap != "" {
+		value = map[string]interface{}{spec.wrap: list}
+	}
+	if err := spec.schema.Validate(value); err != nil {
+		return nil, fmt.Errorf("invalid %s widget: %w", widgetType, err)
+	}
+	data := value.(map[string]interface{})
+	if spec.check != nil {
+		if err := spec.check(data); err != nil {
+			return nil, fmt.Errorf("invalid %s widget: %w", widgetType, err)
+		}
+	}
+	return &WidgetData{Type: widgetType, Data: data}, nil
+}
+
+// WidgetBlock renders a widget as the block the model writes, so that a saved answer keeps it.
+func WidgetBlock(w *WidgetData) string {
+	body, err := json.Marshal(w.Data)
+	if err != nil {
+		body = []byte("{}")
+	}
+	return fmt.Sprintf(`<widget type="%s">%s</widget>`, w.Type, body)
+}
+
+// WidgetParser extracts <widget type="...">JSON</widget> blocks from streamed text. Tags and bodies
+// may be split across any number of deltas. Valid blocks become widget deltas; text outside blocks
+// passes through in order. Blocks that fail validation are passed through as text, so nothing the
+// model wrote is lost.
+type WidgetParser struct {
+	pending    string // Text that may still turn out to be part of a tag
+	widgetType string // Type of the open block; empty outside a block
+	openTag    string // Raw opening tag of the open block, kept for the fallback
+}
+
+func NewWidgetParser() *WidgetParser {
+	return &WidgetParser{}
+}
+
+// Feed consumes the next text delta and returns the deltas that can be emitted so far.
+func (p *WidgetParser) Feed(text string) []DeltaContent {
+	p.pending += text
+	var out []DeltaContent
+	emitText := func(s string) {
+		if s == "" {
+			return
+		}
+		if last := len(out) - 1; last >= 0 && out[last].Widget == nil {
+			out[last].Content += s
+			return
+		}
+		out = append(out, DeltaContent{Content: s})
+	}
+
+	for {
+		if p.widgetType == "" {
+			i := strings.Index(p.pending, widgetOpenPrefix)
+			if i < 0 {
+				// Hold back a trailing "<wid" that the next delta may complete.
+				keep := partialPrefixLen(p.pending, widgetOpenPrefix)
+				emitText(p.pending[:len(p.pending)-keep])
+				p.pending = p.pending[len(p.pending)-keep:]
+				return out
+			}
+			emitText(p.pending[:i])
+			p.pending = p.pending[i:]
+
+			end := strings.IndexByte(p.pending, '>')
+			if end < 0 && len(p.pending) <= maxWidgetTagBytes {
+				return out // The opening tag is not complete yet
+			}
+			var m []string
+			if end >= 0 {
+				m = widgetOpenTagPattern.FindStringSubmatch(p.pending[:end+1])
+			}
+			if m == nil {
+				// Not a widget tag after all.
+				emitText(p.pending[:1])
+				p.pending = p.pending[1:]
+				continue
+			}
+			p.widgetType, p.openTag = m[1], m[0]
+			p.pending = p.pending[end+1:]
+			continue
+		}
+
+		j := strings.Index(p.pending, widgetCloseTag)
+		if j < 0 {
+			if len(p.pending) > maxWidgetBodyBytes {
+				emitText(p.openTag + p.pending)
+				p.pending, p.widgetType, p.openTag = "", "", ""
+			}
+			return out
+		}
+		raw := p.openTag + p.pending[:j+len(widgetCloseTag)]
+		if widget, err := ParseWidget(p.widgetType, p.pending[:j]); err == nil {
+			out = append(out, DeltaContent{Widget: widget})
+		} else {
+			emitText(raw)
+		}
+		p.pending = p.pending[j+len(widgetCloseTag):]
+		p.widgetType, p.openTag = "", ""
+	}
+}
+
+// Flush ends the stream and returns whatever is still held back. An unterminated block is returned
+// as text.
+func (p *WidgetParser) Flush() []DeltaContent {
+	rest := p.openTag + p.pending
+	p.pending, p.widgetType, p.openTag = "", "", ""
+	if rest == "" {
+		return nil
+	}
+	return []DeltaContent{{Content: rest}}
+}
+
+// partialPrefixLen returns the length of the longest suffix of s that is a proper prefix of tag.
+func partialPrefixLen(s, tag string) int {
+	for n := len(tag) - 1; n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
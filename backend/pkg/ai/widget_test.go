@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// feedAll streams text through a parser in chunks of size n and joins the output.
+func feedAll(text string, n int) (string, []*WidgetData) {
+	p := NewWidgetParser()
+	var deltas []DeltaContent
+	for i := 0; i < len(text); i += n {
+		end := i + n
+		if end > len(text) {
+			end = len(text)
+		}
+		deltas = append(deltas, p.Feed(text[i:end])...)
+	}
+	deltas = append(deltas, p.Flush()...)
+
+	var out strings.Builder
+	var widgets []*WidgetData
+	for _, d := range deltas {
+		out.WriteString(d.Content)
+		if d.Widget != nil {
+			widgets = append(widgets, d.Widget)
+		}
+	}
+	return out.String(), widgets
+}
+
+func TestWidgetParser_SplitAtEveryBoundary(t *testing.T) {
+	text := `Draft below. <widget type="email_draft">{"to": "bob@example.com", "subject": "Q3", "body": "Hi Bob, 5 < 6."}</widget> Done.`
+	for n := 1; n <= len(text); n++ {
+		out, widgets := feedAll(text, n)
+		assert.Equal(t, "Draft below.  Done.", out, "chunk size %d", n)
+		require.Len(t, widgets, 1, "chunk size %d", n)
+		assert.Equal(t, "email_draft", widgets[0].Type)
+		assert.Equal(t, "Hi Bob, 5 < 6.", widgets[0].Data["body"])
+	}
+}
+
+func TestWidgetParser_FallsBackToText(t *testing.T) {
+	cases := map[string]string{
+		"malformed JSON":     `See <widget type="task_list">[{"title": "A"</widget> ok`,
+		"schema violation":   `See <widget type="calendar_event">{"title": "Sync", "start": "2025-05-02T10:00", "end": "2025-05-02T09:00"}</widget> ok`,
+		"unknown type":       `See <widget type="poll">{"question": "?"}</widget> ok`,
+		"not a widget tag":   `Use <widgets> or <widget-like> tags <b>freely</b>`,
+		"unterminated block": `See <widget type="task_list">[{"title": "A"}]`,
+		"trailing prefix":    `Ends with <wid`,
+	}
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			out, widgets := feedAll(text, 7)
+			assert.Equal(t, text, out, "the text is passed through unchanged")
+			assert.Empty(t, widgets)
+		})
+	}
+}
+
+func TestWidgetParser_MultipleBlocks(t *testing.T) {
+	text := `<widget type='task_list'>{"tasks": [{"title": "A", "due": "2025-05-02"}]}</widget>and<widget type="calendar_event">{"title": "Sync", "start": "2025-05-02 10:00"}</widget>`
+	out, widgets := feedAll(text, 5)
+	assert.Equal(t, "and", out)
+	require.Len(t, widgets, 2)
+	assert.Equal(t, "task_list", widgets[0].Type)
+	assert.Equal(t, "calendar_event", widgets[1].Type)
+}
+
+func TestParseWidget(t *testing.T) {
+	widget, err := ParseWidget("task_list", `[{"title": "Call Alice", "priority": "High"}]`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{map[string]interface{}{"title": "Call Alice", "priority": "High"}}, widget.Data["tasks"],
+		"a bare list is stored under its property")
+
+	_, err = ParseWidget("task_list", `[{"title": "Call Alice", "due": "next week"}]`)
+	assert.EqualError(t, err, `invalid task_list widget: tasks[0].due: "next week" is not a date or time`)
+
+	_, err = ParseWidget("task_list", `[{"title": 3}]`)
+	assert.EqualError(t, err, "invalid task_list widget: tasks[0].title: must be a string")
+
+	_, err = ParseWidget("email_draft", `{"subject": "Hi"}`)
+	assert.EqualError(t, err, "invalid email_draft widget: body is required")
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &Schema{Type: "object", Required: []string{"count"}, Properties: map[string]*Schema{
+		"count": {Type: "integer"},
+		"level": {Type: "string", Enum: []string{"low", "high"}},
+		"flags": {Type: "array", Items: &Schema{Type: "boolean"}},
+	}}
+
+	assert.NoError(t, schema.Validate(map[string]interface{}{"count": 2.0, "level": "low", "flags": []interface{}{true}, "extra": "ok"}))
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"count": 2.5}), "count: must be an integer")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"count": 1.0, "level": "mid"}), "level: must be one of low, high")
+	assert.EqualError(t, schema.Validate(map[string]interface{}{"count": 1.0, "flags": []interface{}{"yes"}}), "flags[0]: must be a boolean")
+	assert.EqualError(t, schema.Validate([]interface{}{}), "must be an object")
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VectorIndexType is a pgvector approximate nearest-neighbour index method.
+type VectorIndexType string
+
+const (
+	VectorIndexHNSW    VectorIndexType = "hnsw"
+	VectorIndexIVFFlat VectorIndexType = "ivfflat"
+)
+
+// Defaults match pgvector's own defaults.
+const (
+	DefaultHNSWM              = 16
+	DefaultHNSWEfConstruction = 64
+	DefaultIVFFlatLists       = 100
+)
+
+// VectorIndexOptions describes a cosine-distance ANN index on a pgvector column.
+type VectorIndexOptions struct {
+	Type           VectorIndexType
+	M              int // HNSW: max connections per layer
+	EfConstruction int // HNSW: candidate list size while building
+	Lists          int // IVFFlat: number of inverted lists (rows/1000 is a good start up to 1M rows)
+}
+
+// WithDefaults fills unset parameters. An empty type means HNSW.
+func (o VectorIndexOptions) WithDefaults() VectorIndexOptions {
+	if o.Type == "" {
+		o.Type = VectorIndexHNSW
+	}
+	o.Type = VectorIndexType(strings.ToLower(string(o.Type)))
+	if o.M <= 0 {
+		o.M = DefaultHNSWM
+	}
+	if o.EfConstruction <= 0 {
+		o.EfConstruction = DefaultHNSWEfConstruction
+	}
+	if o.Lists <= 0 {
+		o.Lists = DefaultIVFFlatLists
+	}
+	return o
+}
+
+// CreateVectorIndexSQL renders CREATE INDEX for the given options. CONCURRENTLY avoids blocking writes
+// while building but cannot run inside a transaction.
+func CreateVectorIndexSQL(name, table, column string, opts VectorIndexOptions, concurrently bool) (string, error) {
+	opts = opts.WithDefaults()
+
+	var with string
+	switch opts.Type {
+	case VectorIndexHNSW:
+		if opts.EfConstruction < 2*opts.M {
+			return "", fmt.Errorf("hnsw ef_construction (%d) must be at least 2*m (%d)", opts.EfConstruction, 2*opts.M)
+		}
+		with = fmt.Sprintf("m = %d, ef_construction = %d", opts.M, opts.EfConstruction)
+	case VectorIndexIVFFlat:
+		with = fmt.Sprintf("lists = %d", opts.Lists)
+	default:
+		return "", fmt.Errorf("unsupported vector index type %q (use hnsw or ivfflat)", opts.Type)
+	}
+
+	concurrent := ""
+	if concurrently {
+		concurrent = "CONCURRENTLY "
+	}
+	return fmt.Sprintf("CREATE INDEX %sIF NOT EXISTS %s ON %s USING %s (%s vector_cosine_ops) WITH (%s)",
+		concurrent, name, table, opts.Type, column, with), nil
+}
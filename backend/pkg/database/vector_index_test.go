@@ -0,0 +1,24 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateVectorIndexSQL(t *testing.T) {
+	sql, err := CreateVectorIndexSQL("idx", "email_embeddings", "vector", VectorIndexOptions{}, false)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE INDEX IF NOT EXISTS idx ON email_embeddings USING hnsw (vector vector_cosine_ops) WITH (m = 16, ef_construction = 64)", sql)
+
+	sql, err = CreateVectorIndexSQL("idx", "email_embeddings", "vector", VectorIndexOptions{Type: "IVFFlat", Lists: 250}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx ON email_embeddings USING ivfflat (vector vector_cosine_ops) WITH (lists = 250)", sql)
+
+	_, err = CreateVectorIndexSQL("idx", "email_embeddings", "vector", VectorIndexOptions{M: 32, EfConstruction: 40}, false)
+	assert.Error(t, err, "ef_construction below 2*m")
+
+	_, err = CreateVectorIndexSQL("idx", "email_embeddings", "vector", VectorIndexOptions{Type: "diskann"}, false)
+	assert.Error(t, err)
+}
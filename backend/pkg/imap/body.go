@@ -10,12 +10,21 @@ import (
 
 // ExtractBody extracts the plain text and HTML bodies from a mail reader.
 func ExtractBody(r io.Reader) (string, string, error) {
+	text, html, _, err := ExtractContent(r)
+	return text, html, err
+}
+
+// ExtractContent extracts the plain text and HTML bodies from a mail reader and
+// reports whether the message carries at least one attachment part.
+// Attachment parts are never copied into the bodies, even when they are text.
+func ExtractContent(r io.Reader) (string, string, bool, error) {
 	mr, err := mail.CreateReader(r)
 	if err != nil {
-		return "", "", err
+		return "", "", false, err
 	}
 
 	var textBody, htmlBody bytes.Buffer
+	hasAttachment := false
 
 	// Check if it's a multipart message
 	// If not multipart, we process the root entity directly.
@@ -37,7 +46,12 @@ func ExtractBody(r io.Reader) (string, string, error) {
 			break
 		}
 		if err != nil {
-			return "", "", err
+			return "", "", false, err
+		}
+
+		if _, ok := p.Header.(*mail.AttachmentHeader); ok {
+			hasAttachment = true
+			continue
 		}
 
 		contentType := p.Header.Get("Content-Type")
@@ -45,14 +59,14 @@ func ExtractBody(r io.Reader) (string, string, error) {
 
 		if strings.Contains(strings.ToLower(contentType), "text/plain") {
 			if _, err := io.Copy(&textBody, p.Body); err != nil {
-				return "", "", err
+				return "", "", false, err
 			}
 		} else if strings.Contains(strings.ToLower(contentType), "text/html") {
 			if _, err := io.Copy(&htmlBody, p.Body); err != nil {
-				return "", "", err
+				return "", "", false, err
 			}
 		}
 	}
 
-	return textBody.String(), htmlBody.String(), nil
+	return textBody.String(), htmlBody.String(), hasAttachment, nil
 }
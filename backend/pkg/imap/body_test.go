@@ -58,3 +58,29 @@ func TestExtractBody_Simple(t *testing.T) {
 		t.Errorf("Expected 'Simple body.', got '%s'", textBody)
 	}
 }
+
+func TestExtractContent_Attachment(t *testing.T) {
+	rawEmail := "Content-Type: multipart/mixed; boundary=mixed\r\n" +
+		"\r\n" +
+		"--mixed\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"\r\n" +
+		"See attached.\r\n" +
+		"--mixed\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"Content-Disposition: attachment; filename=notes.txt\r\n" +
+		"\r\n" +
+		"attachment contents\r\n" +
+		"--mixed--\r\n"
+
+	textBody, _, hasAttachment, err := ExtractContent(strings.NewReader(rawEmail))
+	if err != nil {
+		t.Fatalf("ExtractContent failed: %v", err)
+	}
+	if !hasAttachment {
+		t.Error("Expected attachment to be detected")
+	}
+	if strings.TrimSpace(textBody) != "See attached." {
+		t.Errorf("Expected attachment to be excluded from body, got '%s'", textBody)
+	}
+}
@@ -16,6 +16,8 @@ type EmailData struct {
 	MessageID string
 	BodyText  string
 	BodyHTML  string
+
+	HasAttachments bool
 }
 
 // FetchEmails fetches the latest N messages' data (including body) from the specified mailbox.
@@ -70,6 +72,7 @@ func FetchEmails(c *client.Client, mailbox string, limit int) ([]EmailData, erro
 
 		// Extract Body
 		var bodyText, bodyHTML string
+		var hasAttachments bool
 
 		// We requested only one body section, so we can just take the first one found.
 		// This avoids potential issues with BodySectionName pointer equality in tests/mocks.
@@ -80,7 +83,7 @@ func FetchEmails(c *client.Client, mailbox string, limit int) ([]EmailData, erro
 		}
 
 		if r != nil {
-			bodyText, bodyHTML, _ = ExtractBody(r)
+			bodyText, bodyHTML, hasAttachments, _ = ExtractContent(r)
 		}
 
 		// Extract To and Cc
@@ -102,6 +105,8 @@ func FetchEmails(c *client.Client, mailbox string, limit int) ([]EmailData, erro
 			MessageID: msg.Envelope.MessageId,
 			BodyText:  bodyText,
 			BodyHTML:  bodyHTML,
+
+			HasAttachments: hasAttachments,
 		})
 	}
 
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/server"
 )
 
@@ -50,3 +51,22 @@ func TestFetchEmails(t *testing.T) {
 		t.Errorf("Expected BodyText 'This is a test body.', got '%s'", emails[0].BodyText)
 	}
 }
+
+func TestSentMailbox(t *testing.T) {
+	tests := []struct {
+		name  string
+		infos []*imap.MailboxInfo
+		want  string
+	}{
+		{"special use", []*imap.MailboxInfo{{Name: "INBOX"}, {Name: "Sent"}, {Name: "Gesendet", Attributes: []string{imap.SentAttr}}}, "Gesendet"},
+		{"usual name", []*imap.MailboxInfo{{Name: "INBOX"}, {Name: "sent items"}}, "sent items"},
+		{"none", []*imap.MailboxInfo{{Name: "INBOX"}, {Name: "Archive"}}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sentMailbox(tt.infos); got != tt.want {
+				t.Errorf("sentMailbox() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
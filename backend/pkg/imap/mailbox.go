@@ -0,0 +1,50 @@
+package imap
+
+import (
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// sentMailboxNames are the usual names of the sent-mail folder, for servers that do not mark it
+// with the \Sent special-use attribute.
+var sentMailboxNames = []string{"Sent", "Sent Items", "Sent Messages", "Sent Mail", "[Gmail]/Sent Mail", "INBOX.Sent", "已发送", "已发送邮件"}
+
+// FindSentMailbox returns the name of the mailbox that holds the user's sent mail, or "" if the
+// server has none.
+func FindSentMailbox(c *client.Client) (string, error) {
+	mailboxes := make(chan *imap.MailboxInfo, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.List("", "*", mailboxes)
+	}()
+
+	var infos []*imap.MailboxInfo
+	for info := range mailboxes {
+		infos = append(infos, info)
+	}
+	if err := <-done; err != nil {
+		return "", err
+	}
+	return sentMailbox(infos), nil
+}
+
+// sentMailbox picks the sent-mail folder: the one marked \Sent, else the first with a usual name.
+func sentMailbox(infos []*imap.MailboxInfo) string {
+	for _, info := range infos {
+		for _, attr := range info.Attributes {
+			if attr == imap.SentAttr {
+				return info.Name
+			}
+		}
+	}
+	for _, name := range sentMailboxNames {
+		for _, info := range infos {
+			if strings.EqualFold(info.Name, name) {
+				return info.Name
+			}
+		}
+	}
+	return ""
+}
@@ -23,7 +23,7 @@ func BenchmarkLoggerCreation(b *testing.B) {
 
 // BenchmarkLoggerCreationProduction 生产环境日志器创建性能
 func BenchmarkLoggerCreationProduction(b *testing.B) {
-	config := ProductionConfig()
+	config := testProductionConfig(b)
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
@@ -260,7 +260,7 @@ func BenchmarkJSONSerialization(b *testing.B) {
 
 // BenchmarkConcurrentLogging 测试并发日志记录性能
 func BenchmarkConcurrentLogging(b *testing.B) {
-	log, _ := NewLogger(ProductionConfig())
+	log, _ := NewLogger(testProductionConfig(b))
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
@@ -372,7 +372,7 @@ func BenchmarkComparisonWithZap(b *testing.B) {
 
 // 压力测试：大量日志记录
 func BenchmarkStressTest(b *testing.B) {
-	log, _ := NewLogger(ProductionConfig())
+	log, _ := NewLogger(testProductionConfig(b))
 
 	// 预热
 	for i := 0; i < 1000; i++ {
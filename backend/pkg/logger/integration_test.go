@@ -307,7 +307,7 @@ func TestContextPropagation(t *testing.T) {
 
 // TestConcurrentLogging 集成测试并发日志记录
 func TestConcurrentLogging(t *testing.T) {
-	logger, err := NewLogger(ProductionConfig())
+	logger, err := NewLogger(testProductionConfig(t))
 	if err != nil {
 		t.Fatalf("Failed to create logger: %v", err)
 	}
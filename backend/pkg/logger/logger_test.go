@@ -2,10 +2,19 @@ package logger
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
 	"time"
 )
 
+// testProductionConfig is ProductionConfig with its log file in a temporary directory, so tests
+// leave no files behind.
+func testProductionConfig(tb testing.TB) *Config {
+	config := ProductionConfig()
+	config.Output.File.Path = filepath.Join(tb.TempDir(), "app.log")
+	return config
+}
+
 func TestNewLogger(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -19,7 +28,7 @@ func TestNewLogger(t *testing.T) {
 		},
 		{
 			name:    "Production config",
-			config:  ProductionConfig(),
+			config:  testProductionConfig(t),
 			wantErr: false,
 		},
 		{
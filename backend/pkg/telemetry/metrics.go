@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 )
 
@@ -289,6 +290,92 @@ func NewAIMetrics(ctx context.Context) (*AIMetrics, error) {
 	}, nil
 }
 
+// AIResilienceMetrics defines OpenTelemetry metrics for retries and failover between AI providers
+type AIResilienceMetrics struct {
+	Retries            metric.Int64Counter
+	Failovers          metric.Int64Counter
+	ProviderErrors     metric.Int64Counter
+	BreakerTransitions metric.Int64Counter
+}
+
+// NewAIResilienceMetrics creates a new AIResilienceMetrics instance
+func NewAIResilienceMetrics(ctx context.Context) (*AIResilienceMetrics, error) {
+	meter := otel.Meter("echomind.ai")
+
+	retries, err := meter.Int64Counter(
+		"ai.retries.total",
+		metric.WithDescription("Total number of AI calls retried on the same provider"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	failovers, err := meter.Int64Counter(
+		"ai.failovers.total",
+		metric.WithDescription("Total number of AI calls handed to the next provider in the fallback chain"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	providerErrors, err := meter.Int64Counter(
+		"ai.provider.errors.total",
+		metric.WithDescription("Total number of failed AI provider calls by error class"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	breakerTransitions, err := meter.Int64Counter(
+		"ai.circuit_breaker.transitions.total",
+		metric.WithDescription("Total number of AI provider circuit breaker state changes"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AIResilienceMetrics{
+		Retries:            retries,
+		Failovers:          failovers,
+		ProviderErrors:     providerErrors,
+		BreakerTransitions: breakerTransitions,
+	}, nil
+}
+
+// RecordRetry records a retry of an operation on a provider
+func (m *AIResilienceMetrics) RecordRetry(ctx context.Context, provider, operation string) {
+	m.Retries.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("operation", operation),
+	))
+}
+
+// RecordFailover records an operation moving from one provider to the next
+func (m *AIResilienceMetrics) RecordFailover(ctx context.Context, from, to, operation string) {
+	m.Failovers.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("from", from),
+		attribute.String("to", to),
+		attribute.String("operation", operation),
+	))
+}
+
+// RecordProviderError records a failed provider call
+func (m *AIResilienceMetrics) RecordProviderError(ctx context.Context, provider, operation, class string) {
+	m.ProviderErrors.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("operation", operation),
+		attribute.String("class", class),
+	))
+}
+
+// RecordBreakerTransition records a circuit breaker entering a new state
+func (m *AIResilienceMetrics) RecordBreakerTransition(ctx context.Context, provider, state string) {
+	m.BreakerTransitions.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("provider", provider),
+		attribute.String("state", state),
+	))
+}
+
 // CacheMetrics defines OpenTelemetry metrics for cache operations
 type CacheMetrics struct {
 	// Latency histograms
@@ -15,3 +15,12 @@ func Slugify(s string) string {
 	// Trim dashes
 	return strings.Trim(s, "-")
 }
+
+// likeEscaper escapes the LIKE wildcards and the escape character itself.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes s for use in a LIKE or ILIKE pattern with ESCAPE '\', so that it matches
+// itself literally.
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
@@ -71,3 +71,8 @@ func TestChunker(t *testing.T) {
 		assert.Greater(t, len(chunks), 1)
 	})
 }
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "plain", EscapeLike("plain"))
+	assert.Equal(t, `100\% off\_now \\ later`, EscapeLike(`100% off_now \ later`))
+}